@@ -15,14 +15,17 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"text/tabwriter"
 	"text/template"
 	"time"
 
 	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/stats"
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/engine"
@@ -61,6 +64,7 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"cp", "Copy files/folders from a container's filesystem to the host path"},
 		{"diff", "Inspect changes on a container's filesystem"},
 		{"events", "Get real time events from the server"},
+		{"exec", "Run a command in an existing container"},
 		{"export", "Stream the contents of a container as a tar archive"},
 		{"history", "Show the history of an image"},
 		{"images", "List images"},
@@ -72,11 +76,13 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"login", "Register or log in to a Docker registry server"},
 		{"logout", "Log out from a Docker registry server"},
 		{"logs", "Fetch the logs of a container"},
+		{"network", "Manage networks"},
 		{"port", "Lookup the public-facing port that is NAT-ed to PRIVATE_PORT"},
 		{"pause", "Pause all processes within a container"},
 		{"ps", "List containers"},
 		{"pull", "Pull an image or a repository from a Docker registry server"},
 		{"push", "Push an image or a repository to a Docker registry server"},
+		{"rename", "Rename an existing container"},
 		{"restart", "Restart a running container"},
 		{"rm", "Remove one or more containers"},
 		{"rmi", "Remove one or more images"},
@@ -84,11 +90,14 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"save", "Save an image to a tar archive"},
 		{"search", "Search for an image on the Docker Hub"},
 		{"start", "Start a stopped container"},
+		{"stats", "Display a live stream of resource usage statistics for containers"},
 		{"stop", "Stop a running container"},
 		{"tag", "Tag an image into a repository"},
 		{"top", "Lookup the running processes of a container"},
 		{"unpause", "Unpause a paused container"},
+		{"update", "Update the resource limits of one or more containers"},
 		{"version", "Show the Docker version information"},
+		{"volume", "Manage volumes"},
 		{"wait", "Block until a container stops, then print its exit code"},
 	} {
 		help += fmt.Sprintf("    %-10.10s%s\n", command[0], command[1])
@@ -662,7 +671,7 @@ func (cli *DockerCli) CmdStart(args ...string) error {
 		v.Set("stderr", "1")
 
 		cErr = utils.Go(func() error {
-			return cli.hijack("POST", "/containers/"+cmd.Arg(0)+"/attach?"+v.Encode(), tty, in, cli.out, cli.err, nil)
+			return cli.hijack("POST", "/containers/"+cmd.Arg(0)+"/attach?"+v.Encode(), tty, in, cli.out, cli.err, nil, nil)
 		})
 	}
 
@@ -745,6 +754,68 @@ func (cli *DockerCli) CmdPause(args ...string) error {
 	return encounteredError
 }
 
+func (cli *DockerCli) CmdRename(args ...string) error {
+	cmd := cli.Subcmd("rename", "OLD_NAME NEW_NAME", "Rename an existing container")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	oldName, newName := cmd.Arg(0), cmd.Arg(1)
+	if _, _, err := readBody(cli.call("POST", fmt.Sprintf("/containers/%s/rename?name=%s", oldName, url.QueryEscape(newName)), nil, false)); err != nil {
+		fmt.Fprintf(cli.err, "%s\n", err)
+		return fmt.Errorf("Error: failed to rename container named %s", oldName)
+	}
+	return nil
+}
+
+func (cli *DockerCli) CmdUpdate(args ...string) error {
+	cmd := cli.Subcmd("update", "[OPTIONS] CONTAINER [CONTAINER...]", "Update the resource limits of one or more containers")
+	flMemoryString := cmd.String([]string{"m", "-memory"}, "", "Memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+	flCpuShares := cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
+	flCpuset := cmd.String([]string{"-cpuset"}, "", "CPUs in which to allow execution (0-3, 0,1)")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	resources := engine.Env{}
+	if *flMemoryString != "" {
+		memory, err := units.RAMInBytes(*flMemoryString)
+		if err != nil {
+			return err
+		}
+		resources.SetInt64("Memory", memory)
+	}
+	if *flCpuShares != 0 {
+		resources.SetInt64("CpuShares", *flCpuShares)
+	}
+	if *flCpuset != "" {
+		resources.Set("Cpuset", *flCpuset)
+	}
+	if resources.Len() == 0 {
+		return fmt.Errorf("You must provide at least one resource limit to update")
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("POST", "/containers/"+name+"/update", resources, false)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to update one or more containers")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
 func (cli *DockerCli) CmdInspect(args ...string) error {
 	cmd := cli.Subcmd("inspect", "CONTAINER|IMAGE [CONTAINER|IMAGE...]", "Return low-level information on a container or image")
 	tmplStr := cmd.String([]string{"f", "#format", "-format"}, "", "Format the output using the given go template.")
@@ -1181,7 +1252,7 @@ func (cli *DockerCli) CmdPush(args ...string) error {
 }
 //下载镜像
 func (cli *DockerCli) CmdPull(args ...string) error {
-	cmd := cli.Subcmd("pull", "NAME[:TAG]", "Pull an image or a repository from the registry")
+	cmd := cli.Subcmd("pull", "NAME[:TAG|@DIGEST]", "Pull an image or a repository from the registry")
 	tag := cmd.String([]string{"#t", "#-tag"}, "", "Download tagged image in a repository")
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -1248,12 +1319,13 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only show numeric IDs")
 	all := cmd.Bool([]string{"a", "-all"}, false, "Show all images (by default filter out the intermediate image layers)")
 	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
+	showDigests := cmd.Bool([]string{"-digests"}, false, "Show the digests of the images")
 	// FIXME: --viz and --tree are deprecated. Remove them in a future version.
 	flViz := cmd.Bool([]string{"#v", "#viz", "#-viz"}, false, "Output graph in graphviz format")
 	flTree := cmd.Bool([]string{"#t", "#tree", "#-tree"}, false, "Output graph in tree format")
 
 	flFilter := opts.NewListOpts(nil)
-	cmd.Var(&flFilter, []string{"f", "-filter"}, "Provide filter values (i.e. 'dangling=true')")
+	cmd.Var(&flFilter, []string{"f", "-filter"}, "Provide filter values (i.e. 'dangling=true', 'label=key=value')")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -1323,7 +1395,7 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 					startImage = image
 				}
 
-				for _, repotag := range image.GetList("RepoTags") {
+				for _, repotag := range append(image.GetList("RepoTags"), image.GetList("RepoDigests")...) {
 					if repotag == matchName {
 						startImage = image
 					}
@@ -1379,20 +1451,34 @@ func (cli *DockerCli) CmdImages(args ...string) error {
 
 		w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
 		if !*quiet {
-			fmt.Fprintln(w, "REPOSITORY\tTAG\tIMAGE ID\tCREATED\tVIRTUAL SIZE")
+			if *showDigests {
+				fmt.Fprintln(w, "REPOSITORY\tTAG\tDIGEST\tIMAGE ID\tCREATED\tVIRTUAL SIZE")
+			} else {
+				fmt.Fprintln(w, "REPOSITORY\tTAG\tIMAGE ID\tCREATED\tVIRTUAL SIZE")
+			}
 		}
 
 		for _, out := range outs.Data {
-			for _, repotag := range out.GetList("RepoTags") {
+			// The images referenced by digest have a line for each digest
+			for _, repoRef := range append(out.GetList("RepoTags"), out.GetList("RepoDigests")...) {
 
-				repo, tag := parsers.ParseRepositoryTag(repotag)
+				repo, ref := parsers.ParseRepositoryTag(repoRef)
+				tag, digest := ref, "<none>"
+				if utils.DigestReference(ref) {
+					tag, digest = "<none>", ref
+				}
 				outID := out.Get("Id")
 				if !*noTrunc {
 					outID = utils.TruncateID(outID)
 				}
 
 				if !*quiet {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\t%s\n", repo, tag, outID, units.HumanDuration(time.Now().UTC().Sub(time.Unix(out.GetInt64("Created"), 0))), units.HumanSize(out.GetInt64("VirtualSize")))
+					created := units.HumanDuration(time.Now().UTC().Sub(time.Unix(out.GetInt64("Created"), 0)))
+					if *showDigests {
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s ago\t%s\n", repo, tag, digest, outID, created, units.HumanSize(out.GetInt64("VirtualSize")))
+					} else {
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s ago\t%s\n", repo, tag, outID, created, units.HumanSize(out.GetInt64("VirtualSize")))
+					}
 				} else {
 					fmt.Fprintln(w, outID)
 				}
@@ -1486,7 +1572,7 @@ func (cli *DockerCli) CmdPs(args ...string) error {
 	last := cmd.Int([]string{"n"}, -1, "Show n last created containers, include non-running ones.")
 
 	flFilter := opts.NewListOpts(nil)
-	cmd.Var(&flFilter, []string{"f", "-filter"}, "Provide filter values. Valid filters:\nexited=<int> - containers with exit code of <int>")
+	cmd.Var(&flFilter, []string{"f", "-filter"}, "Provide filter values. Valid filters:\nexited=<int> - containers with exit code of <int>\nlabel=<key> or label=<key>=<value> - containers with the label <key>")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -1659,6 +1745,8 @@ func (cli *DockerCli) CmdEvents(args ...string) error {
 	cmd := cli.Subcmd("events", "[OPTIONS]", "Get real time events from the server")
 	since := cmd.String([]string{"#since", "-since"}, "", "Show all events created since timestamp")
 	until := cmd.String([]string{"-until"}, "", "Stream events until this timestamp")
+	flFilter := opts.NewListOpts(nil)
+	cmd.Var(&flFilter, []string{"f", "-filter"}, "Provide filter values (i.e. 'label=key=value')")
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
@@ -1688,6 +1776,22 @@ func (cli *DockerCli) CmdEvents(args ...string) error {
 	if *until != "" {
 		setTime("until", *until)
 	}
+
+	eventFilterArgs := filters.Args{}
+	for _, f := range flFilter.GetAll() {
+		var err error
+		eventFilterArgs, err = filters.ParseFlag(f, eventFilterArgs)
+		if err != nil {
+			return err
+		}
+	}
+	if len(eventFilterArgs) > 0 {
+		filterJson, err := filters.ToParam(eventFilterArgs)
+		if err != nil {
+			return err
+		}
+		v.Set("filters", filterJson)
+	}
 	if err := cli.stream("GET", "/events?"+v.Encode(), nil, cli.out, nil); err != nil {
 		return err
 	}
@@ -1849,7 +1953,7 @@ func (cli *DockerCli) CmdAttach(args ...string) error {
 		defer signal.StopCatch(sigc)
 	}
 
-	if err := cli.hijack("POST", "/containers/"+cmd.Arg(0)+"/attach?"+v.Encode(), tty, in, cli.out, cli.err, nil); err != nil {
+	if err := cli.hijack("POST", "/containers/"+cmd.Arg(0)+"/attach?"+v.Encode(), tty, in, cli.out, cli.err, nil, nil); err != nil {
 		return err
 	}
 
@@ -2131,7 +2235,7 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 		}
 
 		errCh = utils.Go(func() error {
-			return cli.hijack("POST", "/containers/"+runResult.Get("Id")+"/attach?"+v.Encode(), config.Tty, in, out, stderr, hijacked)
+			return cli.hijack("POST", "/containers/"+runResult.Get("Id")+"/attach?"+v.Encode(), config.Tty, in, out, stderr, hijacked, nil)
 		})
 	} else {
 		close(hijacked)
@@ -2311,3 +2415,602 @@ func (cli *DockerCli) CmdLoad(args ...string) error {
 	}
 	return nil
 }
+
+func (cli *DockerCli) CmdExec(args ...string) error {
+	cmd := cli.Subcmd("exec", "[OPTIONS] CONTAINER COMMAND [ARG...]", "Run a command in an existing container")
+
+	name, execConfig, err := runconfig.ParseExec(cmd, args)
+	if err != nil {
+		if err == runconfig.ErrMissingExecArgs {
+			cmd.Usage()
+			return nil
+		}
+		return err
+	}
+
+	var (
+		out, stderr io.Writer
+		in          io.ReadCloser
+	)
+
+	if execConfig.AttachStdin {
+		in = cli.in
+	}
+	if execConfig.AttachStdout {
+		out = cli.out
+	}
+	if execConfig.AttachStderr {
+		if execConfig.Tty {
+			stderr = cli.out
+		} else {
+			stderr = cli.err
+		}
+	}
+
+	header, err := cli.hijackResponse("POST", "/containers/"+name+"/exec", execConfig.Tty, in, out, stderr, nil, execConfig)
+	if err != nil {
+		return err
+	}
+
+	// Exit with the exit code of the process, which older daemons do not give
+	execID := header.Get("Docker-Exec-Id")
+	if execID == "" {
+		return nil
+	}
+	status, err := getExecExitCode(cli, execID)
+	if err != nil {
+		return err
+	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+type containerStats struct {
+	Name             string
+	CpuPercentage    float64
+	Memory           float64
+	MemoryLimit      float64
+	MemoryPercentage float64
+	NetworkRx        float64
+	NetworkTx        float64
+	BlockRead        float64
+	BlockWrite       float64
+	mu               sync.RWMutex
+	err              error
+}
+
+func (s *containerStats) Collect(cli *DockerCli) {
+	stream, _, err := cli.call("GET", "/containers/"+s.Name+"/stats", nil, false)
+	if err != nil {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		return
+	}
+	defer stream.Close()
+
+	var (
+		previousCpu    uint64
+		previousSystem uint64
+		dec            = json.NewDecoder(stream)
+	)
+	for {
+		var v *stats.Stats
+		if err := dec.Decode(&v); err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			return
+		}
+		var (
+			memPercent = calculateMemPercent(v)
+			cpuPercent = 0.0
+		)
+		// the first sample only serves as the reference for the next one
+		if previousSystem != 0 {
+			cpuPercent = calculateCpuPercent(previousCpu, previousSystem, v)
+		}
+		previousCpu = v.CpuStats.CpuUsage.TotalUsage
+		previousSystem = v.CpuStats.SystemUsage
+		blkRead, blkWrite := calculateBlockIO(v.BlkioStats)
+
+		s.mu.Lock()
+		s.CpuPercentage = cpuPercent
+		s.Memory = float64(v.MemoryStats.Usage)
+		s.MemoryLimit = float64(v.MemoryStats.Limit)
+		s.MemoryPercentage = memPercent
+		s.NetworkRx = float64(v.Network.RxBytes)
+		s.NetworkTx = float64(v.Network.TxBytes)
+		s.BlockRead = float64(blkRead)
+		s.BlockWrite = float64(blkWrite)
+		s.mu.Unlock()
+	}
+}
+
+func (s *containerStats) Display(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.err != nil {
+		return s.err
+	}
+	fmt.Fprintf(w, "%s\t%.2f%%\t%s/%s\t%.2f%%\t%s/%s\t%s/%s\n",
+		s.Name,
+		s.CpuPercentage,
+		units.HumanSize(int64(s.Memory)), units.HumanSize(int64(s.MemoryLimit)),
+		s.MemoryPercentage,
+		units.HumanSize(int64(s.NetworkRx)), units.HumanSize(int64(s.NetworkTx)),
+		units.HumanSize(int64(s.BlockRead)), units.HumanSize(int64(s.BlockWrite)))
+	return nil
+}
+
+func (cli *DockerCli) CmdStats(args ...string) error {
+	cmd := cli.Subcmd("stats", "CONTAINER [CONTAINER...]", "Display a live stream of one or more containers' resource usage statistics")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	names := cmd.Args()
+	sort.Strings(names)
+	var cStats []*containerStats
+	for _, n := range names {
+		s := &containerStats{Name: n}
+		cStats = append(cStats, s)
+		go s.Collect(cli)
+	}
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	for _ = range time.Tick(500 * time.Millisecond) {
+		// clear the screen and move the cursor to the top left corner
+		fmt.Fprint(cli.out, "\033[2J")
+		fmt.Fprint(cli.out, "\033[H")
+		fmt.Fprintln(w, "CONTAINER\tCPU %\tMEM USAGE/LIMIT\tMEM %\tNET I/O\tBLOCK I/O")
+		toRemove := []int{}
+		for i, s := range cStats {
+			if err := s.Display(w); err != nil {
+				toRemove = append(toRemove, i)
+			}
+		}
+		for j := len(toRemove) - 1; j >= 0; j-- {
+			i := toRemove[j]
+			cStats = append(cStats[:i], cStats[i+1:]...)
+		}
+		if len(cStats) == 0 {
+			return nil
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+func calculateCpuPercent(previousCpu, previousSystem uint64, v *stats.Stats) float64 {
+	var (
+		cpuPercent = 0.0
+		// calculate the change for the cpu usage of the container in between readings
+		cpuDelta = float64(v.CpuStats.CpuUsage.TotalUsage) - float64(previousCpu)
+		// calculate the change for the entire system between readings
+		systemDelta = float64(v.CpuStats.SystemUsage) - float64(previousSystem)
+	)
+
+	if systemDelta > 0.0 && cpuDelta > 0.0 {
+		cpuPercent = (cpuDelta / systemDelta) * float64(len(v.CpuStats.CpuUsage.PercpuUsage)) * 100.0
+	}
+	return cpuPercent
+}
+
+func calculateMemPercent(v *stats.Stats) float64 {
+	if v.MemoryStats.Limit == 0 {
+		return 0.0
+	}
+	return float64(v.MemoryStats.Usage) / float64(v.MemoryStats.Limit) * 100.0
+}
+
+func calculateBlockIO(blkio stats.BlkioStats) (blkRead uint64, blkWrite uint64) {
+	for _, bioEntry := range blkio.IoServiceBytesRecursive {
+		switch strings.ToLower(bioEntry.Op) {
+		case "read":
+			blkRead = blkRead + bioEntry.Value
+		case "write":
+			blkWrite = blkWrite + bioEntry.Value
+		}
+	}
+	return
+}
+
+// 'docker volume COMMAND' manages the volumes of the daemon
+func (cli *DockerCli) CmdVolume(args ...string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "create":
+			return cli.volumeCreate(args[1:]...)
+		case "inspect":
+			return cli.volumeInspect(args[1:]...)
+		case "ls":
+			return cli.volumeLs(args[1:]...)
+		case "prune":
+			return cli.volumePrune(args[1:]...)
+		case "rm":
+			return cli.volumeRm(args[1:]...)
+		}
+	}
+
+	description := "Manage volumes\n\nCommands:\n"
+	for _, command := range [][]string{
+		{"create", "Create a volume"},
+		{"inspect", "Return low-level information on a volume"},
+		{"ls", "List volumes"},
+		{"prune", "Remove all the volumes not used by any container"},
+		{"rm", "Remove one or more volumes"},
+	} {
+		description += fmt.Sprintf("    %-10.10s%s\n", command[0], command[1])
+	}
+	cmd := cli.Subcmd("volume", "COMMAND", description)
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	cmd.Usage()
+	return nil
+}
+
+func (cli *DockerCli) volumeCreate(args ...string) error {
+	cmd := cli.Subcmd("volume create", "[OPTIONS]", "Create a volume")
+	flName := cmd.String([]string{"-name"}, "", "Name of the volume, an anonymous volume is created when empty")
+	flDriver := cmd.String([]string{"d", "-driver"}, "local", "Driver storing the volume (local or the name of a volume plugin)")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("POST", "/volumes/create", map[string]string{"Name": *flName, "Driver": *flDriver}, false))
+	if err != nil {
+		return err
+	}
+	var vol engine.Env
+	if err := vol.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	if name := vol.Get("Name"); name != "" {
+		fmt.Fprintf(cli.out, "%s\n", name)
+	} else {
+		fmt.Fprintf(cli.out, "%s\n", vol.Get("Id"))
+	}
+	return nil
+}
+
+func (cli *DockerCli) volumeInspect(args ...string) error {
+	cmd := cli.Subcmd("volume inspect", "VOLUME [VOLUME...]", "Return low-level information on a volume")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	indented := new(bytes.Buffer)
+	indented.WriteByte('[')
+	status := 0
+
+	for _, name := range cmd.Args() {
+		obj, _, err := readBody(cli.call("GET", "/volumes/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if err := json.Indent(indented, obj, "", "    "); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		indented.WriteString(",")
+	}
+
+	if indented.Len() > 1 {
+		// Remove trailing ','
+		indented.Truncate(indented.Len() - 1)
+	}
+	indented.WriteString("]\n")
+
+	if _, err := io.Copy(cli.out, indented); err != nil {
+		return err
+	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+func (cli *DockerCli) volumeLs(args ...string) error {
+	cmd := cli.Subcmd("volume ls", "[OPTIONS]", "List volumes")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display volume IDs")
+	noTrunc := cmd.Bool([]string{"-no-trunc"}, false, "Don't truncate output")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/volumes", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("Created", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, "VOLUME ID\tNAME\tDRIVER\tCONTAINERS\tCREATED")
+	}
+	for _, out := range outs.Data {
+		id := out.Get("Id")
+		if !*noTrunc {
+			id = utils.TruncateID(id)
+		}
+		if *quiet {
+			fmt.Fprintln(w, id)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s ago\n", id, out.Get("Name"), out.Get("Driver"), len(out.GetList("Containers")),
+			units.HumanDuration(time.Now().UTC().Sub(time.Unix(out.GetInt64("Created"), 0))))
+	}
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) volumePrune(args ...string) error {
+	cmd := cli.Subcmd("volume prune", "", "Remove all the volumes not used by any container")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("POST", "/volumes/prune", nil, false))
+	if err != nil {
+		return err
+	}
+	var out engine.Env
+	if err := out.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	for _, id := range out.GetList("VolumesDeleted") {
+		fmt.Fprintf(cli.out, "%s\n", id)
+	}
+	return nil
+}
+
+func (cli *DockerCli) volumeRm(args ...string) error {
+	cmd := cli.Subcmd("volume rm", "VOLUME [VOLUME...]", "Remove one or more volumes")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		_, _, err := readBody(cli.call("DELETE", "/volumes/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to remove one or more volumes")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
+func (cli *DockerCli) CmdNetwork(args ...string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "connect":
+			return cli.networkConnect(args[1:]...)
+		case "create":
+			return cli.networkCreate(args[1:]...)
+		case "disconnect":
+			return cli.networkDisconnect(args[1:]...)
+		case "inspect":
+			return cli.networkInspect(args[1:]...)
+		case "ls":
+			return cli.networkLs(args[1:]...)
+		case "rm":
+			return cli.networkRm(args[1:]...)
+		}
+	}
+
+	description := "Manage networks\n\nCommands:\n"
+	for _, command := range [][]string{
+		{"connect", "Connect a container to a network"},
+		{"create", "Create a network"},
+		{"disconnect", "Disconnect a container from a network"},
+		{"inspect", "Return low-level information on a network"},
+		{"ls", "List networks"},
+		{"rm", "Remove one or more networks"},
+	} {
+		description += fmt.Sprintf("    %-12.12s%s\n", command[0], command[1])
+	}
+	cmd := cli.Subcmd("network", "COMMAND", description)
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	cmd.Usage()
+	return nil
+}
+
+func (cli *DockerCli) networkCreate(args ...string) error {
+	cmd := cli.Subcmd("network create", "[OPTIONS] NETWORK", "Create a network")
+	flSubnet := cmd.String([]string{"-subnet"}, "", "Subnet of the network in CIDR format, a free range is picked when empty")
+	flGateway := cmd.String([]string{"-gateway"}, "", "Gateway of the subnet, its first address by default")
+	flDriver := cmd.String([]string{"d", "-driver"}, "bridge", "Driver of the network: bridge, or overlay to span the daemons sharing a cluster store")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("POST", "/networks/create", map[string]string{"Name": cmd.Arg(0), "Subnet": *flSubnet, "Gateway": *flGateway, "Driver": *flDriver}, false))
+	if err != nil {
+		return err
+	}
+	var network engine.Env
+	if err := network.Decode(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", network.Get("Id"))
+	return nil
+}
+
+func (cli *DockerCli) networkInspect(args ...string) error {
+	cmd := cli.Subcmd("network inspect", "NETWORK [NETWORK...]", "Return low-level information on a network")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	indented := new(bytes.Buffer)
+	indented.WriteByte('[')
+	status := 0
+
+	for _, name := range cmd.Args() {
+		obj, _, err := readBody(cli.call("GET", "/networks/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if err := json.Indent(indented, obj, "", "    "); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		indented.WriteString(",")
+	}
+
+	if indented.Len() > 1 {
+		// Remove trailing ','
+		indented.Truncate(indented.Len() - 1)
+	}
+	indented.WriteString("]\n")
+
+	if _, err := io.Copy(cli.out, indented); err != nil {
+		return err
+	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+func (cli *DockerCli) networkLs(args ...string) error {
+	cmd := cli.Subcmd("network ls", "[OPTIONS]", "List networks")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display network IDs")
+	noTrunc := cmd.Bool([]string{"-no-trunc"}, false, "Don't truncate output")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+
+	body, _, err := readBody(cli.call("GET", "/networks", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, "NETWORK ID\tNAME\tDRIVER\tSUBNET\tBRIDGE")
+	}
+	for _, out := range outs.Data {
+		id := out.Get("Id")
+		if !*noTrunc {
+			id = utils.TruncateID(id)
+		}
+		if *quiet {
+			if id != "" {
+				fmt.Fprintln(w, id)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", id, out.Get("Name"), out.Get("Driver"), out.Get("Subnet"), out.Get("Bridge"))
+	}
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) networkRm(args ...string) error {
+	cmd := cli.Subcmd("network rm", "NETWORK [NETWORK...]", "Remove one or more networks")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		_, _, err := readBody(cli.call("DELETE", "/networks/"+name, nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to remove one or more networks")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
+func (cli *DockerCli) networkConnect(args ...string) error {
+	cmd := cli.Subcmd("network connect", "NETWORK CONTAINER", "Connect a container to a network")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	_, _, err := readBody(cli.call("POST", "/networks/"+cmd.Arg(0)+"/connect", map[string]string{"Container": cmd.Arg(1)}, false))
+	return err
+}
+
+func (cli *DockerCli) networkDisconnect(args ...string) error {
+	cmd := cli.Subcmd("network disconnect", "NETWORK CONTAINER", "Disconnect a container from a network")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	_, _, err := readBody(cli.call("POST", "/networks/"+cmd.Arg(0)+"/disconnect", map[string]string{"Container": cmd.Arg(1)}, false))
+	return err
+}
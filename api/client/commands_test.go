@@ -0,0 +1,33 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/stats"
+)
+
+func TestStatsPercentages(t *testing.T) {
+	v := &stats.Stats{
+		CpuStats: stats.CpuStats{
+			CpuUsage:    stats.CpuUsage{TotalUsage: 1500, PercpuUsage: []uint64{700, 800}},
+			SystemUsage: 12000,
+		},
+		MemoryStats: stats.MemoryStats{Usage: 256, Limit: 1024},
+	}
+
+	// The container used 1000ns of CPU time on 2 CPUs while the host used
+	// 8000ns in total
+	if cpu := calculateCpuPercent(500, 4000, v); cpu != 25.0 {
+		t.Fatalf("Expected 25%% of CPU, got %f", cpu)
+	}
+	if cpu := calculateCpuPercent(1500, 12000, v); cpu != 0.0 {
+		t.Fatalf("Expected 0%% of CPU without any change, got %f", cpu)
+	}
+	if mem := calculateMemPercent(v); mem != 25.0 {
+		t.Fatalf("Expected 25%% of memory, got %f", mem)
+	}
+	v.MemoryStats.Limit = 0
+	if mem := calculateMemPercent(v); mem != 0.0 {
+		t.Fatalf("Expected 0%% of memory without a limit, got %f", mem)
+	}
+}
@@ -25,33 +25,51 @@ func (cli *DockerCli) dial() (net.Conn, error) {
 	return net.Dial(cli.proto, cli.addr)
 }
 
-func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.ReadCloser, stdout, stderr io.Writer, started chan io.Closer) error {
+func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.ReadCloser, stdout, stderr io.Writer, started chan io.Closer, data interface{}) error {
+	_, err := cli.hijackResponse(method, path, setRawTerminal, in, stdout, stderr, started, data)
+	return err
+}
+
+// hijackResponse hijacks the connection of the request like hijack, and
+// returns the header of the response once the streams end
+func (cli *DockerCli) hijackResponse(method, path string, setRawTerminal bool, in io.ReadCloser, stdout, stderr io.Writer, started chan io.Closer, data interface{}) (http.Header, error) {
 	defer func() {
 		if started != nil {
 			close(started)
 		}
 	}()
 
-	req, err := http.NewRequest(method, fmt.Sprintf("/v%s%s", api.APIVERSION, path), nil)
+	params, err := encodeData(data)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("/v%s%s", api.APIVERSION, path), params)
+	if err != nil {
+		return nil, err
 	}
 	req.Header.Set("User-Agent", "Docker-Client/"+dockerversion.VERSION)
-	req.Header.Set("Content-Type", "plain/text")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "plain/text")
+	}
 	req.Host = cli.addr
 
 	dial, err := cli.dial()
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
-			return fmt.Errorf("Cannot connect to the Docker daemon. Is 'docker -d' running on this host?")
+			return nil, fmt.Errorf("Cannot connect to the Docker daemon. Is 'docker -d' running on this host?")
 		}
-		return err
+		return nil, err
 	}
 	clientconn := httputil.NewClientConn(dial, nil)
 	defer clientconn.Close()
 
 	// Server hijacks the connection, error 'connection closed' expected
-	clientconn.Do(req)
+	var header http.Header
+	if resp, _ := clientconn.Do(req); resp != nil {
+		header = resp.Header
+	}
 
 	rwc, br := clientconn.Hijack()
 	defer rwc.Close()
@@ -67,7 +85,7 @@ func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.Rea
 	if in != nil && setRawTerminal && cli.isTerminal && os.Getenv("NORAW") == "" {
 		oldState, err = term.SetRawTerminal(cli.terminalFd)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer term.RestoreTerminal(cli.terminalFd, oldState)
 	}
@@ -120,15 +138,15 @@ func (cli *DockerCli) hijack(method, path string, setRawTerminal bool, in io.Rea
 	if stdout != nil || stderr != nil {
 		if err := <-receiveStdout; err != nil {
 			log.Debugf("Error receiveStdout: %s", err)
-			return err
+			return nil, err
 		}
 	}
 
 	if !cli.isTerminal {
 		if err := <-sendStdin; err != nil {
 			log.Debugf("Error sendStdin: %s", err)
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	return header, nil
 }
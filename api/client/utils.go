@@ -40,23 +40,31 @@ func (cli *DockerCli) HTTPClient() *http.Client {
 	return &http.Client{Transport: tr}
 }
 
-func (cli *DockerCli) call(method, path string, data interface{}, passAuthInfo bool) (io.ReadCloser, int, error) {
+func encodeData(data interface{}) (*bytes.Buffer, error) {
 	params := bytes.NewBuffer(nil)
 	if data != nil {
 		if env, ok := data.(engine.Env); ok {
 			if err := env.Encode(params); err != nil {
-				return nil, -1, err
+				return nil, err
 			}
 		} else {
 			buf, err := json.Marshal(data)
 			if err != nil {
-				return nil, -1, err
+				return nil, err
 			}
 			if _, err := params.Write(buf); err != nil {
-				return nil, -1, err
+				return nil, err
 			}
 		}
 	}
+	return params, nil
+}
+
+func (cli *DockerCli) call(method, path string, data interface{}, passAuthInfo bool) (io.ReadCloser, int, error) {
+	params, err := encodeData(data)
+	if err != nil {
+		return nil, -1, err
+	}
 
 	req, err := http.NewRequest(method, fmt.Sprintf("/v%s%s", api.APIVERSION, path), params)
 	if err != nil {
@@ -219,6 +227,25 @@ func getExitCode(cli *DockerCli, containerId string) (bool, int, error) {
 	return state.GetBool("Running"), state.GetInt("ExitCode"), nil
 }
 
+// getExecExitCode returns the exit code of the process of the exec execID, -1
+// if it could not be run
+func getExecExitCode(cli *DockerCli, execID string) (int, error) {
+	stream, statusCode, err := cli.call("GET", "/exec/"+execID+"/json", nil, false)
+	if statusCode == 404 {
+		// the daemon does not keep the execs which found no container
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+
+	var result engine.Env
+	if err := result.Decode(stream); err != nil {
+		return -1, err
+	}
+	return result.GetInt("ExitCode"), nil
+}
+
 func (cli *DockerCli) monitorTtySize(id string) error {
 	cli.resizeTty(id)
 
@@ -50,7 +50,7 @@ func hijackServer(w http.ResponseWriter) (io.ReadCloser, io.Writer, error) {
 	return conn, conn, nil
 }
 
-//If we don't do this, POST method without Content-type (even with empty body) will fail
+// If we don't do this, POST method without Content-type (even with empty body) will fail
 func parseForm(r *http.Request) error {
 	if r == nil {
 		return nil
@@ -196,6 +196,39 @@ func postContainersUnpause(eng *engine.Engine, version version.Version, w http.R
 	return nil
 }
 
+func postContainersRename(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	job := eng.Job("container_rename", vars["name"], r.Form.Get("name"))
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func postContainersUpdate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	job := eng.Job("container_update", vars["name"])
+	if err := job.DecodeEnv(r.Body); err != nil {
+		return err
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func getContainersExport(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
@@ -281,6 +314,7 @@ func getEvents(eng *engine.Engine, version version.Version, w http.ResponseWrite
 	streamJSON(job, w, true)
 	job.Setenv("since", r.Form.Get("since"))
 	job.Setenv("until", r.Form.Get("until"))
+	job.Setenv("filters", r.Form.Get("filters"))
 	return job.Run()
 }
 
@@ -363,6 +397,18 @@ func getContainersJSON(eng *engine.Engine, version version.Version, w http.Respo
 	return nil
 }
 
+func getContainersStats(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	job := eng.Job("container_stats", vars["name"])
+	streamJSON(job, w, true)
+	return job.Run()
+}
+
 func getContainersLogs(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -854,6 +900,65 @@ func postContainersAttach(eng *engine.Engine, version version.Version, w http.Re
 	return nil
 }
 
+func postContainersExec(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := parseForm(r); err != nil {
+		return err
+	}
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+
+	job := eng.Job("exec", vars["name"])
+	if err := job.DecodeEnv(r.Body); err != nil {
+		return err
+	}
+	// The id of the exec is given to the client, to inspect its exit code
+	// once the connection ends
+	execID := utils.GenerateRandomID()
+	job.Setenv("ExecID", execID)
+
+	inStream, outStream, err := hijackServer(w)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tcpc, ok := inStream.(*net.TCPConn); ok {
+			tcpc.CloseWrite()
+		} else {
+			inStream.Close()
+		}
+	}()
+	defer func() {
+		if tcpc, ok := outStream.(*net.TCPConn); ok {
+			tcpc.CloseWrite()
+		} else if closer, ok := outStream.(io.Closer); ok {
+			closer.Close()
+		}
+	}()
+
+	var errStream io.Writer
+
+	fmt.Fprintf(outStream, "HTTP/1.1 200 OK\r\nContent-Type: application/vnd.docker.raw-stream\r\nDocker-Exec-Id: %s\r\n\r\n", execID)
+
+	if !job.GetenvBool("Tty") {
+		errStream = utils.NewStdWriter(outStream, utils.Stderr)
+		outStream = utils.NewStdWriter(outStream, utils.Stdout)
+	} else {
+		errStream = outStream
+	}
+
+	job.Stdin.Add(inStream)
+	job.Stdout.Add(outStream)
+	job.Stderr.Set(errStream)
+	if err := job.Run(); err != nil {
+		fmt.Fprintf(outStream, "Error running exec: %s\n", err)
+	}
+	return nil
+}
+
 func wsContainersAttach(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -886,6 +991,131 @@ func wsContainersAttach(eng *engine.Engine, version version.Version, w http.Resp
 	return nil
 }
 
+func getVolumesJSON(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	job := eng.Job("volumes")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getVolumesByName(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	job := eng.Job("volume_inspect", vars["name"])
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postVolumesCreate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	job := eng.Job("volume_create")
+	// an empty body creates an anonymous volume
+	if r.Body != nil && r.ContentLength > 0 {
+		if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+			return fmt.Errorf("Content-Type of application/json is required")
+		}
+		if err := job.DecodeEnv(r.Body); err != nil {
+			return err
+		}
+	}
+	out, err := job.Stdout.AddEnv()
+	if err != nil {
+		return err
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, *out)
+}
+
+func postVolumesPrune(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	job := eng.Job("volume_prune")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func deleteVolumes(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := eng.Job("volume_rm", vars["name"]).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func getNetworksJSON(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	job := eng.Job("networks")
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func getNetworksByName(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	job := eng.Job("network_inspect", vars["name"])
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
+func postNetworksCreate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	job := eng.Job("network_create")
+	if err := job.DecodeEnv(r.Body); err != nil {
+		return err
+	}
+	out, err := job.Stdout.AddEnv()
+	if err != nil {
+		return err
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, *out)
+}
+
+func postNetworksConnect(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return networkContainer(eng, "connect", w, r, vars)
+}
+
+func postNetworksDisconnect(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return networkContainer(eng, "disconnect", w, r, vars)
+}
+
+// networkContainer runs the job connecting or disconnecting the container
+// given in the body of the request to a network
+func networkContainer(eng *engine.Engine, name string, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if !api.MatchesContentType(r.Header.Get("Content-Type"), "application/json") {
+		return fmt.Errorf("Content-Type of application/json is required")
+	}
+	var body engine.Env
+	if err := body.Decode(r.Body); err != nil {
+		return err
+	}
+	if err := eng.Job(name, vars["name"], body.Get("Container")).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func deleteNetworks(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := eng.Job("network_rm", vars["name"]).Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func getContainersByName(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
@@ -898,6 +1128,15 @@ func getContainersByName(eng *engine.Engine, version version.Version, w http.Res
 	return job.Run()
 }
 
+func getExecByID(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	var job = eng.Job("exec_inspect", vars["id"])
+	streamJSON(job, w, false)
+	return job.Run()
+}
+
 func getImagesByName(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if vars == nil {
 		return fmt.Errorf("Missing parameter")
@@ -1115,31 +1354,47 @@ func createRouter(eng *engine.Engine, logging, enableCors bool, dockerVersion st
 			"/containers/{name:.*}/json":      getContainersByName,
 			"/containers/{name:.*}/top":       getContainersTop,
 			"/containers/{name:.*}/logs":      getContainersLogs,
+			"/containers/{name:.*}/stats":     getContainersStats,
 			"/containers/{name:.*}/attach/ws": wsContainersAttach,
+			"/volumes":                        getVolumesJSON,
+			"/volumes/{name:.*}":              getVolumesByName,
+			"/networks":                       getNetworksJSON,
+			"/networks/{name:.*}":             getNetworksByName,
+			"/exec/{id:.*}/json":              getExecByID,
 		},
 		"POST": {
-			"/auth":                         postAuth,
-			"/commit":                       postCommit,
-			"/build":                        postBuild,
-			"/images/create":                postImagesCreate,
-			"/images/load":                  postImagesLoad,
-			"/images/{name:.*}/push":        postImagesPush,
-			"/images/{name:.*}/tag":         postImagesTag,
-			"/containers/create":            postContainersCreate,
-			"/containers/{name:.*}/kill":    postContainersKill,
-			"/containers/{name:.*}/pause":   postContainersPause,
-			"/containers/{name:.*}/unpause": postContainersUnpause,
-			"/containers/{name:.*}/restart": postContainersRestart,
-			"/containers/{name:.*}/start":   postContainersStart,
-			"/containers/{name:.*}/stop":    postContainersStop,
-			"/containers/{name:.*}/wait":    postContainersWait,
-			"/containers/{name:.*}/resize":  postContainersResize,
-			"/containers/{name:.*}/attach":  postContainersAttach,
-			"/containers/{name:.*}/copy":    postContainersCopy,
+			"/auth":                          postAuth,
+			"/commit":                        postCommit,
+			"/build":                         postBuild,
+			"/images/create":                 postImagesCreate,
+			"/images/load":                   postImagesLoad,
+			"/images/{name:.*}/push":         postImagesPush,
+			"/images/{name:.*}/tag":          postImagesTag,
+			"/containers/create":             postContainersCreate,
+			"/containers/{name:.*}/kill":     postContainersKill,
+			"/containers/{name:.*}/pause":    postContainersPause,
+			"/containers/{name:.*}/unpause":  postContainersUnpause,
+			"/containers/{name:.*}/rename":   postContainersRename,
+			"/containers/{name:.*}/update":   postContainersUpdate,
+			"/containers/{name:.*}/restart":  postContainersRestart,
+			"/containers/{name:.*}/start":    postContainersStart,
+			"/containers/{name:.*}/stop":     postContainersStop,
+			"/containers/{name:.*}/wait":     postContainersWait,
+			"/containers/{name:.*}/resize":   postContainersResize,
+			"/containers/{name:.*}/attach":   postContainersAttach,
+			"/containers/{name:.*}/exec":     postContainersExec,
+			"/containers/{name:.*}/copy":     postContainersCopy,
+			"/volumes/create":                postVolumesCreate,
+			"/volumes/prune":                 postVolumesPrune,
+			"/networks/create":               postNetworksCreate,
+			"/networks/{name:.*}/connect":    postNetworksConnect,
+			"/networks/{name:.*}/disconnect": postNetworksDisconnect,
 		},
 		"DELETE": {
 			"/containers/{name:.*}": deleteContainers,
 			"/images/{name:.*}":     deleteImages,
+			"/volumes/{name:.*}":    deleteVolumes,
+			"/networks/{name:.*}":   deleteNetworks,
 		},
 		"OPTIONS": {
 			"": optionsHandler,
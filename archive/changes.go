@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -340,10 +341,24 @@ func minor(device uint64) uint64 {
 	return (device & 0xff) | ((device >> 12) & 0xfff00)
 }
 
+// changesByPath sorts the changes by path, the parents first
+type changesByPath []Change
+
+func (c changesByPath) Less(i, j int) bool { return c[i].Path < c[j].Path }
+func (c changesByPath) Len() int           { return len(c) }
+func (c changesByPath) Swap(i, j int)      { c[j], c[i] = c[i], c[j] }
+
+// ExportChanges returns the archive of the changes of dir. The entries are
+// sorted by path and the whiteouts have no time, so that the archive of the
+// same changes is the same, as the digests of the layers pushed to a
+// registry require.
 func ExportChanges(dir string, changes []Change) (Archive, error) {
 	reader, writer := io.Pipe()
 	tw := tar.NewWriter(writer)
 
+	changes = append([]Change{}, changes...)
+	sort.Sort(changesByPath(changes))
+
 	go func() {
 		twBuf := bufio.NewWriterSize(nil, twBufSize)
 		// In general we log errors here but ignore them because
@@ -355,7 +370,7 @@ func ExportChanges(dir string, changes []Change) (Archive, error) {
 				whiteOutDir := filepath.Dir(change.Path)
 				whiteOutBase := filepath.Base(change.Path)
 				whiteOut := filepath.Join(whiteOutDir, ".wh."+whiteOutBase)
-				timestamp := time.Now()
+				timestamp := time.Unix(0, 0)
 				hdr := &tar.Header{
 					Name:       whiteOut[1:],
 					Size:       0,
@@ -1,6 +1,7 @@
 package archive
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -299,3 +300,47 @@ func TestApplyLayer(t *testing.T) {
 		t.Fatalf("Unexpected differences after reapplying mutation: %v", changes2)
 	}
 }
+
+func TestExportChangesIsStable(t *testing.T) {
+	src, err := ioutil.TempDir("", "docker-changes-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createSampleDir(t, src)
+	dst := src + "-copy"
+	if err := copyDir(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	defer os.RemoveAll(dst)
+
+	mutateSampleDir(t, dst)
+
+	changes, err := ChangesDirs(dst, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reversed := make([]Change, len(changes))
+	for i, change := range changes {
+		reversed[len(changes)-1-i] = change
+	}
+
+	var exported [][]byte
+	for _, c := range [][]Change{changes, reversed} {
+		layer, err := ExportChanges(dst, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(layer)
+		layer.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exported = append(exported, data)
+		// The whiteouts of the two exports have different times otherwise
+		time.Sleep(time.Second)
+	}
+	if !bytes.Equal(exported[0], exported[1]) {
+		t.Fatal("Expected the same changes to be exported to the same archive")
+	}
+}
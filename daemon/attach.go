@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/docker/docker/daemon/logger"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/pkg/jsonlog"
 	"github.com/docker/docker/pkg/log"
@@ -34,8 +35,10 @@ func (daemon *Daemon) ContainerAttach(job *engine.Job) engine.Status {
 
 	//logs
 	if logs {
-		cLog, err := container.ReadLog("json")
-		if err != nil && os.IsNotExist(err) {
+		cLog, err := container.readLogs()
+		if err == logger.ErrReadLogsNotSupported {
+			log.Debugf("Logging driver of %s does not support reading, skipping logs", container.ID)
+		} else if err != nil && os.IsNotExist(err) {
 			// Legacy logs
 			log.Debugf("Old logs format")
 			if stdout {
@@ -57,6 +60,7 @@ func (daemon *Daemon) ContainerAttach(job *engine.Job) engine.Status {
 		} else if err != nil {
 			log.Errorf("Error reading logs (json): %s", err)
 		} else {
+			defer cLog.Close()
 			dec := json.NewDecoder(cLog)
 			for {
 				l := &jsonlog.JSONLog{}
@@ -16,6 +16,7 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -376,6 +377,83 @@ func (b *buildFile) CmdEnv(args string) error {
 	return b.commit("", b.config.Cmd, fmt.Sprintf("ENV %s", replacedVar))
 }
 
+// CmdLabel sets metadata on the image, given either as `LABEL key value` or
+// as one or more `key=value` pairs, whose values can be double quoted
+func (b *buildFile) CmdLabel(args string) error {
+	pairs, err := parseLabelArgs(args)
+	if err != nil {
+		return err
+	}
+
+	// the config may be shared with the parent image, do not modify its labels
+	labels := make(map[string]string, len(b.config.Labels)+len(pairs))
+	for k, v := range b.config.Labels {
+		labels[k] = v
+	}
+	for i, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		value, err := b.ReplaceEnvMatches(kv[1])
+		if err != nil {
+			return err
+		}
+		labels[kv[0]] = value
+		pairs[i] = fmt.Sprintf("%s=%s", kv[0], value)
+	}
+	b.config.Labels = labels
+	return b.commit("", b.config.Cmd, fmt.Sprintf("LABEL %s", strings.Join(pairs, " ")))
+}
+
+// parseLabelArgs returns the key=value pairs given to a LABEL instruction
+func parseLabelArgs(args string) ([]string, error) {
+	if args == "" {
+		return nil, fmt.Errorf("LABEL requires at least one argument")
+	}
+	tmp := strings.SplitN(args, " ", 2)
+	if !strings.Contains(tmp[0], "=") {
+		if len(tmp) != 2 {
+			return nil, fmt.Errorf("Invalid LABEL format")
+		}
+		return []string{fmt.Sprintf("%s=%s", tmp[0], strings.Trim(tmp[1], " \t"))}, nil
+	}
+
+	var (
+		pairs   []string
+		current []rune
+		quoted  bool
+		escaped bool
+	)
+	for _, c := range args {
+		switch {
+		case escaped:
+			current = append(current, c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ' ' || c == '\t'):
+			if len(current) > 0 {
+				pairs = append(pairs, string(current))
+				current = nil
+			}
+		default:
+			current = append(current, c)
+		}
+	}
+	if quoted || escaped {
+		return nil, fmt.Errorf("Invalid LABEL format: unterminated quote or escape")
+	}
+	if len(current) > 0 {
+		pairs = append(pairs, string(current))
+	}
+	for _, pair := range pairs {
+		if strings.Index(pair, "=") < 1 {
+			return nil, fmt.Errorf("Invalid LABEL format: %s is not a key=value pair", pair)
+		}
+	}
+	return pairs, nil
+}
+
 func (b *buildFile) buildCmdFromJson(args string) []string {
 	var cmd []string
 	if err := json.Unmarshal([]byte(args), &cmd); err != nil {
@@ -395,6 +473,82 @@ func (b *buildFile) CmdCmd(args string) error {
 	return nil
 }
 
+// CmdHealthcheck sets the command probing the health of the containers of
+// the image, given as `HEALTHCHECK [--interval=d] [--timeout=d] [--retries=n]
+// CMD command`, or disables the one of the parent image with `HEALTHCHECK NONE`
+func (b *buildFile) CmdHealthcheck(args string) error {
+	healthcheck, err := parseHealthcheckArgs(args)
+	if err != nil {
+		return err
+	}
+	b.config.Healthcheck = healthcheck
+	return b.commit("", b.config.Cmd, fmt.Sprintf("HEALTHCHECK %s", args))
+}
+
+// parseHealthcheckArgs returns the healthcheck given to a HEALTHCHECK instruction
+func parseHealthcheckArgs(args string) (*runconfig.HealthConfig, error) {
+	args = strings.TrimSpace(args)
+	if strings.ToUpper(args) == "NONE" {
+		return &runconfig.HealthConfig{Test: []string{"NONE"}}, nil
+	}
+
+	healthcheck := &runconfig.HealthConfig{}
+	for strings.HasPrefix(args, "--") {
+		var flag string
+		if parts := strings.SplitN(args, " ", 2); len(parts) == 2 {
+			flag, args = parts[0], strings.TrimLeft(parts[1], " \t")
+		} else {
+			flag, args = parts[0], ""
+		}
+		kv := strings.SplitN(flag[2:], "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("HEALTHCHECK option %s requires a value", flag)
+		}
+		switch kv[0] {
+		case "interval", "timeout":
+			d, err := time.ParseDuration(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid HEALTHCHECK --%s: %s", kv[0], err)
+			}
+			if d < 0 {
+				return nil, fmt.Errorf("HEALTHCHECK --%s cannot be negative", kv[0])
+			}
+			if kv[0] == "interval" {
+				healthcheck.Interval = d
+			} else {
+				healthcheck.Timeout = d
+			}
+		case "retries":
+			retries, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid HEALTHCHECK --retries: %s", err)
+			}
+			if retries < 0 {
+				return nil, fmt.Errorf("HEALTHCHECK --retries cannot be negative")
+			}
+			healthcheck.Retries = retries
+		default:
+			return nil, fmt.Errorf("Unknown HEALTHCHECK option %s", flag)
+		}
+	}
+
+	parts := strings.SplitN(args, " ", 2)
+	if strings.ToUpper(parts[0]) != "CMD" || len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return nil, fmt.Errorf("HEALTHCHECK requires either NONE or CMD followed by a command")
+	}
+	command := strings.TrimSpace(parts[1])
+	var cmd []string
+	if err := json.Unmarshal([]byte(command), &cmd); err == nil {
+		if len(cmd) == 0 {
+			return nil, fmt.Errorf("HEALTHCHECK requires either NONE or CMD followed by a command")
+		}
+		healthcheck.Test = append([]string{"CMD"}, cmd...)
+	} else {
+		healthcheck.Test = []string{"CMD-SHELL", command}
+	}
+	return healthcheck, nil
+}
+
 func (b *buildFile) CmdEntrypoint(args string) error {
 	entrypoint := b.buildCmdFromJson(args)
 	b.config.Entrypoint = entrypoint
@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLabelArgs(t *testing.T) {
+	valid := map[string][]string{
+		`owner infra team`:                    {"owner=infra team"},
+		`owner=infra`:                         {"owner=infra"},
+		`owner=infra tier=db`:                 {"owner=infra", "tier=db"},
+		`owner="infra team"  tier=db`:         {"owner=infra team", "tier=db"},
+		`com.example.description="a \"b\" c"`: {`com.example.description=a "b" c`},
+		`flag=`:                               {"flag="},
+	}
+	for args, expected := range valid {
+		pairs, err := parseLabelArgs(args)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %s", args, err)
+		}
+		if !reflect.DeepEqual(pairs, expected) {
+			t.Fatalf("Expected %v for %q, got %v", expected, args, pairs)
+		}
+	}
+
+	for _, args := range []string{"", "owner", `owner="infra`, "owner=infra tier", "=infra"} {
+		if _, err := parseLabelArgs(args); err == nil {
+			t.Fatalf("Expected an error for %q", args)
+		}
+	}
+}
+
+func TestParseHealthcheckArgs(t *testing.T) {
+	hc, err := parseHealthcheckArgs("--interval=5s --retries=2 CMD curl -f http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hc.Test, []string{"CMD-SHELL", "curl -f http://localhost/"}) || hc.Interval != 5*time.Second || hc.Timeout != 0 || hc.Retries != 2 {
+		t.Fatalf("Unexpected healthcheck %v", hc)
+	}
+
+	hc, err = parseHealthcheckArgs(`--timeout=1s CMD ["/bin/check", "-q"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hc.Test, []string{"CMD", "/bin/check", "-q"}) || hc.Timeout != time.Second {
+		t.Fatalf("Unexpected healthcheck %v", hc)
+	}
+
+	hc, err = parseHealthcheckArgs("none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hc.Test, []string{"NONE"}) {
+		t.Fatalf("Expected the healthcheck to be disabled, got %v", hc)
+	}
+
+	for _, args := range []string{"", "CMD", "--retries=2", "--interval CMD true", "--interval=x CMD true", "--retries=-1 CMD true", "--unknown=1 CMD true", "RUN true", "CMD []"} {
+		if _, err := parseHealthcheckArgs(args); err == nil {
+			t.Fatalf("Expected an error for %q", args)
+		}
+	}
+}
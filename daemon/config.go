@@ -28,13 +28,22 @@ type Config struct {
 	DefaultIp                   net.IP
 	BridgeIface                 string
 	BridgeIP                    string
+	FixedCIDR                   string
+	EnableIPv6                  bool
+	FixedCIDRv6                 string
+	MacvlanRanges               []string
 	InterContainerCommunication bool
+	EnableUserlandProxy         bool
+	ClusterStore                string
+	ClusterAdvertise            string
 	GraphDriver                 string
 	GraphOptions                []string
 	ExecDriver                  string
 	Mtu                         int
 	DisableNetwork              bool
 	EnableSelinuxSupport        bool
+	LogDriver                   string
+	ImageTrust                  string
 	Context                     map[string][]string
 }
 
@@ -49,14 +58,23 @@ func (config *Config) InstallFlags() {
 	flag.BoolVar(&config.EnableIptables, []string{"#iptables", "-iptables"}, true, "Enable Docker's addition of iptables rules")
 	flag.BoolVar(&config.EnableIpForward, []string{"#ip-forward", "-ip-forward"}, true, "Enable net.ipv4.ip_forward")
 	flag.StringVar(&config.BridgeIP, []string{"#bip", "-bip"}, "", "Use this CIDR notation address for the network bridge's IP, not compatible with -b")
+	flag.StringVar(&config.FixedCIDR, []string{"-fixed-cidr"}, "", "IPv4 subnet for fixed IPs (ex: 10.20.0.0/16)\nthis subnet must be nested in the bridge subnet (which is defined by -b or --bip)")
+	flag.BoolVar(&config.EnableIPv6, []string{"-ipv6"}, false, "Enable IPv6 networking")
+	flag.StringVar(&config.FixedCIDRv6, []string{"-fixed-cidr-v6"}, "", "IPv6 subnet for fixed IPs (ex: 2001:db8::/64), requires --ipv6")
 	flag.StringVar(&config.BridgeIface, []string{"b", "-bridge"}, "", "Attach containers to a pre-existing network bridge\nuse 'none' to disable container networking")
 	flag.BoolVar(&config.InterContainerCommunication, []string{"#icc", "-icc"}, true, "Enable inter-container communication")
+	flag.BoolVar(&config.EnableUserlandProxy, []string{"-userland-proxy"}, true, "Use a userland proxy for the published ports\nwhen false, the ports are reached through hairpin NAT on the bridge")
+	flag.StringVar(&config.ClusterStore, []string{"-cluster-store"}, "", "URL of the key-value store shared by the daemons of a cluster for the overlay networks\n(ex: file:///mnt/shared/cluster.json)")
+	flag.StringVar(&config.ClusterAdvertise, []string{"-cluster-advertise"}, "", "IP address the other daemons of the cluster reach this host at\nif no value is provided: default to the address of the default route interface")
 	flag.StringVar(&config.GraphDriver, []string{"s", "-storage-driver"}, "", "Force the Docker runtime to use a specific storage driver")
 	flag.StringVar(&config.ExecDriver, []string{"e", "-exec-driver"}, "native", "Force the Docker runtime to use a specific exec driver")
 	flag.BoolVar(&config.EnableSelinuxSupport, []string{"-selinux-enabled"}, false, "Enable selinux support. SELinux does not presently support the BTRFS storage driver")
+	flag.StringVar(&config.LogDriver, []string{"-log-driver"}, "json-file", "Default logging driver for containers (json-file, syslog, journald, none)")
+	flag.StringVar(&config.ImageTrust, []string{"-image-trust"}, "warn", "Policy for the pulled images without a signature by a trusted key (off, warn, refuse)\nthe trusted public keys are in the trust/trusted-keys directory of the root")
 	flag.IntVar(&config.Mtu, []string{"#mtu", "-mtu"}, 0, "Set the containers network MTU\nif no value is provided: default to the default route MTU or 1500 if no default route is available")
 	opts.IPVar(&config.DefaultIp, []string{"#ip", "-ip"}, "0.0.0.0", "Default IP address to use when binding container ports")
 	opts.ListVar(&config.GraphOptions, []string{"-storage-opt"}, "Set storage driver options")
+	opts.ListVar(&config.MacvlanRanges, []string{"-macvlan-range"}, "Part of the subnet of an interface of the host given out to the containers of its macvlan network (ex: eth1=192.168.1.64/26)")
 	// FIXME: why the inconsistency between "hosts" and "sockets"?
 	opts.IPListVar(&config.Dns, []string{"#dns", "-dns"}, "Force Docker to use specific DNS servers")
 	opts.DnsSearchListVar(&config.DnsSearch, []string{"-dns-search"}, "Force Docker to use specific DNS search domains")
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -20,6 +21,7 @@ import (
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/logger"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/links"
@@ -31,6 +33,7 @@ import (
 	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/docker/runconfig"
 	"github.com/docker/docker/utils"
+	"github.com/docker/docker/volumes"
 )
 
 const DefaultPathEnv = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
@@ -59,6 +62,10 @@ type Container struct {
 	Image  string
 
 	NetworkSettings *NetworkSettings
+	// LastIPAddress is the address the container had the last time it was
+	// started. Unlike NetworkSettings, it is kept when the container stops,
+	// so that it gets the same address back when the daemon restarts it.
+	LastIPAddress string
 
 	ResolvConfPath string
 	HostnamePath   string
@@ -83,8 +90,18 @@ type Container struct {
 	VolumesRW  map[string]bool
 	hostConfig *runconfig.HostConfig
 
-	activeLinks map[string]*links.Link
-	monitor     *containerMonitor
+	activeLinks   map[string]*links.Link
+	monitor       *containerMonitor
+	healthMonitor *healthMonitor
+	logDriver     logger.Logger
+	logCopier     *logger.Copier
+
+	// volumes mounted through their driver for the container to run, by id
+	mountedVolumes map[string]*volumes.Volume
+
+	// the address to reclaim on the next start, set when the daemon
+	// restores the container
+	restoredIP string
 }
 
 func (container *Container) FromDisk() error {
@@ -173,7 +190,9 @@ func (container *Container) WriteHostConfig() error {
 
 func (container *Container) LogEvent(action string) {
 	d := container.daemon
-	if err := d.eng.Job("log", action, container.ID, d.Repositories().ImageName(container.Image)).Run(); err != nil {
+	job := d.eng.Job("log", action, container.ID, d.Repositories().ImageName(container.Image))
+	job.SetenvJson("Labels", container.Config.Labels)
+	if err := job.Run(); err != nil {
 		log.Errorf("Error logging event %s for %s: %s", action, container.ID, err)
 	}
 }
@@ -202,18 +221,33 @@ func populateCommand(c *Container, env []string) error {
 	}
 
 	parts := strings.SplitN(string(c.hostConfig.NetworkMode), ":", 2)
-	switch parts[0] {
+	mode := parts[0]
+	if c.hostConfig.NetworkMode.IsUserDefined() {
+		// the bridge of a user-defined network is set up as the default one
+		mode = "bridge"
+	}
+	switch mode {
 	case "none":
 	case "host":
 		en.HostNetworking = true
-	case "bridge", "": // empty string to support existing containers
+	case "bridge", "", "macvlan": // empty string to support existing containers
 		if !c.Config.NetworkDisabled {
 			network := c.NetworkSettings
 			en.Interface = &execdriver.NetworkInterface{
-				Gateway:     network.Gateway,
-				Bridge:      network.Bridge,
-				IPAddress:   network.IPAddress,
-				IPPrefixLen: network.IPPrefixLen,
+				Gateway:              network.Gateway,
+				Bridge:               network.Bridge,
+				IPAddress:            network.IPAddress,
+				IPPrefixLen:          network.IPPrefixLen,
+				MacAddress:           network.MacAddress,
+				LinkLocalIPv6Address: network.LinkLocalIPv6Address,
+				GlobalIPv6Address:    network.GlobalIPv6Address,
+				GlobalIPv6PrefixLen:  network.GlobalIPv6PrefixLen,
+				IPv6Gateway:          network.IPv6Gateway,
+				HairpinMode:          network.HairpinMode,
+				Parent:               c.hostConfig.NetworkMode.MacvlanParent(),
+			}
+			if network.Mtu != 0 {
+				en.Mtu = network.Mtu
 			}
 		}
 	case "container":
@@ -244,10 +278,28 @@ func populateCommand(c *Container, env []string) error {
 	mergeLxcConfIntoOptions(c.hostConfig, context)
 
 	resources := &execdriver.Resources{
-		Memory:     c.Config.Memory,
-		MemorySwap: c.Config.MemorySwap,
-		CpuShares:  c.Config.CpuShares,
-		Cpuset:     c.Config.Cpuset,
+		Memory:            c.Config.Memory,
+		MemorySwap:        c.Config.MemorySwap,
+		CpuShares:         c.Config.CpuShares,
+		Cpuset:            c.Config.Cpuset,
+		MemoryReservation: c.hostConfig.MemoryReservation,
+		OomKillDisable:    c.hostConfig.OomKillDisable,
+		CpuPeriod:         c.hostConfig.CpuPeriod,
+		CpuQuota:          c.hostConfig.CpuQuota,
+		BlkioWeight:       c.hostConfig.BlkioWeight,
+	}
+	var err error
+	if resources.BlkioDeviceReadBps, err = getThrottleDevices(c.hostConfig.BlkioDeviceReadBps); err != nil {
+		return err
+	}
+	if resources.BlkioDeviceWriteBps, err = getThrottleDevices(c.hostConfig.BlkioDeviceWriteBps); err != nil {
+		return err
+	}
+	if resources.BlkioDeviceReadIOps, err = getThrottleDevices(c.hostConfig.BlkioDeviceReadIOps); err != nil {
+		return err
+	}
+	if resources.BlkioDeviceWriteIOps, err = getThrottleDevices(c.hostConfig.BlkioDeviceWriteIOps); err != nil {
+		return err
 	}
 	c.command = &execdriver.Command{
 		ID:                 c.ID,
@@ -272,6 +324,27 @@ func populateCommand(c *Container, env []string) error {
 	return nil
 }
 
+// getThrottleDevices resolves the paths of the block devices to throttle to
+// their device numbers
+func getThrottleDevices(throttles []runconfig.ThrottleDevice) ([]*execdriver.ThrottleDevice, error) {
+	var throttleDevices []*execdriver.ThrottleDevice
+	for _, t := range throttles {
+		device, err := devices.GetDevice(t.Path, "")
+		if err != nil {
+			return nil, fmt.Errorf("error gathering device information while throttling device %s: %s", t.Path, err)
+		}
+		if device.Type != 'b' {
+			return nil, fmt.Errorf("Cannot throttle %s: not a block device", t.Path)
+		}
+		throttleDevices = append(throttleDevices, &execdriver.ThrottleDevice{
+			Major: device.MajorNumber,
+			Minor: device.MinorNumber,
+			Rate:  t.Rate,
+		})
+	}
+	return throttleDevices, nil
+}
+
 func (container *Container) Start() (err error) {
 	container.Lock()
 	defer container.Unlock()
@@ -394,7 +467,12 @@ func (container *Container) buildHostnameAndHostsFiles(IP string) error {
 	if err := container.buildHostnameFile(); err != nil {
 		return err
 	}
+	return container.buildHostsFile(IP)
+}
 
+// buildHostsFile writes the /etc/hosts file of the container, where the linked
+// containers are listed under their alias and their name.
+func (container *Container) buildHostsFile(IP string) error {
 	hostsPath, err := container.getRootResourcePath("hosts")
 	if err != nil {
 		return err
@@ -409,8 +487,17 @@ func (container *Container) buildHostnameAndHostsFiles(IP string) error {
 	}
 
 	for linkAlias, child := range children {
+		// a stopped child has no address until it starts again, when the
+		// file is rewritten
+		if child.NetworkSettings.IPAddress == "" {
+			continue
+		}
 		_, alias := path.Split(linkAlias)
-		extraContent[alias] = child.NetworkSettings.IPAddress
+		hosts := alias
+		if name := strings.TrimPrefix(child.Name, "/"); name != alias {
+			hosts = fmt.Sprintf("%s %s", alias, name)
+		}
+		extraContent[hosts] = child.NetworkSettings.IPAddress
 	}
 
 	return etchosts.Build(container.HostsPath, IP, container.Config.Hostname, container.Config.Domainname, &extraContent)
@@ -422,20 +509,72 @@ func (container *Container) allocateNetwork() error {
 		return nil
 	}
 
-	var (
-		env *engine.Env
-		err error
-		eng = container.daemon.eng
-	)
+	// The previous address of the container is reclaimed, so that the
+	// containers keep their address across restarts of the daemon. The
+	// address left from a run the daemon did not see the end of is in the
+	// network settings, the one of a container the daemon stopped cleanly
+	// is given by restore.
+	requestedIP := container.hostConfig.IPAddress
+	previousIP := container.NetworkSettings.IPAddress
+	if previousIP == "" {
+		previousIP = container.restoredIP
+	}
+	container.restoredIP = ""
+	if requestedIP == "" {
+		requestedIP = previousIP
+	}
 
-	job := eng.Job("allocate_interface", container.ID)
-	if env, err = job.Stdout.AddEnv(); err != nil {
+	env, err := container.allocateInterface(requestedIP)
+	if err != nil && container.hostConfig.IPAddress == "" && previousIP != "" {
+		log.Infof("Unable to reclaim the ip %s of %s, allocating a new one: %s", previousIP, container.ID, err)
+		env, err = container.allocateInterface("")
+	}
+	if err != nil {
 		return err
 	}
-	if err := job.Run(); err != nil {
+
+	if mode.IsMacvlan() {
+		// the ports of the container are reachable as they are on the
+		// network of the parent interface, none is published
+		container.NetworkSettings.Ports = make(nat.PortMap)
+		for port := range container.Config.ExposedPorts {
+			container.NetworkSettings.Ports[port] = nil
+		}
+	} else if err := container.publishPorts(); err != nil {
 		return err
 	}
 
+	container.NetworkSettings.Bridge = env.Get("Bridge")
+	container.NetworkSettings.IPAddress = env.Get("IP")
+	container.LastIPAddress = container.NetworkSettings.IPAddress
+	container.NetworkSettings.IPPrefixLen = env.GetInt("IPPrefixLen")
+	container.NetworkSettings.Gateway = env.Get("Gateway")
+	container.NetworkSettings.MacAddress = env.Get("MacAddress")
+	container.NetworkSettings.LinkLocalIPv6Address = env.Get("LinkLocalIPv6")
+	container.NetworkSettings.LinkLocalIPv6PrefixLen = env.GetInt("LinkLocalIPv6PrefixLen")
+	container.NetworkSettings.GlobalIPv6Address = env.Get("GlobalIPv6")
+	container.NetworkSettings.GlobalIPv6PrefixLen = env.GetInt("GlobalIPv6PrefixLen")
+	container.NetworkSettings.IPv6Gateway = env.Get("IPv6Gateway")
+	container.NetworkSettings.HairpinMode = env.GetBool("HairpinMode")
+	container.NetworkSettings.Mtu = env.GetInt("Mtu")
+	container.NetworkSettings.Networks = map[string]*EndpointSettings{
+		container.networkName(): {
+			IPAddress:   env.Get("IP"),
+			IPPrefixLen: env.GetInt("IPPrefixLen"),
+			Gateway:     env.Get("Gateway"),
+			Bridge:      env.Get("Bridge"),
+			Device:      "eth0",
+		},
+	}
+
+	return nil
+}
+
+// publishPorts allocates the host ports the exposed ports of the container
+// are published on
+func (container *Container) publishPorts() error {
+	eng := container.daemon.eng
+
 	if container.Config.PortSpecs != nil {
 		if err := migratePortMappings(container.Config, container.hostConfig); err != nil {
 			return err
@@ -469,22 +608,41 @@ func (container *Container) allocateNetwork() error {
 
 	container.NetworkSettings.PortMapping = nil
 
-	for port := range portSpecs {
-		if err := container.allocatePort(eng, port, bindings); err != nil {
-			return err
-		}
+	if err := container.allocatePorts(eng, portSpecs, bindings); err != nil {
+		return err
 	}
 	container.WriteHostConfig()
 
 	container.NetworkSettings.Ports = bindings
-	container.NetworkSettings.Bridge = env.Get("Bridge")
-	container.NetworkSettings.IPAddress = env.Get("IP")
-	container.NetworkSettings.IPPrefixLen = env.GetInt("IPPrefixLen")
-	container.NetworkSettings.Gateway = env.Get("Gateway")
-
 	return nil
 }
 
+// networkName returns the name of the network the container is attached to
+// by its network mode
+func (container *Container) networkName() string {
+	if mode := container.hostConfig.NetworkMode; mode.IsUserDefined() || mode.IsMacvlan() {
+		return string(mode)
+	}
+	return "bridge"
+}
+
+// allocateInterface allocates the interface of the container on the network
+// of its network mode, with requestedIP when it is not empty
+func (container *Container) allocateInterface(requestedIP string) (*engine.Env, error) {
+	job := container.daemon.eng.Job("allocate_interface", container.ID)
+	job.Setenv("Network", container.networkName())
+	job.Setenv("RequestedIP", requestedIP)
+	job.SetenvJson("NetworkPolicy", container.hostConfig.NetworkPolicy)
+	env, err := job.Stdout.AddEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := job.Run(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
 func (container *Container) releaseNetwork() {
 	if container.Config.NetworkDisabled {
 		return
@@ -506,6 +664,9 @@ func (container *Container) cleanup() {
 			link.Disable()
 		}
 	}
+	container.daemon.refreshParentLinks(container)
+
+	container.unmountVolumes()
 
 	if err := container.Unmount(); err != nil {
 		log.Errorf("%v: Failed to umount filesystem: %v", container.ID, err)
@@ -858,12 +1019,16 @@ func (container *Container) setupContainerDns() error {
 		return err
 	}
 
-	if config.NetworkMode != "host" && (len(config.Dns) > 0 || len(daemon.config.Dns) > 0 || len(config.DnsSearch) > 0 || len(daemon.config.DnsSearch) > 0) {
+	// the embedded dns server forwards the queries for the other names to the
+	// nameservers of the daemon
+	if container.usesDnsServer() || (config.NetworkMode != "host" && (len(config.Dns) > 0 || len(daemon.config.Dns) > 0 || len(config.DnsSearch) > 0 || len(daemon.config.DnsSearch) > 0)) {
 		var (
 			dns       = resolvconf.GetNameservers(resolvConf)
 			dnsSearch = resolvconf.GetSearchDomains(resolvConf)
 		)
-		if len(config.Dns) > 0 {
+		if container.usesDnsServer() {
+			dns = []string{daemon.dnsServer.Addr().IP.String()}
+		} else if len(config.Dns) > 0 {
 			dns = config.Dns
 		} else if len(daemon.config.Dns) > 0 {
 			dns = daemon.config.Dns
@@ -1049,24 +1214,98 @@ func (container *Container) setupWorkingDirectory() error {
 	return nil
 }
 
-func (container *Container) startLoggingToDisk() error {
-	// Setup logging of stdout and stderr to disk
+// getLogConfig returns the logging configuration of the container, falling
+// back to the default driver of the daemon
+func (container *Container) getLogConfig() runconfig.LogConfig {
+	cfg := container.hostConfig.LogConfig
+	if cfg.Type == "" {
+		cfg.Type = container.daemon.config.LogDriver
+	}
+	return cfg
+}
+
+func (container *Container) getLogContext(cfg runconfig.LogConfig) (logger.Context, error) {
 	pth, err := container.logPath("json")
 	if err != nil {
-		return err
+		return logger.Context{}, err
+	}
+	return logger.Context{
+		Config:        cfg.Config,
+		ContainerID:   container.ID,
+		ContainerName: container.Name,
+		LogPath:       pth,
+	}, nil
+}
+
+// startLogging sends the output of the container to its logging driver
+func (container *Container) startLogging() error {
+	cfg := container.getLogConfig()
+	if cfg.Type == "none" {
+		return nil
 	}
 
-	if err := container.daemon.LogToDisk(container.stdout, pth, "stdout"); err != nil {
+	creator, err := logger.GetLogDriver(cfg.Type)
+	if err != nil {
 		return err
 	}
+	ctx, err := container.getLogContext(cfg)
+	if err != nil {
+		return err
+	}
+	l, err := creator(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to initialize logging driver: %v", err)
+	}
 
-	if err := container.daemon.LogToDisk(container.stderr, pth, "stderr"); err != nil {
+	stdout, err := container.StdoutPipe()
+	if err != nil {
+		l.Close()
+		return err
+	}
+	stderr, err := container.StderrPipe()
+	if err != nil {
+		stdout.Close()
+		l.Close()
 		return err
 	}
 
+	container.logDriver = l
+	container.logCopier = logger.NewCopier(container.ID, map[string]io.Reader{"stdout": stdout, "stderr": stderr}, l)
+	container.logCopier.Run()
+
 	return nil
 }
 
+// stopLogging waits for the remaining output of the container to be sent
+// to its logging driver and closes it. The output streams of the container
+// must have been closed beforehand.
+func (container *Container) stopLogging() {
+	if container.logDriver == nil {
+		return
+	}
+	container.logCopier.Wait()
+	if err := container.logDriver.Close(); err != nil {
+		log.Errorf("%s: Error closing logger: %s", container.ID, err)
+	}
+	container.logDriver = nil
+	container.logCopier = nil
+}
+
+// readLogs returns the logs of the container as a stream of serialized
+// jsonlog.JSONLog, provided its logging driver is able to read them back
+func (container *Container) readLogs() (io.ReadCloser, error) {
+	cfg := container.getLogConfig()
+	reader, err := logger.GetLogReader(cfg.Type)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := container.getLogContext(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return reader(ctx)
+}
+
 func (container *Container) waitForStart() error {
 	container.monitor = newContainerMonitor(container, container.hostConfig.RestartPolicy)
 
@@ -1081,20 +1320,33 @@ func (container *Container) waitForStart() error {
 	return nil
 }
 
-func (container *Container) allocatePort(eng *engine.Engine, port nat.Port, bindings nat.PortMap) error {
-	binding := bindings[port]
-	if container.hostConfig.PublishAllPorts && len(binding) == 0 {
-		binding = append(binding, nat.PortBinding{})
+// allocatePorts publishes the exposed ports of portSpecs according to their
+// bindings, filling in the addresses they get on the host. The bindings of
+// consecutive ports are published as a single range.
+func (container *Container) allocatePorts(eng *engine.Engine, portSpecs nat.PortSet, bindings nat.PortMap) error {
+	published := make(nat.PortMap)
+	for port := range portSpecs {
+		binding := bindings[port]
+		if container.hostConfig.PublishAllPorts && len(binding) == 0 {
+			binding = append(binding, nat.PortBinding{})
+		}
+		if len(binding) > 0 {
+			published[port] = binding
+		}
 	}
 
-	for i := 0; i < len(binding); i++ {
-		b := binding[i]
-
+	ranges, err := nat.GroupBindings(published)
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
 		job := eng.Job("allocate_port", container.ID)
-		job.Setenv("HostIP", b.HostIp)
-		job.Setenv("HostPort", b.HostPort)
-		job.Setenv("Proto", port.Proto())
-		job.Setenv("ContainerPort", port.Port())
+		job.Setenv("Network", container.networkName())
+		job.Setenv("HostIP", r.HostIp)
+		job.SetenvInt("HostPort", r.HostPort)
+		job.Setenv("Proto", r.Proto)
+		job.SetenvInt("ContainerPort", r.Port)
+		job.SetenvInt("PortCount", r.Count)
 
 		portEnv, err := job.Stdout.AddEnv()
 		if err != nil {
@@ -1104,12 +1356,19 @@ func (container *Container) allocatePort(eng *engine.Engine, port nat.Port, bind
 			eng.Job("release_interface", container.ID).Run()
 			return err
 		}
-		b.HostIp = portEnv.Get("HostIP")
-		b.HostPort = portEnv.Get("HostPort")
 
-		binding[i] = b
+		hostPort := portEnv.GetInt("HostPort")
+		for i, port := range r.Ports() {
+			published[port][r.Index] = nat.PortBinding{
+				HostIp:   portEnv.Get("HostIP"),
+				HostPort: strconv.Itoa(hostPort + i),
+			}
+		}
+	}
+
+	for port, binding := range published {
+		bindings[port] = binding
 	}
-	bindings[port] = binding
 	return nil
 }
 
@@ -20,13 +20,17 @@ import (
 	"github.com/docker/docker/daemon/execdriver/lxc"
 	"github.com/docker/docker/daemon/graphdriver"
 	_ "github.com/docker/docker/daemon/graphdriver/vfs"
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/jsonfilelog"
 	_ "github.com/docker/docker/daemon/networkdriver/bridge"
 	"github.com/docker/docker/daemon/networkdriver/portallocator"
+	_ "github.com/docker/docker/daemon/volumedriver/local"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/graph"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/broadcastwriter"
+	"github.com/docker/docker/pkg/dnsserver"
 	"github.com/docker/docker/pkg/graphdb"
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/namesgenerator"
@@ -36,7 +40,9 @@ import (
 	"github.com/docker/docker/pkg/sysinfo"
 	"github.com/docker/docker/pkg/truncindex"
 	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/trust"
 	"github.com/docker/docker/utils"
+	"github.com/docker/docker/volumes"
 )
 
 var (
@@ -88,12 +94,14 @@ type Daemon struct {
 	repositories   *graph.TagStore
 	idIndex        *truncindex.TruncIndex
 	sysInfo        *sysinfo.SysInfo
-	volumes        *graph.Graph
+	volumes        *volumes.Repository
 	eng            *engine.Engine
 	config         *Config
 	containerGraph *graphdb.Database
 	driver         graphdriver.Driver
 	execDriver     execdriver.Driver
+	dnsServer      *dnsserver.Server
+	execCommands   *execStore
 }
 
 // Install installs daemon capabilities to eng.
@@ -105,12 +113,19 @@ func (daemon *Daemon) Install(eng *engine.Engine) error {
 		"attach":            daemon.ContainerAttach,
 		"build":             daemon.CmdBuild,
 		"commit":            daemon.ContainerCommit,
+		"connect":           daemon.ContainerNetworkConnect,
 		"container_changes": daemon.ContainerChanges,
 		"container_copy":    daemon.ContainerCopy,
 		"container_inspect": daemon.ContainerInspect,
+		"container_rename":  daemon.ContainerRename,
+		"container_stats":   daemon.ContainerStats,
+		"container_update":  daemon.ContainerUpdate,
 		"containers":        daemon.Containers,
 		"create":            daemon.ContainerCreate,
 		"delete":            daemon.ContainerDestroy,
+		"disconnect":        daemon.ContainerNetworkDisconnect,
+		"exec":              daemon.ContainerExec,
+		"exec_inspect":      daemon.ContainerExecInspect,
 		"export":            daemon.ContainerExport,
 		"info":              daemon.CmdInfo,
 		"kill":              daemon.ContainerKill,
@@ -122,6 +137,11 @@ func (daemon *Daemon) Install(eng *engine.Engine) error {
 		"stop":              daemon.ContainerStop,
 		"top":               daemon.ContainerTop,
 		"unpause":           daemon.ContainerUnpause,
+		"volume_create":     daemon.VolumeCreate,
+		"volume_inspect":    daemon.VolumeInspect,
+		"volume_prune":      daemon.VolumePrune,
+		"volume_rm":         daemon.VolumeRm,
+		"volumes":           daemon.VolumeList,
 		"wait":              daemon.ContainerWait,
 		"image_delete":      daemon.ImageDelete, // FIXME: see above
 	} {
@@ -209,6 +229,9 @@ func (daemon *Daemon) register(container *Container, updateSuffixarray bool) err
 	// done
 	daemon.containers.Add(container.ID, container)
 
+	// Account for the volumes the container is using
+	daemon.registerVolumes(container)
+
 	// don't update the Suffixarray if we're starting up
 	// we'll waste time if we update it for every container
 	daemon.idIndex.Add(container.ID)
@@ -276,13 +299,14 @@ func (daemon *Daemon) ensureName(container *Container) error {
 	return nil
 }
 
-func (daemon *Daemon) LogToDisk(src *broadcastwriter.BroadcastWriter, dst, stream string) error {
-	log, err := os.OpenFile(dst, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
-	if err != nil {
-		return err
+// validateLogDriver checks that name is either "none" or a registered
+// logging driver
+func validateLogDriver(name string) error {
+	if name == "none" {
+		return nil
 	}
-	src.AddWriter(log, stream)
-	return nil
+	_, err := logger.GetLogDriver(name)
+	return err
 }
 
 func (daemon *Daemon) restore() error {
@@ -364,16 +388,29 @@ func (daemon *Daemon) restore() error {
 	if daemon.config.AutoRestart {
 		log.Debugf("Restarting containers...")
 
+		// the containers which had an ip before the daemon stopped are
+		// started first, so that they get their previous ip back before it
+		// is given to another container
+		var running, others []*Container
 		for _, container := range registeredContainers {
 			if container.hostConfig.RestartPolicy.Name == "always" ||
 				(container.hostConfig.RestartPolicy.Name == "on-failure" && container.State.ExitCode != 0) {
-				log.Debugf("Starting container %s", container.ID)
-
-				if err := container.Start(); err != nil {
-					log.Debugf("Failed to start container %s: %s", container.ID, err)
+				if container.LastIPAddress != "" || (container.NetworkSettings != nil && container.NetworkSettings.IPAddress != "") {
+					container.restoredIP = container.LastIPAddress
+					running = append(running, container)
+				} else {
+					others = append(others, container)
 				}
 			}
 		}
+
+		for _, container := range append(running, others...) {
+			log.Debugf("Starting container %s", container.ID)
+
+			if err := container.Start(); err != nil {
+				log.Debugf("Failed to start container %s: %s", container.ID, err)
+			}
+		}
 	}
 
 	if !debug {
@@ -556,7 +593,7 @@ func (daemon *Daemon) createRootfs(container *Container, img *image.Image) error
 		return err
 	}
 	initID := fmt.Sprintf("%s-init", container.ID)
-	if err := daemon.driver.Create(initID, img.ID); err != nil {
+	if err := daemon.driver.Create(initID, img.Layer); err != nil {
 		return err
 	}
 	initPath, err := daemon.driver.Get(initID, "")
@@ -676,6 +713,15 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		// FIXME: GetDefaultNetwork Mtu doesn't need to be public anymore
 		config.Mtu = GetDefaultNetworkMtu()
 	}
+	if config.LogDriver == "" {
+		config.LogDriver = jsonfilelog.Name
+	}
+	if config.ImageTrust == "" {
+		config.ImageTrust = trust.PolicyWarn
+	}
+	if err := validateLogDriver(config.LogDriver); err != nil {
+		return nil, err
+	}
 	// Check for mutually incompatible config options
 	if config.BridgeIface != "" && config.BridgeIP != "" {
 		return nil, fmt.Errorf("You specified -b & --bip, mutually exclusive options. Please specify only one.")
@@ -683,6 +729,9 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	if !config.EnableIptables && !config.InterContainerCommunication {
 		return nil, fmt.Errorf("You specified --iptables=false with --icc=false. ICC uses iptables to function. Please set --icc or --iptables to true.")
 	}
+	if config.FixedCIDRv6 != "" && !config.EnableIPv6 {
+		return nil, fmt.Errorf("You specified --fixed-cidr-v6 without --ipv6. Please set --ipv6 to true.")
+	}
 	// FIXME: DisableNetworkBidge doesn't need to be public anymore
 	config.DisableNetwork = config.BridgeIface == DisableNetworkBridge
 
@@ -772,19 +821,18 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		return nil, err
 	}
 
-	// We don't want to use a complex driver like aufs or devmapper
-	// for volumes, just a plain filesystem
-	volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions)
+	log.Debugf("Creating volumes repository")
+	volumesRepo, err := volumes.NewRepository(path.Join(config.Root, "volumes"), config.Root)
 	if err != nil {
 		return nil, err
 	}
-	log.Debugf("Creating volumes graph")
-	volumes, err := graph.NewGraph(path.Join(config.Root, "volumes"), volumesDriver)
+	log.Debugf("Creating the trust store")
+	trustStore, err := trust.NewStore(path.Join(config.Root, "trust"), config.ImageTrust)
 	if err != nil {
 		return nil, err
 	}
 	log.Debugf("Creating repository list") //创建镜像仓库列表
-	repositories, err := graph.NewTagStore(path.Join(config.Root, "repositories-"+driver.String()), g)
+	repositories, err := graph.NewTagStore(path.Join(config.Root, "repositories-"+driver.String()), g, trustStore)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't create Tag store: %s", err)
 	}
@@ -795,9 +843,17 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		job.SetenvBool("EnableIptables", config.EnableIptables)
 		job.SetenvBool("InterContainerCommunication", config.InterContainerCommunication)
 		job.SetenvBool("EnableIpForward", config.EnableIpForward)
+		job.SetenvBool("EnableUserlandProxy", config.EnableUserlandProxy)
 		job.Setenv("BridgeIface", config.BridgeIface)
 		job.Setenv("BridgeIP", config.BridgeIP)
+		job.Setenv("FixedCIDR", config.FixedCIDR)
+		job.SetenvBool("EnableIPv6", config.EnableIPv6)
+		job.Setenv("FixedCIDRv6", config.FixedCIDRv6)
+		job.SetenvList("MacvlanRanges", config.MacvlanRanges)
 		job.Setenv("DefaultBindingIP", config.DefaultIp.String())
+		job.Setenv("NetworksPath", path.Join(config.Root, "networks.json"))
+		job.Setenv("ClusterStore", config.ClusterStore)
+		job.Setenv("ClusterAdvertise", config.ClusterAdvertise)
 
 		if err := job.Run(); err != nil {
 			return nil, err
@@ -843,17 +899,23 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		repositories:   repositories, //存储本机所有docker镜像repo信息的对象
 		idIndex:        truncindex.NewTruncIndex([]string{}),
 		sysInfo:        sysInfo, //系统功能信息
-		volumes:        volumes, //管理宿主机逻辑卷的内容griver
+		volumes:        volumesRepo, //管理宿主机逻辑卷的内容griver
 		config:         config,
 		containerGraph: graph,
 		driver:         driver, //管理镜像的驱动grphdriver 默认为aufs类型
 		sysInitPath:    sysInitPath,
 		execDriver:     ed,
 		eng:            eng,
+		execCommands:   newExecStore(),
 	}
 	if err := daemon.checkLocaldns(); err != nil {
 		return nil, err
 	}
+	if !config.DisableNetwork {
+		if err := daemon.startDnsServer(); err != nil {
+			log.Infof("WARNING: unable to start the embedded dns server, the containers will not resolve the names of the other containers: %s", err)
+		}
+	}
 	if err := daemon.restore(); err != nil { //加载已有Docker容器
 		return nil, err
 	}
@@ -866,6 +928,11 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		if err := daemon.shutdown(); err != nil {
 			log.Errorf("daemon.shutdown(): %s", err)
 		}
+		if daemon.dnsServer != nil {
+			if err := daemon.dnsServer.Close(); err != nil {
+				log.Errorf("daemon.dnsServer.Close(): %s", err)
+			}
+		}
 		if err := portallocator.ReleaseAll(); err != nil {
 			log.Errorf("portallocator.ReleaseAll(): %s", err)
 		}
@@ -970,6 +1037,10 @@ func (daemon *Daemon) Run(c *Container, pipes *execdriver.Pipes, startCallback e
 	return daemon.execDriver.Run(c.command, pipes, startCallback)
 }
 
+func (daemon *Daemon) Exec(c *Container, processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes, startCallback execdriver.ExecStartCallback) (int, error) {
+	return daemon.execDriver.Exec(c.command, processConfig, pipes, startCallback)
+}
+
 func (daemon *Daemon) Pause(c *Container) error {
 	if err := daemon.execDriver.Pause(c.command); err != nil {
 		return err
@@ -1042,7 +1113,7 @@ func (daemon *Daemon) ExecutionDriver() execdriver.Driver {
 	return daemon.execDriver
 }
 
-func (daemon *Daemon) Volumes() *graph.Graph {
+func (daemon *Daemon) Volumes() *volumes.Repository {
 	return daemon.volumes
 }
 
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"net"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/bridge"
+	"github.com/docker/docker/pkg/dnsserver"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/networkfs/resolvconf"
+)
+
+// startDnsServer starts the embedded dns server on the gateway of the bridge.
+// It answers the queries of the containers for the names of the containers
+// and the aliases of their links, and forwards the other queries to the
+// nameservers of the daemon.
+func (daemon *Daemon) startDnsServer() error {
+	iface := daemon.config.BridgeIface
+	if iface == "" {
+		iface = bridge.DefaultNetworkBridge
+	}
+	addr, err := networkdriver.GetIfaceAddr(iface)
+	if err != nil {
+		return err
+	}
+	server, err := dnsserver.NewServer(addr.(*net.IPNet).IP, 53, daemon.resolveName, daemon.dnsForwarders)
+	if err != nil {
+		return err
+	}
+	server.Start()
+	daemon.dnsServer = server
+	return nil
+}
+
+// dnsForwarders returns the nameservers the queries for the names which are
+// not container names are forwarded to
+func (daemon *Daemon) dnsForwarders() []string {
+	if len(daemon.config.Dns) > 0 {
+		return daemon.config.Dns
+	}
+	resolvConf, err := resolvconf.Get()
+	if err != nil {
+		log.Debugf("Unable to read the nameservers of the host: %s", err)
+		return DefaultDns
+	}
+	if nameservers := resolvconf.GetNameservers(resolvConf); len(nameservers) > 0 {
+		return nameservers
+	}
+	return DefaultDns
+}
+
+// resolveName returns the current addresses of the container called name for
+// the container having the address client: an alias of one of the links of
+// the client comes first, then the names of the containers sharing a network
+// with the client. It returns nil when name is not the one of a running
+// container the client may reach, so that the containers of the isolated
+// networks are not disclosed.
+func (daemon *Daemon) resolveName(name string, client net.IP) []net.IP {
+	if strings.Contains(name, "/") {
+		return nil
+	}
+	requester := daemon.containerByIP(client)
+	if requester == nil {
+		return nil
+	}
+
+	var (
+		target *Container
+		linked bool
+	)
+	if entity := daemon.containerGraph.Get(path.Join(requester.Name, name)); entity != nil {
+		target, linked = daemon.containers.Get(entity.ID()), true
+	} else if entity := daemon.containerGraph.Get("/" + name); entity != nil {
+		target = daemon.containers.Get(entity.ID())
+	}
+	if target == nil || !target.State.IsRunning() {
+		return nil
+	}
+	if ip := target.addressFor(requester, linked); ip != nil {
+		return []net.IP{ip}
+	}
+	return nil
+}
+
+// containerByIP returns the container with an interface having the address ip
+func (daemon *Daemon) containerByIP(ip net.IP) *Container {
+	for _, container := range daemon.List() {
+		for _, address := range container.endpoints() {
+			if address == ip.String() {
+				return container
+			}
+		}
+	}
+	return nil
+}
+
+// endpoints returns the addresses of the container by network: the one on
+// the network of its network mode, and the ones on the networks it was
+// connected to
+func (container *Container) endpoints() map[string]string {
+	settings := container.NetworkSettings
+	if settings == nil || settings.IPAddress == "" {
+		return nil
+	}
+	endpoints := map[string]string{container.networkName(): settings.IPAddress}
+	for network, endpoint := range settings.Networks {
+		endpoints[network] = endpoint.IPAddress
+	}
+	return endpoints
+}
+
+// addressFor returns the address of the container reachable by requester:
+// the one on a network they share, else, when requester is linked to the
+// container, the one on the network of its network mode. It returns nil
+// when the container is not reachable by requester.
+func (container *Container) addressFor(requester *Container, linked bool) net.IP {
+	endpoints := container.endpoints()
+	for network, address := range requester.endpoints() {
+		if ip, shared := endpoints[network]; shared && address != "" {
+			return net.ParseIP(ip)
+		}
+	}
+	if linked && container.NetworkSettings != nil {
+		return net.ParseIP(container.NetworkSettings.IPAddress)
+	}
+	return nil
+}
+
+// usesDnsServer tells whether the resolv.conf of the container points to the
+// embedded dns server, which only listens on the gateway of the default
+// network: the containers on the other networks keep the nameservers of the
+// daemon
+func (container *Container) usesDnsServer() bool {
+	mode := container.hostConfig.NetworkMode
+	if container.daemon.dnsServer == nil || len(container.hostConfig.Dns) > 0 || container.Config.NetworkDisabled {
+		return false
+	}
+	return mode == "" || mode == "bridge"
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/runconfig"
+)
+
+func dnsTestContainer(mode, ip string, networks map[string]string) *Container {
+	container := &Container{
+		hostConfig:      &runconfig.HostConfig{NetworkMode: runconfig.NetworkMode(mode)},
+		NetworkSettings: &NetworkSettings{IPAddress: ip, Networks: make(map[string]*EndpointSettings)},
+	}
+	for network, address := range networks {
+		container.NetworkSettings.Networks[network] = &EndpointSettings{IPAddress: address}
+	}
+	return container
+}
+
+func TestAddressFor(t *testing.T) {
+	var (
+		web      = dnsTestContainer("bridge", "172.17.0.2", map[string]string{"backend": "10.5.0.2"})
+		db       = dnsTestContainer("backend", "10.5.0.3", nil)
+		isolated = dnsTestContainer("secret", "10.6.0.2", nil)
+		other    = dnsTestContainer("bridge", "172.17.0.4", nil)
+	)
+
+	if ip := db.addressFor(web, false); ip == nil || ip.String() != "10.5.0.3" {
+		t.Fatalf("Expected the address of db on the network it shares with web, got %s", ip)
+	}
+	if ip := web.addressFor(db, false); ip == nil || ip.String() != "10.5.0.2" {
+		t.Fatalf("Expected the address of web on the network it shares with db, got %s", ip)
+	}
+	if ip := web.addressFor(other, false); ip == nil || ip.String() != "172.17.0.2" {
+		t.Fatalf("Expected the address of web on the default network, got %s", ip)
+	}
+	if ip := isolated.addressFor(web, false); ip != nil {
+		t.Fatalf("Expected a container on another network not to be disclosed, got %s", ip)
+	}
+	if ip := isolated.addressFor(web, true); ip == nil || ip.String() != "10.6.0.2" {
+		t.Fatalf("Expected a linked container to be resolved, got %s", ip)
+	}
+
+	stopped := dnsTestContainer("bridge", "", nil)
+	if ip := stopped.addressFor(other, false); ip != nil {
+		t.Fatalf("Expected no address for a container without network, got %s", ip)
+	}
+}
@@ -0,0 +1,185 @@
+package daemon
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
+)
+
+// An execRecord keeps the state of an exec'd process, for its exit code to be
+// inspected once its connection ends
+type execRecord struct {
+	ID          string
+	ContainerID string
+	Running     bool
+	ExitCode    int
+}
+
+// maxFinishedExecs is the number of finished execs kept by container, the
+// oldest ones are forgotten past it
+const maxFinishedExecs = 64
+
+type execStore struct {
+	s map[string]*execRecord
+	// the ids of the finished execs of each container, oldest first
+	finished map[string][]string
+	sync.Mutex
+}
+
+func newExecStore() *execStore {
+	return &execStore{
+		s:        make(map[string]*execRecord),
+		finished: make(map[string][]string),
+	}
+}
+
+func (e *execStore) Add(record *execRecord) {
+	e.Lock()
+	e.s[record.ID] = record
+	e.Unlock()
+}
+
+func (e *execStore) Get(id string) *execRecord {
+	e.Lock()
+	defer e.Unlock()
+	if record, exists := e.s[id]; exists {
+		copied := *record
+		return &copied
+	}
+	return nil
+}
+
+// Exited records the exit code of the process of the exec id, forgetting the
+// oldest finished exec of its container when it has too many
+func (e *execStore) Exited(id string, exitCode int) {
+	e.Lock()
+	defer e.Unlock()
+	record, exists := e.s[id]
+	if !exists || !record.Running {
+		return
+	}
+	record.Running = false
+	record.ExitCode = exitCode
+
+	finished := append(e.finished[record.ContainerID], id)
+	if len(finished) > maxFinishedExecs {
+		delete(e.s, finished[0])
+		finished = finished[1:]
+	}
+	e.finished[record.ContainerID] = finished
+}
+
+// DeleteContainer forgets the execs of the container id
+func (e *execStore) DeleteContainer(id string) {
+	e.Lock()
+	for execID, record := range e.s {
+		if record.ContainerID == id {
+			delete(e.s, execID)
+		}
+	}
+	delete(e.finished, id)
+	e.Unlock()
+}
+
+func (daemon *Daemon) ContainerExec(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+
+	var (
+		name       = job.Args[0]
+		execConfig = runconfig.ExecConfigFromJob(job)
+		execID     = job.Getenv("ExecID")
+		exitCode   = -1
+	)
+	if execID == "" {
+		execID = utils.GenerateRandomID()
+	}
+
+	if len(execConfig.Cmd) == 0 {
+		return job.Errorf("No exec command specified")
+	}
+
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	// The exit code is -1 for the processes which could not be run
+	daemon.execCommands.Add(&execRecord{ID: execID, ContainerID: container.ID, Running: true, ExitCode: exitCode})
+	defer func() {
+		daemon.execCommands.Exited(execID, exitCode)
+	}()
+
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+	if container.State.IsPaused() {
+		return job.Errorf("Container %s is paused, unpause the container before exec", name)
+	}
+
+	var (
+		cStdin           io.ReadCloser
+		cStdout, cStderr io.Writer
+	)
+
+	if execConfig.AttachStdin {
+		r, w := io.Pipe()
+		go func() {
+			defer w.Close()
+			defer log.Debugf("Closing buffered stdin pipe")
+			io.Copy(w, job.Stdin)
+		}()
+		cStdin = r
+	}
+	if execConfig.AttachStdout {
+		cStdout = job.Stdout
+	}
+	if execConfig.AttachStderr {
+		cStderr = job.Stderr
+	}
+
+	processConfig := &execdriver.ProcessConfig{
+		User:       execConfig.User,
+		Tty:        execConfig.Tty,
+		Entrypoint: execConfig.Cmd[0],
+		Arguments:  execConfig.Cmd[1:],
+	}
+
+	container.LogEvent("exec_start: " + strings.Join(execConfig.Cmd, " "))
+
+	code, err := daemon.Exec(container, processConfig, execdriver.NewPipes(cStdin, cStdout, cStderr, execConfig.AttachStdin), nil)
+	if err != nil {
+		return job.Errorf("Cannot run exec command %v in container %s: %s", execConfig.Cmd, name, err)
+	}
+	exitCode = code
+	log.Debugf("Exec command %v in container %s exited with %d", execConfig.Cmd, container.ID, exitCode)
+
+	return engine.StatusOK
+}
+
+// ContainerExecInspect writes the state of the exec of id, with the exit code
+// of its process once it exited
+func (daemon *Daemon) ContainerExecInspect(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s EXEC", job.Name)
+	}
+	record := daemon.execCommands.Get(job.Args[0])
+	if record == nil {
+		return job.Errorf("No such exec instance: %s", job.Args[0])
+	}
+	out := &engine.Env{}
+	out.Set("ID", record.ID)
+	out.Set("ContainerID", record.ContainerID)
+	out.SetBool("Running", record.Running)
+	out.SetInt("ExitCode", record.ExitCode)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
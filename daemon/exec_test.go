@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExecStore(t *testing.T) {
+	execs := newExecStore()
+	execs.Add(&execRecord{ID: "exec1", ContainerID: "container1", Running: true, ExitCode: -1})
+	execs.Add(&execRecord{ID: "exec2", ContainerID: "container2", Running: true, ExitCode: -1})
+
+	execs.Exited("exec1", 23)
+	record := execs.Get("exec1")
+	if record == nil || record.Running || record.ExitCode != 23 || record.ContainerID != "container1" {
+		t.Fatalf("Expected the exit code 23 of exec1 in container1, got %+v", record)
+	}
+	if record := execs.Get("exec2"); record == nil || !record.Running {
+		t.Fatalf("Expected exec2 to be running, got %+v", record)
+	}
+
+	execs.Exited("exec2", 0)
+	execs.DeleteContainer("container1")
+	if execs.Get("exec1") != nil {
+		t.Fatal("Expected the execs of a removed container to be forgotten")
+	}
+	if execs.Get("exec2") == nil {
+		t.Fatal("Expected the execs of the other containers to be kept")
+	}
+}
+
+func TestExecStoreIsBounded(t *testing.T) {
+	execs := newExecStore()
+	execs.Add(&execRecord{ID: "running", ContainerID: "container1", Running: true, ExitCode: -1})
+	for i := 0; i < 3*maxFinishedExecs; i++ {
+		id := fmt.Sprintf("exec%d", i)
+		execs.Add(&execRecord{ID: id, ContainerID: "container1", Running: true, ExitCode: -1})
+		execs.Exited(id, 0)
+	}
+
+	if len(execs.s) != maxFinishedExecs+1 {
+		t.Fatalf("Expected %d execs to be kept, got %d", maxFinishedExecs+1, len(execs.s))
+	}
+	if execs.Get("running") == nil {
+		t.Fatal("Expected the running exec to be kept")
+	}
+	if execs.Get("exec0") != nil {
+		t.Fatal("Expected the oldest finished exec to be forgotten")
+	}
+	if last := fmt.Sprintf("exec%d", 3*maxFinishedExecs-1); execs.Get(last) == nil {
+		t.Fatalf("Expected the last finished exec %s to be kept", last)
+	}
+
+	// exiting twice does not count the exec twice
+	execs.Exited("exec0", 0)
+	execs.Exited(fmt.Sprintf("exec%d", 3*maxFinishedExecs-1), 1)
+	if len(execs.s) != maxFinishedExecs+1 {
+		t.Fatalf("Expected %d execs to be kept, got %d", maxFinishedExecs+1, len(execs.s))
+	}
+
+	execs.DeleteContainer("container1")
+	if len(execs.s) != 0 || len(execs.finished) != 0 {
+		t.Fatalf("Expected the execs of the removed container to be forgotten, got %d", len(execs.s))
+	}
+}
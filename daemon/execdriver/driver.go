@@ -2,10 +2,13 @@ package execdriver
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"time"
 
+	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/devices"
 )
 
@@ -22,6 +25,8 @@ var (
 
 type StartCallback func(*Command)
 
+type ExecStartCallback func(*ProcessConfig)
+
 // Driver specific information based on
 // processes registered with the driver
 type Info interface {
@@ -42,6 +47,8 @@ type TtyTerminal interface {
 
 type Driver interface {
 	Run(c *Command, pipes *Pipes, startCallback StartCallback) (int, error) // Run executes the process and blocks until the process exits and returns the exit code
+	// Exec executes an additional process inside the running container c and blocks until it exits and returns the exit code
+	Exec(c *Command, processConfig *ProcessConfig, pipes *Pipes, startCallback ExecStartCallback) (int, error)
 	Kill(c *Command, sig int) error
 	Pause(c *Command) error
 	Unpause(c *Command) error
@@ -49,6 +56,8 @@ type Driver interface {
 	Info(id string) Info                          // "temporary" hack (until we move state from core to plugins)
 	GetPidsForContainer(id string) ([]int, error) // Returns a list of pids for the given container.
 	Terminate(c *Command) error                   // kill it with fire
+	Stats(id string) (*ResourceStats, error)      // Returns resource usage statistics of a running container
+	Update(c *Command) error                      // Applies the resources of c to the running container
 }
 
 // Network settings of the container
@@ -60,10 +69,19 @@ type Network struct {
 }
 
 type NetworkInterface struct {
-	Gateway     string `json:"gateway"`
-	IPAddress   string `json:"ip"`
-	Bridge      string `json:"bridge"`
-	IPPrefixLen int    `json:"ip_prefix_len"`
+	Gateway              string `json:"gateway"`
+	IPAddress            string `json:"ip"`
+	Bridge               string `json:"bridge"`
+	IPPrefixLen          int    `json:"ip_prefix_len"`
+	MacAddress           string `json:"mac_address"`
+	LinkLocalIPv6Address string `json:"link_local_ipv6"`
+	GlobalIPv6Address    string `json:"global_ipv6"`
+	GlobalIPv6PrefixLen  int    `json:"global_ipv6_prefix_len"`
+	IPv6Gateway          string `json:"ipv6_gateway"`
+	HairpinMode          bool   `json:"hairpin_mode"`
+	// Parent is the interface of the host a macvlan interface is created
+	// on for the container, in place of a veth pair attached to Bridge
+	Parent string `json:"parent"`
 }
 
 type Resources struct {
@@ -71,6 +89,35 @@ type Resources struct {
 	MemorySwap int64  `json:"memory_swap"`
 	CpuShares  int64  `json:"cpu_shares"`
 	Cpuset     string `json:"cpuset"`
+
+	MemoryReservation    int64             `json:"memory_reservation"`
+	OomKillDisable       bool              `json:"oom_kill_disable"`
+	CpuPeriod            int64             `json:"cpu_period"`
+	CpuQuota             int64             `json:"cpu_quota"`
+	BlkioWeight          int64             `json:"blkio_weight"`
+	BlkioDeviceReadBps   []*ThrottleDevice `json:"blkio_device_read_bps"`
+	BlkioDeviceWriteBps  []*ThrottleDevice `json:"blkio_device_write_bps"`
+	BlkioDeviceReadIOps  []*ThrottleDevice `json:"blkio_device_read_iops"`
+	BlkioDeviceWriteIOps []*ThrottleDevice `json:"blkio_device_write_iops"`
+}
+
+// ThrottleDevice is a limit on the rate of IO of a block device
+type ThrottleDevice struct {
+	Major int64  `json:"major"`
+	Minor int64  `json:"minor"`
+	Rate  uint64 `json:"rate"`
+}
+
+// String returns the device and rate in the format of the blkio.throttle files
+func (t *ThrottleDevice) String() string {
+	return fmt.Sprintf("%d:%d %d", t.Major, t.Minor, t.Rate)
+}
+
+// ResourceStats is a sample of a container's resource usage
+type ResourceStats struct {
+	*libcontainer.ContainerStats
+	Read        time.Time `json:"read"`
+	MemoryLimit int64     `json:"memory_limit"` // 0 if the container's memory is not limited
 }
 
 type Mount struct {
@@ -113,3 +160,16 @@ type Command struct {
 func (c *Command) Pid() int {
 	return c.ContainerPid
 }
+
+// ProcessConfig describes an additional process started
+// inside the namespaces of an already running container
+type ProcessConfig struct {
+	User       string   `json:"user"`
+	Tty        bool     `json:"tty"`
+	Entrypoint string   `json:"entrypoint"`
+	Arguments  []string `json:"arguments"`
+
+	Terminal Terminal `json:"-"`   // standard or tty terminal
+	Console  string   `json:"-"`   // dev/console path
+	Pid      int      `json:"pid"` // the pid of the process as seen from the host
+}
@@ -195,6 +195,18 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	return getExitCode(c), waitErr
 }
 
+func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes, startCallback execdriver.ExecStartCallback) (int, error) {
+	return -1, fmt.Errorf("Unsupported: Exec is not supported by the %s driver", DriverName)
+}
+
+func (d *driver) Stats(id string) (*execdriver.ResourceStats, error) {
+	return nil, fmt.Errorf("Unsupported: Stats is not supported by the %s driver", DriverName)
+}
+
+func (d *driver) Update(c *execdriver.Command) error {
+	return fmt.Errorf("Unsupported: Update is not supported by the %s driver", DriverName)
+}
+
 /// Return the exit code of the process
 // if the process has not exited -1 will be returned
 func getExitCode(c *execdriver.Command) int {
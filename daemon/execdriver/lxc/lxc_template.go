@@ -11,10 +11,23 @@ import (
 const LxcTemplate = `
 {{if .Network.Interface}}
 # network configuration
+{{if .Network.Interface.Parent}}
+lxc.network.type = macvlan
+lxc.network.macvlan.mode = bridge
+lxc.network.link = {{.Network.Interface.Parent}}
+{{else}}
 lxc.network.type = veth
 lxc.network.link = {{.Network.Interface.Bridge}}
+{{end}}
 lxc.network.name = eth0
 lxc.network.mtu = {{.Network.Mtu}}
+{{if .Network.Interface.MacAddress}}
+lxc.network.hwaddr = {{.Network.Interface.MacAddress}}
+{{end}}
+{{if .Network.Interface.GlobalIPv6Address}}
+lxc.network.ipv6 = {{.Network.Interface.GlobalIPv6Address}}/{{.Network.Interface.GlobalIPv6PrefixLen}}
+lxc.network.ipv6.gateway = {{.Network.Interface.IPv6Gateway}}
+{{end}}
 {{else if .Network.HostNetworking}}
 lxc.network.type = none
 {{else}}
@@ -104,6 +117,33 @@ lxc.cgroup.cpu.shares = {{.Resources.CpuShares}}
 {{if .Resources.Cpuset}}
 lxc.cgroup.cpuset.cpus = {{.Resources.Cpuset}}
 {{end}}
+{{if .Resources.MemoryReservation}}
+lxc.cgroup.memory.soft_limit_in_bytes = {{.Resources.MemoryReservation}}
+{{end}}
+{{if .Resources.OomKillDisable}}
+lxc.cgroup.memory.oom_control = 1
+{{end}}
+{{if .Resources.CpuPeriod}}
+lxc.cgroup.cpu.cfs_period_us = {{.Resources.CpuPeriod}}
+{{end}}
+{{if .Resources.CpuQuota}}
+lxc.cgroup.cpu.cfs_quota_us = {{.Resources.CpuQuota}}
+{{end}}
+{{if .Resources.BlkioWeight}}
+lxc.cgroup.blkio.weight = {{.Resources.BlkioWeight}}
+{{end}}
+{{range .Resources.BlkioDeviceReadBps}}
+lxc.cgroup.blkio.throttle.read_bps_device = {{.}}
+{{end}}
+{{range .Resources.BlkioDeviceWriteBps}}
+lxc.cgroup.blkio.throttle.write_bps_device = {{.}}
+{{end}}
+{{range .Resources.BlkioDeviceReadIOps}}
+lxc.cgroup.blkio.throttle.read_iops_device = {{.}}
+{{end}}
+{{range .Resources.BlkioDeviceWriteIOps}}
+lxc.cgroup.blkio.throttle.write_iops_device = {{.}}
+{{end}}
 {{end}}
 
 {{if .Config.lxc}}
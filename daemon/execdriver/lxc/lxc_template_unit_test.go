@@ -64,6 +64,53 @@ func TestLXCConfig(t *testing.T) {
 		fmt.Sprintf("lxc.cgroup.memory.memsw.limit_in_bytes = %d", mem*2))
 }
 
+func TestLXCConfigResources(t *testing.T) {
+	root, err := ioutil.TempDir("", "TestLXCConfigResources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(path.Join(root, "containers", "1"), 0777)
+
+	driver, err := NewDriver(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := &execdriver.Command{
+		ID: "1",
+		Resources: &execdriver.Resources{
+			MemoryReservation: 33554432,
+			OomKillDisable:    true,
+			CpuPeriod:         50000,
+			CpuQuota:          25000,
+			BlkioWeight:       300,
+			BlkioDeviceReadBps: []*execdriver.ThrottleDevice{
+				{Major: 8, Minor: 0, Rate: 1048576},
+			},
+			BlkioDeviceWriteIOps: []*execdriver.ThrottleDevice{
+				{Major: 8, Minor: 16, Rate: 100},
+			},
+		},
+		Network: &execdriver.Network{
+			Mtu:       1500,
+			Interface: nil,
+		},
+		AllowedDevices: make([]*devices.Device, 0),
+	}
+	p, err := driver.generateLXCConfig(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grepFile(t, p, "lxc.cgroup.memory.soft_limit_in_bytes = 33554432")
+	grepFile(t, p, "lxc.cgroup.memory.oom_control = 1")
+	grepFile(t, p, "lxc.cgroup.cpu.cfs_period_us = 50000")
+	grepFile(t, p, "lxc.cgroup.cpu.cfs_quota_us = 25000")
+	grepFile(t, p, "lxc.cgroup.blkio.weight = 300")
+	grepFile(t, p, "lxc.cgroup.blkio.throttle.read_bps_device = 8:0 1048576")
+	grepFile(t, p, "lxc.cgroup.blkio.throttle.write_iops_device = 8:16 100")
+}
+
 func TestCustomLxcConfig(t *testing.T) {
 	root, err := ioutil.TempDir("", "TestCustomLxcConfig")
 	if err != nil {
@@ -101,6 +148,41 @@ func TestCustomLxcConfig(t *testing.T) {
 	grepFile(t, p, "lxc.cgroup.cpuset.cpus = 0,1")
 }
 
+func TestLxcConfigMacvlan(t *testing.T) {
+	root, err := ioutil.TempDir("", "TestLxcConfigMacvlan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(path.Join(root, "containers", "1"), 0777)
+
+	driver, err := NewDriver(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := &execdriver.Command{
+		ID: "1",
+		Network: &execdriver.Network{
+			Mtu: 1500,
+			Interface: &execdriver.NetworkInterface{
+				IPAddress:   "192.168.1.20",
+				IPPrefixLen: 24,
+				Gateway:     "192.168.1.1",
+				Parent:      "eth1",
+			},
+		},
+	}
+
+	p, err := driver.generateLXCConfig(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grepFile(t, p, "lxc.network.type = macvlan")
+	grepFile(t, p, "lxc.network.link = eth1")
+}
+
 func grepFile(t *testing.T, path string, pattern string) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -0,0 +1,179 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups/fs"
+)
+
+// setupResources writes the limits of c libcontainer has no settings for in
+// the cgroups it created for the container. With systemd, the memory
+// reservation and the cpu quota are not applied by libcontainer either.
+func (d *driver) setupResources(c *execdriver.Command) error {
+	if c.Resources == nil {
+		return nil
+	}
+	state, err := libcontainer.GetState(filepath.Join(d.root, c.ID))
+	if err != nil {
+		return err
+	}
+	return writeResources(state.CgroupPaths, c.Resources)
+}
+
+// updateResources rewrites the memory, cpu shares and cpuset limits of the
+// running container c
+func (d *driver) updateResources(c *execdriver.Command) error {
+	state, err := libcontainer.GetState(filepath.Join(d.root, c.ID))
+	if err != nil {
+		return err
+	}
+	return updateCgroups(state.CgroupPaths, c.Resources, c.ContainerPid)
+}
+
+// writeResources writes the resources libcontainer does not handle in the
+// cgroups at paths, keyed by subsystem
+func writeResources(paths map[string]string, r *execdriver.Resources) error {
+	if r.MemoryReservation != 0 {
+		if err := writeCgroupFile(paths, "memory", "memory.soft_limit_in_bytes", strconv.FormatInt(r.MemoryReservation, 10)); err != nil {
+			return err
+		}
+	}
+	if r.OomKillDisable {
+		if err := writeCgroupFile(paths, "memory", "memory.oom_control", "1"); err != nil {
+			return err
+		}
+	}
+
+	if r.CpuPeriod != 0 {
+		if err := writeCgroupFile(paths, "cpu", "cpu.cfs_period_us", strconv.FormatInt(r.CpuPeriod, 10)); err != nil {
+			return err
+		}
+	}
+	if r.CpuQuota != 0 {
+		if err := writeCgroupFile(paths, "cpu", "cpu.cfs_quota_us", strconv.FormatInt(r.CpuQuota, 10)); err != nil {
+			return err
+		}
+	}
+
+	if r.BlkioWeight != 0 {
+		if err := writeCgroupFile(paths, "blkio", "blkio.weight", strconv.FormatInt(r.BlkioWeight, 10)); err != nil {
+			return err
+		}
+	}
+	for file, devices := range map[string][]*execdriver.ThrottleDevice{
+		"blkio.throttle.read_bps_device":   r.BlkioDeviceReadBps,
+		"blkio.throttle.write_bps_device":  r.BlkioDeviceWriteBps,
+		"blkio.throttle.read_iops_device":  r.BlkioDeviceReadIOps,
+		"blkio.throttle.write_iops_device": r.BlkioDeviceWriteIOps,
+	} {
+		// the kernel takes a single device per write
+		for _, td := range devices {
+			if err := writeCgroupFile(paths, "blkio", file, td.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateCgroups writes the memory, cpu shares and cpuset limits of r in the
+// cgroups at paths, keyed by subsystem, of the running process pid
+func updateCgroups(paths map[string]string, r *execdriver.Resources, pid int) error {
+	if r.Memory != 0 {
+		reservation := r.MemoryReservation
+		if reservation == 0 {
+			reservation = r.Memory
+		}
+		memorySwap := r.MemorySwap
+		if memorySwap == 0 {
+			memorySwap = r.Memory * 2
+		}
+		// the memory limit cannot be raised above the memory+swap limit, so
+		// the latter is written first when growing and last when shrinking
+		writeSwap := func() error {
+			if memorySwap < 0 {
+				return nil
+			}
+			return writeCgroupFile(paths, "memory", "memory.memsw.limit_in_bytes", strconv.FormatInt(memorySwap, 10))
+		}
+		growing := false
+		if current, err := readCgroupFile(paths, "memory", "memory.memsw.limit_in_bytes"); err == nil {
+			if value, err := strconv.ParseInt(current, 10, 64); err == nil && value < memorySwap {
+				growing = true
+			}
+		}
+		if growing {
+			if err := writeSwap(); err != nil {
+				return err
+			}
+		}
+		if err := writeCgroupFile(paths, "memory", "memory.limit_in_bytes", strconv.FormatInt(r.Memory, 10)); err != nil {
+			return err
+		}
+		if err := writeCgroupFile(paths, "memory", "memory.soft_limit_in_bytes", strconv.FormatInt(reservation, 10)); err != nil {
+			return err
+		}
+		if !growing {
+			if err := writeSwap(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.CpuShares != 0 {
+		if err := writeCgroupFile(paths, "cpu", "cpu.shares", strconv.FormatInt(r.CpuShares, 10)); err != nil {
+			return err
+		}
+	}
+
+	if r.Cpuset != "" {
+		dir, err := cgroupDir(paths, "cpuset")
+		if err != nil {
+			return err
+		}
+		// the container joins the cpuset cgroup if it was started without one
+		if err := (&fs.CpusetGroup{}).SetDir(dir, r.Cpuset, pid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cgroupDir returns the cgroup of the container for the subsystem
+func cgroupDir(paths map[string]string, subsystem string) (string, error) {
+	dir, exists := paths[subsystem]
+	if !exists {
+		return "", fmt.Errorf("The %s cgroup of the container was not found", subsystem)
+	}
+	return dir, nil
+}
+
+func readCgroupFile(paths map[string]string, subsystem, file string) (string, error) {
+	dir, err := cgroupDir(paths, subsystem)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func writeCgroupFile(paths map[string]string, subsystem, file, data string) error {
+	dir, err := cgroupDir(paths, subsystem)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, file), []byte(data), 0700)
+}
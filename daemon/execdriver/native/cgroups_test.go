@@ -0,0 +1,121 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/execdriver"
+)
+
+// tempCgroups returns the fake cgroup directories of the subsystems
+func tempCgroups(t *testing.T, subsystems ...string) (string, map[string]string) {
+	root, err := ioutil.TempDir("", "native-cgroups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[string]string)
+	for _, subsystem := range subsystems {
+		paths[subsystem] = filepath.Join(root, subsystem)
+		if err := os.Mkdir(paths[subsystem], 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, paths
+}
+
+func checkCgroupFile(t *testing.T, paths map[string]string, subsystem, file, expected string) {
+	data, err := ioutil.ReadFile(filepath.Join(paths[subsystem], file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Fatalf("Expected %s to be %q, got %q", file, expected, data)
+	}
+}
+
+func TestWriteResources(t *testing.T) {
+	root, paths := tempCgroups(t, "memory", "cpu", "blkio")
+	defer os.RemoveAll(root)
+
+	r := &execdriver.Resources{
+		MemoryReservation: 524288,
+		OomKillDisable:    true,
+		CpuPeriod:         50000,
+		CpuQuota:          25000,
+		BlkioWeight:       300,
+		BlkioDeviceWriteIOps: []*execdriver.ThrottleDevice{
+			{Major: 8, Minor: 16, Rate: 100},
+		},
+	}
+	if err := writeResources(paths, r); err != nil {
+		t.Fatal(err)
+	}
+	checkCgroupFile(t, paths, "memory", "memory.soft_limit_in_bytes", "524288")
+	checkCgroupFile(t, paths, "memory", "memory.oom_control", "1")
+	checkCgroupFile(t, paths, "cpu", "cpu.cfs_period_us", "50000")
+	checkCgroupFile(t, paths, "cpu", "cpu.cfs_quota_us", "25000")
+	checkCgroupFile(t, paths, "blkio", "blkio.weight", "300")
+	checkCgroupFile(t, paths, "blkio", "blkio.throttle.write_iops_device", "8:16 100")
+
+	// nothing is written for the limits not set
+	for _, file := range []string{"blkio.throttle.read_bps_device", "blkio.throttle.write_bps_device", "blkio.throttle.read_iops_device"} {
+		if _, err := os.Stat(filepath.Join(paths["blkio"], file)); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s not to be written, got %v", file, err)
+		}
+	}
+}
+
+func TestWriteResourcesMissingCgroup(t *testing.T) {
+	root, paths := tempCgroups(t, "memory", "cpu")
+	defer os.RemoveAll(root)
+
+	if err := writeResources(paths, &execdriver.Resources{CpuShares: 512}); err != nil {
+		t.Fatalf("Expected the resources without blkio limits to be written, got %s", err)
+	}
+	if err := writeResources(paths, &execdriver.Resources{BlkioWeight: 500}); err == nil {
+		t.Fatal("Expected an error for a blkio limit without the blkio cgroup")
+	}
+}
+
+func TestUpdateCgroups(t *testing.T) {
+	root, paths := tempCgroups(t, "memory", "cpu", "cpuset")
+	defer os.RemoveAll(root)
+
+	// the cpuset cgroup of the container is nested in the one of the daemon
+	paths["cpuset"] = filepath.Join(root, "cpuset", "docker")
+	for _, dir := range []string{filepath.Join(root, "cpuset"), paths["cpuset"]} {
+		os.MkdirAll(dir, 0755)
+		for _, file := range []string{"cpuset.cpus", "cpuset.mems"} {
+			if err := ioutil.WriteFile(filepath.Join(dir, file), []byte("0\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(paths["memory"], "memory.memsw.limit_in_bytes"), []byte("8388608\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &execdriver.Resources{Memory: 16777216, CpuShares: 512, Cpuset: "0,1"}
+	if err := updateCgroups(paths, r, 42); err != nil {
+		t.Fatal(err)
+	}
+	checkCgroupFile(t, paths, "memory", "memory.limit_in_bytes", "16777216")
+	checkCgroupFile(t, paths, "memory", "memory.soft_limit_in_bytes", "16777216")
+	checkCgroupFile(t, paths, "memory", "memory.memsw.limit_in_bytes", "33554432")
+	checkCgroupFile(t, paths, "cpu", "cpu.shares", "512")
+	checkCgroupFile(t, paths, "cpuset", "cpuset.cpus", "0,1")
+	checkCgroupFile(t, paths, "cpuset", "cgroup.procs", "42")
+
+	// the swap is left alone when it is unlimited, the reservation is kept
+	r = &execdriver.Resources{Memory: 4194304, MemorySwap: -1, MemoryReservation: 2097152}
+	if err := updateCgroups(paths, r, 42); err != nil {
+		t.Fatal(err)
+	}
+	checkCgroupFile(t, paths, "memory", "memory.limit_in_bytes", "4194304")
+	checkCgroupFile(t, paths, "memory", "memory.soft_limit_in_bytes", "2097152")
+	checkCgroupFile(t, paths, "memory", "memory.memsw.limit_in_bytes", "33554432")
+}
@@ -92,15 +92,14 @@ func (d *driver) createNetwork(container *libcontainer.Config, c *execdriver.Com
 	}
 
 	if c.Network.Interface != nil {
-		vethNetwork := libcontainer.Network{
-			Mtu:        c.Network.Mtu,
-			Address:    fmt.Sprintf("%s/%d", c.Network.Interface.IPAddress, c.Network.Interface.IPPrefixLen),
-			Gateway:    c.Network.Interface.Gateway,
-			Type:       "veth",
-			Bridge:     c.Network.Interface.Bridge,
-			VethPrefix: "veth",
-		}
-		container.Networks = append(container.Networks, &vethNetwork)
+		// the container joins the network namespace prepared with its
+		// interface by createNetns, where its loopback is brought up
+		container.Networks = append([]*libcontainer.Network{
+			{
+				Type:   "netns",
+				NsPath: d.netnsPath(c.ID),
+			},
+		}, container.Networks...)
 	}
 
 	if c.Network.ContainerID != "" {
@@ -13,8 +13,10 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/netdev"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/apparmor"
@@ -33,6 +35,7 @@ const (
 type activeContainer struct {
 	container *libcontainer.Config
 	cmd       *exec.Cmd
+	vethHost  string // end of the veth pair of the container left on the host
 }
 
 type driver struct {
@@ -78,11 +81,12 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	}
 	c.Terminal = term
 
-	d.Lock()
-	d.activeContainers[c.ID] = &activeContainer{
+	active := &activeContainer{
 		container: container,
 		cmd:       &c.Cmd,
 	}
+	d.Lock()
+	d.activeContainers[c.ID] = active
 	d.Unlock()
 
 	var (
@@ -95,11 +99,27 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	}
 	defer d.removeContainerRoot(c.ID)
 
+	if c.Network.Interface != nil {
+		vethHost, err := d.createNetns(c)
+		if err != nil {
+			return -1, err
+		}
+		defer netdev.DeleteNetns(d.netnsPath(c.ID))
+
+		d.Lock()
+		active.vethHost = vethHost
+		d.Unlock()
+	}
+
 	if err := d.writeContainerFile(container, c.ID); err != nil {
 		return -1, err
 	}
 
-	return namespaces.Exec(container, c.Stdin, c.Stdout, c.Stderr, c.Console, c.Rootfs, dataPath, args, func(container *libcontainer.Config, console, rootfs, dataPath, init string, child *os.File, args []string) *exec.Cmd {
+	// the limits libcontainer has no settings for are written in the cgroups
+	// it created as soon as the container has started, a failure kills it
+	var resourcesErr error
+
+	exitCode, err := namespaces.Exec(container, c.Stdin, c.Stdout, c.Stderr, c.Console, c.Rootfs, dataPath, args, func(container *libcontainer.Config, console, rootfs, dataPath, init string, child *os.File, args []string) *exec.Cmd {
 		c.Path = d.initPath
 		c.Args = append([]string{
 			DriverName,
@@ -120,11 +140,19 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 
 		return &c.Cmd
 	}, func() {
+		if resourcesErr = d.setupResources(c); resourcesErr != nil {
+			c.Process.Kill()
+			return
+		}
 		if startCallback != nil {
 			c.ContainerPid = c.Process.Pid
 			startCallback(c)
 		}
 	})
+	if resourcesErr != nil {
+		return -1, fmt.Errorf("Unable to set the resource limits of %s: %s", c.ID, resourcesErr)
+	}
+	return exitCode, err
 }
 
 func (d *driver) Kill(p *execdriver.Command, sig int) error {
@@ -155,6 +183,20 @@ func (d *driver) Unpause(c *execdriver.Command) error {
 	return fs.Freeze(active.container.Cgroups, active.container.Cgroups.Freezer)
 }
 
+func (d *driver) Update(c *execdriver.Command) error {
+	d.Lock()
+	active := d.activeContainers[c.ID]
+	d.Unlock()
+
+	if active == nil {
+		return execdriver.ErrNotRunning
+	}
+	if err := d.setupCgroups(active.container, c); err != nil {
+		return err
+	}
+	return d.updateResources(c)
+}
+
 func (d *driver) Terminate(p *execdriver.Command) error {
 	// lets check the start time for the process
 	state, err := libcontainer.GetState(filepath.Join(d.root, p.ID))
@@ -218,6 +260,41 @@ func (d *driver) GetPidsForContainer(id string) ([]int, error) {
 	return fs.GetPids(c)
 }
 
+func (d *driver) Stats(id string) (*execdriver.ResourceStats, error) {
+	d.Lock()
+	active := d.activeContainers[id]
+	d.Unlock()
+
+	if active == nil {
+		return nil, execdriver.ErrNotRunning
+	}
+
+	now := time.Now()
+	state, err := libcontainer.GetState(filepath.Join(d.root, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, execdriver.ErrNotRunning
+		}
+		return nil, err
+	}
+
+	// the interface of the container is not created by libcontainer
+	d.Lock()
+	state.NetworkState.VethHost = active.vethHost
+	d.Unlock()
+
+	stats, err := libcontainer.GetStats(active.container, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &execdriver.ResourceStats{
+		ContainerStats: stats,
+		Read:           now,
+		MemoryLimit:    active.container.Cgroups.Memory,
+	}, nil
+}
+
 func (d *driver) writeContainerFile(container *libcontainer.Config, id string) error {
 	data, err := json.Marshal(container)
 	if err != nil {
@@ -253,23 +330,34 @@ type TtyConsole struct {
 }
 
 func NewTtyConsole(command *execdriver.Command, pipes *execdriver.Pipes) (*TtyConsole, error) {
-	ptyMaster, console, err := consolepkg.CreateMasterAndConsole()
+	tty, console, err := newTtyConsole(pipes)
 	if err != nil {
 		return nil, err
 	}
 
+	command.Console = console
+
+	return tty, nil
+}
+
+// newTtyConsole creates a new pty pair attached to pipes and returns the
+// console together with the path of the slave side
+func newTtyConsole(pipes *execdriver.Pipes) (*TtyConsole, string, error) {
+	ptyMaster, console, err := consolepkg.CreateMasterAndConsole()
+	if err != nil {
+		return nil, "", err
+	}
+
 	tty := &TtyConsole{
 		MasterPty: ptyMaster,
 	}
 
-	if err := tty.AttachPipes(&command.Cmd, pipes); err != nil {
+	if err := tty.AttachPipes(nil, pipes); err != nil {
 		tty.Close()
-		return nil, err
+		return nil, "", err
 	}
 
-	command.Console = console
-
-	return tty, nil
+	return tty, console, nil
 }
 
 func (t *TtyConsole) Master() *os.File {
@@ -0,0 +1,124 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/reexec"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/namespaces"
+	_ "github.com/docker/libcontainer/namespaces/nsenter"
+	"github.com/docker/libcontainer/syncpipe"
+)
+
+// execCommandName is the argv[0] used to reexec the init binary so that
+// nsenter joins the container's namespaces before the go runtime starts
+const execCommandName = "nsenter-exec"
+
+func init() {
+	reexec.Register(execCommandName, nsenterExec)
+}
+
+func nsenterExec() {
+	runtime.LockOSThread()
+
+	// user args are passed after '--' in the command line
+	userArgs := findUserArgs()
+
+	syncPipe, err := syncpipe.NewSyncPipeFromFd(0, 3)
+	if err != nil {
+		log.Fatalf("docker-exec: unable to open sync pipe: %s", err)
+	}
+
+	var container *libcontainer.Config
+	if err := syncPipe.ReadFromParent(&container); err != nil {
+		log.Fatalf("docker-exec: unable to receive config from sync pipe: %s", err)
+	}
+
+	if err := namespaces.FinalizeSetns(container, userArgs); err != nil {
+		log.Fatalf("docker-exec: failed to exec: %s", err)
+	}
+}
+
+func findUserArgs() []string {
+	for i, a := range os.Args {
+		if a == "--" {
+			return os.Args[i+1:]
+		}
+	}
+	return []string{}
+}
+
+func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes, startCallback execdriver.ExecStartCallback) (int, error) {
+	d.Lock()
+	active := d.activeContainers[c.ID]
+	d.Unlock()
+
+	if active == nil {
+		return -1, fmt.Errorf("active container for %s does not exist", c.ID)
+	}
+
+	state, err := libcontainer.GetState(filepath.Join(d.root, c.ID))
+	if err != nil {
+		return -1, err
+	}
+
+	// copy the container's config so that overriding the user does not
+	// leak into the config of the running container
+	container := *active.container
+	if processConfig.User != "" {
+		container.User = processConfig.User
+	}
+
+	var (
+		stdin          io.Reader
+		stdout, stderr io.Writer
+	)
+
+	if processConfig.Tty {
+		tty, console, err := newTtyConsole(pipes)
+		if err != nil {
+			return -1, err
+		}
+		defer tty.Close()
+
+		processConfig.Terminal = tty
+		processConfig.Console = console
+	} else {
+		processConfig.Terminal = &execdriver.StdConsole{}
+
+		if pipes.Stdin != nil {
+			// hand the child a real file so that waiting on the process does
+			// not block on copying stdin once the process has exited
+			r, w, err := os.Pipe()
+			if err != nil {
+				return -1, err
+			}
+			defer r.Close()
+
+			go func() {
+				io.Copy(w, pipes.Stdin)
+				w.Close()
+			}()
+			stdin = r
+		}
+		stdout, stderr = pipes.Stdout, pipes.Stderr
+	}
+
+	args := append([]string{processConfig.Entrypoint}, processConfig.Arguments...)
+
+	return namespaces.ExecIn(&container, state, args, d.initPath, "exec", stdin, stdout, stderr, processConfig.Console, func(cmd *exec.Cmd) {
+		processConfig.Pid = cmd.Process.Pid
+		if startCallback != nil {
+			startCallback(processConfig)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/netdev"
+)
+
+// netnsPath is the file the network namespace of the container id is bound on
+func (d *driver) netnsPath(id string) string {
+	return filepath.Join(d.root, id, "netns")
+}
+
+// createNetns creates the network namespace of the container c, with its
+// interface attached to the bridge, or created on the parent interface of a
+// macvlan network. It returns the end of the veth pair left on the host,
+// which is empty for a macvlan interface.
+func (d *driver) createNetns(c *execdriver.Command) (string, error) {
+	var (
+		path   = d.netnsPath(c.ID)
+		iface  = c.Network.Interface
+		config = &netdev.Config{
+			Device:      "eth0",
+			Address:     fmt.Sprintf("%s/%d", iface.IPAddress, iface.IPPrefixLen),
+			MacAddress:  iface.MacAddress,
+			Mtu:         c.Network.Mtu,
+			Gateway:     iface.Gateway,
+			HairpinMode: iface.HairpinMode,
+		}
+		vethHost string
+	)
+	if iface.GlobalIPv6Address != "" {
+		config.IPv6Address = fmt.Sprintf("%s/%d", iface.GlobalIPv6Address, iface.GlobalIPv6PrefixLen)
+		config.IPv6Gateway = iface.IPv6Gateway
+	}
+
+	if err := netdev.NewNetns(path); err != nil {
+		return "", fmt.Errorf("Unable to create the network namespace of %s: %s", c.ID, err)
+	}
+	var err error
+	if iface.Parent != "" {
+		// the container sits on the network of the parent interface
+		err = netdev.CreateMacvlan(iface.Parent, path, config)
+	} else {
+		vethHost, err = netdev.CreateVeth(iface.Bridge, path, config)
+	}
+	if err != nil {
+		netdev.DeleteNetns(path)
+		return "", fmt.Errorf("Unable to create the interface of %s: %s", c.ID, err)
+	}
+	return vethHost, nil
+}
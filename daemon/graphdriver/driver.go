@@ -13,8 +13,9 @@ import (
 type FsMagic uint64
 
 const (
-	FsMagicBtrfs = FsMagic(0x9123683E)
-	FsMagicAufs  = FsMagic(0x61756673)
+	FsMagicBtrfs   = FsMagic(0x9123683E)
+	FsMagicAufs    = FsMagic(0x61756673)
+	FsMagicOverlay = FsMagic(0x794C7630)
 )
 
 type InitFunc func(root string, options []string) (Driver, error)
@@ -49,6 +50,7 @@ var (
 	priority = []string{
 		"aufs",
 		"btrfs",
+		"overlay",
 		"devicemapper",
 		"vfs",
 	}
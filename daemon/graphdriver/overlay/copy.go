@@ -0,0 +1,113 @@
+// +build linux
+
+package overlay
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/docker/docker/pkg/system"
+)
+
+// copyUp adds the content of the dir src over the one of the dir dst, as the
+// overlay filesystem shows an upper dir over a lower one: the whiteouts of
+// src remove the entries of dst, and its opaque dirs replace the ones of dst.
+// The files are hard links to the ones of src rather than copies, so copying
+// a layer costs its dirs and entries only, not its content. The layers being
+// only written through their mounts, where the overlay filesystem copies a
+// lower file in the upper dir before changing it, the links are never
+// written to.
+func copyUp(src, dst string) error {
+	var dirs []string
+	err := filepath.Walk(src, func(srcPath string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, srcPath)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dst, rel)
+		stat, ok := f.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("Unable to stat %s", srcPath)
+		}
+
+		mode := f.Mode()
+		if mode&os.ModeCharDevice != 0 && stat.Rdev == 0 {
+			// a whiteout hides the entry of the lower dirs
+			return os.RemoveAll(dstPath)
+		}
+		if mode.IsDir() {
+			if opaque, _ := system.Lgetxattr(srcPath, "trusted.overlay.opaque"); rel != "." && string(opaque) == "y" {
+				if err := os.RemoveAll(dstPath); err != nil {
+					return err
+				}
+			} else if fi, err := os.Lstat(dstPath); err == nil && !fi.IsDir() {
+				if err := os.Remove(dstPath); err != nil {
+					return err
+				}
+			}
+			if err := os.Mkdir(dstPath, 0700); err != nil && !os.IsExist(err) {
+				return err
+			}
+			// the dirs get their attributes once their entries are added
+			dirs = append(dirs, rel)
+			return nil
+		}
+
+		if err := os.RemoveAll(dstPath); err != nil {
+			return err
+		}
+		switch {
+		case mode.IsRegular():
+			return os.Link(srcPath, dstPath)
+		case mode&os.ModeSymlink != 0:
+			target, err := os.Readlink(srcPath)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(target, dstPath); err != nil {
+				return err
+			}
+		default:
+			// devices, fifos and sockets
+			if err := syscall.Mknod(dstPath, stat.Mode, int(stat.Rdev)); err != nil {
+				return err
+			}
+		}
+		return copyAttributes(dstPath, stat, mode&os.ModeSymlink != 0)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		var stat syscall.Stat_t
+		if err := syscall.Lstat(filepath.Join(src, dirs[i]), &stat); err != nil {
+			return err
+		}
+		if err := copyAttributes(filepath.Join(dst, dirs[i]), &stat, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyAttributes gives the owner, mode and times of stat to the entry at
+// path, which is a symlink when symlink is true
+func copyAttributes(path string, stat *syscall.Stat_t, symlink bool) error {
+	if err := os.Lchown(path, int(stat.Uid), int(stat.Gid)); err != nil {
+		return err
+	}
+	ts := []syscall.Timespec{stat.Atim, stat.Mtim}
+	if symlink {
+		return system.LUtimesNano(path, ts)
+	}
+	if err := syscall.Chmod(path, stat.Mode&07777); err != nil {
+		return err
+	}
+	return system.UtimesNano(path, ts)
+}
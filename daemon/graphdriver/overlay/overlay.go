@@ -0,0 +1,432 @@
+// +build linux
+
+/*
+
+overlay driver directory structure
+
+.
+├── 1
+│   ├── diff    // Content of the layer, the upper dir of its mount
+│   ├── work    // Work dir of the overlay filesystem
+│   ├── merged  // Mount point of the layer
+│   ├── root    // Hard links to the parent layer, when its lower dirs are too many
+│   ├── lower   // Lower dirs of the layer, the one of its parent first
+│   └── parent  // Id of the parent layer
+└── 2
+    └── diff    // A layer without parent is used as it is, never mounted
+
+*/
+
+package overlay
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/pkg/log"
+	mountpk "github.com/docker/docker/pkg/mount"
+	"github.com/docker/docker/pkg/parsers/kernel"
+	"github.com/docker/docker/utils"
+	"github.com/docker/libcontainer/label"
+)
+
+var (
+	ErrOverlayNotSupported = fmt.Errorf("Overlay was not found in /proc/filesystems")
+	incompatibleFsMagic    = []graphdriver.FsMagic{
+		graphdriver.FsMagicAufs,
+		graphdriver.FsMagicOverlay,
+	}
+)
+
+const (
+	// maxLowers is the number of lower dirs the kernel stacks from 4.0 on,
+	// the older kernels taking a single one
+	maxLowers = 500
+)
+
+func init() {
+	graphdriver.Register("overlay", Init)
+}
+
+type Driver struct {
+	home       string
+	maxLowers  int
+	sync.Mutex // Protects concurrent modification to active
+	active     map[string]int
+}
+
+// Init returns a new overlay driver.
+// An error is returned if overlay is not supported.
+func Init(home string, options []string) (graphdriver.Driver, error) {
+	if err := supportsOverlay(); err != nil {
+		return nil, graphdriver.ErrNotSupported
+	}
+
+	var buf syscall.Statfs_t
+	if err := syscall.Statfs(path.Dir(home), &buf); err != nil {
+		return nil, fmt.Errorf("Couldn't stat the root directory: %s", err)
+	}
+	for _, magic := range incompatibleFsMagic {
+		if graphdriver.FsMagic(buf.Type) == magic {
+			return nil, graphdriver.ErrIncompatibleFS
+		}
+	}
+
+	if err := os.MkdirAll(home, 0755); err != nil {
+		return nil, err
+	}
+	if err := graphdriver.MakePrivate(home); err != nil {
+		return nil, err
+	}
+
+	d := &Driver{
+		home:      home,
+		maxLowers: 1,
+		active:    make(map[string]int),
+	}
+	if v, err := kernel.GetKernelVersion(); err == nil && kernel.CompareKernelVersion(v, &kernel.KernelVersionInfo{Kernel: 4, Major: 0, Minor: 0}) >= 0 {
+		d.maxLowers = maxLowers
+	}
+	return d, nil
+}
+
+// Return a nil error if the kernel supports overlay
+func supportsOverlay() error {
+	// We can try to modprobe overlay first before looking at
+	// proc/filesystems for when overlay is supported
+	exec.Command("modprobe", "overlay").Run()
+
+	f, err := os.Open("/proc/filesystems")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if fields := strings.Fields(s.Text()); len(fields) > 0 && fields[len(fields)-1] == "overlay" {
+			return nil
+		}
+	}
+	return ErrOverlayNotSupported
+}
+
+func (d *Driver) String() string {
+	return "overlay"
+}
+
+func (d *Driver) Status() [][2]string {
+	ids, _ := ioutil.ReadDir(d.home)
+	return [][2]string{
+		{"Root Dir", d.home},
+		{"Dirs", fmt.Sprintf("%d", len(ids))},
+	}
+}
+
+func (d *Driver) dir(id string) string {
+	return path.Join(d.home, path.Base(id))
+}
+
+// Exists returns true if the given id is registered with
+// this driver
+func (d *Driver) Exists(id string) bool {
+	_, err := os.Lstat(d.dir(id))
+	return err == nil
+}
+
+// Create creates the dirs of the layer id. The lower dirs of its mount are
+// the ones of the parent, preceded by the content of the parent. When they
+// are too many for the kernel or for the options of a mount, the parent is
+// copied in the layer with hard links and used as its only lower dir instead.
+func (d *Driver) Create(id, parent string) (err error) {
+	dir := d.dir(id)
+	if err := os.MkdirAll(path.Join(dir, "diff"), 0755); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	if parent == "" {
+		return nil
+	}
+
+	for _, p := range []string{"work", "merged"} {
+		if err := os.MkdirAll(path.Join(dir, p), 0755); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "parent"), []byte(parent), 0644); err != nil {
+		return err
+	}
+
+	parentLowers, err := d.lowers(parent)
+	if err != nil {
+		return err
+	}
+	lowers := append([]string{path.Join(d.dir(parent), "diff")}, parentLowers...)
+	if !d.fits(dir, lowers) {
+		log.Debugf("Copying the parent %s of %s, which has %d lower dirs", utils.TruncateID(parent), utils.TruncateID(id), len(parentLowers))
+		if lowers, err = flatten(dir, lowers); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(path.Join(dir, "lower"), []byte(strings.Join(lowers, ":")), 0644)
+}
+
+// flatten copies the lower dirs lowers, the upper one first, in the root dir
+// of the layer at dir, and returns it as the lower dirs of the layer
+func flatten(dir string, lowers []string) ([]string, error) {
+	root := path.Join(dir, "root")
+	if err := os.Mkdir(root, 0755); err != nil {
+		return nil, err
+	}
+	for i := len(lowers) - 1; i >= 0; i-- {
+		if err := copyUp(lowers[i], root); err != nil {
+			return nil, fmt.Errorf("Error copying the lower dir %s: %s", lowers[i], err)
+		}
+	}
+	return []string{root}, nil
+}
+
+// fits tells whether the layer at dir can be mounted on lowers
+func (d *Driver) fits(dir string, lowers []string) bool {
+	if len(lowers) > d.maxLowers {
+		return false
+	}
+	// the options of a mount are limited to a page, the room left is kept
+	// for the mount label
+	return len(mountData(dir, lowers)) < syscall.Getpagesize()-256
+}
+
+func mountData(dir string, lowers []string) string {
+	return fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", strings.Join(lowers, ":"), path.Join(dir, "diff"), path.Join(dir, "work"))
+}
+
+// lowers returns the lower dirs of the layer id, none for a layer without
+// parent
+func (d *Driver) lowers(id string) ([]string, error) {
+	data, err := ioutil.ReadFile(path.Join(d.dir(id), "lower"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return strings.Split(string(data), ":"), nil
+}
+
+// parent returns the id of the parent of the layer id, or an empty string
+func (d *Driver) parent(id string) (string, error) {
+	data, err := ioutil.ReadFile(path.Join(d.dir(id), "parent"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Unmount and remove the dir information
+func (d *Driver) Remove(id string) error {
+	// Protect the d.active from concurrent access
+	d.Lock()
+	defer d.Unlock()
+
+	if d.active[id] != 0 {
+		log.Errorf("Warning: removing active id %s", id)
+	}
+	delete(d.active, id)
+
+	dir := d.dir(id)
+	if _, err := os.Lstat(dir); err != nil {
+		return err
+	}
+	if err := d.unmount(id); err != nil {
+		return err
+	}
+
+	// Move the dir out of the way first, so that a removal failing half-way
+	// does not leave a layer which seems to exist
+	tmpDir := dir + "-removing"
+	if err := os.Rename(dir, tmpDir); err != nil {
+		return err
+	}
+	return os.RemoveAll(tmpDir)
+}
+
+// Return the rootfs path for the id
+// This will mount the dir at it's given path
+func (d *Driver) Get(id, mountLabel string) (string, error) {
+	dir := d.dir(id)
+	if _, err := os.Lstat(dir); err != nil {
+		return "", err
+	}
+	lowers, err := d.lowers(id)
+	if err != nil {
+		return "", err
+	}
+	// If a dir does not have a parent ( no layers )do not try to mount
+	// just return the diff path to the data
+	if len(lowers) == 0 {
+		return path.Join(dir, "diff"), nil
+	}
+
+	// Protect the d.active from concurrent access
+	d.Lock()
+	defer d.Unlock()
+
+	count := d.active[id]
+	target := path.Join(dir, "merged")
+	if count == 0 {
+		if mounted, err := mountpk.Mounted(target); err != nil {
+			return "", err
+		} else if !mounted {
+			data := label.FormatMountLabel(mountData(dir, lowers), mountLabel)
+			if err := syscall.Mount("overlay", target, "overlay", 0, data); err != nil {
+				return "", fmt.Errorf("Error mounting the layer %s: %s", id, err)
+			}
+		}
+	}
+	d.active[id] = count + 1
+
+	return target, nil
+}
+
+func (d *Driver) Put(id string) {
+	// Protect the d.active from concurrent access
+	d.Lock()
+	defer d.Unlock()
+
+	if count := d.active[id]; count > 1 {
+		d.active[id] = count - 1
+	} else {
+		if err := d.unmount(id); err != nil {
+			log.Errorf("Unmounting %s: %s", utils.TruncateID(id), err)
+		}
+		delete(d.active, id)
+	}
+}
+
+func (d *Driver) unmount(id string) error {
+	target := path.Join(d.dir(id), "merged")
+	if mounted, err := mountpk.Mounted(target); err != nil || !mounted {
+		return nil
+	}
+	return syscall.Unmount(target, 0)
+}
+
+// During cleanup overlay needs to unmount all mountpoints
+func (d *Driver) Cleanup() error {
+	d.Lock()
+	defer d.Unlock()
+
+	for id := range d.active {
+		if err := d.unmount(id); err != nil {
+			log.Errorf("Unmounting %s: %s", utils.TruncateID(id), err)
+		}
+	}
+	d.active = make(map[string]int)
+
+	return mountpk.Unmount(d.home)
+}
+
+// Returns an archive of the changes of the layer id to its parent. The
+// whiteouts of the overlay filesystem having a format of their own, the
+// changes are found by comparing the mounted layers.
+func (d *Driver) Diff(id string) (archive.Archive, error) {
+	parent, err := d.parent(id)
+	if err != nil {
+		return nil, err
+	}
+	if parent == "" {
+		return archive.TarWithOptions(path.Join(d.dir(id), "diff"), &archive.TarOptions{
+			Compression: archive.Uncompressed,
+		})
+	}
+
+	changes, err := d.Changes(id)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := d.Get(id, "")
+	if err != nil {
+		return nil, err
+	}
+	arch, err := archive.ExportChanges(dir, changes)
+	if err != nil {
+		d.Put(id)
+		return nil, err
+	}
+	return utils.NewReadCloserWrapper(arch, func() error {
+		err := arch.Close()
+		d.Put(id)
+		return err
+	}), nil
+}
+
+func (d *Driver) Changes(id string) ([]archive.Change, error) {
+	parent, err := d.parent(id)
+	if err != nil {
+		return nil, err
+	}
+	if parent == "" {
+		return archive.Changes(nil, path.Join(d.dir(id), "diff"))
+	}
+
+	dir, err := d.Get(id, "")
+	if err != nil {
+		return nil, err
+	}
+	defer d.Put(id)
+	parentDir, err := d.Get(parent, "")
+	if err != nil {
+		return nil, err
+	}
+	defer d.Put(parent)
+	return archive.ChangesDirs(dir, parentDir)
+}
+
+// ApplyDiff applies diff on the mounted layer, where the overlay filesystem
+// turns the deletions into its own whiteouts
+func (d *Driver) ApplyDiff(id string, diff archive.ArchiveReader) error {
+	dir, err := d.Get(id, "")
+	if err != nil {
+		return err
+	}
+	defer d.Put(id)
+	return archive.ApplyLayer(dir, diff)
+}
+
+// Returns the size of the contents for the id
+func (d *Driver) DiffSize(id string) (int64, error) {
+	parent, err := d.parent(id)
+	if err != nil {
+		return 0, err
+	}
+	if parent == "" {
+		return utils.TreeSize(path.Join(d.dir(id), "diff"))
+	}
+
+	changes, err := d.Changes(id)
+	if err != nil {
+		return 0, err
+	}
+	dir, err := d.Get(id, "")
+	if err != nil {
+		return 0, err
+	}
+	defer d.Put(id)
+	return archive.ChangesSize(dir, changes), nil
+}
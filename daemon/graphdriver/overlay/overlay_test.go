@@ -0,0 +1,246 @@
+package overlay
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/graphtest"
+)
+
+// This avoids creating a new driver for each test if all tests are run
+// Make sure to put new tests between TestOverlaySetup and TestOverlayTeardown
+func TestOverlaySetup(t *testing.T) {
+	graphtest.GetDriver(t, "overlay")
+}
+
+func TestOverlayCreateEmpty(t *testing.T) {
+	graphtest.DriverTestCreateEmpty(t, "overlay")
+}
+
+func TestOverlayCreateBase(t *testing.T) {
+	graphtest.DriverTestCreateBase(t, "overlay")
+}
+
+func TestOverlayCreateSnap(t *testing.T) {
+	graphtest.DriverTestCreateSnap(t, "overlay")
+}
+
+func TestOverlayTeardown(t *testing.T) {
+	graphtest.PutDriver(t)
+}
+
+func newDriver(t *testing.T) *Driver {
+	root, err := ioutil.TempDir("/var/tmp", "docker-overlay-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Init(path.Join(root, "overlay"), nil)
+	if err != nil {
+		os.RemoveAll(root)
+		if err == graphdriver.ErrNotSupported {
+			t.Skip("Overlay not supported")
+		}
+		t.Fatal(err)
+	}
+	return d.(*Driver)
+}
+
+func cleanup(t *testing.T, d *Driver) {
+	if err := d.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(path.Dir(d.home))
+}
+
+func TestOverlayDiffApplyDiff(t *testing.T) {
+	d := newDriver(t)
+	defer cleanup(t, d)
+
+	if err := d.Create("base", ""); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := d.Get("base", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"kept", "removed"} {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Put("base")
+
+	if err := d.Create("child", "base"); err != nil {
+		t.Fatal(err)
+	}
+	dir, err = d.Get("child", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path.Join(dir, "removed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "added"), []byte("added"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d.Put("child")
+
+	changes, err := d.Changes("child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %v", changes)
+	}
+
+	diff, err := d.Diff("child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer diff.Close()
+
+	// applying the diff on another layer of the base gives the same content
+	if err := d.Create("applied", "base"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ApplyDiff("applied", diff); err != nil {
+		t.Fatal(err)
+	}
+	dir, err = d.Get("applied", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Put("applied")
+	for _, name := range []string{"kept", "added"} {
+		if _, err := os.Stat(path.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path.Join(dir, "removed")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the file removed by the diff to be hidden, got %v", err)
+	}
+}
+
+func TestOverlayDeepChain(t *testing.T) {
+	d := newDriver(t)
+	defer cleanup(t, d)
+	d.maxLowers = 2
+
+	parent := ""
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if err := d.Create(id, parent); err != nil {
+			t.Fatal(err)
+		}
+		dir, err := d.Get(id, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(dir, id), []byte(id), 0644); err != nil {
+			t.Fatal(err)
+		}
+		d.Put(id)
+		parent = id
+	}
+
+	// the parent of the layer 4 has 2 lower dirs already, it is copied
+	lowers, err := d.lowers("4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lowers) != 1 || lowers[0] != path.Join(d.dir("4"), "root") {
+		t.Fatalf("Expected the parent to be copied, got the lower dirs %v", lowers)
+	}
+
+	dir, err := d.Get("4", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Put("4")
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if _, err := os.Stat(path.Join(dir, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := d.Changes("4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "/4" || changes[0].Kind != archive.ChangeAdd {
+		t.Fatalf("Expected the layer 4 to only add /4, got %v", changes)
+	}
+
+	// the files of the parent are linked rather than copied
+	if !sameFile(t, path.Join(d.dir("1"), "diff", "1"), path.Join(d.dir("4"), "root", "1")) {
+		t.Fatal("Expected the copy of the parent to link to its files")
+	}
+}
+
+func sameFile(t *testing.T, a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return os.SameFile(fa, fb)
+}
+
+func TestCopyUp(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-overlay-copy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	lower, upper, dst := path.Join(root, "lower"), path.Join(root, "upper"), path.Join(root, "dst")
+	for _, dir := range []string{lower, upper, dst, path.Join(lower, "dir"), path.Join(upper, "dir")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{path.Join(lower, "kept"), path.Join(lower, "removed"), path.Join(lower, "dir", "lower"), path.Join(upper, "dir", "upper")} {
+		if err := ioutil.WriteFile(file, []byte("content"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("kept", path.Join(upper, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mknod(path.Join(upper, "removed"), syscall.S_IFCHR, 0); err != nil {
+		t.Skipf("Unable to create a whiteout: %s", err)
+	}
+	if err := os.Chmod(path.Join(upper, "dir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []string{lower, upper} {
+		if err := copyUp(src, dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"kept", "dir/lower", "dir/upper"} {
+		if _, err := os.Stat(path.Join(dst, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Lstat(path.Join(dst, "removed")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the whiteout to remove the file, got %v", err)
+	}
+	if target, err := os.Readlink(path.Join(dst, "link")); err != nil || target != "kept" {
+		t.Fatalf("Expected the symlink to be copied, got %s, %v", target, err)
+	}
+	if !sameFile(t, path.Join(lower, "kept"), path.Join(dst, "kept")) {
+		t.Fatal("Expected the files to be linked")
+	}
+	if fi, err := os.Stat(path.Join(dst, "dir")); err != nil || fi.Mode().Perm() != 0700 {
+		t.Fatalf("Expected the dir to get the mode of the upper one, got %v, %v", fi.Mode(), err)
+	}
+}
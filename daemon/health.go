@@ -0,0 +1,261 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/runconfig"
+)
+
+const (
+	// Health status of a container with a healthcheck
+	HealthStarting = "starting"
+	Healthy        = "healthy"
+	Unhealthy      = "unhealthy"
+
+	defaultProbeInterval = 30 * time.Second
+	defaultProbeTimeout  = 30 * time.Second
+	defaultProbeRetries  = 3
+
+	// maxHealthLogEntries is the number of probe results kept in the state
+	maxHealthLogEntries = 5
+	// maxProbeOutputLen is the number of bytes of the output of a probe kept
+	// in its result
+	maxProbeOutputLen = 4096
+)
+
+// HealthcheckResult is the result of a single run of the healthcheck command
+type HealthcheckResult struct {
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+	Output   string
+}
+
+// Health is the health status of a container along with the results of its
+// last probes
+type Health struct {
+	Status        string
+	FailingStreak int
+	Log           []*HealthcheckResult
+}
+
+// healthMonitor periodically runs the healthcheck command of a running
+// container and records the results in its state
+type healthMonitor struct {
+	container *Container
+	config    *runconfig.HealthConfig
+	stop      chan struct{}
+}
+
+// startHealthcheck starts probing the health of the container, if it has a
+// healthcheck
+func (container *Container) startHealthcheck() {
+	config := container.Config.Healthcheck
+	if config == nil || len(config.Test) == 0 || config.Test[0] == "NONE" {
+		container.State.setHealth(nil)
+		return
+	}
+	container.State.setHealth(&Health{Status: HealthStarting})
+
+	m := &healthMonitor{
+		container: container,
+		config:    config,
+		stop:      make(chan struct{}),
+	}
+	container.healthMonitor = m
+	go m.run()
+}
+
+// stopHealthcheck stops probing the health of the container and forgets its
+// health status, which is only meaningful while it runs
+func (container *Container) stopHealthcheck() {
+	if container.healthMonitor != nil {
+		close(container.healthMonitor.stop)
+		container.healthMonitor = nil
+	}
+	container.State.setHealth(nil)
+}
+
+func (m *healthMonitor) run() {
+	interval := m.config.Interval
+	if interval == 0 {
+		interval = defaultProbeInterval
+	}
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-time.After(interval):
+		}
+		// a paused container cannot answer, the probe waits for it to resume
+		if m.container.State.IsPaused() {
+			continue
+		}
+		m.record(m.probe())
+	}
+}
+
+// probe runs the healthcheck command inside the container
+func (m *healthMonitor) probe() *HealthcheckResult {
+	var (
+		container = m.container
+		result    = &HealthcheckResult{Start: time.Now().UTC()}
+		timeout   = m.config.Timeout
+		cmd       []string
+	)
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	switch m.config.Test[0] {
+	case "CMD":
+		cmd = m.config.Test[1:]
+	case "CMD-SHELL":
+		cmd = []string{"/bin/sh", "-c", strings.Join(m.config.Test[1:], " ")}
+	}
+	if len(cmd) == 0 {
+		result.End = time.Now().UTC()
+		result.ExitCode = -1
+		result.Output = fmt.Sprintf("Invalid healthcheck command %v", m.config.Test)
+		return result
+	}
+
+	var (
+		output        = &limitedBuffer{}
+		processConfig = &execdriver.ProcessConfig{
+			Entrypoint: cmd[0],
+			Arguments:  cmd[1:],
+		}
+		pidChan  = make(chan int, 1)
+		exitChan = make(chan error, 1)
+		exitCode int
+	)
+	go func() {
+		var err error
+		exitCode, err = container.daemon.Exec(container, processConfig, execdriver.NewPipes(nil, output, output, false), func(p *execdriver.ProcessConfig) {
+			pidChan <- p.Pid
+		})
+		exitChan <- err
+	}()
+
+	select {
+	case err := <-exitChan:
+		result.End = time.Now().UTC()
+		if err != nil {
+			result.ExitCode = -1
+			result.Output = err.Error()
+			return result
+		}
+		result.ExitCode = exitCode
+		result.Output = output.String()
+	case <-time.After(timeout):
+		result.End = time.Now().UTC()
+		// the process may not be started yet, it is killed as soon as its pid
+		// is known unless it exits by itself before
+		go func() {
+			select {
+			case pid := <-pidChan:
+				if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+					log.Debugf("Error killing the timed out healthcheck of %s: %s", container.ID, err)
+				}
+			case <-exitChan:
+			}
+		}()
+		result.ExitCode = -1
+		result.Output = fmt.Sprintf("Healthcheck exceeded timeout (%s)", timeout)
+	}
+	return result
+}
+
+// record stores the result of a probe and updates the health status of the
+// container, emitting an event when it changes
+func (m *healthMonitor) record(result *HealthcheckResult) {
+	retries := m.config.Retries
+	if retries == 0 {
+		retries = defaultProbeRetries
+	}
+
+	s := m.container.State
+	s.Lock()
+	select {
+	case <-m.stop:
+		// the probe was interrupted by the container stopping, the health of
+		// the next run must not inherit its result
+		s.Unlock()
+		return
+	default:
+	}
+	if s.Health == nil {
+		s.Unlock()
+		return
+	}
+	h := s.Health
+	oldStatus := h.Status
+	h.Log = append(h.Log, result)
+	if len(h.Log) > maxHealthLogEntries {
+		h.Log = h.Log[len(h.Log)-maxHealthLogEntries:]
+	}
+	if result.ExitCode == 0 {
+		h.FailingStreak = 0
+		h.Status = Healthy
+	} else {
+		h.FailingStreak++
+		if h.FailingStreak >= retries {
+			h.Status = Unhealthy
+		}
+	}
+	newStatus := h.Status
+	s.Unlock()
+
+	if newStatus == oldStatus {
+		return
+	}
+	m.container.LogEvent("health_status: " + newStatus)
+	if newStatus == Unhealthy {
+		m.handleUnhealthy()
+	}
+}
+
+// handleUnhealthy kills an unhealthy container having a restart policy, so
+// that the container monitor restarts it according to the policy
+func (m *healthMonitor) handleUnhealthy() {
+	container := m.container
+	if policy := container.hostConfig.RestartPolicy.Name; policy != "always" && policy != "on-failure" {
+		return
+	}
+	log.Infof("Container %s is unhealthy, killing it to apply its restart policy", container.ID)
+	if err := container.daemon.Kill(container, 9); err != nil {
+		log.Errorf("Error killing unhealthy container %s: %s", container.ID, err)
+	}
+}
+
+// limitedBuffer keeps the first maxProbeOutputLen bytes written to it
+type limitedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n := maxProbeOutputLen - b.buf.Len(); n < len(p) {
+		if n > 0 {
+			b.buf.Write(p[:n])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
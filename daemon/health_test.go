@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/graph"
+	"github.com/docker/docker/runconfig"
+)
+
+// healthDriver is an execution driver recording the signals it sends and
+// running the healthchecks with the exec function of the test
+type healthDriver struct {
+	execdriver.Driver
+	exec   func(processConfig *execdriver.ProcessConfig, startCallback execdriver.ExecStartCallback) (int, error)
+	killed []int
+}
+
+func (d *healthDriver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes, startCallback execdriver.ExecStartCallback) (int, error) {
+	return d.exec(processConfig, startCallback)
+}
+
+func (d *healthDriver) Kill(c *execdriver.Command, sig int) error {
+	d.killed = append(d.killed, sig)
+	return nil
+}
+
+// newHealthMonitor returns the monitor of a running container with the given
+// healthcheck, along with the events it logs
+func newHealthMonitor(t *testing.T, config *runconfig.HealthConfig, restartPolicy string) (*healthMonitor, *healthDriver, *[]string) {
+	events := []string{}
+	eng := engine.New()
+	if err := eng.Register("log", func(job *engine.Job) engine.Status {
+		events = append(events, job.Args[0])
+		return engine.StatusOK
+	}); err != nil {
+		t.Fatal(err)
+	}
+	driver := &healthDriver{}
+	container := &Container{
+		ID:         "health",
+		State:      NewState(),
+		Config:     &runconfig.Config{Healthcheck: config},
+		hostConfig: &runconfig.HostConfig{RestartPolicy: runconfig.RestartPolicy{Name: restartPolicy}},
+		daemon:     &Daemon{eng: eng, repositories: &graph.TagStore{}, execDriver: driver},
+	}
+	container.State.SetRunning(1)
+	container.State.setHealth(&Health{Status: HealthStarting})
+	m := &healthMonitor{
+		container: container,
+		config:    config,
+		stop:      make(chan struct{}),
+	}
+	return m, driver, &events
+}
+
+func TestHealthRecord(t *testing.T) {
+	m, driver, events := newHealthMonitor(t, &runconfig.HealthConfig{Test: []string{"CMD", "true"}, Retries: 2}, "")
+	health := m.container.State.Health
+
+	m.record(&HealthcheckResult{ExitCode: 1})
+	if health.Status != HealthStarting || health.FailingStreak != 1 {
+		t.Fatalf("Expected a starting container failing once, got %s failing %d times", health.Status, health.FailingStreak)
+	}
+	m.record(&HealthcheckResult{ExitCode: 0})
+	if health.Status != Healthy || health.FailingStreak != 0 {
+		t.Fatalf("Expected a healthy container, got %s failing %d times", health.Status, health.FailingStreak)
+	}
+	m.record(&HealthcheckResult{ExitCode: 1})
+	if health.Status != Healthy {
+		t.Fatalf("Expected the container to stay healthy below the retries, got %s", health.Status)
+	}
+	m.record(&HealthcheckResult{ExitCode: 1})
+	if health.Status != Unhealthy || health.FailingStreak != 2 {
+		t.Fatalf("Expected an unhealthy container, got %s failing %d times", health.Status, health.FailingStreak)
+	}
+	m.record(&HealthcheckResult{ExitCode: 1})
+	if health.FailingStreak != 3 {
+		t.Fatalf("Expected the failing streak to keep growing, got %d", health.FailingStreak)
+	}
+
+	expected := []string{"health_status: healthy", "health_status: unhealthy"}
+	if strings.Join(*events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the events %v, got %v", expected, *events)
+	}
+	if len(driver.killed) != 0 {
+		t.Fatalf("Expected a container without restart policy not to be killed, got %v", driver.killed)
+	}
+
+	for i := 0; i < maxHealthLogEntries; i++ {
+		m.record(&HealthcheckResult{ExitCode: 0})
+	}
+	if len(health.Log) != maxHealthLogEntries {
+		t.Fatalf("Expected %d results in the log, got %d", maxHealthLogEntries, len(health.Log))
+	}
+
+	// The results of the probes interrupted by the container stopping are
+	// dropped
+	close(m.stop)
+	m.record(&HealthcheckResult{ExitCode: 1})
+	if health.FailingStreak != 0 {
+		t.Fatalf("Expected the result after the stop to be ignored, got a failing streak of %d", health.FailingStreak)
+	}
+}
+
+func TestHealthUnhealthyRestart(t *testing.T) {
+	for _, policy := range []string{"always", "on-failure"} {
+		m, driver, _ := newHealthMonitor(t, &runconfig.HealthConfig{Test: []string{"CMD", "true"}, Retries: 1}, policy)
+		m.record(&HealthcheckResult{ExitCode: 1})
+		if len(driver.killed) != 1 || driver.killed[0] != 9 {
+			t.Fatalf("Expected the unhealthy container with the %s policy to be killed, got %v", policy, driver.killed)
+		}
+		m.record(&HealthcheckResult{ExitCode: 1})
+		if len(driver.killed) != 1 {
+			t.Fatalf("Expected the container to be killed only when it becomes unhealthy, got %v", driver.killed)
+		}
+	}
+}
+
+func TestHealthStop(t *testing.T) {
+	m, _, _ := newHealthMonitor(t, &runconfig.HealthConfig{Test: []string{"CMD", "true"}}, "")
+	container := m.container
+	container.healthMonitor = m
+	container.stopHealthcheck()
+	if container.State.Health != nil {
+		t.Fatalf("Expected the health to be reset on stop, got %+v", container.State.Health)
+	}
+	select {
+	case <-m.stop:
+	default:
+		t.Fatal("Expected the monitor to be stopped")
+	}
+}
+
+func TestHealthProbe(t *testing.T) {
+	m, driver, _ := newHealthMonitor(t, &runconfig.HealthConfig{Test: []string{"CMD-SHELL", "exit", "3"}}, "")
+	driver.exec = func(processConfig *execdriver.ProcessConfig, startCallback execdriver.ExecStartCallback) (int, error) {
+		if processConfig.Entrypoint != "/bin/sh" || strings.Join(processConfig.Arguments, " ") != "-c exit 3" {
+			t.Errorf("Unexpected healthcheck command %s %v", processConfig.Entrypoint, processConfig.Arguments)
+		}
+		return 3, nil
+	}
+	if result := m.probe(); result.ExitCode != 3 {
+		t.Fatalf("Expected the exit code of the command, got %d", result.ExitCode)
+	}
+}
+
+func TestHealthProbeTimeout(t *testing.T) {
+	m, driver, _ := newHealthMonitor(t, &runconfig.HealthConfig{Test: []string{"CMD", "sleep", "10"}, Timeout: 50 * time.Millisecond}, "")
+
+	// The process only starts after the timeout, it must still be killed
+	var (
+		started = make(chan struct{})
+		exited  = make(chan error, 1)
+	)
+	driver.exec = func(processConfig *execdriver.ProcessConfig, startCallback execdriver.ExecStartCallback) (int, error) {
+		<-started
+		cmd := exec.Command(processConfig.Entrypoint, processConfig.Arguments...)
+		if err := cmd.Start(); err != nil {
+			exited <- err
+			return -1, err
+		}
+		processConfig.Pid = cmd.Process.Pid
+		startCallback(processConfig)
+		err := cmd.Wait()
+		exited <- err
+		return -1, err
+	}
+
+	result := m.probe()
+	if result.ExitCode != -1 || !strings.Contains(result.Output, "timeout") {
+		t.Fatalf("Expected the probe to time out, got %d: %s", result.ExitCode, result.Output)
+	}
+	close(started)
+	select {
+	case err := <-exited:
+		if err == nil || !strings.Contains(err.Error(), "killed") {
+			t.Fatalf("Expected the healthcheck to be killed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The timed out healthcheck was not killed")
+	}
+}
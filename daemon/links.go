@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"github.com/docker/docker/pkg/log"
+)
+
+// forEachRunningParent calls fn with each of the running containers linking
+// to child, along with the alias of the link. A container linking to child
+// under several aliases is passed once per alias.
+func (daemon *Daemon) forEachRunningParent(child *Container, fn func(parent *Container, alias string)) {
+	root := daemon.containerGraph.RootEntity().ID()
+	for _, ref := range daemon.containerGraph.RefPaths(child.ID) {
+		if ref.ParentID == root {
+			continue
+		}
+		parent := daemon.Get(ref.ParentID)
+		if parent == nil || !parent.State.IsRunning() {
+			continue
+		}
+		fn(parent, ref.Name)
+	}
+}
+
+// refreshParentLinks follows child from the running containers linking to
+// it when child starts or stops, as it gets a new address on each start:
+// the iptables rules of the links are moved to its current address, and the
+// /etc/hosts files of the parents are rewritten. The environment variables
+// of the links cannot change in a running process and keep the address the
+// child had when the parent started.
+func (daemon *Daemon) refreshParentLinks(child *Container) {
+	childIP := ""
+	if child.State.IsRunning() && child.NetworkSettings != nil {
+		childIP = child.NetworkSettings.IPAddress
+	}
+
+	daemon.forEachRunningParent(child, func(parent *Container, alias string) {
+		link, exists := parent.activeLinks[alias]
+		if !exists {
+			return
+		}
+		// The rules of the previous address are removed even when the
+		// child stopped, so that a container taking its address is not
+		// reachable from the parent
+		if link.IsEnabled {
+			link.Disable()
+		}
+		if childIP == "" {
+			return
+		}
+		link.ChildIP = childIP
+		if err := link.Enable(); err != nil {
+			log.Errorf("Error updating the link %s of %s: %s", link.Name, parent.ID, err)
+		}
+	})
+	daemon.updateParentsHosts(child)
+}
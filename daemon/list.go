@@ -87,6 +87,9 @@ func (daemon *Daemon) Containers(job *engine.Job) engine.Status {
 				return errLast
 			}
 		}
+		if !psFilters.MatchKVList("label", container.Config.Labels) {
+			return nil
+		}
 		if len(filt_exited) > 0 && !container.State.IsRunning() {
 			should_skip := true
 			for _, code := range filt_exited {
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+// Copier reads the output streams of a container line by line and sends
+// each line to a logging driver
+type Copier struct {
+	// cid is the container id for which we are copying logs
+	cid string
+	// srcs maps the name of a stream to its reader
+	srcs     map[string]io.Reader
+	dst      Logger
+	copyJobs sync.WaitGroup
+}
+
+func NewCopier(cid string, srcs map[string]io.Reader, dst Logger) *Copier {
+	return &Copier{
+		cid:  cid,
+		srcs: srcs,
+		dst:  dst,
+	}
+}
+
+// Run starts copying the streams in the background
+func (c *Copier) Run() {
+	for src, r := range c.srcs {
+		c.copyJobs.Add(1)
+		go c.copySrc(src, r)
+	}
+}
+
+func (c *Copier) copySrc(name string, src io.Reader) {
+	defer c.copyJobs.Done()
+	reader := bufio.NewReader(src)
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+
+		// ReadBytes can return a full or partial output even when it
+		// failed, e.g. when the stream ends without a trailing newline
+		if len(line) > 0 || err == nil {
+			msg := &Message{
+				ContainerID: c.cid,
+				Line:        line,
+				Source:      name,
+				Timestamp:   time.Now().UTC(),
+			}
+			if logErr := c.dst.Log(msg); logErr != nil {
+				log.Errorf("Failed to log msg %q for logger %s: %s", msg.Line, c.dst.Name(), logErr)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Errorf("Error scanning log stream: %s", err)
+			}
+			return
+		}
+	}
+}
+
+// Wait blocks until all the streams have been copied
+func (c *Copier) Wait() {
+	c.copyJobs.Wait()
+}
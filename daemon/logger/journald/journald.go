@@ -0,0 +1,105 @@
+// +build linux
+
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/utils"
+)
+
+const (
+	Name = "journald"
+
+	// journalSocket is the socket on which journald accepts entries using
+	// its native protocol
+	journalSocket = "/run/systemd/journal/socket"
+
+	// syslog priorities used for the lines of stdout and stderr
+	priorityInfo = "6"
+	priorityErr  = "3"
+)
+
+// Journald sends the output of a container to the systemd journal, one
+// datagram per line, tagging every entry with the container it comes from
+type Journald struct {
+	mu     sync.Mutex
+	conn   *net.UnixConn
+	fields map[string]string
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(Name, New); err != nil {
+		panic(err)
+	}
+}
+
+func New(ctx logger.Context) (logger.Logger, error) {
+	for key := range ctx.Config {
+		return nil, fmt.Errorf("unknown log opt '%s' for %s log driver", key, Name)
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &Journald{
+		conn: conn,
+		fields: map[string]string{
+			"CONTAINER_ID":      utils.TruncateID(ctx.ContainerID),
+			"CONTAINER_ID_FULL": ctx.ContainerID,
+			"CONTAINER_NAME":    strings.TrimPrefix(ctx.ContainerName, "/"),
+		},
+	}, nil
+}
+
+func (j *Journald) Log(msg *logger.Message) error {
+	priority := priorityInfo
+	if msg.Source == "stderr" {
+		priority = priorityErr
+	}
+	data := serialize(string(msg.Line), priority, j.fields)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err := j.conn.Write(data)
+	return err
+}
+
+func (j *Journald) Name() string {
+	return Name
+}
+
+func (j *Journald) Close() error {
+	return j.conn.Close()
+}
+
+// serialize encodes an entry using the journald native protocol. Values
+// containing a newline are written in the binary form, prefixed by their
+// length as a little endian 64 bit integer.
+func serialize(message, priority string, fields map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	appendField(buf, "MESSAGE", message)
+	appendField(buf, "PRIORITY", priority)
+	for k, v := range fields {
+		appendField(buf, k, v)
+	}
+	return buf.Bytes()
+}
+
+func appendField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if strings.ContainsRune(value, '\n') {
+		buf.WriteByte('\n')
+		binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	} else {
+		buf.WriteByte('=')
+	}
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
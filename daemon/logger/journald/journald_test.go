@@ -0,0 +1,24 @@
+// +build linux
+
+package journald
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSerialize(t *testing.T) {
+	data := serialize("hello", priorityInfo, map[string]string{"CONTAINER_ID": "a7317399f3f8"})
+	expected := "MESSAGE=hello\nPRIORITY=6\nCONTAINER_ID=a7317399f3f8\n"
+	if string(data) != expected {
+		t.Fatalf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestSerializeMultiline(t *testing.T) {
+	data := serialize("a\nb", priorityErr, nil)
+	expected := []byte("MESSAGE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\nPRIORITY=3\n")
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("Expected %q, got %q", expected, data)
+	}
+}
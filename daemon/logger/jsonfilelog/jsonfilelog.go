@@ -0,0 +1,220 @@
+package jsonfilelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/pkg/jsonlog"
+	"github.com/docker/docker/pkg/units"
+)
+
+const Name = "json-file"
+
+// JSONFileLogger writes the output of a container to a file as
+// serialized jsonlog.JSONLog, rotating the file once it reaches its
+// maximum size
+type JSONFileLogger struct {
+	mu       sync.Mutex
+	f        *os.File
+	path     string
+	size     int64 // current size of f
+	capacity int64 // maximum size of f before rotation, -1 for unlimited
+	maxFiles int   // number of files kept, including the current one
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(Name, New); err != nil {
+		panic(err)
+	}
+	if err := logger.RegisterLogReader(Name, ReadLogs); err != nil {
+		panic(err)
+	}
+}
+
+// parseConfig validates the options of the driver and returns the
+// maximum size of a log file and the number of files to keep
+func parseConfig(cfg map[string]string) (int64, int, error) {
+	var (
+		capacity int64 = -1
+		maxFiles       = 1
+	)
+	for key, value := range cfg {
+		switch key {
+		case "max-size":
+			size, err := units.RAMInBytes(value)
+			if err != nil {
+				return 0, 0, err
+			}
+			if size <= 0 {
+				return 0, 0, fmt.Errorf("max-size must be a positive size, got %s", value)
+			}
+			capacity = size
+		case "max-file":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, 0, err
+			}
+			if n < 1 {
+				return 0, 0, fmt.Errorf("max-file cannot be less than 1, got %s", value)
+			}
+			maxFiles = n
+		default:
+			return 0, 0, fmt.Errorf("unknown log opt '%s' for %s log driver", key, Name)
+		}
+	}
+	if maxFiles > 1 && capacity == -1 {
+		return 0, 0, fmt.Errorf("max-file cannot be set without max-size")
+	}
+	return capacity, maxFiles, nil
+}
+
+// New creates a new JSONFileLogger writing to ctx.LogPath
+func New(ctx logger.Context) (logger.Logger, error) {
+	capacity, maxFiles, err := parseConfig(ctx.Config)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(ctx.LogPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &JSONFileLogger{
+		f:        f,
+		path:     ctx.LogPath,
+		size:     st.Size(),
+		capacity: capacity,
+		maxFiles: maxFiles,
+	}, nil
+}
+
+// Log writes msg to the log file, rotating it first if msg does not fit
+func (l *JSONFileLogger) Log(msg *logger.Message) error {
+	b, err := json.Marshal(jsonlog.JSONLog{Log: string(msg.Line) + "\n", Stream: msg.Source, Created: msg.Timestamp})
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.capacity != -1 && l.size > 0 && l.size+int64(len(b)) > l.capacity {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.f.Write(b)
+	l.size += int64(n)
+	return err
+}
+
+// rotate shifts the log files by one, dropping the oldest, and starts a
+// new empty log file. It must be called with l.mu held.
+func (l *JSONFileLogger) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	if l.maxFiles > 1 {
+		for i := l.maxFiles - 1; i > 1; i-- {
+			if err := os.Rename(rotatedPath(l.path, i-1), rotatedPath(l.path, i)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(l.path, rotatedPath(l.path, 1)); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	l.size = 0
+	return nil
+}
+
+func (l *JSONFileLogger) Name() string {
+	return Name
+}
+
+func (l *JSONFileLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+func rotatedPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// ReadLogs returns the content of the log files of a container, oldest
+// first. If the container only has a single log file, it is returned as
+// is so that callers can seek in it.
+func ReadLogs(ctx logger.Context) (io.ReadCloser, error) {
+	_, maxFiles, err := parseConfig(ctx.Config)
+	if err != nil {
+		return nil, err
+	}
+	var files []*os.File
+	for i := maxFiles - 1; i > 0; i-- {
+		f, err := os.Open(rotatedPath(ctx.LogPath, i))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			closeFiles(files)
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	f, err := os.Open(ctx.LogPath)
+	if err != nil {
+		closeFiles(files)
+		return nil, err
+	}
+	if len(files) == 0 {
+		return f, nil
+	}
+	return &multiFileReader{files: append(files, f)}, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// multiFileReader reads a sequence of files one after the other
+type multiFileReader struct {
+	files []*os.File
+	cur   int
+}
+
+func (r *multiFileReader) Read(p []byte) (int, error) {
+	for r.cur < len(r.files) {
+		n, err := r.files[r.cur].Read(p)
+		if err == io.EOF {
+			r.cur++
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+	return 0, io.EOF
+}
+
+func (r *multiFileReader) Close() error {
+	closeFiles(r.files)
+	return nil
+}
@@ -0,0 +1,135 @@
+package jsonfilelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/pkg/jsonlog"
+)
+
+func newTestLogger(t *testing.T, cfg map[string]string) (logger.Logger, logger.Context, func()) {
+	tmp, err := ioutil.TempDir("", "docker-logger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := logger.Context{
+		Config:      cfg,
+		ContainerID: "a7317399f3f857173c6179d44823594f8294678dea9999662e5c625b5a1c7657",
+		LogPath:     filepath.Join(tmp, "container.log"),
+	}
+	l, err := New(ctx)
+	if err != nil {
+		os.RemoveAll(tmp)
+		t.Fatal(err)
+	}
+	return l, ctx, func() {
+		l.Close()
+		os.RemoveAll(tmp)
+	}
+}
+
+func readLines(t *testing.T, ctx logger.Context) []jsonlog.JSONLog {
+	r, err := ReadLogs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var lines []jsonlog.JSONLog
+	dec := json.NewDecoder(r)
+	for {
+		var l jsonlog.JSONLog
+		if err := dec.Decode(&l); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+func TestJSONFileLogger(t *testing.T) {
+	l, ctx, cleanup := newTestLogger(t, nil)
+	defer cleanup()
+
+	created := time.Now().UTC()
+	for _, m := range []*logger.Message{
+		{Line: []byte("line1"), Source: "stdout", Timestamp: created},
+		{Line: []byte("line2"), Source: "stderr", Timestamp: created},
+	} {
+		if err := l.Log(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := readLines(t, ctx)
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].Log != "line1\n" || lines[0].Stream != "stdout" {
+		t.Fatalf("Unexpected first line: %#v", lines[0])
+	}
+	if lines[1].Log != "line2\n" || lines[1].Stream != "stderr" {
+		t.Fatalf("Unexpected second line: %#v", lines[1])
+	}
+	if !lines[0].Created.Equal(created) {
+		t.Fatalf("Expected time %s, got %s", created, lines[0].Created)
+	}
+}
+
+func TestJSONFileLoggerRotate(t *testing.T) {
+	l, ctx, cleanup := newTestLogger(t, map[string]string{"max-size": "200", "max-file": "3"})
+	defer cleanup()
+
+	for i := 0; i < 20; i++ {
+		if err := l.Log(&logger.Message{Line: []byte(fmt.Sprintf("line%02d", i)), Source: "stdout", Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []string{ctx.LogPath, ctx.LogPath + ".1", ctx.LogPath + ".2"} {
+		st, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Size() > 200 {
+			t.Fatalf("%s is larger than max-size: %d", p, st.Size())
+		}
+	}
+	if _, err := os.Stat(ctx.LogPath + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("Expected only 3 log files to be kept")
+	}
+
+	lines := readLines(t, ctx)
+	if len(lines) == 0 || len(lines) >= 20 {
+		t.Fatalf("Expected the oldest lines to be dropped, got %d lines", len(lines))
+	}
+	first := 20 - len(lines)
+	for i, line := range lines {
+		if expected := fmt.Sprintf("line%02d\n", first+i); line.Log != expected {
+			t.Fatalf("Expected %q at position %d, got %q", expected, i, line.Log)
+		}
+	}
+}
+
+func TestJSONFileLoggerInvalidConfig(t *testing.T) {
+	for _, cfg := range []map[string]string{
+		{"max-size": "invalid"},
+		{"max-size": "0"},
+		{"max-file": "0"},
+		{"max-file": "2"},
+		{"unknown": "value"},
+	} {
+		if _, _, err := parseConfig(cfg); err == nil {
+			t.Fatalf("Expected an error for %v", cfg)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrReadLogsNotSupported = errors.New("configured logging driver does not support reading")
+
+// Message is a single line of output written by a container
+type Message struct {
+	ContainerID string
+	Line        []byte
+	Source      string
+	Timestamp   time.Time
+}
+
+// Logger is the interface implemented by the logging drivers which receive
+// the output of the containers
+type Logger interface {
+	Log(*Message) error
+	Name() string
+	Close() error
+}
+
+// Context holds the information a logging driver needs to log the output
+// of a container
+type Context struct {
+	Config        map[string]string
+	ContainerID   string
+	ContainerName string
+	LogPath       string
+}
+
+// Creator returns a new logging driver for the given container
+type Creator func(Context) (Logger, error)
+
+// Reader opens the logs of a container previously written by a logging
+// driver. The logs are returned as a stream of serialized jsonlog.JSONLog.
+type Reader func(Context) (io.ReadCloser, error)
+
+var (
+	creators = make(map[string]Creator)
+	readers  = make(map[string]Reader)
+)
+
+// RegisterLogDriver registers a logging driver under the given name
+func RegisterLogDriver(name string, c Creator) error {
+	if _, exists := creators[name]; exists {
+		return fmt.Errorf("logger: log driver named '%s' is already registered", name)
+	}
+	creators[name] = c
+	return nil
+}
+
+// RegisterLogReader registers the function used to read back the logs
+// written by the logging driver registered under name
+func RegisterLogReader(name string, r Reader) error {
+	if _, exists := readers[name]; exists {
+		return fmt.Errorf("logger: log reader named '%s' is already registered", name)
+	}
+	readers[name] = r
+	return nil
+}
+
+// GetLogDriver returns the creator of the logging driver registered under name
+func GetLogDriver(name string) (Creator, error) {
+	if c, exists := creators[name]; exists {
+		return c, nil
+	}
+	return nil, fmt.Errorf("logger: no log driver named '%s' is registered", name)
+}
+
+// GetLogReader returns the reader of the logging driver registered under
+// name, or ErrReadLogsNotSupported if the driver cannot read its logs back
+func GetLogReader(name string) (Reader, error) {
+	if r, exists := readers[name]; exists {
+		return r, nil
+	}
+	return nil, ErrReadLogsNotSupported
+}
@@ -0,0 +1,94 @@
+// +build linux
+
+package syslog
+
+import (
+	"fmt"
+	"log/syslog"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/utils"
+)
+
+const Name = "syslog"
+
+// Syslog sends the output of a container to the syslog daemon listening
+// on a local unix socket. Lines of stdout are logged with the info
+// severity, lines of stderr with the error one.
+type Syslog struct {
+	writer *syslog.Writer
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(Name, New); err != nil {
+		panic(err)
+	}
+}
+
+// parseAddress returns the path of the unix socket of the syslog daemon,
+// or an empty string to let log/syslog probe the default locations
+func parseAddress(address string) (string, error) {
+	if address == "" {
+		return "", nil
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "unix" || u.Path == "" {
+		return "", fmt.Errorf("syslog-address must be of the form unix:///path/to/socket, got %s", address)
+	}
+	return u.Path, nil
+}
+
+func New(ctx logger.Context) (logger.Logger, error) {
+	var (
+		address string
+		tag     = path.Base(os.Args[0]) + "/" + utils.TruncateID(ctx.ContainerID)
+	)
+	for key, value := range ctx.Config {
+		switch key {
+		case "syslog-address":
+			addr, err := parseAddress(value)
+			if err != nil {
+				return nil, err
+			}
+			address = addr
+		case "syslog-tag":
+			tag = value
+		default:
+			return nil, fmt.Errorf("unknown log opt '%s' for %s log driver", key, Name)
+		}
+	}
+
+	var network string
+	if address != "" {
+		network = "unixgram"
+	}
+	w, err := syslog.Dial(network, address, syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &Syslog{writer: w}, nil
+}
+
+func (s *Syslog) Log(msg *logger.Message) error {
+	if msg.Source == "stderr" {
+		return s.writer.Err(string(msg.Line))
+	}
+	return s.writer.Info(string(msg.Line))
+}
+
+func (s *Syslog) Name() string {
+	return Name
+}
+
+func (s *Syslog) Close() error {
+	if s.writer != nil {
+		return s.writer.Close()
+	}
+	return nil
+}
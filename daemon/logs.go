@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/tailfile"
 
+	"github.com/docker/docker/daemon/logger"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/pkg/jsonlog"
 )
@@ -44,8 +46,10 @@ func (daemon *Daemon) ContainerLogs(job *engine.Job) engine.Status {
 	if container == nil {
 		return job.Errorf("No such container: %s", name)
 	}
-	cLog, err := container.ReadLog("json")
-	if err != nil && os.IsNotExist(err) {
+	cLog, err := container.readLogs()
+	if err == logger.ErrReadLogsNotSupported {
+		return job.Errorf("\"logs\" is not supported by the %s logging driver of container %s", container.getLogConfig().Type, name)
+	} else if err != nil && os.IsNotExist(err) {
 		// Legacy logs
 		log.Debugf("Old logs format")
 		if stdout {
@@ -67,6 +71,7 @@ func (daemon *Daemon) ContainerLogs(job *engine.Job) engine.Status {
 	} else if err != nil {
 		log.Errorf("Error reading logs (json): %s", err)
 	} else {
+		defer cLog.Close()
 		if tail != "all" {
 			var err error
 			lines, err = strconv.Atoi(tail)
@@ -76,9 +81,9 @@ func (daemon *Daemon) ContainerLogs(job *engine.Job) engine.Status {
 			}
 		}
 		if lines != 0 {
+			var src io.Reader = cLog
 			if lines > 0 {
-				f := cLog.(*os.File)
-				ls, err := tailfile.TailFile(f, lines)
+				ls, err := tailLogs(cLog, lines)
 				if err != nil {
 					return job.Error(err)
 				}
@@ -86,9 +91,9 @@ func (daemon *Daemon) ContainerLogs(job *engine.Job) engine.Status {
 				for _, l := range ls {
 					fmt.Fprintf(tmp, "%s\n", l)
 				}
-				cLog = tmp
+				src = tmp
 			}
-			dec := json.NewDecoder(cLog)
+			dec := json.NewDecoder(src)
 			for {
 				l := &jsonlog.JSONLog{}
 
@@ -132,3 +137,22 @@ func (daemon *Daemon) ContainerLogs(job *engine.Job) engine.Status {
 	}
 	return engine.StatusOK
 }
+
+// tailLogs returns the last n lines of the logs. A single log file is read
+// backwards from its end, while logs split over rotated files are scanned.
+func tailLogs(r io.Reader, n int) ([][]byte, error) {
+	if f, ok := r.(*os.File); ok {
+		return tailfile.TailFile(f, n)
+	}
+	var (
+		ls      = make([][]byte, 0, n)
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		if len(ls) == n {
+			ls = ls[1:]
+		}
+		ls = append(ls, append([]byte(nil), scanner.Bytes()...))
+	}
+	return ls, scanner.Err()
+}
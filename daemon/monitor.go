@@ -121,7 +121,7 @@ func (m *containerMonitor) Start() error {
 	for {
 		m.container.RestartCount++
 
-		if err := m.container.startLoggingToDisk(); err != nil {
+		if err := m.container.startLogging(); err != nil {
 			m.resetContainer()
 
 			return err
@@ -145,6 +145,8 @@ func (m *containerMonitor) Start() error {
 			log.Errorf("Error running container: %s", err)
 		}
 
+		m.container.stopHealthcheck()
+
 		// here container.Lock is already lost
 		underLock = false
 
@@ -256,6 +258,13 @@ func (m *containerMonitor) callback(command *execdriver.Command) {
 
 	m.container.State.SetRunning(command.Pid())
 
+	m.container.connectNetworks()
+
+	// the containers linking to this one follow its new address
+	m.container.daemon.refreshParentLinks(m.container)
+
+	m.container.startHealthcheck()
+
 	// signal that the process has started
 	// close channel only if not closed
 	select {
@@ -288,6 +297,8 @@ func (m *containerMonitor) resetContainer() {
 		log.Errorf("%s: Error close stderr: %s", container.ID, err)
 	}
 
+	container.stopLogging()
+
 	if container.command != nil && container.command.Terminal != nil {
 		if err := container.command.Terminal.Close(); err != nil {
 			log.Errorf("%s: Error closing terminal: %s", container.ID, err)
@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/iptables"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/runconfig"
+)
+
+// ContainerNetworkConnect connects a container to one more network besides
+// the one of its network mode. A running container gets its new interface
+// right away, and the container is connected again each time it starts.
+func (daemon *Daemon) ContainerNetworkConnect(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s NETWORK CONTAINER", job.Name)
+	}
+	name := job.Args[1]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	network, err := daemon.resolveNetwork(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+
+	container.Lock()
+	defer container.Unlock()
+
+	mode := container.hostConfig.NetworkMode
+	if container.Config.NetworkDisabled || mode.IsHost() || mode.IsContainer() || mode == "none" {
+		return job.Errorf("Container %s uses the %s network mode and cannot be connected to a network", name, mode)
+	}
+	if network == container.networkName() || container.isConnectedTo(network) {
+		return job.Errorf("Container %s is already connected to the network %s", name, network)
+	}
+
+	if container.State.IsRunning() {
+		if err := container.connectNetwork(network); err != nil {
+			return job.Error(err)
+		}
+	}
+	container.hostConfig.Networks = append(container.hostConfig.Networks, network)
+	if err := container.WriteHostConfig(); err != nil {
+		return job.Error(err)
+	}
+	if err := container.toDisk(); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// ContainerNetworkDisconnect disconnects a container from a network it was
+// connected to with ContainerNetworkConnect
+func (daemon *Daemon) ContainerNetworkDisconnect(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s NETWORK CONTAINER", job.Name)
+	}
+	name := job.Args[1]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	// the network may be gone while the container is stopped
+	network, err := daemon.resolveNetwork(job.Args[0])
+	if err != nil {
+		network = job.Args[0]
+	}
+
+	container.Lock()
+	defer container.Unlock()
+
+	if network == container.networkName() {
+		return job.Errorf("Container %s cannot be disconnected from the network %s of its network mode", name, network)
+	}
+	if !container.isConnectedTo(network) {
+		return job.Errorf("Container %s is not connected to the network %s", name, network)
+	}
+
+	if container.State.IsRunning() {
+		if err := container.disconnectNetwork(network); err != nil {
+			return job.Error(err)
+		}
+	}
+	var networks []string
+	for _, n := range container.hostConfig.Networks {
+		if n != network {
+			networks = append(networks, n)
+		}
+	}
+	container.hostConfig.Networks = networks
+	if err := container.WriteHostConfig(); err != nil {
+		return job.Error(err)
+	}
+	if err := container.toDisk(); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// resolveNetwork returns the name of the network with the given name or id
+func (daemon *Daemon) resolveNetwork(name string) (string, error) {
+	job := daemon.eng.Job("network_inspect", name)
+	env, err := job.Stdout.AddEnv()
+	if err != nil {
+		return "", err
+	}
+	if err := job.Run(); err != nil {
+		return "", err
+	}
+	return env.Get("Name"), nil
+}
+
+// verifyNetworkPolicy checks the rules of the policy, which the clients of
+// the API may give without going through the parser of the cli
+func verifyNetworkPolicy(policy runconfig.NetworkPolicy) error {
+	for _, rules := range [][]iptables.PolicyRule{policy.Egress, policy.Ingress} {
+		for _, rule := range rules {
+			if err := rule.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (container *Container) isConnectedTo(network string) bool {
+	for _, n := range container.hostConfig.Networks {
+		if n == network {
+			return true
+		}
+	}
+	return false
+}
+
+// connectNetworks connects a container which just started to the networks it
+// was connected to with docker network connect
+func (container *Container) connectNetworks() {
+	for _, network := range container.hostConfig.Networks {
+		if err := container.connectNetwork(network); err != nil {
+			log.Errorf("Unable to connect container %s to the network %s: %s", container.ID, network, err)
+		}
+	}
+}
+
+// connectNetwork adds an interface on network to the running container
+func (container *Container) connectNetwork(network string) error {
+	job := container.daemon.eng.Job("connect_interface", container.ID)
+	job.Setenv("Network", network)
+	job.SetenvInt("Pid", container.State.GetPid())
+	job.Setenv("Device", container.nextDevice())
+	job.SetenvJson("NetworkPolicy", container.hostConfig.NetworkPolicy)
+	env, err := job.Stdout.AddEnv()
+	if err != nil {
+		return err
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+
+	if container.NetworkSettings.Networks == nil {
+		container.NetworkSettings.Networks = make(map[string]*EndpointSettings)
+	}
+	container.NetworkSettings.Networks[network] = &EndpointSettings{
+		IPAddress:   env.Get("IP"),
+		IPPrefixLen: env.GetInt("IPPrefixLen"),
+		Gateway:     env.Get("Gateway"),
+		Bridge:      env.Get("Bridge"),
+		Device:      job.Getenv("Device"),
+	}
+	return nil
+}
+
+// disconnectNetwork removes the interface on network of the running container
+func (container *Container) disconnectNetwork(network string) error {
+	job := container.daemon.eng.Job("disconnect_interface", container.ID)
+	job.Setenv("Network", network)
+	job.SetenvInt("Pid", container.State.GetPid())
+	if err := job.Run(); err != nil {
+		return err
+	}
+	delete(container.NetworkSettings.Networks, network)
+	return nil
+}
+
+// nextDevice returns the first name of interface not used by the container
+func (container *Container) nextDevice() string {
+	for i := 1; ; i++ {
+		device := fmt.Sprintf("eth%d", i)
+		used := false
+		for _, endpoint := range container.NetworkSettings.Networks {
+			if endpoint.Device == device {
+				used = true
+				break
+			}
+		}
+		if !used {
+			return device
+		}
+	}
+}
@@ -9,12 +9,30 @@ import (
 type PortMapping map[string]string // Deprecated
 
 type NetworkSettings struct {
+	IPAddress              string
+	IPPrefixLen            int
+	MacAddress             string
+	LinkLocalIPv6Address   string
+	LinkLocalIPv6PrefixLen int
+	GlobalIPv6Address      string
+	GlobalIPv6PrefixLen    int
+	Gateway                string
+	IPv6Gateway            string
+	Bridge                 string
+	HairpinMode            bool
+	Mtu                    int                    // mtu of the network when it is lower than the one of the daemon, as on overlay networks
+	PortMapping            map[string]PortMapping // Deprecated
+	Ports                  nat.PortMap
+	Networks               map[string]*EndpointSettings
+}
+
+// EndpointSettings is the interface of a container on one of its networks
+type EndpointSettings struct {
 	IPAddress   string
 	IPPrefixLen int
 	Gateway     string
 	Bridge      string
-	PortMapping map[string]PortMapping // Deprecated
-	Ports       nat.PortMap
+	Device      string
 }
 
 func (settings *NetworkSettings) PortMappingAPI() *engine.Table {
@@ -0,0 +1,131 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/netdev"
+	"github.com/docker/libcontainer/netlink"
+)
+
+// ConnectInterface attaches a running container to one more network: an
+// interface named after the Device env is created in the network namespace
+// of the process given by the Pid env, with an ip of the network.
+func ConnectInterface(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	var (
+		id     = job.Args[0]
+		pid    = job.GetenvInt("Pid")
+		device = job.Getenv("Device")
+	)
+	if pid == 0 || device == "" {
+		return job.Errorf("The pid of the container and the name of its interface are required")
+	}
+
+	n, err := getNetwork(job.Getenv("Network"))
+	if err != nil {
+		return job.Error(err)
+	}
+	if currentInterfaces.Get(id, n.Name) != nil {
+		return job.Errorf("Container %s is already connected to the network %s", id, n.Name)
+	}
+	policy, err := containerPolicy(job, id, n)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	network := n.ipNet()
+	ip, err := requestIP(n, nil, id)
+	if err != nil {
+		return job.Error(err)
+	}
+	size, _ := network.Mask.Size()
+	config := &netdev.Config{
+		Device:      device,
+		Address:     fmt.Sprintf("%s/%d", ip, size),
+		MacAddress:  generateMacAddr(*ip).String(),
+		HairpinMode: hairpinMode,
+	}
+	if n.Driver == overlayDriver {
+		config.Mtu = overlayMtu
+	}
+
+	if n.Parent != "" {
+		err = netdev.CreateMacvlan(n.Parent, netnsPath(pid), config)
+	} else {
+		_, err = netdev.CreateVeth(n.Bridge, netnsPath(pid), config)
+	}
+	if err != nil {
+		releaseIP(n, ip)
+		return job.Errorf("Cannot connect to the network %s: %s", n.Name, err)
+	}
+	if policy != nil {
+		if err := policy.Enable(ip.String()); err != nil {
+			netdev.InNetns(netnsPath(pid), func() error {
+				return netlink.NetworkLinkDel(device)
+			})
+			releaseIP(n, ip)
+			return job.Errorf("Cannot apply the network policy of %s: %s", id, err)
+		}
+	}
+
+	currentInterfaces.Set(id, n.Name, &networkInterface{
+		IP:     *ip,
+		Device: device,
+		Policy: policy,
+	})
+
+	out := engine.Env{}
+	out.Set("IP", ip.String())
+	out.Set("Mask", network.Mask.String())
+	out.Set("Gateway", n.Gateway)
+	out.Set("Bridge", n.Bridge)
+	out.SetInt("IPPrefixLen", size)
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// DisconnectInterface removes the interface of a container on a network
+// connected with ConnectInterface, and releases its ip
+func DisconnectInterface(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	var (
+		id  = job.Args[0]
+		pid = job.GetenvInt("Pid")
+	)
+
+	n, err := getNetwork(job.Getenv("Network"))
+	if err != nil {
+		return job.Error(err)
+	}
+	containerInterface := currentInterfaces.Get(id, n.Name)
+	if containerInterface == nil {
+		return job.Errorf("Container %s is not connected to the network %s", id, n.Name)
+	}
+	if containerInterface.Device == "" {
+		return job.Errorf("Container %s cannot be disconnected from its primary network %s", id, n.Name)
+	}
+
+	if pid != 0 {
+		err := netdev.InNetns(netnsPath(pid), func() error {
+			return netlink.NetworkLinkDel(containerInterface.Device)
+		})
+		if err != nil {
+			log.Infof("Unable to remove interface %s of %s: %s", containerInterface.Device, id, err)
+		}
+	}
+	releaseInterface(id, n.Name, containerInterface)
+	return engine.StatusOK
+}
+
+// netnsPath is the network namespace of the process pid
+func netnsPath(pid int) string {
+	return fmt.Sprintf("/proc/%d/ns/net", pid)
+}
@@ -21,29 +21,84 @@ import (
 
 const (
 	DefaultNetworkBridge     = "docker0"
+	DefaultNetworkName       = "bridge"
 	MaxAllocatedPortAttempts = 10
 )
 
 // Network interface represents the networking stack of a container
 type networkInterface struct {
-	IP           net.IP
-	PortMappings []net.Addr // there are mappings to the host interfaces
+	IP            net.IP
+	IPv6          net.IP           // global address, set when the bridge has a fixed IPv6 subnet
+	LinkLocalIPv6 net.IP           // set on the default network with IPv6 enabled
+	Device        string           // name of the interface in the container, set for the networks connected at runtime
+	PortMappings  []net.Addr       // there are mappings to the host interfaces
+	Policy        *iptables.Policy // restrictions of the traffic of the container, nil when it has none
 }
 
+// addresses returns the addresses of the interface, the ones restricted by
+// the network policy of the container
+func (i *networkInterface) addresses() []string {
+	addresses := []string{i.IP.String()}
+	for _, ip := range []net.IP{i.LinkLocalIPv6, i.IPv6} {
+		if ip != nil {
+			addresses = append(addresses, ip.String())
+		}
+	}
+	return addresses
+}
+
+// ifaces holds the interfaces of the containers, by container id and
+// network name
 type ifaces struct {
-	c map[string]*networkInterface
+	c map[string]map[string]*networkInterface
 	sync.Mutex
 }
 
-func (i *ifaces) Set(key string, n *networkInterface) {
+func (i *ifaces) Set(key, network string, n *networkInterface) {
+	i.Lock()
+	if i.c[key] == nil {
+		i.c[key] = make(map[string]*networkInterface)
+	}
+	i.c[key][network] = n
+	i.Unlock()
+}
+
+func (i *ifaces) Get(key, network string) *networkInterface {
+	i.Lock()
+	res := i.c[key][network]
+	i.Unlock()
+	return res
+}
+
+func (i *ifaces) Remove(key, network string) {
 	i.Lock()
-	i.c[key] = n
+	delete(i.c[key], network)
+	if len(i.c[key]) == 0 {
+		delete(i.c, key)
+	}
 	i.Unlock()
 }
 
-func (i *ifaces) Get(key string) *networkInterface {
+// List returns the interfaces of a container by network name
+func (i *ifaces) List(key string) map[string]*networkInterface {
 	i.Lock()
-	res := i.c[key]
+	res := make(map[string]*networkInterface, len(i.c[key]))
+	for network, n := range i.c[key] {
+		res[network] = n
+	}
+	i.Unlock()
+	return res
+}
+
+// Endpoints returns the interfaces attached to a network by container id
+func (i *ifaces) Endpoints(network string) map[string]*networkInterface {
+	i.Lock()
+	res := make(map[string]*networkInterface)
+	for key, networks := range i.c {
+		if n, exists := networks[network]; exists {
+			res[key] = n
+		}
+	}
 	i.Unlock()
 	return res
 }
@@ -69,21 +124,23 @@ var (
 		"192.168.44.1/24",
 	}
 
-	bridgeIface   string
-	bridgeNetwork *net.IPNet
+	bridgeIface    string
+	bridgeNetwork  *net.IPNet
+	enableIPTables bool
 
 	defaultBindingIP  = net.ParseIP("0.0.0.0")
-	currentInterfaces = ifaces{c: make(map[string]*networkInterface)}
+	currentInterfaces = ifaces{c: make(map[string]map[string]*networkInterface)}
 )
 
 func InitDriver(job *engine.Job) engine.Status {
 	var (
-		network        *net.IPNet
-		enableIPTables = job.GetenvBool("EnableIptables")
-		icc            = job.GetenvBool("InterContainerCommunication")
-		ipForward      = job.GetenvBool("EnableIpForward")
-		bridgeIP       = job.Getenv("BridgeIP")
+		network   *net.IPNet
+		icc       = job.GetenvBool("InterContainerCommunication")
+		ipForward = job.GetenvBool("EnableIpForward")
+		bridgeIP  = job.Getenv("BridgeIP")
 	)
+	enableIPTables = job.GetenvBool("EnableIptables")
+	enableIPv6 = job.GetenvBool("EnableIPv6")
 
 	if defaultIP := job.Getenv("DefaultBindingIP"); defaultIP != "" {
 		defaultBindingIP = net.ParseIP(defaultIP)
@@ -129,6 +186,17 @@ func InitDriver(job *engine.Job) engine.Status {
 		}
 	}
 
+	hairpinMode = false
+	if job.EnvExists("EnableUserlandProxy") && !job.GetenvBool("EnableUserlandProxy") {
+		if !enableIPTables {
+			job.Logf("WARNING: the published ports need iptables to be reached without the userland proxy, using the proxy\n")
+		} else if err := enableRouteLocalnet(bridgeIface); err != nil {
+			job.Logf("WARNING: %s, using the userland proxy\n", err)
+		} else {
+			hairpinMode = true
+		}
+	}
+
 	// Configure iptables for link support
 	if enableIPTables {
 		if err := setupIPTables(addr, icc); err != nil {
@@ -149,23 +217,68 @@ func InitDriver(job *engine.Job) engine.Status {
 	}
 
 	if enableIPTables {
-		chain, err := iptables.NewChain("DOCKER", bridgeIface)
+		chain, err := iptables.NewChain("DOCKER", bridgeIface, hairpinMode)
 		if err != nil {
 			return job.Error(err)
 		}
 		portmapper.SetIptablesChain(chain)
 	}
 
+	if hairpinMode {
+		// iptables forwards the whole traffic of the published ports, the
+		// proxies only hold them
+		portmapper.NewProxy = portmapper.NewDummyProxy
+	}
+
+	if enableIPv6 {
+		if err := setupIPv6Bridge(job.Getenv("FixedCIDRv6"), ipForward, icc); err != nil {
+			return job.Error(err)
+		}
+	}
+
 	bridgeNetwork = network
 
+	if fixedCIDR := job.Getenv("FixedCIDR"); fixedCIDR != "" {
+		_, subnet, err := net.ParseCIDR(fixedCIDR)
+		if err != nil {
+			return job.Error(err)
+		}
+		log.Debugf("Restricting the allocation of ips to %s", subnet)
+		if err := ipallocator.RegisterSubnet(bridgeNetwork, subnet); err != nil {
+			return job.Errorf("Cannot use the fixed cidr %s on the bridge network %s: %s", subnet, bridgeNetwork, err)
+		}
+	}
+
 	// https://github.com/docker/docker/issues/2768
 	job.Eng.Hack_SetGlobalVar("httpapi.bridgeIP", bridgeNetwork.IP)
 
+	if macvlanRanges, err = parseMacvlanRanges(job.GetenvList("MacvlanRanges")); err != nil {
+		return job.Error(err)
+	}
+
+	if err := initClusterStore(job.Getenv("ClusterStore"), job.Getenv("ClusterAdvertise")); err != nil {
+		return job.Error(err)
+	}
+
+	// Bring back the user-defined networks
+	if err := userNetworks.load(job.Getenv("NetworksPath")); err != nil {
+		return job.Error(err)
+	}
+	if clusterStore != nil {
+		go syncOverlaysLoop()
+	}
+
 	for name, f := range map[string]engine.Handler{
-		"allocate_interface": Allocate,
-		"release_interface":  Release,
-		"allocate_port":      AllocatePort,
-		"link":               LinkContainers,
+		"allocate_interface":   Allocate,
+		"release_interface":    Release,
+		"allocate_port":        AllocatePort,
+		"link":                 LinkContainers,
+		"connect_interface":    ConnectInterface,
+		"disconnect_interface": DisconnectInterface,
+		"network_create":       CreateNetwork,
+		"network_inspect":      InspectNetwork,
+		"network_rm":           RemoveNetwork,
+		"networks":             ListNetworks,
 	} {
 		if err := job.Eng.Register(name, f); err != nil {
 			return job.Error(err)
@@ -186,6 +299,15 @@ func setupIPTables(addr net.Addr, icc bool) error { //icc决定docker容器之
 		}
 	}
 
+	// the rule of the other mode may be left from a previous run
+	if hairpinMode {
+		if err := insertRule(hairpinRule(bridgeIface)...); err != nil {
+			return fmt.Errorf("Unable to enable hairpin NAT: %s", err)
+		}
+	} else {
+		iptables.Raw(append([]string{"-D"}, hairpinRule(bridgeIface)...)...)
+	}
+
 	var (
 		args       = []string{"FORWARD", "-i", bridgeIface, "-o", bridgeIface, "-j"}
 		acceptArgs = append(args, "ACCEPT")
@@ -323,54 +445,142 @@ func Allocate(job *engine.Job) engine.Status {
 		requestedIP = net.ParseIP(job.Getenv("RequestedIP"))
 	)
 
-	if requestedIP != nil {
-		ip, err = ipallocator.RequestIP(bridgeNetwork, &requestedIP)
-	} else {
-		ip, err = ipallocator.RequestIP(bridgeNetwork, nil)
+	n, err := getNetwork(job.Getenv("Network"))
+	if err != nil {
+		return job.Error(err)
 	}
+	network := n.ipNet()
+	policy, err := containerPolicy(job, id, n)
 	if err != nil {
 		return job.Error(err)
 	}
 
+	if requestedIP != nil {
+		ip, err = requestIP(n, &requestedIP, id)
+		if err != nil {
+			return job.Errorf("Cannot allocate the ip %s on the network %s: %s", requestedIP, n.Name, err)
+		}
+	} else {
+		ip, err = requestIP(n, nil, id)
+		if err != nil {
+			return job.Error(err)
+		}
+	}
+
 	out := engine.Env{}
 	out.Set("IP", ip.String())
-	out.Set("Mask", bridgeNetwork.Mask.String())
-	out.Set("Gateway", bridgeNetwork.IP.String())
-	out.Set("Bridge", bridgeIface)
+	out.Set("Mask", network.Mask.String())
+	out.Set("Gateway", n.Gateway)
+	out.Set("Bridge", n.Bridge)
 
-	size, _ := bridgeNetwork.Mask.Size()
+	size, _ := network.Mask.Size()
 	out.SetInt("IPPrefixLen", size)
 
-	currentInterfaces.Set(id, &networkInterface{
+	mac := generateMacAddr(*ip)
+	out.Set("MacAddress", mac.String())
+	out.SetBool("HairpinMode", hairpinMode && n.Parent == "")
+	if n.Driver == overlayDriver {
+		out.SetInt("Mtu", overlayMtu)
+	}
+
+	containerInterface := &networkInterface{
 		IP: *ip,
-	})
+	}
+	if enableIPv6 && n.Name == DefaultNetworkName {
+		containerInterface.LinkLocalIPv6 = linkLocalIPv6(mac)
+		out.Set("LinkLocalIPv6", containerInterface.LinkLocalIPv6.String())
+		out.SetInt("LinkLocalIPv6PrefixLen", linkLocalPrefixLen)
+
+		if globalIPv6Network != nil {
+			ipv6, err := ipallocator.RequestIP(globalIPv6Network, nil)
+			if err != nil {
+				releaseIP(n, ip)
+				return job.Error(err)
+			}
+			containerInterface.IPv6 = *ipv6
+			sizev6, _ := globalIPv6Network.Mask.Size()
+			out.Set("GlobalIPv6", ipv6.String())
+			out.SetInt("GlobalIPv6PrefixLen", sizev6)
+			out.Set("IPv6Gateway", globalIPv6Network.IP.String())
+		}
+	}
+
+	if policy != nil {
+		// the IPv6 addresses are restricted as well, so that the traffic of
+		// the container cannot get around its policy over IPv6
+		containerInterface.Policy = policy
+		for _, addr := range containerInterface.addresses() {
+			if err := policy.Enable(addr); err != nil {
+				releaseInterface(id, n.Name, containerInterface)
+				return job.Errorf("Cannot apply the network policy of %s: %s", id, err)
+			}
+		}
+	}
+
+	currentInterfaces.Set(id, n.Name, containerInterface)
 
 	out.WriteTo(job.Stdout)
 
 	return engine.StatusOK
 }
 
-// release an interface for a select ip
+// release an interface for a select ip, the interfaces of the container on
+// all its networks are released when no network is given
 func Release(job *engine.Job) engine.Status {
 	var (
-		id                 = job.Args[0]
-		containerInterface = currentInterfaces.Get(id)
+		id         = job.Args[0]
+		interfaces = currentInterfaces.List(id)
 	)
 
-	if containerInterface == nil {
+	if name := job.Getenv("Network"); name != "" {
+		n, err := getNetwork(name)
+		if err != nil {
+			return job.Error(err)
+		}
+		interfaces = map[string]*networkInterface{n.Name: interfaces[n.Name]}
+	}
+
+	for name, containerInterface := range interfaces {
+		if containerInterface == nil {
+			return job.Errorf("No network information to release for %s", id)
+		}
+		releaseInterface(id, name, containerInterface)
+	}
+	if len(interfaces) == 0 {
 		return job.Errorf("No network information to release for %s", id)
 	}
+	return engine.StatusOK
+}
 
+// releaseInterface unmaps the ports of the interface of a container, lifts
+// its network policy and gives its ip back to the network
+func releaseInterface(id, name string, containerInterface *networkInterface) {
 	for _, nat := range containerInterface.PortMappings {
 		if err := portmapper.Unmap(nat); err != nil {
 			log.Infof("Unable to unmap port %s: %s", nat, err)
 		}
 	}
+	if policy := containerInterface.Policy; policy != nil {
+		for _, addr := range containerInterface.addresses() {
+			policy.Disable(addr)
+		}
+	}
 
-	if err := ipallocator.ReleaseIP(bridgeNetwork, &containerInterface.IP); err != nil {
-		log.Infof("Unable to release ip %s", err)
+	if n, err := getNetwork(name); err == nil {
+		if err := releaseIP(n, &containerInterface.IP); err != nil {
+			log.Infof("Unable to release ip %s", err)
+		}
+	}
+	if containerInterface.IPv6 != nil && globalIPv6Network != nil {
+		if err := ipallocator.ReleaseIP(globalIPv6Network, &containerInterface.IPv6); err != nil {
+			log.Infof("Unable to release ipv6 %s", err)
+		}
+	}
+	currentInterfaces.Remove(id, name)
+	// the chain of the policy goes with the last interface of the container
+	if containerInterface.Policy != nil && len(currentInterfaces.List(id)) == 0 {
+		containerInterface.Policy.Remove()
 	}
-	return engine.StatusOK
 }
 
 // Allocate an external port and map it to the interface
@@ -383,9 +593,21 @@ func AllocatePort(job *engine.Job) engine.Status {
 		hostIP        = job.Getenv("HostIP")
 		hostPort      = job.GetenvInt("HostPort")
 		containerPort = job.GetenvInt("ContainerPort")
+		count         = job.GetenvInt("PortCount")
 		proto         = job.Getenv("Proto")
-		network       = currentInterfaces.Get(id)
 	)
+	if count < 1 {
+		count = 1
+	}
+
+	n, err := getNetwork(job.Getenv("Network"))
+	if err != nil {
+		return job.Error(err)
+	}
+	network := currentInterfaces.Get(id, n.Name)
+	if network == nil {
+		return job.Errorf("No network information for %s on network %s", id, n.Name)
+	}
 
 	if hostIP != "" {
 		ip = net.ParseIP(hostIP)
@@ -411,7 +633,7 @@ func AllocatePort(job *engine.Job) engine.Status {
 
 	var host net.Addr
 	for i := 0; i < MaxAllocatedPortAttempts; i++ {
-		if host, err = portmapper.Map(container, ip, hostPort); err == nil {
+		if host, err = portmapper.MapRange(container, ip, hostPort, count, n.Bridge); err == nil {
 			break
 		}
 
@@ -0,0 +1,29 @@
+package bridge
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// hairpinMode is set when the published ports are reached through iptables
+// only, without a userland proxy: the ports of the bridges are in hairpin
+// mode so that a container reaches its own published ports, and the traffic
+// of the loopback interface is routed to the bridges.
+var hairpinMode bool
+
+// enableRouteLocalnet lets the packets sent to the loopback addresses be
+// routed to bridge once translated. It fails on the kernels older than 3.6,
+// which lack the setting.
+func enableRouteLocalnet(bridge string) error {
+	if err := ioutil.WriteFile(fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/route_localnet", bridge), []byte{'1', '\n'}, 0644); err != nil {
+		return fmt.Errorf("Unable to route the loopback traffic to %s: %s", bridge, err)
+	}
+	return nil
+}
+
+// hairpinRule is the rule giving the packets sent from the host to a
+// container through a published port an address of the host the container
+// can answer to, instead of the loopback one they may have
+func hairpinRule(bridge string) []string {
+	return []string{"POSTROUTING", "-t", "nat", "-m", "addrtype", "--src-type", "LOCAL", "-o", bridge, "-j", "MASQUERADE"}
+}
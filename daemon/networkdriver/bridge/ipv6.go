@@ -0,0 +1,167 @@
+package bridge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/pkg/iptables"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/libcontainer/netlink"
+)
+
+var (
+	enableIPv6 bool
+	// globalIPv6Network is the subnet of --fixed-cidr-v6, with the address of
+	// the bridge as IP
+	globalIPv6Network *net.IPNet
+
+	linkLocalPrefix     = net.ParseIP("fe80::")
+	bridgeLinkLocalAddr = &net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}
+)
+
+const linkLocalPrefixLen = 64
+
+// setupIPv6Bridge enables IPv6 on the bridge, gives it a link-local address
+// and, when fixedCIDRv6 is set, the first address of that subnet as gateway
+// for the containers
+func setupIPv6Bridge(fixedCIDRv6 string, ipForward, icc bool) error {
+	if err := ioutil.WriteFile(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/disable_ipv6", bridgeIface), []byte{'0', '\n'}, 0644); err != nil {
+		return fmt.Errorf("Unable to enable IPv6 on %s: %s", bridgeIface, err)
+	}
+
+	if err := addBridgeAddr(bridgeLinkLocalAddr); err != nil {
+		return err
+	}
+
+	if fixedCIDRv6 != "" {
+		_, subnet, err := net.ParseCIDR(fixedCIDRv6)
+		if err != nil {
+			return err
+		}
+		if subnet.IP.To4() != nil {
+			return fmt.Errorf("%s is not an IPv6 subnet", fixedCIDRv6)
+		}
+		if size, _ := subnet.Mask.Size(); size > 126 {
+			return fmt.Errorf("The IPv6 subnet %s is too small for containers", fixedCIDRv6)
+		}
+		firstIP, _ := networkdriver.NetworkRange(subnet)
+		gateway := make(net.IP, len(firstIP))
+		copy(gateway, firstIP)
+		gateway[len(gateway)-1]++
+
+		globalIPv6Network = &net.IPNet{IP: gateway, Mask: subnet.Mask}
+		if err := addBridgeAddr(globalIPv6Network); err != nil {
+			return err
+		}
+	}
+
+	if ipForward {
+		if err := ioutil.WriteFile("/proc/sys/net/ipv6/conf/all/forwarding", []byte{'1', '\n'}, 0644); err != nil {
+			log.Infof("WARNING: unable to enable IPv6 forwarding: %s", err)
+		}
+	}
+
+	if enableIPTables {
+		subnet := ""
+		if globalIPv6Network != nil {
+			subnet = globalIPv6Network.String()
+		}
+		// the rule of the other icc policy may be left from a previous run
+		iptables.Raw6(append([]string{"-D"}, iccRule(!icc)...)...)
+		for _, rule := range ipv6Rules(subnet, icc) {
+			if err := insertRule6(rule...); err != nil {
+				return fmt.Errorf("Unable to set up the ip6tables rules: %s", err)
+			}
+		}
+	}
+	return nil
+}
+
+// addBridgeAddr adds addr to the bridge unless it already has it
+func addBridgeAddr(addr *net.IPNet) error {
+	iface, err := net.InterfaceByName(bridgeIface)
+	if err != nil {
+		return err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		if a.String() == addr.String() {
+			return nil
+		}
+	}
+	if err := netlink.NetworkLinkAddIp(iface, addr.IP, addr); err != nil {
+		return fmt.Errorf("Unable to add %s to %s: %s", addr, bridgeIface, err)
+	}
+	return nil
+}
+
+// ipv6Rules mirror the iptables rules of the bridge for IPv6: the NAT of the
+// fixed subnet when there is one, the inter-container communication policy,
+// the outgoing packets and the packets of existing connections
+func ipv6Rules(subnet string, icc bool) [][]string {
+	var rules [][]string
+	if subnet != "" {
+		rules = append(rules, []string{"POSTROUTING", "-t", "nat", "-s", subnet, "!", "-o", bridgeIface, "-j", "MASQUERADE"})
+	}
+	return append(rules,
+		iccRule(icc),
+		[]string{"FORWARD", "-i", bridgeIface, "!", "-o", bridgeIface, "-j", "ACCEPT"},
+		[]string{"FORWARD", "-o", bridgeIface, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+	)
+}
+
+// iccRule is the rule accepting or dropping the traffic between the
+// containers of the bridge
+func iccRule(icc bool) []string {
+	action := "ACCEPT"
+	if !icc {
+		action = "DROP"
+	}
+	return []string{"FORWARD", "-i", bridgeIface, "-o", bridgeIface, "-j", action}
+}
+
+// insertRule6 is insertRule for the ip6tables rules
+func insertRule6(args ...string) error {
+	if iptables.Exists6(args...) {
+		return nil
+	}
+	if output, err := iptables.Raw6(append([]string{"-I"}, args...)...); err != nil {
+		return err
+	} else if len(output) != 0 {
+		return fmt.Errorf("Error ip6tables %s: %s", args[0], output)
+	}
+	return nil
+}
+
+// generateMacAddr returns the MAC address of the interface of a container,
+// built from its IPv4 address so that it is unique on the bridge
+func generateMacAddr(ip net.IP) net.HardwareAddr {
+	hw := make(net.HardwareAddr, 6)
+	// the first byte has the locally administered bit set and the multicast
+	// bit cleared
+	hw[0] = 0x02
+	hw[1] = 0x42
+	copy(hw[2:], ip.To4())
+	return hw
+}
+
+// linkLocalIPv6 returns the link-local address the kernel derives from mac
+// with the modified EUI-64 format
+func linkLocalIPv6(mac net.HardwareAddr) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, linkLocalPrefix)
+	ip[8] = mac[0] ^ 0x02
+	ip[9] = mac[1]
+	ip[10] = mac[2]
+	ip[11] = 0xff
+	ip[12] = 0xfe
+	ip[13] = mac[3]
+	ip[14] = mac[4]
+	ip[15] = mac[5]
+	return ip
+}
@@ -0,0 +1,40 @@
+package bridge
+
+import (
+	"net"
+	"testing"
+)
+
+func TestGenerateMacAddr(t *testing.T) {
+	mac := generateMacAddr(net.ParseIP("172.17.0.2"))
+	if mac.String() != "02:42:ac:11:00:02" {
+		t.Fatalf("Expected the MAC address to embed the ip, got %s", mac)
+	}
+}
+
+func TestLinkLocalIPv6(t *testing.T) {
+	mac, _ := net.ParseMAC("02:42:ac:11:00:02")
+	if ip := linkLocalIPv6(mac); ip.String() != "fe80::42:acff:fe11:2" {
+		t.Fatalf("Expected the EUI-64 address of the MAC address, got %s", ip)
+	}
+}
+
+func TestIPv6Rules(t *testing.T) {
+	bridgeIface = DefaultNetworkBridge
+
+	rules := ipv6Rules("", false)
+	if len(rules) != 3 {
+		t.Fatalf("Expected no NAT rule without a fixed subnet, got %v", rules)
+	}
+	if rules[0][len(rules[0])-1] != "DROP" {
+		t.Fatalf("Expected the inter-container traffic to be dropped, got %v", rules[0])
+	}
+
+	rules = ipv6Rules("2001:db8::1/64", true)
+	if len(rules) != 4 || rules[0][len(rules[0])-1] != "MASQUERADE" || rules[0][4] != "2001:db8::1/64" {
+		t.Fatalf("Expected a NAT rule for the fixed subnet, got %v", rules)
+	}
+	if rules[1][len(rules[1])-1] != "ACCEPT" {
+		t.Fatalf("Expected the inter-container traffic to be accepted, got %v", rules[1])
+	}
+}
@@ -0,0 +1,89 @@
+package bridge
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+)
+
+// macvlanPrefix starts the names of the networks of the interfaces of the
+// host, on which the containers get macvlan interfaces rather than veth
+// pairs, as with the network mode macvlan:<parent>
+const macvlanPrefix = "macvlan:"
+
+// macvlanRanges holds the parts of the subnets of the interfaces of the host
+// given out to the containers of their macvlan networks, by interface
+var macvlanRanges map[string]*net.IPNet
+
+// parseMacvlanRanges parses the ranges given as interface=cidr
+func parseMacvlanRanges(specs []string) (map[string]*net.IPNet, error) {
+	ranges := make(map[string]*net.IPNet, len(specs))
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid macvlan range %s, expected interface=cidr", spec)
+		}
+		_, ipRange, err := net.ParseCIDR(parts[1])
+		if err != nil || ipRange.IP.To4() == nil {
+			return nil, fmt.Errorf("Invalid macvlan range %s, expected an IPv4 subnet", spec)
+		}
+		ranges[parts[0]] = ipRange
+	}
+	return ranges, nil
+}
+
+// macvlanNetwork returns the network of the interface of the host named
+// after name. The containers reach the other hosts of the subnet of the
+// interface without NAT. As the subnet is shared with the other machines of
+// the network, they get the address they request, or one of the range of
+// the interface when the daemon has one.
+func macvlanNetwork(name string) (*network, error) {
+	parent := strings.TrimPrefix(name, macvlanPrefix)
+	addr, err := networkdriver.GetIfaceAddr(parent)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot use the interface %s for macvlan: %s", parent, err)
+	}
+	ipNet := addr.(*net.IPNet)
+	gateway, err := networkdriver.GetIfaceGateway(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &network{
+		Name:   name,
+		Parent: parent,
+		Subnet: (&net.IPNet{IP: ipNet.IP.Mask(ipNet.Mask), Mask: ipNet.Mask}).String(),
+		addr:   ipNet,
+	}
+	if ipRange := macvlanRanges[parent]; ipRange != nil {
+		// the range is registered before any address of the subnet is
+		// requested, the error of the later calls does not matter
+		if err := ipallocator.RegisterSubnet(ipNet, ipRange); err != nil && err != ipallocator.ErrNetworkAlreadyRegistered {
+			return nil, fmt.Errorf("Cannot use the range %s on the interface %s: %s", ipRange, parent, err)
+		}
+		n.ipRange = ipRange
+	}
+	if gateway != nil {
+		n.Gateway = gateway.String()
+		// the gateway stays allocated once requested, the error of the
+		// later requests does not matter
+		ipallocator.RequestIP(ipNet, &gateway)
+	}
+	return n, nil
+}
+
+// requestMacvlanIP allocates the address requested on the macvlan network
+// n, which has to be in the range of the network when it has one, or the
+// next free address of that range
+func requestMacvlanIP(n *network, requested *net.IP) (*net.IP, error) {
+	if n.ipRange == nil && requested == nil {
+		return nil, fmt.Errorf("An address has to be given with --ip on the macvlan network %s, or a range of its subnet with the --macvlan-range option of the daemon", n.Name)
+	}
+	if n.ipRange != nil && requested != nil && !n.ipRange.Contains(*requested) {
+		return nil, fmt.Errorf("The address %s is out of the range %s of the macvlan network %s", requested, n.ipRange, n.Name)
+	}
+	return ipallocator.RequestIP(n.ipNet(), requested)
+}
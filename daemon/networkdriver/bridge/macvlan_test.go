@@ -0,0 +1,63 @@
+package bridge
+
+import (
+	"net"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+)
+
+func TestParseMacvlanRanges(t *testing.T) {
+	ranges, err := parseMacvlanRanges([]string{"eth1=192.168.1.64/26"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := ranges["eth1"]; r == nil || r.String() != "192.168.1.64/26" {
+		t.Fatalf("Expected the range 192.168.1.64/26 on eth1, got %v", ranges)
+	}
+	for _, spec := range []string{"eth1", "=192.168.1.64/26", "eth1=192.168.1.64", "eth1=2001:db8::/64"} {
+		if _, err := parseMacvlanRanges([]string{spec}); err == nil {
+			t.Fatalf("Expected the range %s to be refused", spec)
+		}
+	}
+}
+
+func TestRequestMacvlanIP(t *testing.T) {
+	addr := &net.IPNet{IP: net.ParseIP("10.78.0.10").To4(), Mask: net.CIDRMask(24, 32)}
+	n := &network{Name: "macvlan:test0", Parent: "test0", addr: addr}
+
+	if _, err := requestMacvlanIP(n, nil); err == nil {
+		t.Fatal("Expected an address to be required without a range")
+	}
+	requested := net.ParseIP("10.78.0.200")
+	ip, err := requestMacvlanIP(n, &requested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ipallocator.ReleaseIP(addr, ip)
+
+	addr = &net.IPNet{IP: net.ParseIP("10.79.0.10").To4(), Mask: net.CIDRMask(24, 32)}
+	_, ipRange, _ := net.ParseCIDR("10.79.0.64/30")
+	if err := ipallocator.RegisterSubnet(addr, ipRange); err != nil {
+		t.Fatal(err)
+	}
+	n = &network{Name: "macvlan:test1", Parent: "test1", addr: addr, ipRange: ipRange}
+
+	// the first and last addresses of the range are left out
+	for _, expected := range []string{"10.79.0.65", "10.79.0.66"} {
+		ip, err := requestMacvlanIP(n, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ip.String() != expected {
+			t.Fatalf("Expected %s in the range, got %s", expected, ip)
+		}
+	}
+	if ip, err := requestMacvlanIP(n, nil); err == nil {
+		t.Fatalf("Expected the range to be exhausted, got %s", ip)
+	}
+	requested = net.ParseIP("10.79.0.200")
+	if _, err := requestMacvlanIP(n, &requested); err == nil {
+		t.Fatal("Expected an address out of the range to be refused")
+	}
+}
@@ -0,0 +1,581 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/iptables"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/networkfs/resolvconf"
+	"github.com/docker/docker/utils"
+	"github.com/docker/libcontainer/netlink"
+)
+
+var (
+	validNetworkName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+	// the network modes which cannot be used as the name of a network
+	reservedNetworkNames = []string{DefaultNetworkName, "host", "none", "container", "macvlan"}
+
+	userNetworks = &networkStore{networks: make(map[string]*network)}
+)
+
+// network is a user-defined network, each one having its own bridge and
+// subnet, isolated from the other networks
+type network struct {
+	Name    string
+	ID      string
+	Bridge  string
+	Subnet  string
+	Gateway string
+	Parent  string `json:",omitempty"` // interface of the host of a macvlan network, which has no bridge
+	Driver  string `json:",omitempty"` // overlay for the networks spanning the daemons of a cluster
+	VNI     uint32 `json:",omitempty"` // vxlan id of an overlay network
+
+	addr    *net.IPNet // address of the gateway on the bridge, or of the host on the parent of a macvlan network
+	ipRange *net.IPNet // part of the subnet of a macvlan network given out to the containers, nil when they request their address
+}
+
+// ipNet returns the address of the gateway of the network along with the
+// mask of its subnet, as used by the ip allocator. On overlay networks, where
+// each host has a gateway of its own, it is the subnet itself.
+func (n *network) ipNet() *net.IPNet {
+	if n.Driver == overlayDriver {
+		_, subnet, err := net.ParseCIDR(n.Subnet)
+		if err != nil {
+			return nil
+		}
+		return subnet
+	}
+	return n.addr
+}
+
+func (n *network) parse() error {
+	_, subnet, err := net.ParseCIDR(n.Subnet)
+	if err != nil {
+		return err
+	}
+	gateway := net.ParseIP(n.Gateway)
+	if gateway == nil {
+		return fmt.Errorf("Invalid gateway %s", n.Gateway)
+	}
+	n.addr = &net.IPNet{IP: gateway.To4(), Mask: subnet.Mask}
+	return nil
+}
+
+// getNetwork returns the network with the given name or id, the default
+// network being used when name is empty
+func getNetwork(name string) (*network, error) {
+	if name == "" || name == DefaultNetworkName {
+		if bridgeNetwork == nil {
+			return nil, fmt.Errorf("The default network is not initialized")
+		}
+		return &network{
+			Name:    DefaultNetworkName,
+			Bridge:  bridgeIface,
+			Subnet:  (&net.IPNet{IP: bridgeNetwork.IP.Mask(bridgeNetwork.Mask), Mask: bridgeNetwork.Mask}).String(),
+			Gateway: bridgeNetwork.IP.String(),
+			addr:    bridgeNetwork,
+		}, nil
+	}
+	if strings.HasPrefix(name, macvlanPrefix) {
+		return macvlanNetwork(name)
+	}
+	n := userNetworks.get(name)
+	if n == nil && clusterStore != nil {
+		// the network may have just been created by another daemon
+		syncOverlays()
+		n = userNetworks.get(name)
+	}
+	if n == nil {
+		return nil, fmt.Errorf("No such network: %s", name)
+	}
+	return n, nil
+}
+
+// networkStore keeps the user-defined networks, saving them to disk so that
+// they are brought back when the daemon restarts
+type networkStore struct {
+	sync.Mutex
+	path     string
+	networks map[string]*network
+}
+
+func (s *networkStore) load(path string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.path = path
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var networks []*network
+	if err := json.Unmarshal(data, &networks); err != nil {
+		return fmt.Errorf("Error loading the networks from %s: %s", path, err)
+	}
+	for _, n := range networks {
+		if err := n.parse(); err != nil {
+			log.Errorf("Ignoring network %s: %s", n.Name, err)
+			continue
+		}
+		if n.Driver == overlayDriver {
+			if err := rejoinOverlay(n); err != nil {
+				log.Errorf("Ignoring network %s: %s", n.Name, err)
+				continue
+			}
+		}
+		if err := setupNetwork(n, s.bridges()); err != nil {
+			log.Errorf("Unable to restore network %s: %s", n.Name, err)
+			continue
+		}
+		s.networks[n.Name] = n
+	}
+	return nil
+}
+
+// save writes the networks to disk, the caller must hold the lock
+func (s *networkStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	networks := make([]*network, 0, len(s.networks))
+	for _, n := range s.networks {
+		networks = append(networks, n)
+	}
+	data, err := json.Marshal(networks)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, data, 0600)
+}
+
+// get returns the network with the given name, full id or unique id prefix
+func (s *networkStore) get(name string) *network {
+	s.Lock()
+	defer s.Unlock()
+	return s.lookup(name)
+}
+
+// lookup is get for callers holding the lock
+func (s *networkStore) lookup(name string) *network {
+	if n, exists := s.networks[name]; exists {
+		return n
+	}
+	var found *network
+	for _, n := range s.networks {
+		if strings.HasPrefix(n.ID, name) {
+			if found != nil {
+				return nil
+			}
+			found = n
+		}
+	}
+	return found
+}
+
+// list returns the networks sorted by name
+func (s *networkStore) list() []*network {
+	s.Lock()
+	defer s.Unlock()
+
+	names := make([]string, 0, len(s.networks))
+	for name := range s.networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	networks := make([]*network, len(names))
+	for i, name := range names {
+		networks[i] = s.networks[name]
+	}
+	return networks
+}
+
+// bridges returns the bridges of the default and user-defined networks, the
+// caller must hold the lock
+func (s *networkStore) bridges() []string {
+	bridges := []string{bridgeIface}
+	for _, n := range s.networks {
+		bridges = append(bridges, n.Bridge)
+	}
+	return bridges
+}
+
+// subnets returns the subnets of the default and user-defined networks, the
+// caller must hold the lock
+func (s *networkStore) subnets() []*net.IPNet {
+	subnets := []*net.IPNet{bridgeNetwork}
+	for _, n := range s.networks {
+		subnets = append(subnets, n.addr)
+	}
+	return subnets
+}
+
+// CreateNetwork creates a network with its own bridge, picking a free subnet
+// when none is given. The overlay networks are created in the cluster store,
+// and joined by the other daemons sharing it.
+func CreateNetwork(job *engine.Job) engine.Status {
+	var (
+		name    = job.Getenv("Name")
+		subnet  = job.Getenv("Subnet")
+		gateway = job.Getenv("Gateway")
+		driver  = job.Getenv("Driver")
+	)
+	if driver != "" && driver != "bridge" && driver != overlayDriver {
+		return job.Errorf("Invalid network driver: %s", driver)
+	}
+	if driver == overlayDriver && gateway != "" {
+		return job.Errorf("The gateway of an overlay network cannot be set, each host has its own")
+	}
+	if !validNetworkName.MatchString(name) {
+		return job.Errorf("Invalid network name (%s), only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	for _, reserved := range reservedNetworkNames {
+		if name == reserved {
+			return job.Errorf("%s is a pre-defined network mode and cannot be used as a network name", name)
+		}
+	}
+
+	userNetworks.Lock()
+	defer userNetworks.Unlock()
+
+	if _, exists := userNetworks.networks[name]; exists {
+		return job.Errorf("Conflict, a network named %s already exists", name)
+	}
+
+	if driver == overlayDriver {
+		n, err := createOverlay(name, subnet)
+		if err != nil {
+			return job.Error(err)
+		}
+		if err := userNetworks.save(); err != nil {
+			return job.Error(err)
+		}
+		if _, err := networkToEnv(n).WriteTo(job.Stdout); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	}
+
+	addr, err := networkAddr(subnet, gateway, userNetworks.subnets())
+	if err != nil {
+		return job.Error(err)
+	}
+	id := utils.GenerateRandomID()
+	n := &network{
+		Name:    name,
+		ID:      id,
+		Bridge:  "br-" + id[:12],
+		Subnet:  (&net.IPNet{IP: addr.IP.Mask(addr.Mask), Mask: addr.Mask}).String(),
+		Gateway: addr.IP.String(),
+		addr:    addr,
+	}
+	if err := setupNetwork(n, userNetworks.bridges()); err != nil {
+		teardownNetwork(n, userNetworks.bridges())
+		return job.Error(err)
+	}
+	userNetworks.networks[name] = n
+	if err := userNetworks.save(); err != nil {
+		return job.Error(err)
+	}
+
+	if _, err := networkToEnv(n).WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// RemoveNetwork removes a network and its bridge, refusing to do so while
+// containers are attached to it
+func RemoveNetwork(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NETWORK", job.Name)
+	}
+	name := job.Args[0]
+	for _, reserved := range reservedNetworkNames {
+		if name == reserved {
+			return job.Errorf("%s is a pre-defined network and cannot be removed", name)
+		}
+	}
+
+	userNetworks.Lock()
+	defer userNetworks.Unlock()
+
+	n := userNetworks.lookup(name)
+	if n == nil {
+		return job.Errorf("No such network: %s", name)
+	}
+	if len(currentInterfaces.Endpoints(n.Name)) > 0 {
+		return job.Errorf("Conflict, network %s has active endpoints", n.Name)
+	}
+	if n.Driver == overlayDriver {
+		if err := removeOverlay(n); err != nil {
+			return job.Error(err)
+		}
+		if err := userNetworks.save(); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	}
+	delete(userNetworks.networks, n.Name)
+	if err := userNetworks.save(); err != nil {
+		return job.Error(err)
+	}
+	teardownNetwork(n, userNetworks.bridges())
+	return engine.StatusOK
+}
+
+// ListNetworks lists the default network followed by the user-defined ones
+func ListNetworks(job *engine.Job) engine.Status {
+	syncOverlays()
+	outs := engine.NewTable("", 0)
+	if n, err := getNetwork(DefaultNetworkName); err == nil {
+		outs.Add(networkToEnv(n))
+	}
+	for _, n := range userNetworks.list() {
+		outs.Add(networkToEnv(n))
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+func InspectNetwork(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NETWORK", job.Name)
+	}
+	n, err := getNetwork(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+	if _, err := networkToEnv(n).WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+func networkToEnv(n *network) *engine.Env {
+	containers := make(map[string]string)
+	for id, iface := range currentInterfaces.Endpoints(n.Name) {
+		size, _ := n.addr.Mask.Size()
+		containers[id] = fmt.Sprintf("%s/%d", iface.IP, size)
+	}
+
+	out := &engine.Env{}
+	out.Set("Name", n.Name)
+	out.Set("Id", n.ID)
+	if n.Parent != "" {
+		out.Set("Driver", "macvlan")
+		out.Set("Parent", n.Parent)
+	} else if n.Driver == overlayDriver {
+		out.Set("Driver", overlayDriver)
+		out.Set("Bridge", n.Bridge)
+		out.SetInt("VNI", int(n.VNI))
+	} else {
+		out.Set("Driver", "bridge")
+		out.Set("Bridge", n.Bridge)
+	}
+	out.Set("Subnet", n.Subnet)
+	out.Set("Gateway", n.Gateway)
+	out.SetJson("Containers", containers)
+	return out
+}
+
+// networkAddr returns the address of the gateway of a new network, along
+// with the mask of its subnet. A free range is picked when no subnet is given.
+func networkAddr(subnet, gateway string, inUse []*net.IPNet) (*net.IPNet, error) {
+	if subnet == "" {
+		if gateway != "" {
+			return nil, fmt.Errorf("A subnet is required to set the gateway of a network")
+		}
+		return findFreeSubnet(inUse)
+	}
+
+	_, network, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, err
+	}
+	if network.IP.To4() == nil {
+		return nil, fmt.Errorf("Only IPv4 subnets are supported: %s", subnet)
+	}
+	for _, other := range inUse {
+		if networkdriver.NetworkOverlaps(network, other) {
+			return nil, fmt.Errorf("Subnet %s overlaps with the network %s", subnet, other)
+		}
+	}
+
+	var ip net.IP
+	if gateway != "" {
+		if ip = net.ParseIP(gateway); ip == nil || !network.Contains(ip) {
+			return nil, fmt.Errorf("Invalid gateway %s for the subnet %s", gateway, subnet)
+		}
+	} else {
+		// the first address of the subnet is the gateway
+		first, _ := networkdriver.NetworkRange(network)
+		ip = net.IPv4(first[0], first[1], first[2], first[3]+1)
+	}
+	return &net.IPNet{IP: ip.To4(), Mask: network.Mask}, nil
+}
+
+// findFreeSubnet picks a range which conflicts neither with the networks
+// already in use nor with the routes and nameservers of the host
+func findFreeSubnet(inUse []*net.IPNet) (*net.IPNet, error) {
+	nameservers := []string{}
+	if resolvConf, _ := resolvconf.Get(); resolvConf != nil {
+		nameservers = append(nameservers, resolvconf.GetNameserversAsCIDR(resolvConf)...)
+	}
+
+	var candidates []string
+	for i := 18; i < 32; i++ {
+		candidates = append(candidates, fmt.Sprintf("172.%d.0.1/16", i))
+	}
+	for i := 0; i < 256; i += 16 {
+		candidates = append(candidates, fmt.Sprintf("192.168.%d.1/20", i))
+	}
+
+next:
+	for _, candidate := range candidates {
+		ip, network, err := net.ParseCIDR(candidate)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range inUse {
+			if networkdriver.NetworkOverlaps(network, other) {
+				continue next
+			}
+		}
+		if err := networkdriver.CheckNameserverOverlaps(nameservers, network); err != nil {
+			continue
+		}
+		if err := networkdriver.CheckRouteOverlaps(network); err != nil {
+			log.Debugf("%s %s", candidate, err)
+			continue
+		}
+		return &net.IPNet{IP: ip.To4(), Mask: network.Mask}, nil
+	}
+	return nil, fmt.Errorf("Could not find a free IP address range for the network")
+}
+
+// setupNetwork creates the bridge of a network when it does not exist and
+// isolates it from the other bridges
+func setupNetwork(n *network, others []string) error {
+	if _, err := net.InterfaceByName(n.Bridge); err != nil {
+		log.Debugf("Creating bridge %s with network %s", n.Bridge, n.addr)
+		if err := createBridgeIface(n.Bridge); err != nil {
+			return err
+		}
+		iface, err := net.InterfaceByName(n.Bridge)
+		if err != nil {
+			return err
+		}
+		if err := netlink.NetworkLinkAddIp(iface, n.addr.IP, n.addr); err != nil {
+			return fmt.Errorf("Unable to add private network: %s", err)
+		}
+		if err := netlink.NetworkLinkUp(iface); err != nil {
+			return fmt.Errorf("Unable to start network bridge: %s", err)
+		}
+	}
+	if n.Driver == overlayDriver {
+		if err := setupVxlan(n); err != nil {
+			return err
+		}
+	}
+
+	if !enableIPTables {
+		return nil
+	}
+	if hairpinMode {
+		if err := enableRouteLocalnet(n.Bridge); err != nil {
+			return err
+		}
+	}
+	for _, rule := range networkRules(n) {
+		if err := insertRule(rule...); err != nil {
+			return err
+		}
+	}
+	for _, rule := range isolationRules(n, others) {
+		if err := insertRule(rule...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// teardownNetwork removes the iptables rules and the bridge of a network
+func teardownNetwork(n *network, others []string) {
+	if enableIPTables {
+		for _, rule := range append(networkRules(n), isolationRules(n, others)...) {
+			iptables.Raw(append([]string{"-D"}, rule...)...)
+		}
+	}
+	if n.Driver == overlayDriver {
+		if err := netlink.NetworkLinkDel(n.vxlanName()); err != nil {
+			log.Infof("Unable to remove the vxlan interface %s: %s", n.vxlanName(), err)
+		}
+	}
+	if err := netlink.DeleteBridge(n.Bridge); err != nil {
+		log.Infof("Unable to remove bridge %s: %s", n.Bridge, err)
+	}
+}
+
+// networkRules are the rules letting the containers of a network talk to
+// each other and reach the outside world
+func networkRules(n *network) [][]string {
+	rules := [][]string{
+		{"POSTROUTING", "-t", "nat", "-s", n.Subnet, "!", "-o", n.Bridge, "-j", "MASQUERADE"},
+		{"FORWARD", "-i", n.Bridge, "-o", n.Bridge, "-j", "ACCEPT"},
+		{"FORWARD", "-i", n.Bridge, "!", "-o", n.Bridge, "-j", "ACCEPT"},
+		{"FORWARD", "-o", n.Bridge, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+	}
+	if hairpinMode {
+		rules = append(rules, hairpinRule(n.Bridge))
+	}
+	return rules
+}
+
+// isolationRules are the rules dropping the traffic between the bridge of a
+// network and the other bridges
+func isolationRules(n *network, others []string) [][]string {
+	var rules [][]string
+	for _, other := range others {
+		if other == n.Bridge {
+			continue
+		}
+		rules = append(rules,
+			[]string{"FORWARD", "-i", n.Bridge, "-o", other, "-j", "DROP"},
+			[]string{"FORWARD", "-i", other, "-o", n.Bridge, "-j", "DROP"},
+		)
+	}
+	return rules
+}
+
+// insertRule inserts an iptables rule at the top of its chain unless it
+// already exists
+func insertRule(args ...string) error {
+	if iptables.Exists(args...) {
+		return nil
+	}
+	if output, err := iptables.Raw(append([]string{"-I"}, args...)...); err != nil {
+		return err
+	} else if len(output) != 0 {
+		return fmt.Errorf("Error iptables %s: %s", args[0], output)
+	}
+	return nil
+}
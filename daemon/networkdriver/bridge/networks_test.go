@@ -0,0 +1,90 @@
+package bridge
+
+import (
+	"net"
+	"testing"
+)
+
+func TestNetworkAddr(t *testing.T) {
+	_, inUse, _ := net.ParseCIDR("172.17.42.1/16")
+
+	addr, err := networkAddr("10.5.0.0/24", "", []*net.IPNet{inUse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.String() != "10.5.0.1/24" {
+		t.Fatalf("Expected the first address of the subnet as gateway, got %s", addr)
+	}
+
+	addr, err = networkAddr("10.5.0.0/24", "10.5.0.254", []*net.IPNet{inUse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.String() != "10.5.0.254/24" {
+		t.Fatalf("Expected the given gateway, got %s", addr)
+	}
+
+	if _, err := networkAddr("10.5.0.0/24", "10.6.0.1", []*net.IPNet{inUse}); err == nil {
+		t.Fatal("A gateway outside of the subnet should be refused")
+	}
+	if _, err := networkAddr("172.17.0.0/24", "", []*net.IPNet{inUse}); err == nil {
+		t.Fatal("A subnet overlapping with a network in use should be refused")
+	}
+	if _, err := networkAddr("", "10.5.0.1", []*net.IPNet{inUse}); err == nil {
+		t.Fatal("A gateway without a subnet should be refused")
+	}
+}
+
+func TestIsolationRules(t *testing.T) {
+	n := &network{Name: "test", Bridge: "br-test"}
+	rules := isolationRules(n, []string{"docker0", "br-test", "br-other"})
+	if len(rules) != 4 {
+		t.Fatalf("Expected 2 rules for each of the other bridges, got %v", rules)
+	}
+	for _, rule := range rules {
+		if rule[len(rule)-1] != "DROP" {
+			t.Fatalf("Expected a DROP rule, got %v", rule)
+		}
+		if rule[2] != "br-test" && rule[4] != "br-test" {
+			t.Fatalf("Expected a rule on the bridge of the network, got %v", rule)
+		}
+	}
+}
+
+func TestNetworkRulesInHairpinMode(t *testing.T) {
+	defer func() { hairpinMode = false }()
+	n := &network{Name: "test", Bridge: "br-test", Subnet: "10.5.0.0/24"}
+
+	rules := networkRules(n)
+	hairpinMode = true
+	hairpinRules := networkRules(n)
+	if len(hairpinRules) != len(rules)+1 {
+		t.Fatalf("Expected one more rule in hairpin mode, got %v", hairpinRules)
+	}
+	if rule := hairpinRules[len(hairpinRules)-1]; rule[len(rule)-1] != "MASQUERADE" || rule[len(rule)-3] != "br-test" {
+		t.Fatalf("Expected the traffic of the host to the bridge to be masqueraded, got %v", rule)
+	}
+}
+
+func TestInterfacesByNetwork(t *testing.T) {
+	interfaces := ifaces{c: make(map[string]map[string]*networkInterface)}
+	interfaces.Set("c1", "bridge", &networkInterface{IP: net.ParseIP("172.17.0.2")})
+	interfaces.Set("c1", "test", &networkInterface{IP: net.ParseIP("10.5.0.2"), Device: "eth1"})
+	interfaces.Set("c2", "test", &networkInterface{IP: net.ParseIP("10.5.0.3")})
+
+	if endpoints := interfaces.Endpoints("test"); len(endpoints) != 2 {
+		t.Fatalf("Expected 2 containers on the network, got %d", len(endpoints))
+	}
+	if list := interfaces.List("c1"); len(list) != 2 {
+		t.Fatalf("Expected c1 to be on 2 networks, got %d", len(list))
+	}
+
+	interfaces.Remove("c1", "test")
+	if interfaces.Get("c1", "test") != nil {
+		t.Fatal("The interface of c1 on the network should be removed")
+	}
+	interfaces.Remove("c1", "bridge")
+	if _, exists := interfaces.c["c1"]; exists {
+		t.Fatal("The container should be forgotten once it has no interface left")
+	}
+}
@@ -0,0 +1,582 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/pkg/kvstore"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/netdev"
+	"github.com/docker/docker/utils"
+	"github.com/docker/libcontainer/netlink"
+)
+
+const (
+	overlayDriver = "overlay"
+
+	vxlanPort     = 4789
+	vxlanOverhead = 50
+	firstVNI      = 256
+
+	overlaySyncInterval = 5 * time.Second
+
+	// clusterPrefix starts the keys of the overlay networks in the store
+	clusterPrefix = "docker/network/v1.0/"
+)
+
+var (
+	// clusterStore is the store the daemons of a cluster share the overlay
+	// networks through, nil when no store is configured
+	clusterStore kvstore.Store
+	// clusterAdvertise is the address the other daemons reach the vxlan
+	// interfaces of this host at
+	clusterAdvertise net.IP
+	// overlayMtu is the mtu of the interfaces on the overlay networks, which
+	// leaves room for the headers of the tunnel
+	overlayMtu int
+
+	// fdbs are the forwarding entries set on the vxlan interfaces, by
+	// network id. They are guarded by the lock of userNetworks.
+	fdbs = make(map[string]map[fdbEntry]bool)
+)
+
+// overlayDef is the definition of an overlay network shared by the daemons
+// through the cluster store. Each daemon joining the network gets its own
+// bridge and address on the subnet, and a vxlan interface on its bridge
+// carrying the frames between the hosts.
+type overlayDef struct {
+	Name   string
+	ID     string
+	Subnet string
+	VNI    uint32
+}
+
+// overlayEndpoint is an address taken on an overlay network, by a container
+// or by the bridge of a host when Container is empty
+type overlayEndpoint struct {
+	Host      string
+	Mac       string
+	Container string `json:",omitempty"`
+}
+
+// fdbEntry is a forwarding entry of a vxlan interface, sending the frames
+// for mac to host. The entries without mac flood the broadcasts to host.
+type fdbEntry struct {
+	mac  string
+	host string
+}
+
+func definitionKey(name string) string {
+	return clusterPrefix + "overlay/" + name
+}
+
+func vniKey(vni uint32) string {
+	return clusterPrefix + "vni/" + strconv.FormatUint(uint64(vni), 10)
+}
+
+func endpointsPrefix(n *network) string {
+	return clusterPrefix + "endpoint/" + n.ID + "/"
+}
+
+func endpointKey(n *network, ip net.IP) string {
+	return endpointsPrefix(n) + ip.String()
+}
+
+func peersPrefix(n *network) string {
+	return clusterPrefix + "peer/" + n.ID + "/"
+}
+
+func peerKey(n *network) string {
+	return peersPrefix(n) + clusterAdvertise.String()
+}
+
+// vxlanName returns the name of the vxlan interface of an overlay network
+func (n *network) vxlanName() string {
+	return "vx-" + n.ID[:12]
+}
+
+func (def *overlayDef) network() *network {
+	return &network{
+		Name:   def.Name,
+		ID:     def.ID,
+		Driver: overlayDriver,
+		VNI:    def.VNI,
+		Bridge: "br-" + def.ID[:12],
+		Subnet: def.Subnet,
+	}
+}
+
+// initClusterStore connects to the store at url, advertising the address
+// advertise to the other daemons, or the one of the interface of the default
+// route when it is empty
+func initClusterStore(url, advertise string) error {
+	clusterStore = nil
+	if url == "" {
+		return nil
+	}
+	store, err := kvstore.New(url)
+	if err != nil {
+		return err
+	}
+
+	overlayMtu = 1500 - vxlanOverhead
+	iface, err := networkdriver.GetDefaultRouteIface()
+	if err == nil {
+		overlayMtu = iface.MTU - vxlanOverhead
+	}
+	if advertise != "" {
+		if clusterAdvertise = net.ParseIP(advertise); clusterAdvertise == nil || clusterAdvertise.To4() == nil {
+			return fmt.Errorf("Invalid address to advertise to the cluster: %s", advertise)
+		}
+	} else {
+		if err != nil {
+			return fmt.Errorf("An address to advertise to the cluster is required without a default route: %s", err)
+		}
+		addr, err := networkdriver.GetIfaceAddr(iface.Name)
+		if err != nil {
+			return err
+		}
+		clusterAdvertise = addr.(*net.IPNet).IP
+	}
+	clusterStore = store
+	return nil
+}
+
+// syncOverlaysLoop keeps the overlay networks up to date with the store
+func syncOverlaysLoop() {
+	for _ = range time.Tick(overlaySyncInterval) {
+		syncOverlays()
+	}
+}
+
+// overlayDefinitions returns the overlay networks of the cluster by name
+func overlayDefinitions() (map[string]*overlayDef, error) {
+	values, err := clusterStore.List(clusterPrefix + "overlay/")
+	if err != nil {
+		return nil, err
+	}
+	defs := make(map[string]*overlayDef)
+	for key, value := range values {
+		def := &overlayDef{}
+		if err := json.Unmarshal(value, def); err != nil {
+			log.Errorf("Ignoring the overlay network at %s: %s", key, err)
+			continue
+		}
+		defs[def.Name] = def
+	}
+	return defs, nil
+}
+
+// syncOverlays joins the overlay networks created by the other daemons,
+// leaves the ones they removed and updates the forwarding entries of the
+// others
+func syncOverlays() {
+	if clusterStore == nil {
+		return
+	}
+	userNetworks.Lock()
+	defer userNetworks.Unlock()
+
+	defs, err := overlayDefinitions()
+	if err != nil {
+		log.Errorf("Unable to list the overlay networks: %s", err)
+		return
+	}
+
+	changed := false
+	for _, def := range defs {
+		if n, exists := userNetworks.networks[def.Name]; exists {
+			if n.ID != def.ID {
+				log.Debugf("The overlay network %s conflicts with the local network of the same name", def.Name)
+			}
+			continue
+		}
+		if err := joinOverlay(def.network(), nil); err != nil {
+			log.Errorf("Unable to join the overlay network %s: %s", def.Name, err)
+			continue
+		}
+		changed = true
+	}
+
+	for name, n := range userNetworks.networks {
+		if n.Driver != overlayDriver {
+			continue
+		}
+		if def, exists := defs[name]; exists && def.ID == n.ID {
+			syncFdb(n)
+			continue
+		}
+		// a network removed while containers are attached is left with
+		// the last of them
+		if len(currentInterfaces.Endpoints(name)) > 0 {
+			continue
+		}
+		leaveOverlay(n)
+		changed = true
+	}
+
+	if changed {
+		if err := userNetworks.save(); err != nil {
+			log.Errorf("Unable to save the networks: %s", err)
+		}
+	}
+}
+
+// createOverlay creates an overlay network in the cluster store and joins
+// it, the caller must hold the lock of userNetworks
+func createOverlay(name, subnet string) (*network, error) {
+	if clusterStore == nil {
+		return nil, fmt.Errorf("Overlay networks require a cluster store (--cluster-store)")
+	}
+	defs, err := overlayDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := defs[name]; exists {
+		return nil, fmt.Errorf("Conflict, a network named %s already exists", name)
+	}
+	inUse := userNetworks.subnets()
+	for _, def := range defs {
+		if _, other, err := net.ParseCIDR(def.Subnet); err == nil {
+			inUse = append(inUse, other)
+		}
+	}
+	addr, err := networkAddr(subnet, "", inUse)
+	if err != nil {
+		return nil, err
+	}
+
+	vni, err := claimVNI(defs)
+	if err != nil {
+		return nil, err
+	}
+	def := &overlayDef{
+		Name:   name,
+		ID:     utils.GenerateRandomID(),
+		Subnet: (&net.IPNet{IP: addr.IP.Mask(addr.Mask), Mask: addr.Mask}).String(),
+		VNI:    vni,
+	}
+	data, err := json.Marshal(def)
+	if err != nil {
+		return nil, err
+	}
+	if err := clusterStore.Create(definitionKey(name), data); err != nil {
+		clusterStore.Delete(vniKey(vni))
+		if err == kvstore.ErrKeyExists {
+			return nil, fmt.Errorf("Conflict, a network named %s already exists", name)
+		}
+		return nil, err
+	}
+
+	n := def.network()
+	// the daemon creating the network gets the address of the gateway
+	if err := joinOverlay(n, &addr.IP); err != nil {
+		clusterStore.Delete(definitionKey(name))
+		clusterStore.Delete(vniKey(vni))
+		return nil, err
+	}
+	return n, nil
+}
+
+// claimVNI claims the first vxlan id not used by an overlay network
+func claimVNI(defs map[string]*overlayDef) (uint32, error) {
+	used := make(map[uint32]bool)
+	for _, def := range defs {
+		used[def.VNI] = true
+	}
+	for vni := uint32(firstVNI); vni < 1<<24; vni++ {
+		if used[vni] {
+			continue
+		}
+		err := clusterStore.Create(vniKey(vni), nil)
+		if err == nil {
+			return vni, nil
+		}
+		if err != kvstore.ErrKeyExists {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("No vxlan id available for the network")
+}
+
+// removeOverlay removes an overlay network from the cluster store and
+// leaves it, the caller must hold the lock of userNetworks
+func removeOverlay(n *network) error {
+	endpoints, err := overlayEndpoints(n)
+	if err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.Container != "" {
+			return fmt.Errorf("Conflict, network %s has active endpoints on %s", n.Name, endpoint.Host)
+		}
+	}
+	if err := clusterStore.Delete(definitionKey(n.Name)); err != nil && err != kvstore.ErrKeyNotFound {
+		return err
+	}
+	clusterStore.Delete(vniKey(n.VNI))
+	leaveOverlay(n)
+	return nil
+}
+
+// joinOverlay sets up the bridge and vxlan interface of an overlay network
+// on this host, and announces them to the other daemons. The bridge gets the
+// address gateway, or a free one when nil. The caller must hold the lock of
+// userNetworks.
+func joinOverlay(n *network, gateway *net.IP) error {
+	subnet := n.ipNet()
+	if subnet == nil {
+		return fmt.Errorf("Invalid subnet %s", n.Subnet)
+	}
+	ip, err := claimOverlayIP(n, gateway, "")
+	if err != nil {
+		return fmt.Errorf("Unable to get an address for the bridge: %s", err)
+	}
+	n.Gateway = ip.String()
+	n.addr = &net.IPNet{IP: ip.To4(), Mask: subnet.Mask}
+
+	if err := setupNetwork(n, userNetworks.bridges()); err != nil {
+		teardownNetwork(n, userNetworks.bridges())
+		releaseOverlayIP(n, ip)
+		return err
+	}
+	if err := clusterStore.Put(peerKey(n), nil); err != nil {
+		teardownNetwork(n, userNetworks.bridges())
+		releaseOverlayIP(n, ip)
+		return err
+	}
+	userNetworks.networks[n.Name] = n
+	syncFdb(n)
+	return nil
+}
+
+// rejoinOverlay takes back the address of the bridge of an overlay network
+// joined before the daemon restarted, and announces the host again
+func rejoinOverlay(n *network) error {
+	if clusterStore == nil {
+		return fmt.Errorf("Overlay networks require a cluster store (--cluster-store)")
+	}
+	if _, err := ipallocator.RequestIP(n.ipNet(), &n.addr.IP); err != nil {
+		return err
+	}
+	data, err := json.Marshal(&overlayEndpoint{
+		Host: clusterAdvertise.String(),
+		Mac:  generateMacAddr(n.addr.IP).String(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := clusterStore.Put(endpointKey(n, n.addr.IP), data); err != nil {
+		return err
+	}
+	return clusterStore.Put(peerKey(n), nil)
+}
+
+// leaveOverlay removes the bridge and vxlan interface of an overlay network
+// from this host, the caller must hold the lock of userNetworks
+func leaveOverlay(n *network) {
+	delete(userNetworks.networks, n.Name)
+	clusterStore.Delete(peerKey(n))
+	releaseOverlayIP(n, &n.addr.IP)
+	teardownNetwork(n, userNetworks.bridges())
+	delete(fdbs, n.ID)
+}
+
+// setupVxlan creates the vxlan interface of an overlay network when it does
+// not exist, and attaches it to the bridge
+func setupVxlan(n *network) error {
+	name := n.vxlanName()
+	if _, err := net.InterfaceByName(name); err != nil {
+		if err := netdev.LinkAddVxlan(name, n.VNI, clusterAdvertise, vxlanPort); err != nil {
+			return fmt.Errorf("Unable to create the vxlan interface %s: %s", name, err)
+		}
+		// the entries of the interface are lost with it
+		delete(fdbs, n.ID)
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	bridge, err := net.InterfaceByName(n.Bridge)
+	if err != nil {
+		return err
+	}
+	if err := netlink.NetworkSetMTU(iface, overlayMtu); err != nil {
+		return err
+	}
+	if err := netlink.NetworkSetMTU(bridge, overlayMtu); err != nil {
+		return err
+	}
+	// the bridge answers with the mac announced to the other daemons
+	if err := netdev.SetMacAddress(bridge, generateMacAddr(n.addr.IP).String()); err != nil {
+		return err
+	}
+	if err := netlink.AddToBridge(iface, bridge); err != nil {
+		return err
+	}
+	return netlink.NetworkLinkUp(iface)
+}
+
+// requestIP requests an address on the network n for the container id,
+// claiming it in the cluster store on overlay networks
+func requestIP(n *network, requested *net.IP, id string) (*net.IP, error) {
+	if n.Driver == overlayDriver {
+		return claimOverlayIP(n, requested, id)
+	}
+	if n.Parent != "" {
+		return requestMacvlanIP(n, requested)
+	}
+	return ipallocator.RequestIP(n.ipNet(), requested)
+}
+
+// releaseIP releases an address requested with requestIP
+func releaseIP(n *network, ip *net.IP) error {
+	if n.Driver == overlayDriver {
+		return releaseOverlayIP(n, ip)
+	}
+	return ipallocator.ReleaseIP(n.ipNet(), ip)
+}
+
+// claimOverlayIP allocates an address on an overlay network, then claims it
+// in the cluster store for container. The addresses claimed by the other
+// daemons stay allocated locally, and the next free one is tried.
+func claimOverlayIP(n *network, requested *net.IP, container string) (*net.IP, error) {
+	pool := n.ipNet()
+	for {
+		ip, err := ipallocator.RequestIP(pool, requested)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(&overlayEndpoint{
+			Host:      clusterAdvertise.String(),
+			Mac:       generateMacAddr(*ip).String(),
+			Container: container,
+		})
+		if err != nil {
+			ipallocator.ReleaseIP(pool, ip)
+			return nil, err
+		}
+		err = clusterStore.Create(endpointKey(n, *ip), data)
+		if err == nil {
+			return ip, nil
+		}
+		if err != kvstore.ErrKeyExists {
+			ipallocator.ReleaseIP(pool, ip)
+			return nil, err
+		}
+		if requested != nil {
+			ipallocator.ReleaseIP(pool, ip)
+			return nil, ipallocator.ErrIPAlreadyAllocated
+		}
+	}
+}
+
+func releaseOverlayIP(n *network, ip *net.IP) error {
+	if err := clusterStore.Delete(endpointKey(n, *ip)); err != nil && err != kvstore.ErrKeyNotFound {
+		return err
+	}
+	return ipallocator.ReleaseIP(n.ipNet(), ip)
+}
+
+// overlayEndpoints returns the addresses taken on an overlay network
+func overlayEndpoints(n *network) ([]*overlayEndpoint, error) {
+	values, err := clusterStore.List(endpointsPrefix(n))
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []*overlayEndpoint
+	for key, value := range values {
+		endpoint := &overlayEndpoint{}
+		if err := json.Unmarshal(value, endpoint); err != nil {
+			log.Errorf("Ignoring the endpoint at %s: %s", key, err)
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// syncFdb updates the forwarding entries of the vxlan interface of an
+// overlay network from the peers and endpoints in the cluster store
+func syncFdb(n *network) {
+	values, err := clusterStore.List(peersPrefix(n))
+	if err != nil {
+		log.Errorf("Unable to list the peers of %s: %s", n.Name, err)
+		return
+	}
+	var hosts []string
+	for key := range values {
+		hosts = append(hosts, strings.TrimPrefix(key, peersPrefix(n)))
+	}
+	endpoints, err := overlayEndpoints(n)
+	if err != nil {
+		log.Errorf("Unable to list the endpoints of %s: %s", n.Name, err)
+		return
+	}
+	iface, err := net.InterfaceByName(n.vxlanName())
+	if err != nil {
+		log.Errorf("Unable to update the forwarding entries of %s: %s", n.Name, err)
+		return
+	}
+
+	current := fdbs[n.ID]
+	if current == nil {
+		current = make(map[fdbEntry]bool)
+		fdbs[n.ID] = current
+	}
+	wanted := fdbEntries(hosts, endpoints, clusterAdvertise.String())
+	for entry := range wanted {
+		if current[entry] {
+			continue
+		}
+		if err := fdbAction(netdev.FdbAdd, iface, entry); err != nil {
+			log.Errorf("Unable to add the forwarding entry %v to %s: %s", entry, iface.Name, err)
+			continue
+		}
+		current[entry] = true
+	}
+	for entry := range current {
+		if wanted[entry] {
+			continue
+		}
+		if err := fdbAction(netdev.FdbDel, iface, entry); err != nil {
+			log.Debugf("Unable to remove the forwarding entry %v from %s: %s", entry, iface.Name, err)
+		}
+		delete(current, entry)
+	}
+}
+
+func fdbAction(action func(*net.Interface, net.HardwareAddr, net.IP) error, iface *net.Interface, entry fdbEntry) error {
+	mac := net.HardwareAddr{0, 0, 0, 0, 0, 0}
+	if entry.mac != "" {
+		var err error
+		if mac, err = net.ParseMAC(entry.mac); err != nil {
+			return err
+		}
+	}
+	return action(iface, mac, net.ParseIP(entry.host))
+}
+
+// fdbEntries returns the forwarding entries of a vxlan interface on the host
+// local: the broadcasts are flooded to the other hosts, and the frames for
+// their endpoints are sent to them directly
+func fdbEntries(hosts []string, endpoints []*overlayEndpoint, local string) map[fdbEntry]bool {
+	entries := make(map[fdbEntry]bool)
+	for _, host := range hosts {
+		if host != local {
+			entries[fdbEntry{host: host}] = true
+		}
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.Host != local && endpoint.Mac != "" {
+			entries[fdbEntry{mac: endpoint.Mac, host: endpoint.Host}] = true
+		}
+	}
+	return entries
+}
@@ -0,0 +1,92 @@
+package bridge
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/docker/docker/pkg/kvstore"
+)
+
+func TestFdbEntries(t *testing.T) {
+	endpoints := []*overlayEndpoint{
+		{Host: "192.168.0.1", Mac: "02:42:0a:06:00:01"},
+		{Host: "192.168.0.2", Mac: "02:42:0a:06:00:02"},
+		{Host: "192.168.0.2", Mac: "02:42:0a:06:00:03", Container: "c1"},
+	}
+	entries := fdbEntries([]string{"192.168.0.1", "192.168.0.2"}, endpoints, "192.168.0.1")
+
+	expected := []fdbEntry{
+		{host: "192.168.0.2"},
+		{mac: "02:42:0a:06:00:02", host: "192.168.0.2"},
+		{mac: "02:42:0a:06:00:03", host: "192.168.0.2"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %v", len(expected), entries)
+	}
+	for _, entry := range expected {
+		if !entries[entry] {
+			t.Fatalf("Expected the entry %v, got %v", entry, entries)
+		}
+	}
+}
+
+func TestClaimOverlayIP(t *testing.T) {
+	store, err := kvstore.NewMemoryStore("TestClaimOverlayIP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusterStore = store
+	clusterAdvertise = net.ParseIP("192.168.0.1")
+	defer func() {
+		clusterStore = nil
+	}()
+
+	n := &network{ID: "overlaytest", Driver: overlayDriver, Subnet: "10.6.0.0/24"}
+
+	// the next address is claimed by another daemon
+	taken, _ := json.Marshal(&overlayEndpoint{Host: "192.168.0.2", Mac: "02:42:0a:06:00:02"})
+	if err := store.Create(endpointKey(n, net.ParseIP("10.6.0.2")), taken); err != nil {
+		t.Fatal(err)
+	}
+
+	gateway := net.ParseIP("10.6.0.1")
+	ip, err := claimOverlayIP(n, &gateway, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(gateway) {
+		t.Fatalf("Expected the requested address %s, got %s", gateway, ip)
+	}
+
+	ip, err = claimOverlayIP(n, nil, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.String() != "10.6.0.3" {
+		t.Fatalf("Expected the address claimed by the other daemon to be skipped, got %s", ip)
+	}
+	data, err := store.Get(endpointKey(n, *ip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := &overlayEndpoint{}
+	if err := json.Unmarshal(data, endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.Host != "192.168.0.1" || endpoint.Container != "c1" || endpoint.Mac != generateMacAddr(*ip).String() {
+		t.Fatalf("Unexpected endpoint %+v", endpoint)
+	}
+
+	requested := net.ParseIP("10.6.0.2")
+	if _, err := claimOverlayIP(n, &requested, "c2"); err == nil {
+		t.Fatal("An address claimed by another daemon should be refused")
+	}
+
+	if err := releaseOverlayIP(n, ip); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(endpointKey(n, *ip)); err != kvstore.ErrKeyNotFound {
+		t.Fatalf("Expected the endpoint to be removed from the store, got %v", err)
+	}
+}
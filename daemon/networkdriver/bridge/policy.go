@@ -0,0 +1,32 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/iptables"
+)
+
+// containerPolicy returns the network policy of the container id given in
+// the NetworkPolicy env of job, or nil when the policy has no rules
+func containerPolicy(job *engine.Job, id string, n *network) (*iptables.Policy, error) {
+	policy := &iptables.Policy{}
+	if err := job.GetenvJson("NetworkPolicy", policy); err != nil {
+		return nil, err
+	}
+	if len(policy.Egress) == 0 && len(policy.Ingress) == 0 {
+		return nil, nil
+	}
+	if !enableIPTables {
+		return nil, fmt.Errorf("Network policies require iptables (--iptables)")
+	}
+	if n.Parent != "" {
+		// the traffic of the macvlan interfaces is not forwarded by the host
+		return nil, fmt.Errorf("Network policies cannot apply on the macvlan network %s", n.Name)
+	}
+	policy.Name = "DOCKER-POLICY-" + id[:12]
+	if n.addr != nil {
+		policy.Gateway = n.addr.IP.String()
+	}
+	return policy, nil
+}
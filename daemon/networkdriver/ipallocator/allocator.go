@@ -1,28 +1,50 @@
 package ipallocator
 
 import (
-	"encoding/binary"
 	"errors"
-	"github.com/docker/docker/daemon/networkdriver"
+	"math/big"
 	"net"
 	"sync"
+
+	"github.com/docker/docker/daemon/networkdriver"
 )
 
-// allocatedMap is thread-unsafe set of allocated IP
+// allocatedMap is thread-unsafe set of allocated IP, the addresses being
+// kept as their offset from the first address of the network, so that the
+// 128-bit addresses of IPv6 networks fit in as well. New addresses are
+// given out from the offsets between begin and end.
 type allocatedMap struct {
-	p    map[int32]struct{}
-	last int32
+	p     map[string]struct{}
+	last  *big.Int
+	begin *big.Int
+	end   *big.Int
+	// size is the number of addresses of the network
+	size *big.Int
 }
 
-func newAllocatedMap() *allocatedMap {
-	return &allocatedMap{p: make(map[int32]struct{})}
+func newAllocatedMap(network *net.IPNet) *allocatedMap {
+	first, last := networkdriver.NetworkRange(network)
+	size := big.NewInt(0).Sub(ipToBigInt(last), ipToBigInt(first))
+	size.Add(size, big.NewInt(1))
+	return &allocatedMap{
+		p:    make(map[string]struct{}),
+		last: big.NewInt(0),
+		// the first address of the network is skipped, for the gateway
+		begin: big.NewInt(2),
+		// -1 for the broadcast address
+		end:  big.NewInt(0).Sub(size, big.NewInt(2)),
+		size: size,
+	}
 }
 
 type networkSet map[string]*allocatedMap
 
 var (
-	ErrNoAvailableIPs     = errors.New("no available ip addresses on network")
-	ErrIPAlreadyAllocated = errors.New("ip already allocated")
+	ErrNoAvailableIPs           = errors.New("no available ip addresses on network")
+	ErrIPAlreadyAllocated       = errors.New("ip already allocated")
+	ErrIPOutOfRange             = errors.New("requested ip is out of range")
+	ErrNetworkAlreadyRegistered = errors.New("network already registered")
+	ErrBadSubnet                = errors.New("network does not contain specified subnet")
 )
 
 var (
@@ -30,6 +52,38 @@ var (
 	allocatedIPs = networkSet{}
 )
 
+// RegisterSubnet restricts the addresses RequestIP gives out on network to
+// the ones of subnet, which must be contained in network. It has to be
+// called before any address of network is requested.
+func RegisterSubnet(network *net.IPNet, subnet *net.IPNet) error {
+	lock.Lock()
+	defer lock.Unlock()
+	key := network.String()
+	if _, ok := allocatedIPs[key]; ok {
+		return ErrNetworkAlreadyRegistered
+	}
+	networkSize, _ := network.Mask.Size()
+	subnetSize, _ := subnet.Mask.Size()
+	if !network.Contains(subnet.IP) || subnetSize < networkSize {
+		return ErrBadSubnet
+	}
+
+	allocated := newAllocatedMap(network)
+	first, last := networkdriver.NetworkRange(subnet)
+	// the first and last addresses of the subnet are left out, as for a
+	// network of its own
+	begin := big.NewInt(0).Add(getPosition(network, &first), big.NewInt(1))
+	end := big.NewInt(0).Sub(getPosition(network, &last), big.NewInt(1))
+	if begin.Cmp(allocated.begin) > 0 {
+		allocated.begin = begin
+	}
+	if end.Cmp(allocated.end) < 0 {
+		allocated.end = end
+	}
+	allocatedIPs[key] = allocated
+	return nil
+}
+
 // RequestIP requests an available ip from the given network.  It
 // will return the next available ip if the ip provided is nil.  If the
 // ip provided is not nil it will validate that the provided ip is available
@@ -40,7 +94,7 @@ func RequestIP(network *net.IPNet, ip *net.IP) (*net.IP, error) {
 	key := network.String()
 	allocated, ok := allocatedIPs[key]
 	if !ok {
-		allocated = newAllocatedMap()
+		allocated = newAllocatedMap(network)
 		allocatedIPs[key] = allocated
 	}
 
@@ -57,24 +111,33 @@ func ReleaseIP(network *net.IPNet, ip *net.IP) error {
 	defer lock.Unlock()
 	if allocated, exists := allocatedIPs[network.String()]; exists {
 		pos := getPosition(network, ip)
-		delete(allocated.p, pos)
+		delete(allocated.p, pos.String())
 	}
 	return nil
 }
 
 // convert the ip into the position in the subnet.  Only
 // position are saved in the set
-func getPosition(network *net.IPNet, ip *net.IP) int32 {
+func getPosition(network *net.IPNet, ip *net.IP) *big.Int {
 	first, _ := networkdriver.NetworkRange(network)
-	return ipToInt(ip) - ipToInt(&first)
+	return big.NewInt(0).Sub(ipToBigInt(*ip), ipToBigInt(first))
 }
 
 func (allocated *allocatedMap) checkIP(network *net.IPNet, ip *net.IP) (*net.IP, error) {
+	if !network.Contains(*ip) {
+		return nil, ErrIPOutOfRange
+	}
 	pos := getPosition(network, ip)
-	if _, ok := allocated.p[pos]; ok {
+	ownPos := getPosition(network, &network.IP)
+	// neither the network address, the broadcast address nor the address of
+	// the gateway can be requested
+	if pos.Sign() == 0 || pos.Cmp(ownPos) == 0 || pos.Cmp(big.NewInt(0).Sub(allocated.size, big.NewInt(1))) >= 0 {
+		return nil, ErrIPOutOfRange
+	}
+	if _, ok := allocated.p[pos.String()]; ok {
 		return nil, ErrIPAlreadyAllocated
 	}
-	allocated.p[pos] = struct{}{}
+	allocated.p[pos.String()] = struct{}{}
 	allocated.last = pos
 	return ip, nil
 }
@@ -83,45 +146,48 @@ func (allocated *allocatedMap) checkIP(network *net.IPNet, ip *net.IP) (*net.IP,
 // return the next available ip for the nextwork
 func (allocated *allocatedMap) getNextIP(network *net.IPNet) (*net.IP, error) {
 	var (
-		ownIP    = ipToInt(&network.IP)
 		first, _ = networkdriver.NetworkRange(network)
-		base     = ipToInt(&first)
-		size     = int(networkdriver.NetworkSize(network.Mask))
-		max      = int32(size - 2) // size -1 for the broadcast network, -1 for the gateway network
-		pos      = allocated.last
+		base     = ipToBigInt(first)
+		ownPos   = big.NewInt(0).Sub(ipToBigInt(network.IP), base)
+		pos      = big.NewInt(0).Set(allocated.last)
+		one      = big.NewInt(1)
+		count    = big.NewInt(0).Sub(allocated.end, allocated.begin)
 	)
+	count.Add(count, one)
 
-	var (
-		firstNetIP = network.IP.To4().Mask(network.Mask)
-		firstAsInt = ipToInt(&firstNetIP) + 1
-	)
-
-	for i := int32(0); i < max; i++ {
-		pos = pos%max + 1
-		next := int32(base + pos)
+	for i := big.NewInt(0); i.Cmp(count) < 0; i.Add(i, one) {
+		// the positions go from begin to end, wrapping around
+		pos.Add(pos, one)
+		if pos.Cmp(allocated.begin) < 0 || pos.Cmp(allocated.end) > 0 {
+			pos.Set(allocated.begin)
+		}
 
-		if next == ownIP || next == firstAsInt {
+		// skip the address of the bridge
+		if pos.Cmp(ownPos) == 0 {
 			continue
 		}
-		if _, ok := allocated.p[pos]; ok {
+		if _, ok := allocated.p[pos.String()]; ok {
 			continue
 		}
-		allocated.p[pos] = struct{}{}
+		allocated.p[pos.String()] = struct{}{}
 		allocated.last = pos
-		return intToIP(next), nil
+		return bigIntToIP(big.NewInt(0).Add(base, pos), len(first)), nil
 	}
 	return nil, ErrNoAvailableIPs
 }
 
-// Converts a 4 bytes IP into a 32 bit integer
-func ipToInt(ip *net.IP) int32 {
-	return int32(binary.BigEndian.Uint32(ip.To4()))
+// Converts an IPv4 or IPv6 address into a big integer
+func ipToBigInt(ip net.IP) *big.Int {
+	if ip4 := ip.To4(); ip4 != nil {
+		return big.NewInt(0).SetBytes(ip4)
+	}
+	return big.NewInt(0).SetBytes(ip.To16())
 }
 
-// Converts 32 bit integer into a 4 bytes IP address
-func intToIP(n int32) *net.IP {
-	b := make([]byte, 4)
-	binary.BigEndian.PutUint32(b, uint32(n))
-	ip := net.IP(b)
+// Converts a big integer into an IP address of size bytes
+func bigIntToIP(n *big.Int, size int) *net.IP {
+	b := n.Bytes()
+	ip := make(net.IP, size)
+	copy(ip[size-len(b):], b)
 	return &ip
 }
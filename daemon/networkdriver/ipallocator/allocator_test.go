@@ -2,6 +2,7 @@ package ipallocator
 
 import (
 	"fmt"
+	"math/big"
 	"net"
 	"testing"
 )
@@ -29,7 +30,7 @@ func TestRequestNewIps(t *testing.T) {
 			t.Fatalf("Expected ip %s got %s", expected, ip.String())
 		}
 	}
-	value := intToIP(ipToInt(ip) + 1).String()
+	value := bigIntToIP(big.NewInt(0).Add(ipToBigInt(*ip), big.NewInt(1)), net.IPv4len).String()
 	if err := ReleaseIP(network, ip); err != nil {
 		t.Fatal(err)
 	}
@@ -104,25 +105,124 @@ func TestRequesetSpecificIp(t *testing.T) {
 		Mask: []byte{255, 255, 255, 0},
 	}
 
-	ip := net.ParseIP("192.168.1.5")
+	ip := net.ParseIP("192.168.0.5")
 
 	if _, err := RequestIP(network, &ip); err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestRequestOutOfRangeIp(t *testing.T) {
+	defer reset()
+	network := &net.IPNet{
+		IP:   []byte{192, 168, 0, 1},
+		Mask: []byte{255, 255, 255, 0},
+	}
+
+	for _, requested := range []string{"192.168.1.5", "192.168.0.0", "192.168.0.1", "192.168.0.255"} {
+		ip := net.ParseIP(requested)
+		if _, err := RequestIP(network, &ip); err != ErrIPOutOfRange {
+			t.Fatalf("Expected %s for %s, got %v", ErrIPOutOfRange, requested, err)
+		}
+	}
+}
+
+func TestRegisterSubnet(t *testing.T) {
+	defer reset()
+	gateway, n, _ := net.ParseCIDR("192.168.0.1/16")
+	network := &net.IPNet{IP: gateway, Mask: n.Mask}
+	_, subnet, _ := net.ParseCIDR("192.168.10.0/30")
+
+	if err := RegisterSubnet(network, subnet); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterSubnet(network, subnet); err != ErrNetworkAlreadyRegistered {
+		t.Fatalf("Expected %s, got %v", ErrNetworkAlreadyRegistered, err)
+	}
+
+	expectedIPs := []net.IP{net.ParseIP("192.168.10.1"), net.ParseIP("192.168.10.2")}
+	for i := range expectedIPs {
+		ip, err := RequestIP(network, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertIPEquals(t, &expectedIPs[i], ip)
+	}
+	if ip, err := RequestIP(network, nil); err != ErrNoAvailableIPs {
+		t.Fatalf("Expected the subnet to be exhausted, got %v %v", ip, err)
+	}
+
+	// the addresses outside of the subnet can still be requested
+	ip := net.ParseIP("192.168.20.5")
+	if _, err := RequestIP(network, &ip); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterBadSubnet(t *testing.T) {
+	defer reset()
+	gateway, n, _ := net.ParseCIDR("192.168.0.1/24")
+	network := &net.IPNet{IP: gateway, Mask: n.Mask}
+
+	for _, cidr := range []string{"192.168.1.0/28", "192.168.0.0/16"} {
+		_, subnet, _ := net.ParseCIDR(cidr)
+		if err := RegisterSubnet(network, subnet); err != ErrBadSubnet {
+			t.Fatalf("Expected %s for %s, got %v", ErrBadSubnet, cidr, err)
+		}
+	}
+}
+
 func TestConversion(t *testing.T) {
 	ip := net.ParseIP("127.0.0.1")
-	i := ipToInt(&ip)
-	if i == 0 {
+	i := ipToBigInt(ip)
+	if i.Sign() == 0 {
 		t.Fatal("converted to zero")
 	}
-	conv := intToIP(i)
+	conv := bigIntToIP(i, net.IPv4len)
 	if !ip.Equal(*conv) {
 		t.Error(conv.String())
 	}
 }
 
+func TestConversionIPv6(t *testing.T) {
+	ip := net.ParseIP("2001:db8::1:ff")
+	i := ipToBigInt(ip)
+	if i.Sign() == 0 {
+		t.Fatal("converted to zero")
+	}
+	conv := bigIntToIP(i, net.IPv6len)
+	if !ip.Equal(*conv) {
+		t.Error(conv.String())
+	}
+}
+
+func TestRequestNewIPv6(t *testing.T) {
+	defer reset()
+	gateway, subnet, _ := net.ParseCIDR("2001:db8:1::1/64")
+	network := &net.IPNet{IP: gateway, Mask: subnet.Mask}
+
+	for i := 2; i < 10; i++ {
+		ip, err := RequestIP(network, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := fmt.Sprintf("2001:db8:1::%d", i); ip.String() != expected {
+			t.Fatalf("Expected ip %s got %s", expected, ip.String())
+		}
+	}
+
+	ip := net.ParseIP("2001:db8:1::5")
+	if _, err := RequestIP(network, &ip); err != ErrIPAlreadyAllocated {
+		t.Fatalf("Expected %s, got %v", ErrIPAlreadyAllocated, err)
+	}
+	if err := ReleaseIP(network, &ip); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RequestIP(network, &ip); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestIPAllocator(t *testing.T) {
 	expectedIPs := []net.IP{
 		0: net.IPv4(127, 0, 0, 2),
@@ -226,15 +326,15 @@ func TestAllocateFirstIP(t *testing.T) {
 	}
 
 	firstIP := network.IP.To4().Mask(network.Mask)
-	first := ipToInt(&firstIP) + 1
+	first := big.NewInt(0).Add(ipToBigInt(firstIP), big.NewInt(1))
 
 	ip, err := RequestIP(network, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	allocated := ipToInt(ip)
+	allocated := ipToBigInt(*ip)
 
-	if allocated == first {
+	if allocated.Cmp(first) == 0 {
 		t.Fatalf("allocated ip should not equal first ip: %d == %d", first, allocated)
 	}
 }
@@ -1,9 +1,12 @@
 package networkdriver
 
 import (
-	"github.com/docker/libcontainer/netlink"
+	"io/ioutil"
 	"net"
+	"os"
 	"testing"
+
+	"github.com/docker/libcontainer/netlink"
 )
 
 func TestNonOverlapingNameservers(t *testing.T) {
@@ -61,6 +64,37 @@ func TestCheckRouteOverlaps(t *testing.T) {
 	}
 }
 
+func TestGetIfaceGateway(t *testing.T) {
+	f, err := ioutil.TempFile("", "docker-route-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth1	0001A8C0	00000000	0001	0	0	0	00FFFFFF	0	0	0
+eth0	00000000	0202000A	0003	0	0	0	00000000	0	0	0
+`)
+	f.Close()
+
+	orig := routeTablePath
+	defer func() {
+		routeTablePath = orig
+	}()
+	routeTablePath = f.Name()
+
+	gateway, err := GetIfaceGateway("eth0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gateway.Equal(net.ParseIP("10.0.2.2")) {
+		t.Fatalf("Expected the gateway 10.0.2.2 of eth0, got %s", gateway)
+	}
+
+	if gateway, err := GetIfaceGateway("eth1"); err != nil || gateway != nil {
+		t.Fatalf("Expected no gateway for eth1, got %s %v", gateway, err)
+	}
+}
+
 func TestCheckNameserverOverlaps(t *testing.T) {
 	nameservers := []string{"10.0.2.3/32", "192.168.102.1/32"}
 
@@ -187,4 +221,14 @@ func TestNetworkRange(t *testing.T) {
 	if size := NetworkSize(network.Mask); size != 64 {
 		t.Error(size)
 	}
+
+	// IPv6 test
+	_, network, _ = net.ParseCIDR("2001:db8::1/64")
+	first, last = NetworkRange(network)
+	if !first.Equal(net.ParseIP("2001:db8::")) {
+		t.Error(first.String())
+	}
+	if !last.Equal(net.ParseIP("2001:db8::ffff:ffff:ffff:ffff")) {
+		t.Error(last.String())
+	}
 }
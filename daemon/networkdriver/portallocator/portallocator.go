@@ -37,6 +37,7 @@ const (
 var (
 	ErrAllPortsAllocated = errors.New("all ports are allocated")
 	ErrUnknownProtocol   = errors.New("unknown protocol")
+	ErrPortRangeInvalid  = errors.New("invalid range of ports")
 )
 
 var (
@@ -78,12 +79,22 @@ func (e ErrPortAlreadyAllocated) Error() string {
 // If port is 0 it returns first free port. Otherwise it cheks port availability
 // in pool and return that port or error if port is already busy.
 func RequestPort(ip net.IP, proto string, port int) (int, error) {
+	return RequestPortRange(ip, proto, port, 1)
+}
+
+// RequestPortRange requests count consecutive ports starting at port, or the
+// first free range of count ports of the pool when port is 0, and returns
+// the first port of the range. None of the ports is allocated on error.
+func RequestPortRange(ip net.IP, proto string, port, count int) (int, error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	if proto != "tcp" && proto != "udp" {
 		return 0, ErrUnknownProtocol
 	}
+	if count < 1 || port+count-1 > 65535 {
+		return 0, ErrPortRangeInvalid
+	}
 
 	if ip == nil {
 		ip = defaultIP
@@ -96,14 +107,18 @@ func RequestPort(ip net.IP, proto string, port int) (int, error) {
 	}
 	mapping := protomap[proto]
 	if port > 0 {
-		if _, ok := mapping.p[port]; !ok {
-			mapping.p[port] = struct{}{}
-			return port, nil
+		for p := port; p < port+count; p++ {
+			if _, ok := mapping.p[p]; ok {
+				return 0, NewErrPortAlreadyAllocated(ipstr, p)
+			}
 		}
-		return 0, NewErrPortAlreadyAllocated(ipstr, port)
+		for p := port; p < port+count; p++ {
+			mapping.p[p] = struct{}{}
+		}
+		return port, nil
 	}
 
-	port, err := mapping.findPort()
+	port, err := mapping.findPortRange(count)
 	if err != nil {
 		return 0, err
 	}
@@ -112,6 +127,11 @@ func RequestPort(ip net.IP, proto string, port int) (int, error) {
 
 // ReleasePort releases port from global ports pool for specified ip and proto.
 func ReleasePort(ip net.IP, proto string, port int) error {
+	return ReleasePortRange(ip, proto, port, 1)
+}
+
+// ReleasePortRange releases count consecutive ports starting at port
+func ReleasePortRange(ip net.IP, proto string, port, count int) error {
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -122,7 +142,9 @@ func ReleasePort(ip net.IP, proto string, port int) error {
 	if !ok {
 		return nil
 	}
-	delete(protomap[proto].p, port)
+	for p := port; p < port+count; p++ {
+		delete(protomap[proto].p, p)
+	}
 	return nil
 }
 
@@ -134,23 +156,39 @@ func ReleaseAll() error {
 	return nil
 }
 
-func (pm *portMap) findPort() (int, error) {
-	if pm.last == 0 {
-		pm.p[BeginPortRange] = struct{}{}
-		pm.last = BeginPortRange
-		return BeginPortRange, nil
+// findPortRange allocates the first range of count free ports after the
+// last allocated one, wrapping around the end of the pool
+func (pm *portMap) findPortRange(count int) (int, error) {
+	size := EndPortRange - BeginPortRange + 1
+	if count > size {
+		return 0, ErrAllPortsAllocated
 	}
 
-	for port := pm.last + 1; port != pm.last; port++ {
-		if port > EndPortRange {
-			port = BeginPortRange
+	start := BeginPortRange
+	if pm.last != 0 {
+		start = pm.last + 1
+	}
+	for i := 0; i < size; i++ {
+		port := BeginPortRange + (start-BeginPortRange+i)%size
+		if port+count-1 > EndPortRange {
+			continue
 		}
-
-		if _, ok := pm.p[port]; !ok {
-			pm.p[port] = struct{}{}
-			pm.last = port
+		if pm.isFree(port, count) {
+			for p := port; p < port+count; p++ {
+				pm.p[p] = struct{}{}
+			}
+			pm.last = port + count - 1
 			return port, nil
 		}
 	}
 	return 0, ErrAllPortsAllocated
 }
+
+func (pm *portMap) isFree(port, count int) bool {
+	for p := port; p < port+count; p++ {
+		if _, ok := pm.p[p]; ok {
+			return false
+		}
+	}
+	return true
+}
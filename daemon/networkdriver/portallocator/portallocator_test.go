@@ -214,3 +214,64 @@ func TestPortAllocation(t *testing.T) {
 		t.Fatal("Requesting a dynamic port should never allocate a used port")
 	}
 }
+
+func TestRequestPortRange(t *testing.T) {
+	defer reset()
+
+	port, err := RequestPortRange(defaultIP, "tcp", 8000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 8000 {
+		t.Fatalf("Expected the range to start at 8000, got %d", port)
+	}
+	if _, err := RequestPort(defaultIP, "tcp", 8009); err == nil {
+		t.Fatal("Expected the last port of the range to be allocated")
+	}
+
+	// a range overlapping an allocated port is not allocated at all
+	if _, err := RequestPortRange(defaultIP, "tcp", 7995, 6); err == nil {
+		t.Fatal("Expected an error for a range overlapping an allocated one")
+	}
+	if _, err := RequestPort(defaultIP, "tcp", 7995); err != nil {
+		t.Fatalf("Expected the ports of a failed range to be released, got %s", err)
+	}
+
+	if err := ReleasePortRange(defaultIP, "tcp", 8000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RequestPortRange(defaultIP, "tcp", 8000, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RequestPortRange(defaultIP, "tcp", 65530, 10); err != ErrPortRangeInvalid {
+		t.Fatalf("Expected %s for a range past the last port, got %v", ErrPortRangeInvalid, err)
+	}
+}
+
+func TestRequestDynamicPortRange(t *testing.T) {
+	defer reset()
+
+	if _, err := RequestPort(defaultIP, "tcp", BeginPortRange+5); err != nil {
+		t.Fatal(err)
+	}
+	// the range does not fit before the allocated port
+	port, err := RequestPortRange(defaultIP, "tcp", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != BeginPortRange+6 {
+		t.Fatalf("Expected the first free range to start at %d, got %d", BeginPortRange+6, port)
+	}
+	port, err = RequestPort(defaultIP, "tcp", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != BeginPortRange+16 {
+		t.Fatalf("Expected the port after the range, got %d", port)
+	}
+
+	if _, err := RequestPortRange(defaultIP, "tcp", 0, EndPortRange-BeginPortRange); err != ErrAllPortsAllocated {
+		t.Fatalf("Expected %s for a range larger than the free ports, got %v", ErrAllPortsAllocated, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/docker/docker/daemon/networkdriver/portallocator"
 	"github.com/docker/docker/pkg/iptables"
+	"github.com/docker/docker/pkg/log"
 )
 
 type mapping struct {
@@ -15,6 +16,8 @@ type mapping struct {
 	userlandProxy UserlandProxy
 	host          net.Addr
 	container     net.Addr
+	bridge        string
+	count         int // number of consecutive ports from host and container
 }
 
 var (
@@ -38,9 +41,32 @@ func SetIptablesChain(c *iptables.Chain) {
 }
 
 func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err error) {
+	return MapRange(container, hostIP, hostPort, 1, "")
+}
+
+// MapRange maps count consecutive ports of the host, starting at hostPort or
+// at the first free range when it is 0, to the ones of the container attached
+// to bridge, starting at its address. The bridge of the iptables chain is used
+// when bridge is empty. The returned address is the one of the first port of
+// the host, which unmaps the whole range.
+//
+// When hostPort is 0, a range of unprivileged ports is first mapped to the
+// same ports of the host, so that a single DNAT rule forwards it. A range
+// shifted to other ports of the host needs one DNAT rule per port.
+func MapRange(container net.Addr, hostIP net.IP, hostPort, count int, bridge string) (host net.Addr, err error) {
 	lock.Lock()
 	defer lock.Unlock()
 
+	if _, containerPort := getIPAndPort(container); hostPort == 0 && count > 1 && containerPort > 1023 {
+		if host, err = mapRange(container, hostIP, containerPort, count, bridge); err == nil {
+			return host, nil
+		}
+		log.Debugf("Unable to map %s to the same ports of the host, mapping it to free ports: %s", container, err)
+	}
+	return mapRange(container, hostIP, hostPort, count, bridge)
+}
+
+func mapRange(container net.Addr, hostIP net.IP, hostPort, count int, bridge string) (host net.Addr, err error) {
 	var (
 		m                 *mapping
 		proto             string
@@ -51,7 +77,7 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 	switch container.(type) {
 	case *net.TCPAddr:
 		proto = "tcp"
-		if allocatedHostPort, err = portallocator.RequestPort(hostIP, proto, hostPort); err != nil {
+		if allocatedHostPort, err = portallocator.RequestPortRange(hostIP, proto, hostPort, count); err != nil {
 			return nil, err
 		}
 
@@ -59,12 +85,14 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 			proto:     proto,
 			host:      &net.TCPAddr{IP: hostIP, Port: allocatedHostPort},
 			container: container,
+			bridge:    bridge,
+			count:     count,
 		}
 
-		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.TCPAddr).IP, container.(*net.TCPAddr).Port)
+		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.TCPAddr).IP, container.(*net.TCPAddr).Port, count)
 	case *net.UDPAddr:
 		proto = "udp"
-		if allocatedHostPort, err = portallocator.RequestPort(hostIP, proto, hostPort); err != nil {
+		if allocatedHostPort, err = portallocator.RequestPortRange(hostIP, proto, hostPort, count); err != nil {
 			return nil, err
 		}
 
@@ -72,9 +100,11 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 			proto:     proto,
 			host:      &net.UDPAddr{IP: hostIP, Port: allocatedHostPort},
 			container: container,
+			bridge:    bridge,
+			count:     count,
 		}
 
-		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.UDPAddr).IP, container.(*net.UDPAddr).Port)
+		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.UDPAddr).IP, container.(*net.UDPAddr).Port, count)
 	default:
 		return nil, ErrUnknownBackendAddressType
 	}
@@ -82,7 +112,7 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 	// release the allocated port on any further error during return.
 	defer func() {
 		if err != nil {
-			portallocator.ReleasePort(hostIP, proto, allocatedHostPort)
+			portallocator.ReleasePortRange(hostIP, proto, allocatedHostPort, count)
 		}
 	}()
 
@@ -92,7 +122,7 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 	}
 
 	containerIP, containerPort := getIPAndPort(m.container)
-	if err := forward(iptables.Add, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort); err != nil {
+	if err := forward(iptables.Add, m.bridge, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort, count); err != nil {
 		return nil, err
 	}
 
@@ -101,7 +131,8 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 
 	if err := proxy.Start(); err != nil {
 		// need to undo the iptables rules before we return
-		forward(iptables.Delete, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort)
+		forward(iptables.Delete, m.bridge, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort, count)
+		delete(currentMappings, key)
 
 		return nil, err
 	}
@@ -125,17 +156,17 @@ func Unmap(host net.Addr) error {
 
 	containerIP, containerPort := getIPAndPort(data.container)
 	hostIP, hostPort := getIPAndPort(data.host)
-	if err := forward(iptables.Delete, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
+	if err := forward(iptables.Delete, data.bridge, data.proto, hostIP, hostPort, containerIP.String(), containerPort, data.count); err != nil {
 		return err
 	}
 
 	switch a := host.(type) {
 	case *net.TCPAddr:
-		if err := portallocator.ReleasePort(a.IP, "tcp", a.Port); err != nil {
+		if err := portallocator.ReleasePortRange(a.IP, "tcp", a.Port, data.count); err != nil {
 			return err
 		}
 	case *net.UDPAddr:
-		if err := portallocator.ReleasePort(a.IP, "udp", a.Port); err != nil {
+		if err := portallocator.ReleasePortRange(a.IP, "udp", a.Port, data.count); err != nil {
 			return err
 		}
 	}
@@ -163,9 +194,13 @@ func getIPAndPort(a net.Addr) (net.IP, int) {
 	return nil, 0
 }
 
-func forward(action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) error {
+func forward(action iptables.Action, bridge, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort, count int) error {
 	if chain == nil {
 		return nil
 	}
-	return chain.Forward(action, sourceIP, sourcePort, proto, containerIP, containerPort)
+	c := chain
+	if bridge != "" && bridge != chain.Bridge {
+		c = &iptables.Chain{Name: chain.Name, Bridge: bridge, HairpinMode: chain.HairpinMode}
+	}
+	return c.ForwardRange(action, sourceIP, sourcePort, proto, containerIP, containerPort, count)
 }
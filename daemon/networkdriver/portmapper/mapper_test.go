@@ -150,3 +150,85 @@ func TestMapAllPortsSingleInterface(t *testing.T) {
 		hosts = []net.Addr{}
 	}
 }
+
+func TestDummyProxy(t *testing.T) {
+	for _, proto := range []string{"tcp", "udp"} {
+		proxy := NewDummyProxy(proto, net.ParseIP("127.0.0.1"), 0, nil, 0, 1).(*dummyProxy)
+		if err := proxy.Start(); err != nil {
+			t.Fatal(err)
+		}
+		// the proxy holds the port chosen by the kernel
+		var addr string
+		switch l := proxy.listeners[0].(type) {
+		case net.Listener:
+			addr = l.Addr().String()
+		case *net.UDPConn:
+			addr = l.LocalAddr().String()
+		}
+		second := &dummyProxy{proto: proto, addrs: []string{addr}}
+		if err := second.Start(); err == nil {
+			second.Stop()
+			t.Fatalf("Expected %s %s to be held by the proxy", proto, addr)
+		}
+
+		if err := proxy.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		if err := second.Start(); err != nil {
+			t.Fatalf("Expected %s %s to be released, got %s", proto, addr, err)
+		}
+		second.Stop()
+	}
+}
+
+func TestMapRange(t *testing.T) {
+	defer reset()
+	hostIp := net.ParseIP("192.168.0.1")
+	container := &net.TCPAddr{IP: net.ParseIP("172.16.0.1"), Port: 9000}
+
+	host, err := MapRange(container, hostIp, 8000, 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host.String() != "192.168.0.1:8000" {
+		t.Fatalf("Expected the first port of the range, got %s", host)
+	}
+	if _, err := Map(&net.TCPAddr{IP: net.ParseIP("172.16.0.2"), Port: 80}, hostIp, 8002); err == nil {
+		t.Fatal("Expected the last port of the range to be in use")
+	}
+
+	if err := Unmap(host); err != nil {
+		t.Fatal(err)
+	}
+	other, err := Map(&net.TCPAddr{IP: net.ParseIP("172.16.0.2"), Port: 80}, hostIp, 8002)
+	if err != nil {
+		t.Fatalf("Expected the whole range to be released, got %s", err)
+	}
+	Unmap(other)
+
+	// a dynamic range gets the ports of the container when they are free
+	if host, err = MapRange(container, hostIp, 0, 3, ""); err != nil {
+		t.Fatal(err)
+	}
+	if host.String() != "192.168.0.1:9000" {
+		t.Fatalf("Expected the range to be mapped to the same ports, got %s", host)
+	}
+	shifted, err := MapRange(&net.TCPAddr{IP: net.ParseIP("172.16.0.2"), Port: 9000}, hostIp, 0, 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := shifted.(*net.TCPAddr).Port; port < portallocator.BeginPortRange || port > portallocator.EndPortRange {
+		t.Fatalf("Expected a range of the pool once the same ports are in use, got %s", shifted)
+	}
+	Unmap(host)
+	Unmap(shifted)
+
+	privileged, err := MapRange(&net.TCPAddr{IP: net.ParseIP("172.16.0.2"), Port: 80}, hostIp, 0, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := privileged.(*net.TCPAddr).Port; port < portallocator.BeginPortRange {
+		t.Fatalf("Expected a range of privileged ports to be mapped to the pool, got %s", privileged)
+	}
+	Unmap(privileged)
+}
@@ -2,12 +2,15 @@ package portmapper
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
 	"os/exec"
 	"os/signal"
 	"strconv"
+	"sync"
 	"syscall"
 
 	"github.com/docker/docker/pkg/proxy"
@@ -33,58 +36,77 @@ type proxyCommand struct {
 
 // execProxy is the reexec function that is registered to start the userland proxies
 func execProxy() {
-	host, container := parseHostContainerAddrs()
-
-	p, err := proxy.NewProxy(host, container)
-	if err != nil {
-		log.Fatal(err)
+	hosts, containers := parseHostContainerAddrs()
+
+	proxies := make([]proxy.Proxy, len(hosts))
+	for i := range hosts {
+		p, err := proxy.NewProxy(hosts[i], containers[i])
+		if err != nil {
+			log.Fatal(err)
+		}
+		proxies[i] = p
 	}
 
-	go handleStopSignals(p)
+	go handleStopSignals(proxies)
 
-	// Run will block until the proxy stops
-	p.Run()
+	// Run will block until the proxies stop
+	var wg sync.WaitGroup
+	for _, p := range proxies {
+		wg.Add(1)
+		go func(p proxy.Proxy) {
+			p.Run()
+			wg.Done()
+		}(p)
+	}
+	wg.Wait()
 }
 
 // parseHostContainerAddrs parses the flags passed on reexec to create the TCP or UDP
-// net.Addrs to map the host and container ports
-func parseHostContainerAddrs() (host net.Addr, container net.Addr) {
+// net.Addrs to map the host and container ports, one pair for each port of the range
+func parseHostContainerAddrs() (hosts []net.Addr, containers []net.Addr) {
 	var (
 		proto         = flag.String("proto", "tcp", "proxy protocol")
 		hostIP        = flag.String("host-ip", "", "host ip")
 		hostPort      = flag.Int("host-port", -1, "host port")
 		containerIP   = flag.String("container-ip", "", "container ip")
 		containerPort = flag.Int("container-port", -1, "container port")
+		count         = flag.Int("port-count", 1, "number of consecutive ports")
 	)
 
 	flag.Parse()
 
-	switch *proto {
-	case "tcp":
-		host = &net.TCPAddr{IP: net.ParseIP(*hostIP), Port: *hostPort}
-		container = &net.TCPAddr{IP: net.ParseIP(*containerIP), Port: *containerPort}
-	case "udp":
-		host = &net.UDPAddr{IP: net.ParseIP(*hostIP), Port: *hostPort}
-		container = &net.UDPAddr{IP: net.ParseIP(*containerIP), Port: *containerPort}
-	default:
-		log.Fatalf("unsupported protocol %s", *proto)
+	for i := 0; i < *count; i++ {
+		switch *proto {
+		case "tcp":
+			hosts = append(hosts, &net.TCPAddr{IP: net.ParseIP(*hostIP), Port: *hostPort + i})
+			containers = append(containers, &net.TCPAddr{IP: net.ParseIP(*containerIP), Port: *containerPort + i})
+		case "udp":
+			hosts = append(hosts, &net.UDPAddr{IP: net.ParseIP(*hostIP), Port: *hostPort + i})
+			containers = append(containers, &net.UDPAddr{IP: net.ParseIP(*containerIP), Port: *containerPort + i})
+		default:
+			log.Fatalf("unsupported protocol %s", *proto)
+		}
 	}
 
-	return host, container
+	return hosts, containers
 }
 
-func handleStopSignals(p proxy.Proxy) {
+func handleStopSignals(proxies []proxy.Proxy) {
 	s := make(chan os.Signal, 10)
 	signal.Notify(s, os.Interrupt, syscall.SIGTERM, syscall.SIGSTOP)
 
 	for _ = range s {
-		p.Close()
+		for _, p := range proxies {
+			p.Close()
+		}
 
 		os.Exit(0)
 	}
 }
 
-func NewProxyCommand(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int) UserlandProxy {
+// NewProxyCommand returns a single process proxying the count consecutive
+// ports of the host starting at hostPort to the ones of the container
+func NewProxyCommand(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort, count int) UserlandProxy {
 	args := []string{
 		userlandProxyCommandName,
 		"-proto", proto,
@@ -92,6 +114,7 @@ func NewProxyCommand(proto string, hostIP net.IP, hostPort int, containerIP net.
 		"-host-port", strconv.Itoa(hostPort),
 		"-container-ip", containerIP.String(),
 		"-container-port", strconv.Itoa(containerPort),
+		"-port-count", strconv.Itoa(count),
 	}
 
 	return &proxyCommand{
@@ -117,3 +140,59 @@ func (p *proxyCommand) Stop() error {
 
 	return err
 }
+
+// dummyProxy only holds the ports of the host while iptables forwards the
+// traffic, so that no other process of the host binds them
+type dummyProxy struct {
+	proto     string
+	addrs     []string
+	listeners []io.Closer
+}
+
+// NewDummyProxy returns a proxy which does not forward anything, for the
+// daemon running in hairpin mode where iptables handles the whole traffic of
+// the published ports
+func NewDummyProxy(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort, count int) UserlandProxy {
+	p := &dummyProxy{proto: proto}
+	for i := 0; i < count; i++ {
+		p.addrs = append(p.addrs, net.JoinHostPort(hostIP.String(), strconv.Itoa(hostPort+i)))
+	}
+	return p
+}
+
+func (p *dummyProxy) Start() error {
+	for _, addr := range p.addrs {
+		l, err := listen(p.proto, addr)
+		if err != nil {
+			p.Stop()
+			return err
+		}
+		p.listeners = append(p.listeners, l)
+	}
+	return nil
+}
+
+func (p *dummyProxy) Stop() error {
+	var err error
+	for _, l := range p.listeners {
+		if closeErr := l.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	p.listeners = nil
+	return err
+}
+
+func listen(proto, addr string) (io.Closer, error) {
+	switch proto {
+	case "tcp":
+		return net.Listen("tcp", addr)
+	case "udp":
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return net.ListenUDP("udp", udpAddr)
+	}
+	return nil, fmt.Errorf("unsupported protocol %s", proto)
+}
@@ -1,16 +1,21 @@
 package networkdriver
 
 import (
+	"bufio"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"net"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/docker/libcontainer/netlink"
 )
 
 var (
 	networkGetRoutesFct = netlink.NetworkGetRoutes
+	routeTablePath      = "/proc/net/route"
 	ErrNoDefaultRoute   = errors.New("no default route")
 )
 
@@ -54,16 +59,15 @@ func NetworkOverlaps(netX *net.IPNet, netY *net.IPNet) bool {
 	return false
 }
 
-// Calculates the first and last IP addresses in an IPNet
+// Calculates the first and last IP addresses in an IPNet, of either family
 func NetworkRange(network *net.IPNet) (net.IP, net.IP) {
 	var (
-		netIP   = network.IP.To4()
-		firstIP = netIP.Mask(network.Mask)
-		lastIP  = net.IPv4(0, 0, 0, 0).To4()
+		firstIP = network.IP.Mask(network.Mask)
+		lastIP  = make(net.IP, len(firstIP))
 	)
 
 	for i := 0; i < len(lastIP); i++ {
-		lastIP[i] = netIP[i] | ^network.Mask[i]
+		lastIP[i] = firstIP[i] | ^network.Mask[i]
 	}
 	return firstIP, lastIP
 }
@@ -116,3 +120,32 @@ func GetDefaultRouteIface() (*net.Interface, error) {
 	}
 	return nil, ErrNoDefaultRoute
 }
+
+// GetIfaceGateway returns the gateway of the default route going through the
+// interface name, or nil when the default route goes through another one
+func GetIfaceGateway(name string) (net.IP, error) {
+	f, err := os.Open(routeTablePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get routes: %v", err)
+	}
+	defer f.Close()
+
+	// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] != name || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		gateway, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gateway %s in %s", fields[2], routeTablePath)
+		}
+		// the addresses are written in the byte order of the host, which is
+		// little endian on amd64
+		ip := make(net.IP, net.IPv4len)
+		binary.LittleEndian.PutUint32(ip, uint32(gateway))
+		return ip, nil
+	}
+	return nil, scanner.Err()
+}
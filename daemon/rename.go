@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"path"
+	"strings"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/graphdb"
+	"github.com/docker/docker/pkg/log"
+)
+
+func (daemon *Daemon) ContainerRename(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s OLD_NAME NEW_NAME", job.Name)
+	}
+	oldName, newName := job.Args[0], job.Args[1]
+	container := daemon.Get(oldName)
+	if container == nil {
+		return job.Errorf("No such container: %s", oldName)
+	}
+	if !validContainerNamePattern.MatchString(newName) {
+		return job.Errorf("Invalid container name (%s), only %s are allowed", newName, validContainerNameChars)
+	}
+	newName, err := GetFullContainerName(newName)
+	if err != nil {
+		return job.Error(err)
+	}
+
+	container.Lock()
+	oldName = container.Name
+	if oldName == newName {
+		container.Unlock()
+		return job.Errorf("Container is already named %s", strings.TrimPrefix(newName, "/"))
+	}
+
+	// The links of the container are edges of its entity, so renaming the
+	// edge of its name is enough for them to follow in the graph.
+	if err := daemon.containerGraph.Rename(oldName, newName); err != nil {
+		container.Unlock()
+		if graphdb.IsNonUniqueNameError(err) {
+			nameAsKnownByUser := strings.TrimPrefix(newName, "/")
+			return job.Errorf("Conflict, The name %s is already assigned to another container.", nameAsKnownByUser)
+		}
+		return job.Errorf("Cannot rename container %s: %s", oldName, err)
+	}
+	container.Name = newName
+	container.renameActiveLinks(newName)
+	if err := container.toDisk(); err != nil {
+		container.Name = oldName
+		container.renameActiveLinks(oldName)
+		if err := daemon.containerGraph.Rename(newName, oldName); err != nil {
+			log.Errorf("Error restoring the name %s of %s: %s", oldName, container.ID, err)
+		}
+		container.Unlock()
+		return job.Errorf("Cannot rename container %s: %s", oldName, err)
+	}
+	container.Unlock()
+
+	daemon.updateParentsHosts(container)
+	container.LogEvent("rename")
+	return engine.StatusOK
+}
+
+// renameActiveLinks updates the names of the links of a running container,
+// which are prefixed with the name of the container.
+func (container *Container) renameActiveLinks(name string) {
+	for alias, link := range container.activeLinks {
+		link.Name = path.Join(name, alias)
+	}
+}
+
+// updateParentsHosts rewrites the /etc/hosts file of the running containers
+// linking to container, which list it under its name.
+func (daemon *Daemon) updateParentsHosts(container *Container) {
+	daemon.forEachRunningParent(container, func(parent *Container, alias string) {
+		if mode := parent.hostConfig.NetworkMode; mode.IsHost() || mode.IsContainer() {
+			return
+		}
+		IP := parent.NetworkSettings.IPAddress
+		if daemon.config.DisableNetwork {
+			IP = "127.0.1.1"
+		}
+		if err := parent.buildHostsFile(IP); err != nil {
+			log.Errorf("Error updating the hosts file of %s: %s", parent.ID, err)
+		}
+	})
+}
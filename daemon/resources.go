@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/sysinfo"
+	"github.com/docker/docker/runconfig"
+)
+
+// verifyResources checks that the kernel supports the resource limits
+// requested, adjusting the swap limit when it cannot be enforced.
+func verifyResources(resources *execdriver.Resources, sysInfo *sysinfo.SysInfo) error {
+	if resources.Memory != 0 && resources.Memory < 524288 {
+		return fmt.Errorf("Minimum memory limit allowed is 512k")
+	}
+	if resources.Memory > 0 && !sysInfo.MemoryLimit {
+		return fmt.Errorf("Your kernel does not support memory limit capabilities")
+	}
+	if resources.MemorySwap > 0 && resources.MemorySwap < resources.Memory {
+		return fmt.Errorf("Minimum memory swap limit should be larger than the memory limit")
+	}
+	if resources.CpuShares < 0 {
+		return fmt.Errorf("Invalid CPU shares: %d", resources.CpuShares)
+	}
+	if resources.Memory > 0 && !sysInfo.SwapLimit {
+		resources.MemorySwap = -1
+	}
+	return nil
+}
+
+// verifyHostConfigResources checks the resource limits of the host config
+// of a container, refusing the ones the kernel has no controller for.
+func verifyHostConfigResources(hostConfig *runconfig.HostConfig, config *runconfig.Config, sysInfo *sysinfo.SysInfo) error {
+	if hostConfig.MemoryReservation < 0 {
+		return fmt.Errorf("Invalid memory reservation: %d", hostConfig.MemoryReservation)
+	}
+	if hostConfig.MemoryReservation > 0 && !sysInfo.MemoryLimit {
+		return fmt.Errorf("Your kernel does not support memory soft limit capabilities")
+	}
+	if hostConfig.MemoryReservation > 0 && config.Memory > 0 && hostConfig.MemoryReservation > config.Memory {
+		return fmt.Errorf("The memory reservation should be lower than the memory limit")
+	}
+	if hostConfig.OomKillDisable && !sysInfo.OomKillDisable {
+		return fmt.Errorf("Your kernel does not support OOM killer control")
+	}
+
+	if hostConfig.CpuPeriod != 0 {
+		if !sysInfo.CpuCfsPeriod {
+			return fmt.Errorf("Your kernel does not support CPU CFS period")
+		}
+		if hostConfig.CpuPeriod < 1000 || hostConfig.CpuPeriod > 1000000 {
+			return fmt.Errorf("The CPU CFS period should be between 1000 and 1000000 microseconds")
+		}
+	}
+	if hostConfig.CpuQuota != 0 {
+		if !sysInfo.CpuCfsQuota {
+			return fmt.Errorf("Your kernel does not support CPU CFS quota")
+		}
+		// -1 means no quota
+		if hostConfig.CpuQuota != -1 && hostConfig.CpuQuota < 1000 {
+			return fmt.Errorf("The CPU CFS quota should be at least 1000 microseconds")
+		}
+	}
+
+	if hostConfig.BlkioWeight != 0 {
+		if !sysInfo.BlkioWeight {
+			return fmt.Errorf("Your kernel does not support Block I/O weight")
+		}
+		if hostConfig.BlkioWeight < 10 || hostConfig.BlkioWeight > 1000 {
+			return fmt.Errorf("The Block I/O weight should be between 10 and 1000")
+		}
+	}
+	throttled := len(hostConfig.BlkioDeviceReadBps) > 0 ||
+		len(hostConfig.BlkioDeviceWriteBps) > 0 ||
+		len(hostConfig.BlkioDeviceReadIOps) > 0 ||
+		len(hostConfig.BlkioDeviceWriteIOps) > 0
+	if throttled && !sysInfo.BlkioThrottle {
+		return fmt.Errorf("Your kernel does not support Block I/O throttling")
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/sysinfo"
+	"github.com/docker/docker/runconfig"
+)
+
+func TestVerifyResources(t *testing.T) {
+	full := &sysinfo.SysInfo{MemoryLimit: true, SwapLimit: true}
+
+	valid := []execdriver.Resources{
+		{},
+		{Memory: 524288},
+		{Memory: 1048576, MemorySwap: 2097152},
+		{Memory: 1048576, MemorySwap: -1},
+		{CpuShares: 512, Cpuset: "0,1"},
+	}
+	for _, r := range valid {
+		if err := verifyResources(&r, full); err != nil {
+			t.Fatalf("Expected %+v to be valid, got %s", r, err)
+		}
+	}
+
+	invalid := []execdriver.Resources{
+		{Memory: 1024},
+		{Memory: 1048576, MemorySwap: 524288},
+		{CpuShares: -1},
+	}
+	for _, r := range invalid {
+		if err := verifyResources(&r, full); err == nil {
+			t.Fatalf("Expected %+v to be invalid", r)
+		}
+	}
+
+	if err := verifyResources(&execdriver.Resources{Memory: 1048576}, &sysinfo.SysInfo{}); err == nil {
+		t.Fatal("Expected a memory limit to be refused without kernel support")
+	}
+
+	r := execdriver.Resources{Memory: 1048576, MemorySwap: 2097152}
+	if err := verifyResources(&r, &sysinfo.SysInfo{MemoryLimit: true}); err != nil {
+		t.Fatal(err)
+	}
+	if r.MemorySwap != -1 {
+		t.Fatalf("Expected the swap limit to be discarded, got %d", r.MemorySwap)
+	}
+}
+
+func TestVerifyHostConfigResources(t *testing.T) {
+	var (
+		full = &sysinfo.SysInfo{
+			MemoryLimit:    true,
+			OomKillDisable: true,
+			CpuCfsPeriod:   true,
+			CpuCfsQuota:    true,
+			BlkioWeight:    true,
+			BlkioThrottle:  true,
+		}
+		config    = &runconfig.Config{Memory: 1048576}
+		throttles = []runconfig.ThrottleDevice{{Path: "/dev/sda", Rate: 1048576}}
+	)
+
+	valid := []*runconfig.HostConfig{
+		{},
+		{MemoryReservation: 524288, OomKillDisable: true},
+		{CpuPeriod: 50000, CpuQuota: 25000},
+		{CpuQuota: -1},
+		{BlkioWeight: 10},
+		{BlkioWeight: 1000, BlkioDeviceReadBps: throttles, BlkioDeviceWriteIOps: throttles},
+	}
+	for _, hostConfig := range valid {
+		if err := verifyHostConfigResources(hostConfig, config, full); err != nil {
+			t.Fatalf("Expected %+v to be valid, got %s", hostConfig, err)
+		}
+	}
+
+	invalid := []*runconfig.HostConfig{
+		{MemoryReservation: -1},
+		{MemoryReservation: 2097152},
+		{CpuPeriod: 999},
+		{CpuPeriod: 1000001},
+		{CpuQuota: 999},
+		{BlkioWeight: 9},
+		{BlkioWeight: 1001},
+	}
+	for _, hostConfig := range invalid {
+		if err := verifyHostConfigResources(hostConfig, config, full); err == nil {
+			t.Fatalf("Expected %+v to be invalid", hostConfig)
+		}
+	}
+
+	unsupported := []*runconfig.HostConfig{
+		{MemoryReservation: 524288},
+		{OomKillDisable: true},
+		{CpuPeriod: 50000},
+		{CpuQuota: 25000},
+		{BlkioWeight: 500},
+		{BlkioDeviceWriteBps: throttles},
+	}
+	for _, hostConfig := range unsupported {
+		if err := verifyHostConfigResources(hostConfig, config, &sysinfo.SysInfo{}); err == nil {
+			t.Fatalf("Expected %+v to be refused without kernel support", hostConfig)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package daemon
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/docker/docker/engine"
@@ -47,6 +49,11 @@ func (daemon *Daemon) setHostConfig(container *Container, hostConfig *runconfig.
 		splitBind := strings.Split(bind, ":")
 		source := splitBind[0]
 
+		// named volumes are created by the volumes repository
+		if !filepath.IsAbs(source) {
+			continue
+		}
+
 		// ensure the source exists on the host
 		_, err := os.Stat(source)
 		if err != nil && os.IsNotExist(err) {
@@ -56,10 +63,30 @@ func (daemon *Daemon) setHostConfig(container *Container, hostConfig *runconfig.
 			}
 		}
 	}
+	if err := verifyHostConfigResources(hostConfig, container.Config, daemon.SystemConfig()); err != nil {
+		return err
+	}
+	if hostConfig.IPAddress != "" {
+		if ip := net.ParseIP(hostConfig.IPAddress); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("Invalid IPv4 address: %s", hostConfig.IPAddress)
+		}
+	}
+	if err := verifyNetworkPolicy(hostConfig.NetworkPolicy); err != nil {
+		return err
+	}
+	if hostConfig.LogConfig.Type != "" {
+		if err := validateLogDriver(hostConfig.LogConfig.Type); err != nil {
+			return err
+		}
+	}
 	// Register any links from the host config before starting the container
 	if err := daemon.RegisterLinks(container, hostConfig); err != nil {
 		return err
 	}
+	// the networks connected with docker network connect are kept
+	if container.hostConfig != nil {
+		hostConfig.Networks = container.hostConfig.Networks
+	}
 	container.SetHostConfig(hostConfig)
 	container.ToDisk()
 
@@ -18,6 +18,7 @@ type State struct {
 	ExitCode   int
 	StartedAt  time.Time
 	FinishedAt time.Time
+	Health     *Health `json:",omitempty"` // nil when the container has no healthcheck or is not running
 	waitChan   chan struct{}
 }
 
@@ -40,6 +41,9 @@ func (s *State) String() string {
 			return fmt.Sprintf("Restarting (%d) %s ago", s.ExitCode, units.HumanDuration(time.Now().UTC().Sub(s.FinishedAt)))
 		}
 
+		if s.Health != nil {
+			return fmt.Sprintf("Up %s (%s)", units.HumanDuration(time.Now().UTC().Sub(s.StartedAt)), s.Health.Status)
+		}
 		return fmt.Sprintf("Up %s", units.HumanDuration(time.Now().UTC().Sub(s.StartedAt)))
 	}
 
@@ -190,6 +194,12 @@ func (s *State) SetUnpaused() {
 	s.Unlock()
 }
 
+func (s *State) setHealth(health *Health) {
+	s.Lock()
+	s.Health = health
+	s.Unlock()
+}
+
 func (s *State) IsPaused() bool {
 	s.RLock()
 	res := s.Paused
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -100,3 +101,15 @@ func TestStateTimeoutWait(t *testing.T) {
 	}
 
 }
+
+func TestStateStringHealth(t *testing.T) {
+	s := NewState()
+	s.SetRunning(100)
+	if str := s.String(); strings.Contains(str, "(") {
+		t.Fatalf("Expected no health status, got %s", str)
+	}
+	s.setHealth(&Health{Status: HealthStarting})
+	if str := s.String(); !strings.HasSuffix(str, "(starting)") {
+		t.Fatalf("Expected the health status, got %s", str)
+	}
+}
@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/stats"
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/system"
+	"github.com/docker/libcontainer/cgroups"
+)
+
+const (
+	// statsInterval is the time between two samples sent to a stats client
+	statsInterval = time.Second
+
+	// clockTicksPerSecond is USER_HZ, the unit of the values in /proc/stat.
+	// It is 100 on every architecture docker supports.
+	clockTicksPerSecond = 100
+)
+
+func (daemon *Daemon) ContainerStats(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+
+	enc := json.NewEncoder(job.Stdout)
+	for container.State.IsRunning() {
+		s, err := daemon.Stats(container)
+		if err != nil {
+			if err == execdriver.ErrNotRunning {
+				break
+			}
+			return job.Error(err)
+		}
+		if err := enc.Encode(s); err != nil {
+			// the client went away
+			break
+		}
+		time.Sleep(statsInterval)
+	}
+	return engine.StatusOK
+}
+
+// Stats returns a sample of the resource usage of a running container
+func (daemon *Daemon) Stats(c *Container) (*stats.Stats, error) {
+	s, err := daemon.execDriver.Stats(c.ID)
+	if err != nil {
+		return nil, err
+	}
+	systemUsage, err := getSystemCpuUsage()
+	if err != nil {
+		return nil, err
+	}
+	memoryLimit := s.MemoryLimit
+	if memoryLimit == 0 {
+		meminfo, err := system.ReadMemInfo()
+		if err != nil {
+			return nil, err
+		}
+		memoryLimit = meminfo.MemTotal
+	}
+	return convertStats(s, systemUsage, memoryLimit), nil
+}
+
+// convertStats translates the execution driver's statistics into the
+// structures exposed through the remote API
+func convertStats(s *execdriver.ResourceStats, systemUsage uint64, memoryLimit int64) *stats.Stats {
+	out := &stats.Stats{Read: s.Read}
+	if n := s.NetworkStats; n != nil {
+		out.Network = stats.Network{
+			RxBytes:   n.RxBytes,
+			RxPackets: n.RxPackets,
+			RxErrors:  n.RxErrors,
+			RxDropped: n.RxDropped,
+			TxBytes:   n.TxBytes,
+			TxPackets: n.TxPackets,
+			TxErrors:  n.TxErrors,
+			TxDropped: n.TxDropped,
+		}
+	}
+	if cs := s.CgroupStats; cs != nil {
+		out.CpuStats = stats.CpuStats{
+			CpuUsage: stats.CpuUsage{
+				TotalUsage:        cs.CpuStats.CpuUsage.TotalUsage,
+				PercpuUsage:       cs.CpuStats.CpuUsage.PercpuUsage,
+				UsageInKernelmode: cs.CpuStats.CpuUsage.UsageInKernelmode,
+				UsageInUsermode:   cs.CpuStats.CpuUsage.UsageInUsermode,
+			},
+			SystemUsage: systemUsage,
+			ThrottlingData: stats.ThrottlingData{
+				Periods:          cs.CpuStats.ThrottlingData.Periods,
+				ThrottledPeriods: cs.CpuStats.ThrottlingData.ThrottledPeriods,
+				ThrottledTime:    cs.CpuStats.ThrottlingData.ThrottledTime,
+			},
+		}
+		out.MemoryStats = stats.MemoryStats{
+			Usage:    cs.MemoryStats.Usage,
+			MaxUsage: cs.MemoryStats.MaxUsage,
+			Stats:    cs.MemoryStats.Stats,
+			Failcnt:  cs.MemoryStats.Failcnt,
+			Limit:    uint64(memoryLimit),
+		}
+		out.BlkioStats = stats.BlkioStats{
+			IoServiceBytesRecursive: copyBlkioEntry(cs.BlkioStats.IoServiceBytesRecursive),
+			IoServicedRecursive:     copyBlkioEntry(cs.BlkioStats.IoServicedRecursive),
+			IoQueuedRecursive:       copyBlkioEntry(cs.BlkioStats.IoQueuedRecursive),
+			SectorsRecursive:        copyBlkioEntry(cs.BlkioStats.SectorsRecursive),
+		}
+	}
+	return out
+}
+
+func copyBlkioEntry(entries []cgroups.BlkioStatEntry) []stats.BlkioStatEntry {
+	out := make([]stats.BlkioStatEntry, len(entries))
+	for i, e := range entries {
+		out[i] = stats.BlkioStatEntry{
+			Major: e.Major,
+			Minor: e.Minor,
+			Op:    e.Op,
+			Value: e.Value,
+		}
+	}
+	return out
+}
+
+// getSystemCpuUsage returns the host's cumulative CPU usage in nanoseconds,
+// as the sum of the times reported on the first line of /proc/stat
+func getSystemCpuUsage() (uint64, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 || parts[0] != "cpu" {
+			continue
+		}
+		if len(parts) < 8 {
+			return 0, fmt.Errorf("invalid number of cpu fields")
+		}
+		var totalClockTicks uint64
+		for _, i := range parts[1:8] {
+			v, err := strconv.ParseUint(i, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("Unable to convert value %s to int: %s", i, err)
+			}
+			totalClockTicks += v
+		}
+		return (totalClockTicks * uint64(time.Second)) / clockTicksPerSecond, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("invalid stat format")
+}
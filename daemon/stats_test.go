@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/network"
+)
+
+func TestConvertStats(t *testing.T) {
+	read := time.Now()
+	s := &execdriver.ResourceStats{
+		ContainerStats: &libcontainer.ContainerStats{
+			NetworkStats: &network.NetworkStats{RxBytes: 1024, RxPackets: 8, TxBytes: 2048, TxPackets: 16, TxDropped: 1},
+			CgroupStats: &cgroups.Stats{
+				CpuStats: cgroups.CpuStats{
+					CpuUsage: cgroups.CpuUsage{
+						TotalUsage:        400,
+						PercpuUsage:       []uint64{100, 300},
+						UsageInKernelmode: 150,
+						UsageInUsermode:   250,
+					},
+					ThrottlingData: cgroups.ThrottlingData{Periods: 10, ThrottledPeriods: 2},
+				},
+				MemoryStats: cgroups.MemoryStats{Usage: 256, MaxUsage: 512, Failcnt: 3, Stats: map[string]uint64{"cache": 64}},
+				BlkioStats: cgroups.BlkioStats{
+					IoServiceBytesRecursive: []cgroups.BlkioStatEntry{{Major: 8, Op: "Read", Value: 4096}},
+				},
+			},
+		},
+		Read: read,
+	}
+
+	out := convertStats(s, 8000, 1024)
+	if !out.Read.Equal(read) {
+		t.Fatalf("Expected the sample to be read at %s, got %s", read, out.Read)
+	}
+	if cpu := out.CpuStats; cpu.SystemUsage != 8000 || cpu.CpuUsage.TotalUsage != 400 || len(cpu.CpuUsage.PercpuUsage) != 2 ||
+		cpu.CpuUsage.UsageInKernelmode != 150 || cpu.CpuUsage.UsageInUsermode != 250 || cpu.ThrottlingData.ThrottledPeriods != 2 {
+		t.Fatalf("Wrong CPU stats %+v", cpu)
+	}
+	if mem := out.MemoryStats; mem.Usage != 256 || mem.MaxUsage != 512 || mem.Failcnt != 3 || mem.Limit != 1024 || mem.Stats["cache"] != 64 {
+		t.Fatalf("Wrong memory stats %+v", mem)
+	}
+	if n := out.Network; n.RxBytes != 1024 || n.RxPackets != 8 || n.TxBytes != 2048 || n.TxPackets != 16 || n.TxDropped != 1 {
+		t.Fatalf("Wrong network stats %+v", n)
+	}
+	if blkio := out.BlkioStats.IoServiceBytesRecursive; len(blkio) != 1 || blkio[0].Op != "Read" || blkio[0].Value != 4096 {
+		t.Fatalf("Wrong block I/O stats %+v", blkio)
+	}
+
+	// A container without network stats has empty ones
+	s.NetworkStats = nil
+	if out := convertStats(s, 8000, 1024); out.Network.RxBytes != 0 {
+		t.Fatalf("Expected empty network stats, got %+v", out.Network)
+	}
+}
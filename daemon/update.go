@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/engine"
+)
+
+// ContainerUpdate changes the resource limits of a container. The limits
+// are applied to the running container right away and persisted in its
+// config so that they are used when it is started again.
+func (daemon *Daemon) ContainerUpdate(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := daemon.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+
+	container.Lock()
+	resources := execdriver.Resources{
+		Memory:     container.Config.Memory,
+		MemorySwap: container.Config.MemorySwap,
+		CpuShares:  container.Config.CpuShares,
+		Cpuset:     container.Config.Cpuset,
+	}
+	container.Unlock()
+
+	if job.EnvExists("Memory") {
+		resources.Memory = job.GetenvInt64("Memory")
+	}
+	if job.EnvExists("MemorySwap") {
+		resources.MemorySwap = job.GetenvInt64("MemorySwap")
+	}
+	if job.EnvExists("CpuShares") {
+		resources.CpuShares = job.GetenvInt64("CpuShares")
+	}
+	if job.EnvExists("Cpuset") {
+		resources.Cpuset = job.Getenv("Cpuset")
+	}
+	if err := verifyResources(&resources, daemon.SystemConfig()); err != nil {
+		return job.Error(err)
+	}
+
+	if err := daemon.Update(container, &resources); err != nil {
+		return job.Errorf("Cannot update container %s: %s", name, err)
+	}
+	container.LogEvent("update")
+	return engine.StatusOK
+}
+
+// Update applies resources to the container, live when it is running, and
+// saves them in its config.
+func (daemon *Daemon) Update(container *Container, resources *execdriver.Resources) error {
+	container.Lock()
+	defer container.Unlock()
+
+	if container.State.IsRunning() && container.command != nil {
+		old := container.command.Resources
+		updated := *old
+		updated.Memory = resources.Memory
+		updated.MemorySwap = resources.MemorySwap
+		updated.CpuShares = resources.CpuShares
+		updated.Cpuset = resources.Cpuset
+		container.command.Resources = &updated
+		if err := daemon.execDriver.Update(container.command); err != nil {
+			container.command.Resources = old
+			return err
+		}
+	}
+
+	container.Config.Memory = resources.Memory
+	container.Config.MemorySwap = resources.MemorySwap
+	container.Config.CpuShares = resources.CpuShares
+	container.Config.Cpuset = resources.Cpuset
+	return container.toDisk()
+}
@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/runconfig"
+)
+
+// updateDriver is an execution driver recording the resources of the
+// commands it updates
+type updateDriver struct {
+	execdriver.Driver
+	updated *execdriver.Resources
+	err     error
+}
+
+func (d *updateDriver) Update(c *execdriver.Command) error {
+	if d.err != nil {
+		return d.err
+	}
+	updated := *c.Resources
+	d.updated = &updated
+	return nil
+}
+
+func TestUpdate(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-update-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	driver := &updateDriver{}
+	daemon := &Daemon{execDriver: driver}
+	container := &Container{
+		root:   root,
+		State:  NewState(),
+		Config: &runconfig.Config{Memory: 314572800, CpuShares: 1024},
+		command: &execdriver.Command{
+			Resources: &execdriver.Resources{Memory: 314572800, CpuShares: 1024},
+		},
+	}
+	container.State.SetRunning(1)
+
+	// The limits of a running container are applied live and persisted
+	if err := daemon.Update(container, &execdriver.Resources{Memory: 524288000, CpuShares: 512}); err != nil {
+		t.Fatal(err)
+	}
+	if driver.updated == nil || driver.updated.Memory != 524288000 || driver.updated.CpuShares != 512 {
+		t.Fatalf("Expected the driver to apply the new limits, got %+v", driver.updated)
+	}
+	if container.command.Resources.Memory != 524288000 {
+		t.Fatalf("Expected the command to have the new limits, got %+v", container.command.Resources)
+	}
+	if container.Config.Memory != 524288000 || container.Config.CpuShares != 512 {
+		t.Fatalf("Expected the config to have the new limits, got %d, %d", container.Config.Memory, container.Config.CpuShares)
+	}
+	if _, err := os.Stat(container.root + "/config.json"); err != nil {
+		t.Fatalf("Expected the config to be saved: %s", err)
+	}
+
+	// The limits the driver refuses are not kept
+	driver.err = errors.New("refused")
+	if err := daemon.Update(container, &execdriver.Resources{Memory: 1048576000}); err == nil {
+		t.Fatal("Expected the error of the driver")
+	}
+	if container.command.Resources.Memory != 524288000 || container.Config.Memory != 524288000 {
+		t.Fatalf("Expected the previous limits to be kept, got %d, %d", container.command.Resources.Memory, container.Config.Memory)
+	}
+
+	// The limits of a stopped container are only persisted
+	container.State.SetStopped(0)
+	driver.updated = nil
+	driver.err = nil
+	if err := daemon.Update(container, &execdriver.Resources{Memory: 1048576000}); err != nil {
+		t.Fatal(err)
+	}
+	if driver.updated != nil {
+		t.Fatal("Expected the limits of a stopped container not to be applied")
+	}
+	if container.Config.Memory != 1048576000 {
+		t.Fatalf("Expected the config to have the new limit, got %d", container.Config.Memory)
+	}
+}
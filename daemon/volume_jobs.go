@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/volumes"
+)
+
+func volumeToEnv(vol *volumes.Volume) *engine.Env {
+	out := &engine.Env{}
+	out.Set("Id", vol.ID)
+	out.Set("Name", vol.Name)
+	out.Set("Driver", vol.Driver)
+	out.Set("Mountpoint", vol.Path)
+	out.SetInt64("Created", vol.Created.Unix())
+	out.SetList("Containers", vol.Containers())
+	return out
+}
+
+func (daemon *Daemon) VolumeList(job *engine.Job) engine.Status {
+	outs := engine.NewTable("Created", 0)
+	for _, vol := range daemon.volumes.List() {
+		outs.Add(volumeToEnv(vol))
+	}
+	outs.ReverseSort()
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+func (daemon *Daemon) VolumeCreate(job *engine.Job) engine.Status {
+	vol, err := daemon.volumes.Create(job.Getenv("Name"), job.Getenv("Driver"))
+	if err != nil {
+		return job.Error(err)
+	}
+	if _, err := volumeToEnv(vol).WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+func (daemon *Daemon) VolumeInspect(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s VOLUME", job.Name)
+	}
+	vol := daemon.volumes.Get(job.Args[0])
+	if vol == nil {
+		return job.Errorf("No such volume: %s", job.Args[0])
+	}
+	if _, err := volumeToEnv(vol).WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+func (daemon *Daemon) VolumeRm(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s VOLUME", job.Name)
+	}
+	name := job.Args[0]
+	if err := daemon.volumes.Delete(name); err != nil {
+		if err == volumes.ErrVolumeInUse {
+			return job.Errorf("Conflict, volume %s is in use", name)
+		}
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// VolumePrune removes all the volumes which are not used by any container
+func (daemon *Daemon) VolumePrune(job *engine.Job) engine.Status {
+	pruned, err := daemon.volumes.Prune()
+	deleted := make([]string, len(pruned))
+	for i, vol := range pruned {
+		deleted[i] = vol.ID
+	}
+	out := &engine.Env{}
+	out.SetList("VolumesDeleted", deleted)
+	if _, werr := out.WriteTo(job.Stdout); werr != nil && err == nil {
+		err = werr
+	}
+	if err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
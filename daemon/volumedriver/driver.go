@@ -0,0 +1,75 @@
+package volumedriver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultDriver is the driver used for the volumes which do not request one
+const DefaultDriver = "local"
+
+// InitFunc initializes a volume driver storing its data under root
+type InitFunc func(root string) (Driver, error)
+
+// Driver is the interface implemented by the volume drivers. Volumes are
+// identified by a name chosen by the daemon.
+type Driver interface {
+	// Name returns the name the driver is registered under
+	Name() string
+	// Create creates the volume name
+	Create(name string) error
+	// Remove removes the volume name along with its data
+	Remove(name string) error
+	// Path returns the path on the host where the volume is, or will be, mounted
+	Path(name string) (string, error)
+	// Mount makes the volume available on the host and returns its mountpoint
+	Mount(name string) (string, error)
+	// Unmount releases the volume once no container uses it anymore
+	Unmount(name string) error
+}
+
+var (
+	// PluginsDir is the directory in which volume plugins create their
+	// unix socket, named after the driver they implement
+	PluginsDir = "/run/docker/plugins"
+
+	ErrNotSupported = errors.New("volume driver not supported")
+
+	drivers     = make(map[string]InitFunc)
+	driversLock sync.Mutex
+)
+
+// Register makes a built-in volume driver available under name
+func Register(name string, initFunc InitFunc) error {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+	if _, exists := drivers[name]; exists {
+		return fmt.Errorf("Name already registered %s", name)
+	}
+	drivers[name] = initFunc
+	return nil
+}
+
+// GetDriver returns the volume driver registered under name. When no
+// built-in driver matches, the plugin listening on PluginsDir/name.sock is
+// used instead.
+func GetDriver(name, root string) (Driver, error) {
+	driversLock.Lock()
+	initFunc, exists := drivers[name]
+	driversLock.Unlock()
+	if exists {
+		return initFunc(root)
+	}
+
+	socket := filepath.Join(PluginsDir, name+".sock")
+	if _, err := os.Stat(socket); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotSupported
+		}
+		return nil, err
+	}
+	return NewPlugin(name, socket)
+}
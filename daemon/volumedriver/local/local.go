@@ -0,0 +1,65 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/daemon/volumedriver"
+)
+
+func init() {
+	volumedriver.Register(volumedriver.DefaultDriver, Init)
+}
+
+// Init returns the local driver, which stores volumes as plain directories
+// under root/vfs/dir, where the vfs graph driver used to keep them
+func Init(root string) (volumedriver.Driver, error) {
+	home := filepath.Join(root, "vfs", "dir")
+	if err := os.MkdirAll(home, 0700); err != nil {
+		return nil, err
+	}
+	return &Driver{home: home}, nil
+}
+
+type Driver struct {
+	home string
+}
+
+func (d *Driver) Name() string {
+	return volumedriver.DefaultDriver
+}
+
+func (d *Driver) dir(name string) string {
+	return filepath.Join(d.home, filepath.Base(name))
+}
+
+func (d *Driver) Create(name string) error {
+	return os.Mkdir(d.dir(name), 0755)
+}
+
+func (d *Driver) Remove(name string) error {
+	if _, err := os.Stat(d.dir(name)); err != nil {
+		return err
+	}
+	return os.RemoveAll(d.dir(name))
+}
+
+func (d *Driver) Path(name string) (string, error) {
+	dir := d.dir(name)
+	if st, err := os.Stat(dir); err != nil {
+		return "", err
+	} else if !st.IsDir() {
+		return "", fmt.Errorf("%s: not a directory", dir)
+	}
+	return dir, nil
+}
+
+// Mount returns the directory of the volume, which is always available
+func (d *Driver) Mount(name string) (string, error) {
+	return d.Path(name)
+}
+
+func (d *Driver) Unmount(name string) error {
+	return nil
+}
@@ -0,0 +1,40 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalDriver(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-volumedriver-local-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	d, err := Init(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Create("vol"); err != nil {
+		t.Fatal(err)
+	}
+	mountpoint, err := d.Mount("vol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(root, "vfs", "dir", "vol"); mountpoint != expected {
+		t.Fatalf("Expected mountpoint %s, got %s", expected, mountpoint)
+	}
+	if err := d.Unmount("vol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove("vol"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Path("vol"); err == nil {
+		t.Fatal("Expected an error for a removed volume")
+	}
+}
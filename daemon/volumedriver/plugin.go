@@ -0,0 +1,130 @@
+package volumedriver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	pluginContentType = "application/vnd.docker.plugins.v1+json"
+	pluginTimeout     = 30 * time.Second
+)
+
+// plugin is a volume driver implemented by an external process. The
+// daemon talks to it with JSON requests posted over a unix socket:
+//
+//	POST /Plugin.Activate       -> {"Implements": ["VolumeDriver"]}
+//	POST /VolumeDriver.Create   {"Name": "..."} -> {"Err": ""}
+//	POST /VolumeDriver.Remove   {"Name": "..."} -> {"Err": ""}
+//	POST /VolumeDriver.Path     {"Name": "..."} -> {"Mountpoint": "...", "Err": ""}
+//	POST /VolumeDriver.Mount    {"Name": "..."} -> {"Mountpoint": "...", "Err": ""}
+//	POST /VolumeDriver.Unmount  {"Name": "..."} -> {"Err": ""}
+//
+// A non empty Err fails the request.
+type plugin struct {
+	name   string
+	client *http.Client
+}
+
+type pluginRequest struct {
+	Name string
+}
+
+type pluginResponse struct {
+	Mountpoint string
+	Err        string
+}
+
+type activateResponse struct {
+	Implements []string
+}
+
+// NewPlugin connects to the volume plugin listening on socket and checks
+// that it implements the volume driver protocol
+func NewPlugin(name, socket string) (Driver, error) {
+	p := &plugin{
+		name: name,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Dial: func(string, string) (net.Conn, error) {
+					return net.DialTimeout("unix", socket, pluginTimeout)
+				},
+			},
+			Timeout: pluginTimeout,
+		},
+	}
+
+	var resp activateResponse
+	if err := p.call("Plugin.Activate", nil, &resp); err != nil {
+		return nil, fmt.Errorf("Error activating volume plugin %s: %s", name, err)
+	}
+	for _, i := range resp.Implements {
+		if i == "VolumeDriver" {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("Plugin %s does not implement the VolumeDriver protocol", name)
+}
+
+func (p *plugin) call(method string, args interface{}, ret interface{}) error {
+	body := &bytes.Buffer{}
+	if args != nil {
+		if err := json.NewEncoder(body).Encode(args); err != nil {
+			return err
+		}
+	}
+	// the host is ignored as requests are sent over the unix socket
+	resp, err := p.client.Post("http://plugin/"+method, pluginContentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s (%d)", method, bytes.TrimSpace(msg), resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(ret)
+}
+
+func (p *plugin) volumeCall(method, name string) (string, error) {
+	var resp pluginResponse
+	if err := p.call("VolumeDriver."+method, &pluginRequest{Name: name}, &resp); err != nil {
+		return "", fmt.Errorf("Volume plugin %s: %s", p.name, err)
+	}
+	if resp.Err != "" {
+		return "", fmt.Errorf("Volume plugin %s: %s", p.name, resp.Err)
+	}
+	return resp.Mountpoint, nil
+}
+
+func (p *plugin) Name() string {
+	return p.name
+}
+
+func (p *plugin) Create(name string) error {
+	_, err := p.volumeCall("Create", name)
+	return err
+}
+
+func (p *plugin) Remove(name string) error {
+	_, err := p.volumeCall("Remove", name)
+	return err
+}
+
+func (p *plugin) Path(name string) (string, error) {
+	return p.volumeCall("Path", name)
+}
+
+func (p *plugin) Mount(name string) (string, error) {
+	return p.volumeCall("Mount", name)
+}
+
+func (p *plugin) Unmount(name string) error {
+	_, err := p.volumeCall("Unmount", name)
+	return err
+}
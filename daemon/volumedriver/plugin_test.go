@@ -0,0 +1,157 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// stubPlugin is a volume plugin keeping its volumes in memory
+type stubPlugin struct {
+	sync.Mutex
+	volumes map[string]int // name -> number of mounts
+	calls   []string
+}
+
+func (s *stubPlugin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	method := r.URL.Path[1:]
+	s.calls = append(s.calls, method)
+	w.Header().Set("Content-Type", pluginContentType)
+
+	if method == "Plugin.Activate" {
+		json.NewEncoder(w).Encode(map[string][]string{"Implements": {"VolumeDriver"}})
+		return
+	}
+
+	var req pluginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var resp pluginResponse
+	mounts, exists := s.volumes[req.Name]
+	switch method {
+	case "VolumeDriver.Create":
+		if exists {
+			resp.Err = "volume already exists"
+		} else {
+			s.volumes[req.Name] = 0
+		}
+	case "VolumeDriver.Remove", "VolumeDriver.Path", "VolumeDriver.Mount", "VolumeDriver.Unmount":
+		if !exists {
+			resp.Err = "no such volume"
+			break
+		}
+		switch method {
+		case "VolumeDriver.Remove":
+			delete(s.volumes, req.Name)
+		case "VolumeDriver.Mount":
+			s.volumes[req.Name] = mounts + 1
+			resp.Mountpoint = "/mnt/stub/" + req.Name
+		case "VolumeDriver.Unmount":
+			s.volumes[req.Name] = mounts - 1
+		case "VolumeDriver.Path":
+			resp.Mountpoint = "/mnt/stub/" + req.Name
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// startStubPlugin serves a stub plugin named stub in a temporary plugins
+// directory and points PluginsDir at it
+func startStubPlugin(t *testing.T, handler http.Handler) func() {
+	dir, err := ioutil.TempDir("", "docker-plugins-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", filepath.Join(dir, "stub.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	go http.Serve(l, handler)
+
+	oldPluginsDir := PluginsDir
+	PluginsDir = dir
+	return func() {
+		PluginsDir = oldPluginsDir
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPluginDriver(t *testing.T) {
+	stub := &stubPlugin{volumes: make(map[string]int)}
+	defer startStubPlugin(t, stub)()
+
+	d, err := GetDriver("stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name() != "stub" {
+		t.Fatalf("Expected driver stub, got %s", d.Name())
+	}
+
+	if err := d.Create("vol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Create("vol"); err == nil {
+		t.Fatal("Expected the error of the plugin to be returned")
+	}
+
+	mountpoint, err := d.Mount("vol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mountpoint != "/mnt/stub/vol" {
+		t.Fatalf("Expected mountpoint /mnt/stub/vol, got %s", mountpoint)
+	}
+	if pth, err := d.Path("vol"); err != nil || pth != mountpoint {
+		t.Fatalf("Expected path %s, got %s (%v)", mountpoint, pth, err)
+	}
+	if stub.volumes["vol"] != 1 {
+		t.Fatalf("Expected the volume to be mounted once, got %d", stub.volumes["vol"])
+	}
+	if err := d.Unmount("vol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove("vol"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := stub.volumes["vol"]; exists {
+		t.Fatal("Expected the volume to be removed")
+	}
+	if _, err := d.Mount("vol"); err == nil {
+		t.Fatal("Expected an error when mounting a removed volume")
+	}
+}
+
+func TestPluginNotImplementingVolumeDriver(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string][]string{"Implements": {"NetworkDriver"}})
+	})
+	defer startStubPlugin(t, handler)()
+
+	if _, err := GetDriver("stub", ""); err == nil {
+		t.Fatal("Expected an error for a plugin not implementing VolumeDriver")
+	}
+}
+
+func TestGetUnknownDriver(t *testing.T) {
+	defer startStubPlugin(t, http.NotFoundHandler())()
+
+	if _, err := GetDriver("unknown", ""); err != ErrNotSupported {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+}
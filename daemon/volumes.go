@@ -10,13 +10,16 @@ import (
 
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/log"
 	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/docker/volumes"
 )
 
 type Volume struct {
 	HostPath    string
 	VolPath     string
 	Mode        string
+	Name        string // name of the volume for named volumes
 	isBindMount bool
 }
 
@@ -45,9 +48,63 @@ func prepareVolumesForContainer(container *Container) error {
 	if err := createVolumes(container); err != nil {
 		return err
 	}
+	// volumes created by a previous start, or shared with --volumes-from,
+	// need to be mounted again
+	for _, pth := range container.Volumes {
+		if vol := container.daemon.volumes.GetByPath(pth); vol != nil {
+			if err := container.mountVolume(vol); err != nil {
+				return err
+			}
+		}
+	}
+	container.daemon.registerVolumes(container)
 	return nil
 }
 
+// mountVolume asks the driver of vol to mount it for the container, unless
+// the container already mounted it
+func (container *Container) mountVolume(vol *volumes.Volume) error {
+	if _, exists := container.mountedVolumes[vol.ID]; exists {
+		return nil
+	}
+	if err := container.daemon.volumes.Mount(vol); err != nil {
+		return err
+	}
+	if container.mountedVolumes == nil {
+		container.mountedVolumes = make(map[string]*volumes.Volume)
+	}
+	container.mountedVolumes[vol.ID] = vol
+	return nil
+}
+
+// unmountVolumes releases the volumes mounted for the container
+func (container *Container) unmountVolumes() {
+	for id, vol := range container.mountedVolumes {
+		if err := container.daemon.volumes.Unmount(vol); err != nil {
+			log.Errorf("%v: Failed to unmount volume %s: %v", container.ID, id, err)
+		}
+	}
+	container.mountedVolumes = nil
+}
+
+// registerVolumes records the container as a user of the volumes it mounts
+func (daemon *Daemon) registerVolumes(container *Container) {
+	for _, pth := range container.Volumes {
+		if vol := daemon.volumes.GetByPath(pth); vol != nil {
+			vol.AddContainer(container.ID)
+		}
+	}
+}
+
+// unregisterVolumes releases the volumes used by the container
+func (daemon *Daemon) unregisterVolumes(container *Container) {
+	for _, pth := range container.Volumes {
+		if vol := daemon.volumes.GetByPath(pth); vol != nil {
+			vol.RemoveContainer(container.ID)
+		}
+	}
+}
+
 func setupMountsForContainer(container *Container) error {
 	mounts := []execdriver.Mount{
 		{container.ResolvConfPath, "/etc/resolv.conf", true, true},
@@ -159,6 +216,14 @@ func parseBindVolumeSpec(spec string) (Volume, error) {
 		return vol, fmt.Errorf("Invalid volume specification: %s", spec)
 	}
 
+	// a host path which is not a path is the name of a volume
+	if vol.HostPath != "" && !strings.Contains(vol.HostPath, "/") && volumes.ValidateName(vol.HostPath) == nil {
+		vol.Name = vol.HostPath
+		vol.HostPath = ""
+		vol.isBindMount = false
+		return vol, nil
+	}
+
 	if !filepath.IsAbs(vol.HostPath) {
 		return vol, fmt.Errorf("cannot bind mount volume: %s volume paths must be absolute.", vol.HostPath)
 	}
@@ -219,22 +284,27 @@ func createVolumes(container *Container) error {
 
 }
 
-func createVolumeHostPath(container *Container) (string, error) {
-	volumesDriver := container.daemon.volumes.Driver()
-
-	// Do not pass a container as the parameter for the volume creation.
-	// The graph driver using the container's information ( Image ) to
-	// create the parent.
-	c, err := container.daemon.volumes.Create(nil, "", "", "", "", nil, nil)
+// createVolumeHostPath returns the path on the host of the volume named
+// name, creating the volume with the volume driver of the container if
+// needed. An anonymous volume is created when name is empty.
+func createVolumeHostPath(container *Container, name string) (string, error) {
+	var (
+		vol    *volumes.Volume
+		err    error
+		driver = container.hostConfig.VolumeDriver
+	)
+	if name == "" {
+		vol, err = container.daemon.volumes.Create("", driver)
+	} else {
+		vol, err = container.daemon.volumes.FindOrCreate(name, driver)
+	}
 	if err != nil {
 		return "", err
 	}
-	hostPath, err := volumesDriver.Get(c.ID, "")
-	if err != nil {
-		return hostPath, fmt.Errorf("Driver %s failed to get volume rootfs %s: %s", volumesDriver, c.ID, err)
+	if err := container.mountVolume(vol); err != nil {
+		return "", err
 	}
-
-	return hostPath, nil
+	return vol.Path, nil
 }
 
 func (v *Volume) initialize(container *Container) error {
@@ -248,7 +318,7 @@ func (v *Volume) initialize(container *Container) error {
 
 	// If it's not a bindmount we need to create the dir on the host
 	if !v.isBindMount {
-		v.HostPath, err = createVolumeHostPath(container)
+		v.HostPath, err = createVolumeHostPath(container, v.Name)
 		if err != nil {
 			return err
 		}
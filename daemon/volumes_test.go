@@ -0,0 +1,27 @@
+package daemon
+
+import "testing"
+
+func TestParseBindVolumeSpec(t *testing.T) {
+	vol, err := parseBindVolumeSpec("/host:/container:ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vol.isBindMount || vol.HostPath != "/host" || vol.VolPath != "/container" || vol.Mode != "ro" {
+		t.Fatalf("Unexpected bind mount: %#v", vol)
+	}
+
+	vol, err = parseBindVolumeSpec("data:/container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vol.isBindMount || vol.Name != "data" || vol.HostPath != "" || vol.VolPath != "/container" || !vol.isRw() {
+		t.Fatalf("Unexpected named volume: %#v", vol)
+	}
+
+	for _, spec := range []string{"./data:/container", "d:/container", "-data:/container"} {
+		if _, err := parseBindVolumeSpec(spec); err == nil {
+			t.Fatalf("Expected an error for %s", spec)
+		}
+	}
+}
@@ -6,22 +6,30 @@ import (
 	"time"
 
 	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/parsers/filters"
 	"github.com/docker/docker/utils"
 )
 
 const eventsLimit = 64
 
-type listener chan<- *utils.JSONMessage
+// event is a message sent to the subscribers along with the labels of the
+// object it is about, which are only used for filtering
+type event struct {
+	*utils.JSONMessage
+	labels map[string]string
+}
+
+type listener chan<- *event
 
 type Events struct {
 	mu          sync.RWMutex
-	events      []*utils.JSONMessage
+	events      []*event
 	subscribers []listener
 }
 
 func New() *Events {
 	return &Events{
-		events: make([]*utils.JSONMessage, 0, eventsLimit),
+		events: make([]*event, 0, eventsLimit),
 	}
 }
 
@@ -48,12 +56,17 @@ func (e *Events) Get(job *engine.Job) engine.Status {
 		timeout = time.NewTimer(time.Unix(until, 0).Sub(time.Now()))
 	)
 
+	eventFilters, err := filters.FromParam(job.Getenv("filters"))
+	if err != nil {
+		return job.Error(err)
+	}
+
 	// If no until, disable timeout
 	if until == 0 {
 		timeout.Stop()
 	}
 
-	listener := make(chan *utils.JSONMessage)
+	listener := make(chan *event)
 	e.subscribe(listener)
 	defer e.unsubscribe(listener)
 
@@ -61,7 +74,7 @@ func (e *Events) Get(job *engine.Job) engine.Status {
 
 	// Resend every event in the [since, until] time interval.
 	if since != 0 {
-		if err := e.writeCurrent(job, since, until); err != nil {
+		if err := e.writeCurrent(job, since, until, eventFilters); err != nil {
 			return job.Error(err)
 		}
 	}
@@ -72,6 +85,9 @@ func (e *Events) Get(job *engine.Job) engine.Status {
 			if !ok {
 				return engine.StatusOK
 			}
+			if !event.matches(eventFilters) {
+				continue
+			}
 			if err := writeEvent(job, event); err != nil {
 				return job.Error(err)
 			}
@@ -81,12 +97,16 @@ func (e *Events) Get(job *engine.Job) engine.Status {
 	}
 }
 
+// Log records an event. The labels of the object the event is about can be
+// given in the Labels environment variable.
 func (e *Events) Log(job *engine.Job) engine.Status {
 	if len(job.Args) != 3 {
 		return job.Errorf("usage: %s ACTION ID FROM", job.Name)
 	}
+	var labels map[string]string
+	job.GetenvJson("Labels", &labels)
 	// not waiting for receivers
-	go e.log(job.Args[0], job.Args[1], job.Args[2])
+	go e.log(job.Args[0], job.Args[1], job.Args[2], labels)
 	return engine.StatusOK
 }
 
@@ -97,10 +117,10 @@ func (e *Events) SubscribersCount(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
-func writeEvent(job *engine.Job, event *utils.JSONMessage) error {
+func writeEvent(job *engine.Job, event *event) error {
 	// When sending an event JSON serialization errors are ignored, but all
 	// other errors lead to the eviction of the listener.
-	if b, err := json.Marshal(event); err == nil {
+	if b, err := json.Marshal(event.JSONMessage); err == nil {
 		if _, err = job.Stdout.Write(b); err != nil {
 			return err
 		}
@@ -108,10 +128,15 @@ func writeEvent(job *engine.Job, event *utils.JSONMessage) error {
 	return nil
 }
 
-func (e *Events) writeCurrent(job *engine.Job, since, until int64) error {
+// matches returns true if the event is accepted by the filters
+func (ev *event) matches(eventFilters filters.Args) bool {
+	return eventFilters.MatchKVList("label", ev.labels)
+}
+
+func (e *Events) writeCurrent(job *engine.Job, since, until int64, eventFilters filters.Args) error {
 	e.mu.RLock()
 	for _, event := range e.events {
-		if event.Time >= since && (event.Time <= until || until == 0) {
+		if event.Time >= since && (event.Time <= until || until == 0) && event.matches(eventFilters) {
 			if err := writeEvent(job, event); err != nil {
 				e.mu.RUnlock()
 				return err
@@ -129,10 +154,13 @@ func (e *Events) subscribersCount() int {
 	return c
 }
 
-func (e *Events) log(action, id, from string) {
+func (e *Events) log(action, id, from string, labels map[string]string) {
 	e.mu.Lock()
 	now := time.Now().UTC().Unix()
-	jm := &utils.JSONMessage{Status: action, ID: id, From: from, Time: now}
+	jm := &event{
+		JSONMessage: &utils.JSONMessage{Status: action, ID: id, From: from, Time: now},
+		labels:      labels,
+	}
 	if len(e.events) == cap(e.events) {
 		// discard oldest event
 		copy(e.events, e.events[1:])
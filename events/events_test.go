@@ -14,15 +14,15 @@ import (
 
 func TestEventsPublish(t *testing.T) {
 	e := New()
-	l1 := make(chan *utils.JSONMessage)
-	l2 := make(chan *utils.JSONMessage)
+	l1 := make(chan *event)
+	l2 := make(chan *event)
 	e.subscribe(l1)
 	e.subscribe(l2)
 	count := e.subscribersCount()
 	if count != 2 {
 		t.Fatalf("Must be 2 subscribers, got %d", count)
 	}
-	go e.log("test", "cont", "image")
+	go e.log("test", "cont", "image", nil)
 	select {
 	case msg := <-l1:
 		if len(e.events) != 1 {
@@ -61,12 +61,12 @@ func TestEventsPublish(t *testing.T) {
 
 func TestEventsPublishTimeout(t *testing.T) {
 	e := New()
-	l := make(chan *utils.JSONMessage)
+	l := make(chan *event)
 	e.subscribe(l)
 
 	c := make(chan struct{})
 	go func() {
-		e.log("test", "cont", "image")
+		e.log("test", "cont", "image", nil)
 		close(c)
 	}()
 
@@ -138,8 +138,8 @@ func TestEventsCountJob(t *testing.T) {
 	if err := e.Install(eng); err != nil {
 		t.Fatal(err)
 	}
-	l1 := make(chan *utils.JSONMessage)
-	l2 := make(chan *utils.JSONMessage)
+	l1 := make(chan *event)
+	l2 := make(chan *event)
 	e.subscribe(l1)
 	e.subscribe(l2)
 	job := eng.Job("subscribers_count")
@@ -152,3 +152,40 @@ func TestEventsCountJob(t *testing.T) {
 		t.Fatalf("There must be 2 subscribers, got %d", count)
 	}
 }
+
+func TestEventsFilterLabels(t *testing.T) {
+	e := New()
+	eng := engine.New()
+	if err := e.Install(eng); err != nil {
+		t.Fatal(err)
+	}
+
+	e.log("start", "cont_1", "image", map[string]string{"owner": "web"})
+	e.log("start", "cont_2", "image", map[string]string{"owner": "infra"})
+	e.log("start", "cont_3", "image", nil)
+
+	job := eng.Job("events")
+	job.SetenvInt64("since", 1)
+	job.SetenvInt64("until", time.Now().Unix())
+	job.Setenv("filters", `{"label":["owner=web"]}`)
+	buf := bytes.NewBuffer(nil)
+	job.Stdout.Add(buf)
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewBuffer(buf.Bytes()))
+	var msgs []utils.JSONMessage
+	for {
+		var jm utils.JSONMessage
+		if err := dec.Decode(&jm); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		msgs = append(msgs, jm)
+	}
+	if len(msgs) != 1 || msgs[0].ID != "cont_1" {
+		t.Fatalf("Expected only the event of cont_1, got %v", msgs)
+	}
+}
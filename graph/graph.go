@@ -27,6 +27,7 @@ type Graph struct {
 	Root    string
 	idIndex *truncindex.TruncIndex
 	driver  graphdriver.Driver
+	layers  *layerStore
 }
 
 // NewGraph instantiates a new graph at the given root path in the filesystem.
@@ -45,6 +46,7 @@ func NewGraph(root string, driver graphdriver.Driver) (*Graph, error) {
 		Root:    abspath,
 		idIndex: truncindex.NewTruncIndex([]string{}),
 		driver:  driver,
+		layers:  newLayerStore(),
 	}
 	if err := graph.restore(); err != nil {
 		return nil, err
@@ -60,12 +62,18 @@ func (graph *Graph) restore() error {
 	var ids = []string{}
 	for _, v := range dir {
 		id := v.Name()
-		if graph.driver.Exists(id) {
+		img, err := image.LoadImage(graph.ImageRoot(id))
+		if err != nil {
+			continue
+		}
+		if graph.driver.Exists(img.Layer) {
 			ids = append(ids, id)
+			graph.layers.add(img)
 		}
 	}
 	graph.idIndex = truncindex.NewTruncIndex(ids)
 	log.Debugf("Restored %d elements", len(dir))
+	graph.migrateLayers(ids)
 	return nil
 }
 
@@ -101,11 +109,11 @@ func (graph *Graph) Get(name string) (*image.Image, error) {
 	img.SetGraph(graph)
 
 	if img.Size < 0 {
-		rootfs, err := graph.driver.Get(img.ID, "")
+		rootfs, err := graph.driver.Get(img.Layer, "")
 		if err != nil {
 			return nil, fmt.Errorf("Driver %s failed to get image rootfs %s: %s", graph.driver, img.ID, err)
 		}
-		defer graph.driver.Put(img.ID)
+		defer graph.driver.Put(img.Layer)
 
 		var size int64
 		if img.Parent == "" {
@@ -113,10 +121,15 @@ func (graph *Graph) Get(name string) (*image.Image, error) {
 				return nil, err
 			}
 		} else {
-			parentFs, err := graph.driver.Get(img.Parent, "")
+			parent, err := graph.Get(img.Parent)
+			if err != nil {
+				return nil, err
+			}
+			parentFs, err := graph.driver.Get(parent.Layer, "")
 			if err != nil {
 				return nil, err
 			}
+			defer graph.driver.Put(parent.Layer)
 			changes, err := archive.ChangesDirs(rootfs, parentFs)
 			if err != nil {
 				return nil, err
@@ -145,6 +158,10 @@ func (graph *Graph) Create(layerData archive.ArchiveReader, containerID, contain
 		OS:            runtime.GOOS,
 	}
 
+	if config != nil {
+		img.Labels = config.Labels
+	}
+
 	if containerID != "" {
 		img.Parent = containerImage
 		img.Container = containerID
@@ -158,16 +175,10 @@ func (graph *Graph) Create(layerData archive.ArchiveReader, containerID, contain
 }
 
 // Register imports a pre-existing image into the graph.
+// The layer of the image is only created in the driver when no other image
+// of the graph has a layer of the same chain id.
 // FIXME: pass img as first argument
 func (graph *Graph) Register(jsonData []byte, layerData archive.ArchiveReader, img *image.Image) (err error) {
-	defer func() {
-		// If any error occurs, remove the new dir from the driver.
-		// Don't check for errors since the dir might not have been created.
-		// FIXME: this leaves a possible race condition.
-		if err != nil {
-			graph.driver.Remove(img.ID)
-		}
-	}()
 	if err := utils.ValidateID(img.ID); err != nil {
 		return err
 	}
@@ -187,26 +198,75 @@ func (graph *Graph) Register(jsonData []byte, layerData archive.ArchiveReader, i
 	// (the graph is the source of truth).
 	// Ignore errors, since we don't know if the driver correctly returns ErrNotExist.
 	// (FIXME: make that mandatory for drivers).
-	graph.driver.Remove(img.ID)
+	// The layer of a former image of this ID may be shared with other images.
+	graph.layers.Lock()
+	if !graph.layers.used(img.ID) {
+		graph.driver.Remove(img.ID)
+	}
+	graph.layers.Unlock()
 
 	tmp, err := graph.Mktemp("")
 	defer os.RemoveAll(tmp)
 	if err != nil {
 		return fmt.Errorf("Mktemp failed: %s", err)
 	}
+	img.SetGraph(graph)
 
-	// Create root filesystem in the driver
-	if err := graph.driver.Create(img.ID, img.Parent); err != nil {
-		return fmt.Errorf("Driver %s failed to create image rootfs %s: %s", graph.driver, img.ID, err)
+	var parentChain, parentLayer string
+	if img.Parent != "" {
+		parent, err := graph.Get(img.Parent)
+		if err != nil {
+			return err
+		}
+		if parent.ChainID == "" {
+			if err := graph.migrateLayer(parent); err != nil {
+				return err
+			}
+		}
+		parentChain, parentLayer = parent.ChainID, parent.Layer
 	}
-	// Mount the root filesystem so we can apply the diff/layer
-	rootfs, err := graph.driver.Get(img.ID, "")
+
+	layerFile, digest, err := spoolLayer(layerData, tmp)
 	if err != nil {
-		return fmt.Errorf("Driver %s failed to get image rootfs %s: %s", graph.driver, img.ID, err)
+		return err
 	}
-	defer graph.driver.Put(img.ID)
-	img.SetGraph(graph)
-	if err := image.StoreImage(img, jsonData, layerData, tmp, rootfs); err != nil {
+	img.LayerDigest = digest
+	img.ChainID = chainID(parentChain, digest)
+
+	// The lock is only held to look the chain id up and to reserve it, so
+	// that the layers of different chains are extracted in parallel
+	layer, shared := graph.layers.reserve(img.ChainID)
+	if shared {
+		log.Debugf("Image %s shares the layer %s", utils.TruncateID(img.ID), utils.TruncateID(layer))
+		// The size is computed on the first Get of the image
+		img.Layer = layer
+		img.Size = -1
+	} else {
+		img.Layer = img.ChainID
+		if err := graph.createLayer(img, parentLayer, layerFile); err != nil {
+			graph.layers.Lock()
+			graph.layers.release(img.ChainID)
+			graph.layers.Unlock()
+			return err
+		}
+	}
+	defer func() {
+		// If any error occurs, give the layer up, removing it from the
+		// driver when no other image uses it
+		if err == nil {
+			return
+		}
+		graph.layers.Lock()
+		defer graph.layers.Unlock()
+		if !shared {
+			graph.layers.release(img.ChainID)
+			graph.driver.Remove(img.Layer)
+		} else if graph.layers.remove(img) {
+			graph.driver.Remove(img.Layer)
+		}
+	}()
+
+	if err := image.StoreImage(img, jsonData, tmp); err != nil {
 		return err
 	}
 	// Commit
@@ -214,6 +274,16 @@ func (graph *Graph) Register(jsonData []byte, layerData archive.ArchiveReader, i
 		return err
 	}
 	graph.idIndex.Add(img.ID)
+
+	graph.layers.Lock()
+	if shared {
+		// The image was counted by reserve
+		graph.layers.index(img)
+	} else {
+		graph.layers.add(img)
+		graph.layers.release(img.ChainID)
+	}
+	graph.layers.Unlock()
 	return nil
 }
 
@@ -321,22 +391,32 @@ func isNotEmpty(err error) bool {
 }
 
 // Delete atomically removes an image from the graph.
+// The layer of the image is removed from the driver with its last image.
 func (graph *Graph) Delete(name string) error {
 	id, err := graph.idIndex.Get(name)
 	if err != nil {
 		return err
 	}
+	img, err := image.LoadImage(graph.ImageRoot(id))
+	if err != nil {
+		// Remove the layer of the image id of a broken image
+		img = &image.Image{ID: id, Layer: id}
+	}
 	tmp, err := graph.Mktemp("")
 	if err != nil {
 		return err
 	}
 	graph.idIndex.Delete(id)
-	err = os.Rename(graph.ImageRoot(id), tmp)
+	err = os.Rename(graph.ImageRoot(id), path.Join(tmp, id))
 	if err != nil {
 		return err
 	}
 	// Remove rootfs data from the driver
-	graph.driver.Remove(id)
+	graph.layers.Lock()
+	if graph.layers.remove(img) {
+		graph.driver.Remove(img.Layer)
+	}
+	graph.layers.Unlock()
 	// Remove the trashed image directory
 	return os.RemoveAll(tmp)
 }
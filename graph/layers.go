@@ -0,0 +1,264 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/pkg/tarsum"
+	"github.com/docker/docker/utils"
+)
+
+// layerStore addresses the layers of the images by their content. The chain
+// id of a layer is the digest of its tarsum and of the chain id of its parent,
+// so that the images of the same chain, pulled from several repositories or
+// imported several times, share their layer in the driver. The layers stored
+// before have the id of their image in the driver.
+type layerStore struct {
+	// Protects the layers from concurrent registrations and deletions
+	sync.Mutex
+	chains   map[string]string        // layer in the driver of each chain id
+	refs     map[string]int           // number of images of each layer in the driver
+	creating map[string]chan struct{} // chain ids of the layers being created, closed once done
+}
+
+func newLayerStore() *layerStore {
+	return &layerStore{
+		chains:   make(map[string]string),
+		refs:     make(map[string]int),
+		creating: make(map[string]chan struct{}),
+	}
+}
+
+// chainID returns the chain id of the layer of tarsum digest on top of the
+// layer of chain id parent, which is empty for a base layer
+func chainID(parent, digest string) string {
+	h := sha256.New()
+	h.Write([]byte(parent + " " + digest))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// reserve returns the layer in the driver of the chain id, counted as the
+// layer of one more image, or reserves the chain id for the caller to create
+// its layer and returns false. The layers are created without holding the
+// lock, so a registration of the same chain id in the meantime waits for
+// the reservation to be released. It takes the lock itself.
+func (s *layerStore) reserve(chainID string) (string, bool) {
+	s.Lock()
+	defer s.Unlock()
+	for {
+		if layer, exists := s.chains[chainID]; exists {
+			s.refs[layer]++
+			return layer, true
+		}
+		creating, busy := s.creating[chainID]
+		if !busy {
+			s.creating[chainID] = make(chan struct{})
+			return "", false
+		}
+		s.Unlock()
+		<-creating
+		s.Lock()
+	}
+}
+
+// release ends the reservation of the chain id, once its layer is added or
+// given up
+func (s *layerStore) release(chainID string) {
+	if creating, exists := s.creating[chainID]; exists {
+		close(creating)
+		delete(s.creating, chainID)
+	}
+}
+
+// used returns true if the layer of the driver is the layer of images
+func (s *layerStore) used(layer string) bool {
+	return s.refs[layer] > 0
+}
+
+// add counts img as an image of its layer
+func (s *layerStore) add(img *image.Image) {
+	s.refs[img.Layer]++
+	s.index(img)
+}
+
+// index addresses the layer of img by its chain id
+func (s *layerStore) index(img *image.Image) {
+	if img.ChainID == "" {
+		return
+	}
+	// A layer of the chain id in the driver is preferred to the layer of a
+	// former image of the same content
+	if _, exists := s.chains[img.ChainID]; !exists || img.Layer == img.ChainID {
+		s.chains[img.ChainID] = img.Layer
+	}
+}
+
+// remove stops counting img as an image of its layer, and returns true if
+// the layer has no image left
+func (s *layerStore) remove(img *image.Image) bool {
+	if s.refs[img.Layer]--; s.refs[img.Layer] > 0 {
+		return false
+	}
+	delete(s.refs, img.Layer)
+	if s.chains[img.ChainID] == img.Layer {
+		delete(s.chains, img.ChainID)
+	}
+	return true
+}
+
+// spoolLayer writes the uncompressed layerData to a file in dir, returning
+// the path of the file and the tarsum of the layer
+func spoolLayer(layerData archive.ArchiveReader, dir string) (string, string, error) {
+	var layer io.Reader = bytes.NewReader(nil)
+	if layerData != nil {
+		decompressed, err := archive.DecompressStream(layerData)
+		if err != nil {
+			return "", "", err
+		}
+		defer decompressed.Close()
+		layer = decompressed
+	}
+
+	f, err := os.Create(path.Join(dir, "layer.tar"))
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	ts := &tarsum.TarSum{Reader: layer, DisableCompression: true}
+	if _, err := io.Copy(f, ts); err != nil {
+		return "", "", err
+	}
+	return f.Name(), ts.Sum(nil), nil
+}
+
+// createLayer creates the layer of img in the driver on top of the layer
+// parent, and applies the layer of the file layerFile to it
+func (graph *Graph) createLayer(img *image.Image, parent, layerFile string) (err error) {
+	if err := graph.driver.Create(img.Layer, parent); err != nil {
+		return fmt.Errorf("Driver %s failed to create image rootfs %s: %s", graph.driver, img.ID, err)
+	}
+	defer func() {
+		if err != nil {
+			graph.driver.Remove(img.Layer)
+		}
+	}()
+
+	layerData, err := os.Open(layerFile)
+	if err != nil {
+		return err
+	}
+	defer layerData.Close()
+
+	if differ, ok := graph.driver.(graphdriver.Differ); ok {
+		if err := differ.ApplyDiff(img.Layer, layerData); err != nil {
+			return err
+		}
+		img.Size, err = differ.DiffSize(img.Layer)
+		return err
+	}
+
+	// Mount the root filesystem so we can apply the diff/layer
+	rootfs, err := graph.driver.Get(img.Layer, "")
+	if err != nil {
+		return fmt.Errorf("Driver %s failed to get image rootfs %s: %s", graph.driver, img.ID, err)
+	}
+	defer graph.driver.Put(img.Layer)
+
+	if err := archive.ApplyLayer(rootfs, layerData); err != nil {
+		return err
+	}
+	if parent == "" {
+		img.Size, err = utils.TreeSize(rootfs)
+		return err
+	}
+	parentFs, err := graph.driver.Get(parent, "")
+	if err != nil {
+		return err
+	}
+	defer graph.driver.Put(parent)
+	changes, err := archive.ChangesDirs(rootfs, parentFs)
+	if err != nil {
+		return err
+	}
+	img.Size = archive.ChangesSize(rootfs, changes)
+	return nil
+}
+
+// migrateLayers computes the chain ids of the images of the graph stored
+// before the layers were addressed by their content
+func (graph *Graph) migrateLayers(ids []string) {
+	var migrated int
+	for _, id := range ids {
+		img, err := graph.Get(id)
+		if err != nil || img.ChainID != "" {
+			continue
+		}
+		if migrated == 0 {
+			log.Infof("Computing the layer digests of the images of %s", graph.Root)
+		}
+		if err := graph.migrateLayer(img); err != nil {
+			log.Errorf("Error computing the layer digest of %s: %s", utils.TruncateID(id), err)
+			continue
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Infof("Computed the layer digests of %d images", migrated)
+	}
+}
+
+// migrateLayer computes the digest and the chain id of the layer of img,
+// migrating its parents first, and stores them with img. The layer of img
+// keeps its id in the driver.
+func (graph *Graph) migrateLayer(img *image.Image) error {
+	var parentChain string
+	if img.Parent != "" {
+		parent, err := graph.Get(img.Parent)
+		if err != nil {
+			return err
+		}
+		if parent.ChainID == "" {
+			if err := graph.migrateLayer(parent); err != nil {
+				return err
+			}
+		}
+		parentChain = parent.ChainID
+	}
+
+	layer, err := img.TarLayer()
+	if err != nil {
+		return err
+	}
+	defer layer.Close()
+	ts := &tarsum.TarSum{Reader: layer, DisableCompression: true}
+	if _, err := io.Copy(ioutil.Discard, ts); err != nil {
+		return err
+	}
+
+	jsonData, err := img.RawJson()
+	if err != nil {
+		return err
+	}
+	img.LayerDigest = ts.Sum(nil)
+	img.ChainID = chainID(parentChain, img.LayerDigest)
+	if err := image.StoreImage(img, jsonData, graph.ImageRoot(img.ID)); err != nil {
+		return err
+	}
+
+	graph.layers.Lock()
+	graph.layers.index(img)
+	graph.layers.Unlock()
+	return nil
+}
@@ -0,0 +1,256 @@
+package graph
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/utils"
+)
+
+func mkTestGraph(root string, t *testing.T) *Graph {
+	driver, err := graphdriver.New(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := NewGraph(root, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+func registerFakeImage(graph *Graph, id, parent string, t *testing.T) *image.Image {
+	archive, err := fakeTar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graph.Register(nil, archive, &image.Image{ID: id, Parent: parent}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := graph.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestRegisterSharesLayers(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	graph := mkTestGraph(tmp, t)
+	defer graph.driver.Cleanup()
+
+	first := registerFakeImage(graph, "first", "", t)
+	second := registerFakeImage(graph, "second", "", t)
+	if !strings.HasPrefix(first.LayerDigest, "tarsum+sha256:") {
+		t.Fatalf("Expected a tarsum as layer digest, got %q", first.LayerDigest)
+	}
+	if first.ChainID != chainID("", first.LayerDigest) || first.Layer != first.ChainID {
+		t.Fatalf("Expected the layer to be stored by its chain id, got %+v", first)
+	}
+	if second.Layer != first.Layer || second.LayerDigest != first.LayerDigest {
+		t.Fatalf("Expected the images of the same content to share their layer, got %s and %s", first.Layer, second.Layer)
+	}
+	if second.Size != first.Size {
+		t.Fatalf("Expected the images sharing their layer to have the same size, got %d and %d", first.Size, second.Size)
+	}
+
+	// The same content on top of another layer is another layer
+	child := registerFakeImage(graph, "child", "first", t)
+	if child.LayerDigest != first.LayerDigest || child.ChainID == first.ChainID || child.Layer == first.Layer {
+		t.Fatalf("Expected the child to have a layer of its own, got %+v", child)
+	}
+
+	rawJSON, err := child.RawJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(rawJSON), child.LayerDigest) {
+		t.Fatalf("Expected the config to reference the layer digest, got %s", rawJSON)
+	}
+
+	if err := graph.Delete("child"); err != nil {
+		t.Fatal(err)
+	}
+	if err := graph.Delete("first"); err != nil {
+		t.Fatal(err)
+	}
+	if !graph.driver.Exists(second.Layer) {
+		t.Fatal("The layer of an image still in the graph should not be removed")
+	}
+	if err := graph.Delete("second"); err != nil {
+		t.Fatal(err)
+	}
+	if graph.driver.Exists(second.Layer) {
+		t.Fatal("Expected the layer to be removed with its last image")
+	}
+
+	// The sharing survives a restart of the daemon
+	restarted := mkTestGraph(tmp, t)
+	other := registerFakeImage(restarted, "other", "", t)
+	if other.Layer != first.Layer || !restarted.driver.Exists(other.Layer) {
+		t.Fatalf("Expected the layer to be created again, got %+v", other)
+	}
+}
+
+func TestLayerStoreReserve(t *testing.T) {
+	s := newLayerStore()
+	if _, shared := s.reserve("chain"); shared {
+		t.Fatal("Expected an unknown chain id to be reserved")
+	}
+
+	// Another registration of the chain id waits for its layer
+	done := make(chan string)
+	go func() {
+		layer, shared := s.reserve("chain")
+		if !shared {
+			layer = ""
+		}
+		done <- layer
+	}()
+	select {
+	case <-done:
+		t.Fatal("Expected the reservation of the chain id to wait for its layer")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.Lock()
+	s.add(&image.Image{ID: "img", Layer: "layer", ChainID: "chain"})
+	s.release("chain")
+	s.Unlock()
+	select {
+	case layer := <-done:
+		if layer != "layer" {
+			t.Fatalf("Expected the layer of the chain id to be shared, got %q", layer)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the reservation to end with its release")
+	}
+	if s.refs["layer"] != 2 {
+		t.Fatalf("Expected the layer to be counted for both images, got %d", s.refs["layer"])
+	}
+
+	// A released reservation without layer lets the next one create it
+	s.reserve("other")
+	s.Lock()
+	s.release("other")
+	s.Unlock()
+	if _, shared := s.reserve("other"); shared {
+		t.Fatal("Expected the chain id given up to be reserved again")
+	}
+}
+
+func TestRegisterConcurrently(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	graph := mkTestGraph(tmp, t)
+	defer graph.driver.Cleanup()
+
+	ids := []string{"first", "second", "third", "fourth"}
+	errs := make(chan error, len(ids))
+	for _, id := range ids {
+		go func(id string) {
+			archive, err := fakeTar()
+			if err == nil {
+				err = graph.Register(nil, archive, &image.Image{ID: id})
+			}
+			errs <- err
+		}(id)
+	}
+	for _ = range ids {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var layer string
+	for _, id := range ids {
+		img, err := graph.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if layer == "" {
+			layer = img.Layer
+		} else if img.Layer != layer {
+			t.Fatalf("Expected the images of the same content to share their layer, got %s and %s", layer, img.Layer)
+		}
+	}
+	if refs := graph.layers.refs[layer]; refs != len(ids) {
+		t.Fatalf("Expected the layer to be counted for %d images, got %d", len(ids), refs)
+	}
+}
+
+func TestMigrateLayers(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	graph := mkTestGraph(tmp, t)
+	defer graph.driver.Cleanup()
+
+	// An image stored before the layers were addressed by their content
+	if err := graph.driver.Create("legacy", ""); err != nil {
+		t.Fatal(err)
+	}
+	rootfs, err := graph.driver.Get("legacy", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, err := fakeTar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Untar(layer, rootfs, nil); err != nil {
+		t.Fatal(err)
+	}
+	graph.driver.Put("legacy")
+	if err := os.MkdirAll(graph.ImageRoot("legacy"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(graph.ImageRoot("legacy"), "json"), []byte(`{"id":"legacy","comment":"legacy image"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated := mkTestGraph(tmp, t)
+	legacy, err := migrated.Get("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.LayerDigest == "" || legacy.ChainID != chainID("", legacy.LayerDigest) {
+		t.Fatalf("Expected the layer digest of the image to be computed, got %+v", legacy)
+	}
+	if legacy.Layer != "legacy" || legacy.Comment != "legacy image" {
+		t.Fatalf("Expected the image to keep its layer and config, got %+v", legacy)
+	}
+
+	// A new image of the same content shares the layer of the former image
+	exported, err := legacy.TarLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exported.Close()
+	if err := migrated.Register(nil, exported, &image.Image{ID: "copy"}); err != nil {
+		t.Fatal(err)
+	}
+	copy, err := migrated.Get("copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copy.Layer != "legacy" {
+		t.Fatalf("Expected the copy to share the layer of the former image, got %s", copy.Layer)
+	}
+}
@@ -1,7 +1,6 @@
 package graph
 
 import (
-	"fmt"
 	"log"
 	"path"
 	"strings"
@@ -9,6 +8,7 @@ import (
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/parsers/filters"
+	"github.com/docker/docker/utils"
 )
 
 func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
@@ -53,9 +53,18 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 				continue
 			}
 
+			if !imageFilters.MatchKVList("label", image.Labels) {
+				continue
+			}
+
+			// The digest references are listed apart from the tags
+			refs := "RepoTags"
+			if utils.DigestReference(tag) {
+				refs = "RepoDigests"
+			}
 			if out, exists := lookup[id]; exists {
 				if filt_tagged {
-					out.SetList("RepoTags", append(out.GetList("RepoTags"), fmt.Sprintf("%s:%s", name, tag)))
+					out.SetList(refs, append(out.GetList(refs), utils.ImageReference(name, tag)))
 				}
 			} else {
 				// get the boolean list for if only the untagged images are requested
@@ -63,7 +72,9 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 				if filt_tagged {
 					out := &engine.Env{}
 					out.Set("ParentId", image.Parent)
-					out.SetList("RepoTags", []string{fmt.Sprintf("%s:%s", name, tag)})
+					out.SetList("RepoTags", []string{})
+					out.SetList("RepoDigests", []string{})
+					out.SetList(refs, []string{utils.ImageReference(name, tag)})
 					out.Set("Id", image.ID)
 					out.SetInt64("Created", image.Created.Unix())
 					out.SetInt64("Size", image.Size)
@@ -84,9 +95,13 @@ func (s *TagStore) CmdImages(job *engine.Job) engine.Status {
 	// Display images which aren't part of a repository/tag
 	if job.Getenv("filter") == "" {
 		for _, image := range allImages {
+			if !imageFilters.MatchKVList("label", image.Labels) {
+				continue
+			}
 			out := &engine.Env{}
 			out.Set("ParentId", image.Parent)
 			out.SetList("RepoTags", []string{"<none>:<none>"})
+			out.SetList("RepoDigests", []string{})
 			out.Set("Id", image.ID)
 			out.SetInt64("Created", image.Created.Unix())
 			out.SetInt64("Size", image.Size)
@@ -11,6 +11,7 @@ import (
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/utils"
 )
 
 // Loads a set of images into the repository. This is the complementary of ImageExport.
@@ -79,7 +80,13 @@ func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 
 		for imageName, tagMap := range repositories {
 			for tag, address := range tagMap {
-				if err := s.Set(imageName, tag, address, true); err != nil {
+				// The digests of the images pulled by digest are not tags
+				if utils.DigestReference(tag) {
+					err = s.SetDigest(imageName, tag, address)
+				} else {
+					err = s.Set(imageName, tag, address, true)
+				}
+				if err != nil {
 					return job.Error(err)
 				}
 			}
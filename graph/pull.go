@@ -51,6 +51,44 @@ func (s *TagStore) CmdPull(job *engine.Job) engine.Status {
 		return job.Error(err)
 	}
 
+	if hostname == registry.IndexServerAddress() {
+		// If pull "index.docker.io/foo/bar", it's stored locally under "foo/bar"
+		localName = remoteName
+	}
+
+	byDigest := utils.DigestReference(tag)
+	if byDigest {
+		if err := validateDigest(tag); err != nil {
+			return job.Error(err)
+		}
+	}
+
+	// Prefer the v2 protocol, falling back to v1 for the registries and the
+	// repositories without it
+	if r2, err := registry.NewV2Session(authConfig, registry.HTTPRequestFactory(metaHeaders), hostname, true); err != nil {
+		log.Debugf("Pulling %s with the v1 protocol: %s", localName, err)
+	} else if err := s.pullV2Repository(r2, job.Stdout, localName, remoteName, tag, sf); err != registry.ErrManifestNotFound {
+		if err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	} else {
+		log.Debugf("No v2 manifest of %s, pulling with the v1 protocol", localName)
+	}
+	if byDigest {
+		// Only the manifests of v2 registries are addressed by digest
+		return job.Errorf("Error: image %s not found in a v2 registry", utils.ImageReference(remoteName, tag))
+	}
+
+	// The images of v1 registries are not signed
+	ref := localName
+	if tag != "" {
+		ref = utils.ImageReference(localName, tag)
+	}
+	if err := s.verifyTrust(job.Stdout, ref, nil, sf); err != nil {
+		return job.Error(err)
+	}
+
 	endpoint, err := registry.ExpandAndVerifyRegistryUrl(hostname)
 	if err != nil {
 		return job.Error(err)
@@ -61,11 +99,6 @@ func (s *TagStore) CmdPull(job *engine.Job) engine.Status {
 		return job.Error(err)
 	}
 
-	if endpoint == registry.IndexServerAddress() {
-		// If pull "index.docker.io/foo/bar", it's stored locally under "foo/bar"
-		localName = remoteName
-	}
-
 	if err = s.pullRepository(r, job.Stdout, localName, remoteName, tag, sf, job.GetenvBool("parallel")); err != nil {
 		return job.Error(err)
 	}
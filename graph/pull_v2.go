@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/trust"
+	"github.com/docker/docker/utils"
+)
+
+// pullV2Repository pulls the tag askedTag of the repository remoteName, or all
+// its tags, from a v2 registry. It returns registry.ErrManifestNotFound if the
+// registry has no manifest of the repository, which may still be in a v1
+// registry.
+func (s *TagStore) pullV2Repository(r *registry.V2Session, out io.Writer, localName, remoteName, askedTag string, sf *utils.StreamFormatter) error {
+	tags := []string{askedTag}
+	if askedTag == "" {
+		var err error
+		if tags, err = r.GetTags(remoteName); err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return registry.ErrManifestNotFound
+		}
+	}
+
+	out.Write(sf.FormatStatus("", "Pulling repository %s", localName))
+	for _, tag := range tags {
+		if err := s.pullV2Tag(r, out, localName, remoteName, tag, sf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pullV2Tag pulls the images of the manifest of tag, or of a digest, from the
+// base image to the tagged one. The manifest of a digest is checked against
+// it, its signatures against the trusted keys, its repository and tag
+// against the ones asked for, and the layers against the digests of their
+// blobs in the manifest.
+func (s *TagStore) pullV2Tag(r *registry.V2Session, out io.Writer, localName, remoteName, tag string, sf *utils.StreamFormatter) error {
+	log.Debugf("Pulling the manifest of %s", utils.ImageReference(remoteName, tag))
+	manifestData, digest, err := r.GetManifest(remoteName, tag)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyTrust(out, utils.ImageReference(localName, tag), manifestData, sf); err != nil {
+		return err
+	}
+	// Only the payload of the signatures is trusted, not what surrounds it
+	payload, err := trust.Payload(manifestData)
+	if err != nil {
+		return fmt.Errorf("Invalid manifest: %s", err)
+	}
+	manifest, imgs, err := parseManifest(payload)
+	if err != nil {
+		return err
+	}
+	// A manifest signed for another repository or tag is not trusted for
+	// this one
+	if manifest.Name != r.Repository(remoteName) {
+		return fmt.Errorf("The manifest of %s is the one of the repository %s", utils.ImageReference(remoteName, tag), manifest.Name)
+	}
+	if !utils.DigestReference(tag) && manifest.Tag != tag {
+		return fmt.Errorf("The manifest of %s is the one of the tag %s", utils.ImageReference(remoteName, tag), manifest.Tag)
+	}
+
+	for i := len(imgs) - 1; i >= 0; i-- {
+		imgJSON := []byte(manifest.History[i].V1Compatibility)
+		if err := s.pullV2Image(r, out, remoteName, imgs[i], imgJSON, manifest.FSLayers[i].BlobSum, sf); err != nil {
+			return err
+		}
+	}
+
+	if utils.DigestReference(tag) {
+		err = s.SetDigest(localName, tag, imgs[0].ID)
+	} else {
+		err = s.Set(localName, tag, imgs[0].ID, true)
+	}
+	if err != nil {
+		return err
+	}
+	out.Write(sf.FormatStatus("", "Digest: %s", digest))
+	return nil
+}
+
+// parseManifest checks the manifest of manifestData, and returns it with the
+// images of its history
+func parseManifest(manifestData []byte) (*registry.ManifestData, []*image.Image, error) {
+	manifest := &registry.ManifestData{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return nil, nil, fmt.Errorf("Invalid manifest: %s", err)
+	}
+	if manifest.SchemaVersion != 1 {
+		return nil, nil, fmt.Errorf("Unsupported schema version %d of the manifest", manifest.SchemaVersion)
+	}
+	if len(manifest.FSLayers) == 0 || len(manifest.FSLayers) != len(manifest.History) {
+		return nil, nil, fmt.Errorf("Invalid manifest: %d layers for %d images", len(manifest.FSLayers), len(manifest.History))
+	}
+
+	imgs := make([]*image.Image, len(manifest.History))
+	for i, history := range manifest.History {
+		img, err := image.NewImgJSON([]byte(history.V1Compatibility))
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid image in the manifest: %s", err)
+		}
+		if err := utils.ValidateID(img.ID); err != nil {
+			return nil, nil, err
+		}
+		if i > 0 && imgs[i-1].Parent != img.ID {
+			return nil, nil, fmt.Errorf("Invalid manifest: %s is not the parent of %s", img.ID, imgs[i-1].ID)
+		}
+		imgs[i] = img
+	}
+	if base := imgs[len(imgs)-1]; base.Parent != "" {
+		return nil, nil, fmt.Errorf("Invalid manifest: the parent %s of %s is missing", base.Parent, base.ID)
+	}
+	return manifest, imgs, nil
+}
+
+// pullV2Image registers img, of config imgJSON, with the layer of the blob
+// blobSum if the graph does not have it
+func (s *TagStore) pullV2Image(r *registry.V2Session, out io.Writer, remoteName string, img *image.Image, imgJSON []byte, blobSum string, sf *utils.StreamFormatter) error {
+	// ensure no two downloads of the same layer happen at the same time
+	if c, err := s.poolAdd("pull", "layer:"+img.ID); err != nil {
+		log.Debugf("Image (id: %s) pull is already running, waiting: %v", img.ID, err)
+		if c != nil {
+			<-c
+		}
+	} else {
+		defer s.poolRemove("pull", "layer:"+img.ID)
+	}
+
+	if s.graph.Exists(img.ID) {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Already exists", nil))
+		return nil
+	}
+
+	out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Pulling fs layer", nil))
+	layer, size, err := r.GetBlob(remoteName, blobSum)
+	if err != nil {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Error pulling dependent layers", nil))
+		return err
+	}
+	defer layer.Close()
+
+	// The layer is downloaded before it is registered, so that its digest is
+	// checked before the graph has the image
+	tmp, err := s.graph.Mktemp("")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	layerFile, err := os.Create(path.Join(tmp, "layer.tar"))
+	if err != nil {
+		return err
+	}
+	defer layerFile.Close()
+	if _, err := io.Copy(layerFile, utils.ProgressReader(layer, int(size), out, sf, false, utils.TruncateID(img.ID), "Downloading")); err != nil {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Error downloading dependent layers", nil))
+		return err
+	}
+	if _, err := layerFile.Seek(0, 0); err != nil {
+		return err
+	}
+
+	if err := s.graph.Register(imgJSON, layerFile, img); err != nil {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Error registering dependent layers", nil))
+		return err
+	}
+	out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Download complete", nil))
+	return nil
+}
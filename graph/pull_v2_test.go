@@ -0,0 +1,261 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/utils"
+)
+
+// mockV2Registry is a v2 registry without authentication, storing the
+// manifests by "name:reference" and the blobs by digest
+type mockV2Registry struct {
+	sync.Mutex
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	uploads   map[string]*bytes.Buffer
+}
+
+func (m *mockV2Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.Lock()
+	defer m.Unlock()
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	body, _ := ioutil.ReadAll(r.Body)
+	p := strings.TrimPrefix(r.URL.Path, "/v2/")
+
+	switch i := strings.LastIndex(p, "/manifests/"); {
+	case p == "":
+		w.WriteHeader(200)
+	case i >= 0:
+		key := p[:i] + ":" + p[i+len("/manifests/"):]
+		if r.Method == "PUT" {
+			digest, _ := registry.ManifestDigest(body)
+			m.manifests[key] = body
+			m.manifests[p[:i]+":"+digest] = body
+			w.WriteHeader(201)
+		} else if manifest, exists := m.manifests[key]; exists {
+			w.Write(manifest)
+		} else {
+			w.WriteHeader(404)
+		}
+	case strings.HasSuffix(p, "/tags/list"):
+		name := strings.TrimSuffix(p, "/tags/list")
+		var tags []string
+		for key := range m.manifests {
+			if strings.HasPrefix(key, name+":") {
+				tags = append(tags, `"`+strings.TrimPrefix(key, name+":")+`"`)
+			}
+		}
+		if tags == nil {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprintf(w, `{"name":%q,"tags":[%s]}`, name, strings.Join(tags, ","))
+	case strings.Contains(p, "/blobs/uploads/"):
+		uuid := p[strings.LastIndex(p, "/")+1:]
+		switch r.Method {
+		case "POST":
+			uuid = fmt.Sprintf("%d", len(m.uploads))
+			m.uploads[uuid] = &bytes.Buffer{}
+			w.Header().Set("Location", r.URL.Path+uuid)
+			w.WriteHeader(202)
+		case "PATCH":
+			m.uploads[uuid].Write(body)
+			w.Header().Set("Location", r.URL.Path)
+			w.WriteHeader(202)
+		case "PUT":
+			m.blobs[r.URL.Query().Get("digest")] = m.uploads[uuid].Bytes()
+			w.WriteHeader(201)
+		}
+	case strings.Contains(p, "/blobs/"):
+		blob, exists := m.blobs[p[strings.LastIndex(p, "/")+1:]]
+		if !exists {
+			w.WriteHeader(404)
+			return
+		}
+		w.WriteHeader(200)
+		if r.Method == "GET" {
+			w.Write(blob)
+		}
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func TestPushPullV2(t *testing.T) {
+	mock := &mockV2Registry{
+		manifests: make(map[string][]byte),
+		blobs:     make(map[string][]byte),
+		uploads:   make(map[string]*bytes.Buffer),
+	}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	r, err := registry.NewV2Session(&registry.AuthConfig{}, utils.NewHTTPRequestFactory(), server.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf := utils.NewStreamFormatter(false)
+
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(path.Join(tmp, "push"), t)
+	defer store.graph.driver.Cleanup()
+	registerFakeImage(store.graph, "bar", testImageID, t)
+	if err := store.Set(testImageName, "child", "bar", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.pushV2Repository(r, ioutil.Discard, testImageName, testImageName, store.Repositories[testImageName], "", sf); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.manifests) != 4 {
+		t.Fatalf("Expected the manifests of 2 tags by tag and by digest, got %d", len(mock.manifests))
+	}
+	if len(mock.blobs) != 2 {
+		t.Fatalf("Expected the blobs of 2 layers, got %d", len(mock.blobs))
+	}
+	// The blobs the registry has are not pushed again
+	if err := store.pushV2Repository(r, ioutil.Discard, testImageName, testImageName, store.Repositories[testImageName], "child", sf); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.uploads) != 2 {
+		t.Fatalf("Expected no other upload, got %d uploads", len(mock.uploads))
+	}
+
+	pulled := mkTestGraph(path.Join(tmp, "pull"), t)
+	defer pulled.driver.Cleanup()
+	pullStore, err := NewTagStore(path.Join(tmp, "pull", "tags"), pulled, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pullStore.pullV2Repository(r, ioutil.Discard, testImageName, testImageName, "", sf); err != nil {
+		t.Fatal(err)
+	}
+	child, err := pullStore.GetImage(testImageName, "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child == nil || child.ID != "bar" || child.Parent != testImageID {
+		t.Fatalf("Expected the tag to be pulled with its parent, got %+v", child)
+	}
+	if !pulled.Exists(testImageID) {
+		t.Fatal("Expected the parent image to be pulled")
+	}
+
+	// An image pulled by digest is referenced by its digest
+	_, digest, err := r.GetManifest(testImageName, "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pullStore.pullV2Repository(r, ioutil.Discard, "pinned", testImageName, digest, sf); err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := pullStore.LookupImage("pinned@" + digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned == nil || pinned.ID != "bar" {
+		t.Fatalf("Expected the digest to reference the image of the tag, got %+v", pinned)
+	}
+	if _, exists := pullStore.Repositories["pinned"]["latest"]; exists {
+		t.Fatal("An image pulled by digest should not be tagged")
+	}
+
+	if err := pullStore.pullV2Repository(r, ioutil.Discard, "unknown", "unknown", "", sf); err != registry.ErrManifestNotFound {
+		t.Fatalf("Expected ErrManifestNotFound, got %v", err)
+	}
+}
+
+func TestPushDigestReference(t *testing.T) {
+	mock := &mockV2Registry{
+		manifests: make(map[string][]byte),
+		blobs:     make(map[string][]byte),
+		uploads:   make(map[string]*bytes.Buffer),
+	}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	r, err := registry.NewV2Session(&registry.AuthConfig{}, utils.NewHTTPRequestFactory(), server.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(path.Join(tmp, "push"), t)
+	defer store.graph.driver.Cleanup()
+	digest := "sha256:" + strings.Repeat("0", 64)
+	if err := store.SetDigest(testImageName, digest, testImageID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The digests are neither pushed to v1 registries
+	_, tagsByImage, err := store.getImageList(store.Repositories[testImageName], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := tagsByImage[testImageID]; len(tags) != 1 || tags[0] != DEFAULTTAG {
+		t.Fatalf("Expected only the tag %s to be pushed, got %v", DEFAULTTAG, tags)
+	}
+
+	// nor to v2 registries
+	if err := store.pushV2Repository(r, ioutil.Discard, testImageName, testImageName, store.Repositories[testImageName], "", utils.NewStreamFormatter(false)); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.manifests) != 2 {
+		t.Fatalf("Expected the manifest of the tag by tag and by digest, got %d", len(mock.manifests))
+	}
+	if _, exists := mock.manifests[testImageName+":"+digest]; exists {
+		t.Fatal("The digest should not be pushed as a tag")
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	manifest := func(history ...string) []byte {
+		data := &registry.ManifestData{SchemaVersion: 1}
+		for _, h := range history {
+			data.FSLayers = append(data.FSLayers, &registry.FSLayer{BlobSum: "sha256:0"})
+			data.History = append(data.History, &registry.ManifestHistory{V1Compatibility: h})
+		}
+		manifestData, err := json.Marshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return manifestData
+	}
+
+	_, imgs, err := parseManifest(manifest(`{"id":"child","parent":"base"}`, `{"id":"base"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imgs) != 2 || imgs[0].ID != "child" || imgs[1].ID != "base" {
+		t.Fatalf("Wrong images of the manifest: %+v", imgs)
+	}
+
+	for _, invalid := range [][]byte{
+		[]byte(`{"schemaVersion":1}`),
+		[]byte(`{"schemaVersion":2,"fsLayers":[{"blobSum":"sha256:0"}],"history":[{"v1Compatibility":"{\"id\":\"base\"}"}]}`),
+		manifest(`{"id":"child","parent":"other"}`, `{"id":"base"}`),
+		manifest(`{"id":"child","parent":"base"}`),
+		manifest(`{"id":"invalid:id"}`),
+	} {
+		if _, _, err := parseManifest(invalid); err == nil {
+			t.Fatalf("Expected an error for the manifest %s", invalid)
+		}
+	}
+}
@@ -26,6 +26,11 @@ func (s *TagStore) getImageList(localRepo map[string]string, requestedTag string
 		if requestedTag != "" && requestedTag != tag {
 			continue
 		}
+		// The digests reference the manifests pulled by digest, they are
+		// not tags of the repository
+		if utils.DigestReference(tag) {
+			continue
+		}
 		var imageListForThisTag []string
 
 		tagsByImage[id] = append(tagsByImage[id], tag)
@@ -214,12 +219,31 @@ func (s *TagStore) CmdPush(job *engine.Job) engine.Status {
 		return job.Error(err)
 	}
 
-	endpoint, err := registry.ExpandAndVerifyRegistryUrl(hostname)
-	if err != nil {
-		return job.Error(err)
+	img, err := s.graph.Get(localName)
+
+	// Prefer the v2 protocol to push the tags of a repository, falling back to
+	// v1 for the registries without it. A single image has no tag to push
+	// with v2.
+	if localRepo, exists := s.Repositories[localName]; err != nil && exists {
+		if r2, err := registry.NewV2Session(authConfig, registry.HTTPRequestFactory(metaHeaders), hostname, false); err != nil {
+			log.Debugf("Pushing %s with the v1 protocol: %s", localName, err)
+		} else {
+			reposLen := 1
+			if tag == "" {
+				reposLen = len(localRepo)
+			}
+			job.Stdout.Write(sf.FormatStatus("", "The push refers to a repository [%s] (len: %d)", localName, reposLen))
+			if err := s.pushV2Repository(r2, job.Stdout, localName, remoteName, localRepo, tag, sf); err != nil {
+				return job.Error(err)
+			}
+			return engine.StatusOK
+		}
 	}
 
-	img, err := s.graph.Get(localName)
+	endpoint, err2 := registry.ExpandAndVerifyRegistryUrl(hostname)
+	if err2 != nil {
+		return job.Error(err2)
+	}
 	r, err2 := registry.NewSession(authConfig, registry.HTTPRequestFactory(metaHeaders), endpoint, false)
 	if err2 != nil {
 		return job.Error(err2)
@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/docker/docker/archive"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/utils"
+)
+
+// pushV2Repository pushes the tag askedTag of localRepo, or all its tags, to
+// the repository remoteName of a v2 registry: the layers of the images of a
+// tag as blobs, then its manifest, signed with the key of the daemon.
+func (s *TagStore) pushV2Repository(r *registry.V2Session, out io.Writer, localName, remoteName string, localRepo map[string]string, askedTag string, sf *utils.StreamFormatter) error {
+	out = utils.NewWriteFlusher(out)
+
+	var tags []string
+	for tag := range localRepo {
+		// The manifest of a digest is the one of the registry it was
+		// pulled from, it is not pushed as a tag
+		if utils.DigestReference(tag) {
+			continue
+		}
+		if askedTag == "" || tag == askedTag {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("No images found for the requested repository / tag")
+	}
+	sort.Strings(tags)
+
+	// The blob of the layer of each image pushed
+	blobSums := make(map[string]string)
+	for _, tag := range tags {
+		var imgs []*image.Image
+		for img, err := s.graph.Get(localRepo[tag]); img != nil; img, err = img.GetParent() {
+			if err != nil {
+				return err
+			}
+			imgs = append(imgs, img)
+		}
+
+		manifest := &registry.ManifestData{
+			SchemaVersion: 1,
+			Name:          r.Repository(remoteName),
+			Tag:           tag,
+			Architecture:  imgs[0].Architecture,
+		}
+		// The layers are pushed from the base image, the manifest lists them
+		// from the tagged image
+		for i := len(imgs) - 1; i >= 0; i-- {
+			blobSum, err := s.pushV2Image(r, out, remoteName, imgs[i], blobSums, sf)
+			if err != nil {
+				return err
+			}
+			imgJSON, err := imgs[i].RawJson()
+			if err != nil {
+				return err
+			}
+			manifest.FSLayers = append([]*registry.FSLayer{{BlobSum: blobSum}}, manifest.FSLayers...)
+			manifest.History = append([]*registry.ManifestHistory{{V1Compatibility: string(imgJSON)}}, manifest.History...)
+		}
+
+		manifestData, err := json.MarshalIndent(manifest, "", "   ")
+		if err != nil {
+			return err
+		}
+		if s.trust != nil {
+			if manifestData, err = s.trust.Sign(manifestData); err != nil {
+				return err
+			}
+			out.Write(sf.FormatStatus("", "Signed the manifest of %s with the key %s", remoteName+":"+tag, s.trust.KeyID()))
+		}
+		out.Write(sf.FormatStatus("", "Pushing tag for rev [%s] on {%s}", utils.TruncateID(imgs[0].ID), remoteName+":"+tag))
+		digest, err := r.PutManifest(remoteName, tag, manifestData)
+		if err != nil {
+			return err
+		}
+		out.Write(sf.FormatStatus("", "%s: digest: %s", tag, digest))
+	}
+	return nil
+}
+
+// pushV2Image pushes the layer of img as a blob, unless the registry already
+// has it, and returns its digest
+func (s *TagStore) pushV2Image(r *registry.V2Session, out io.Writer, remoteName string, img *image.Image, blobSums map[string]string, sf *utils.StreamFormatter) (string, error) {
+	if blobSum, exists := blobSums[img.ID]; exists {
+		return blobSum, nil
+	}
+
+	layerData, err := s.graph.TempLayerArchive(img.ID, archive.Uncompressed, sf, out)
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate layer archive: %s", err)
+	}
+	defer os.RemoveAll(layerData.Name())
+	defer layerData.Close()
+
+	// The temporary archive is removed once read, but stays open to be read
+	// again for the upload
+	blobSum, err := registry.Digest(layerData)
+	if err != nil {
+		return "", err
+	}
+	if _, err := layerData.Seek(0, 0); err != nil {
+		return "", err
+	}
+
+	exists, err := r.HeadBlob(remoteName, blobSum)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Image already pushed, skipping", nil))
+	} else {
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Pushing", nil))
+		if err := r.PushBlob(remoteName, blobSum, layerData); err != nil {
+			return "", err
+		}
+		out.Write(sf.FormatProgress(utils.TruncateID(img.ID), "Image successfully pushed", nil))
+	}
+	blobSums[img.ID] = blobSum
+	return blobSum, nil
+}
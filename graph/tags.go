@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/trust"
 	"github.com/docker/docker/utils"
 )
 
@@ -26,11 +29,14 @@ type TagStore struct {
 	// to a helper type
 	pullingPool map[string]chan struct{}
 	pushingPool map[string]chan struct{}
+	// trust signs the manifests pushed and verifies the manifests pulled,
+	// unless it is nil
+	trust *trust.Store
 }
 
 type Repository map[string]string
 
-func NewTagStore(path string, graph *Graph) (*TagStore, error) {
+func NewTagStore(path string, graph *Graph, trustStore *trust.Store) (*TagStore, error) {
 	abspath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
@@ -41,6 +47,7 @@ func NewTagStore(path string, graph *Graph) (*TagStore, error) {
 		Repositories: make(map[string]Repository), //记录镜像仓库的映射数据结构
 		pullingPool:  make(map[string]chan struct{}), //记录那些镜像在被下载
 		pushingPool:  make(map[string]chan struct{}), //记录那些镜像在被上传
+		trust:        trustStore,
 	}
 	// Load the json file if it exists, otherwise create it.
 	if err := store.reload(); os.IsNotExist(err) {
@@ -97,14 +104,14 @@ func (store *TagStore) LookupImage(name string) (*image.Image, error) {
 }
 
 // Return a reverse-lookup table of all the names which refer to each image
-// Eg. {"43b5f19b10584": {"base:latest", "base:v1"}}
+// Eg. {"43b5f19b10584": {"base:latest", "base:v1", "base@sha256:..."}}
 func (store *TagStore) ByID() map[string][]string {
 	store.Lock()
 	defer store.Unlock()
 	byID := make(map[string][]string)
 	for repoName, repository := range store.Repositories {
 		for tag, id := range repository {
-			name := repoName + ":" + tag
+			name := utils.ImageReference(repoName, tag)
 			if _, exists := byID[id]; !exists {
 				byID[id] = []string{name}
 			} else {
@@ -129,15 +136,9 @@ func (store *TagStore) DeleteAll(id string) error {
 		return nil
 	}
 	for _, name := range names {
-		if strings.Contains(name, ":") {
-			nameParts := strings.Split(name, ":")
-			if _, err := store.Delete(nameParts[0], nameParts[1]); err != nil {
-				return err
-			}
-		} else {
-			if _, err := store.Delete(name, ""); err != nil {
-				return err
-			}
+		repoName, tag := parsers.ParseRepositoryTag(name)
+		if _, err := store.Delete(repoName, tag); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -204,6 +205,34 @@ func (store *TagStore) Set(repoName, tag, imageName string, force bool) error {
 	return store.save()
 }
 
+// SetDigest references the image imageName by the digest of its manifest in
+// the repository repoName. Unlike a tag, the digest always references the
+// same image.
+func (store *TagStore) SetDigest(repoName, digest, imageName string) error {
+	img, err := store.LookupImage(imageName)
+	store.Lock()
+	defer store.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := validateRepoName(repoName); err != nil {
+		return err
+	}
+	if err := validateDigest(digest); err != nil {
+		return err
+	}
+	if err := store.reload(); err != nil {
+		return err
+	}
+	repo, exists := store.Repositories[repoName]
+	if !exists {
+		repo = make(map[string]string)
+		store.Repositories[repoName] = repo
+	}
+	repo[digest] = img.ID
+	return store.save()
+}
+
 func (store *TagStore) Get(repoName string) (Repository, error) {
 	store.Lock()
 	defer store.Unlock()
@@ -244,7 +273,7 @@ func (store *TagStore) GetRepoRefs() map[string][]string {
 	for name, repository := range store.Repositories {
 		for tag, id := range repository {
 			shortID := utils.TruncateID(id)
-			reporefs[shortID] = append(reporefs[shortID], fmt.Sprintf("%s:%s", name, tag))
+			reporefs[shortID] = append(reporefs[shortID], utils.ImageReference(name, tag))
 		}
 	}
 	store.Unlock()
@@ -270,6 +299,18 @@ func validateTagName(name string) error {
 	return nil
 }
 
+// Validate a digest, such as "sha256:" and the hex of a sha256
+func validateDigest(digest string) error {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] != "sha256" || len(parts[1]) != 2*sha256.Size {
+		return fmt.Errorf("Illegal digest: %s", digest)
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return fmt.Errorf("Illegal digest: %s", digest)
+	}
+	return nil
+}
+
 func (s *TagStore) poolAdd(kind, key string) (chan struct{}, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -52,7 +53,7 @@ func mkTestTagStore(root string, t *testing.T) *TagStore {
 	if err != nil {
 		t.Fatal(err)
 	}
-	store, err := NewTagStore(path.Join(root, "tags"), graph)
+	store, err := NewTagStore(path.Join(root, "tags"), graph, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -114,3 +115,44 @@ func TestLookupImage(t *testing.T) {
 		t.Errorf("Expected 1 image, none found")
 	}
 }
+
+func TestSetDigest(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	store := mkTestTagStore(tmp, t)
+	defer store.graph.driver.Cleanup()
+
+	digest := "sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b"
+	if err := store.SetDigest(testImageName, digest, testImageID); err != nil {
+		t.Fatal(err)
+	}
+	if img, err := store.LookupImage(testImageName + "@" + digest); err != nil {
+		t.Fatal(err)
+	} else if img == nil || img.ID != testImageID {
+		t.Errorf("Expected the image of the digest, got %v", img)
+	}
+	names := store.ByID()[testImageID]
+	if len(names) != 2 || names[0] != testImageName+":"+DEFAULTTAG || names[1] != testImageName+"@"+digest {
+		t.Errorf("Expected the tag and the digest of the image, got %v", names)
+	}
+
+	for _, invalid := range []string{"sha256:abc", "md5:" + digest[7:], "sha256:" + strings.Repeat("z", 64)} {
+		if err := store.SetDigest(testImageName, invalid, testImageID); err == nil {
+			t.Errorf("Expected an error for the digest %s", invalid)
+		}
+	}
+	// A digest is not a tag
+	if err := store.Set(testImageName, digest, testImageID, false); err == nil {
+		t.Error("Expected an error for a tag with a colon")
+	}
+
+	if err := store.DeleteAll(testImageID); err != nil {
+		t.Fatal(err)
+	}
+	if names := store.ByID()[testImageID]; len(names) != 0 {
+		t.Errorf("Expected the tag and the digest to be deleted, got %v", names)
+	}
+}
@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/trust"
+	"github.com/docker/docker/utils"
+)
+
+// verifyTrust checks the signatures of manifestData, the manifest of the
+// image ref, or nil for the images of v1 registries which have none. The
+// images no trusted key signed are refused or pulled with a warning according
+// to the trust policy of the daemon, the manifests of invalid signatures are
+// always refused.
+func (s *TagStore) verifyTrust(out io.Writer, ref string, manifestData []byte, sf *utils.StreamFormatter) error {
+	if s.trust == nil || s.trust.Policy == trust.PolicyOff {
+		return nil
+	}
+
+	var (
+		keyID string
+		err   error = &trust.UntrustedError{}
+	)
+	if manifestData != nil {
+		keyID, err = s.trust.Verify(manifestData)
+	}
+	if err == nil {
+		out.Write(sf.FormatStatus("", "%s: verified, signed by the key %s", ref, keyID))
+		return nil
+	}
+	if _, untrusted := err.(*trust.UntrustedError); !untrusted {
+		return fmt.Errorf("Error verifying the manifest of %s: %s", ref, err)
+	}
+	if s.trust.Policy == trust.PolicyRefuse {
+		return fmt.Errorf("Refusing to pull %s: %s", ref, err)
+	}
+	out.Write(sf.FormatStatus("", "Warning: %s: %s", ref, err))
+	return nil
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/docker/docker/registry"
+	"github.com/docker/docker/trust"
+	"github.com/docker/docker/utils"
+)
+
+func TestPullV2Trust(t *testing.T) {
+	mock := &mockV2Registry{
+		manifests: make(map[string][]byte),
+		blobs:     make(map[string][]byte),
+		uploads:   make(map[string]*bytes.Buffer),
+	}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	r, err := registry.NewV2Session(&registry.AuthConfig{}, utils.NewHTTPRequestFactory(), server.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf := utils.NewStreamFormatter(false)
+
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// The manifests pushed are signed with the key of the daemon
+	store := mkTestTagStore(path.Join(tmp, "push"), t)
+	defer store.graph.driver.Cleanup()
+	if store.trust, err = trust.NewStore(path.Join(tmp, "push", "trust"), trust.PolicyRefuse); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.pushV2Repository(r, ioutil.Discard, testImageName, testImageName, store.Repositories[testImageName], "", sf); err != nil {
+		t.Fatal(err)
+	}
+	manifestData, _, err := r.GetManifest(testImageName, DEFAULTTAG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := store.trust.Verify(manifestData); err != nil || id != store.trust.KeyID() {
+		t.Fatalf("Expected the manifest to be signed by %s, got %q, %v", store.trust.KeyID(), id, err)
+	}
+
+	pulled := mkTestGraph(path.Join(tmp, "pull"), t)
+	defer pulled.driver.Cleanup()
+	trustStore, err := trust.NewStore(path.Join(tmp, "pull", "trust"), trust.PolicyRefuse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pullStore, err := NewTagStore(path.Join(tmp, "pull", "tags"), pulled, trustStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key of the pushing daemon is not trusted yet
+	err = pullStore.pullV2Repository(r, ioutil.Discard, testImageName, testImageName, DEFAULTTAG, sf)
+	if err == nil {
+		t.Fatal("Expected an error pulling an untrusted image with the refuse policy")
+	}
+	if pulled.Exists(testImageID) {
+		t.Fatal("The layers of an untrusted image should not be pulled")
+	}
+
+	trustStore.Policy = trust.PolicyWarn
+	out := bytes.NewBuffer(nil)
+	if err := pullStore.pullV2Repository(r, out, testImageName, testImageName, DEFAULTTAG, sf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("Warning: ")) {
+		t.Fatalf("Expected a warning pulling an untrusted image, got %s", out)
+	}
+
+	// Once trusted, the key of the pushing daemon is accepted
+	trustStore.Policy = trust.PolicyRefuse
+	publicKey, err := ioutil.ReadFile(path.Join(tmp, "push", "trust", "public-key.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(tmp, "pull", "trust", "trusted-keys", "push.json"), publicKey, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pullStore.pullV2Repository(r, ioutil.Discard, testImageName, testImageName, DEFAULTTAG, sf); err != nil {
+		t.Fatal(err)
+	}
+
+	// A validly signed manifest of another repository or tag is refused
+	if err := store.Set(testImageName, "stale", testImageID, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.pushV2Repository(r, ioutil.Discard, testImageName, "other", store.Repositories[testImageName], DEFAULTTAG, sf); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.pushV2Repository(r, ioutil.Discard, testImageName, testImageName, store.Repositories[testImageName], "stale", sf); err != nil {
+		t.Fatal(err)
+	}
+	for _, reference := range []string{"other:" + DEFAULTTAG, testImageName + ":stale"} {
+		mock.manifests[testImageName+":"+DEFAULTTAG] = mock.manifests[reference]
+		if err := pullStore.pullV2Repository(r, ioutil.Discard, testImageName, testImageName, DEFAULTTAG, sf); err == nil {
+			t.Fatalf("Expected an error pulling the signed manifest of %s as %s:%s", reference, testImageName, DEFAULTTAG)
+		}
+	}
+
+	// Tampered manifests are refused whatever the policy
+	mock.manifests[testImageName+":"+DEFAULTTAG] = bytes.Replace(manifestData, []byte(`"tag": "latest"`), []byte(`"tag": "other"`), 1)
+	trustStore.Policy = trust.PolicyWarn
+	if err := pullStore.pullV2Repository(r, ioutil.Discard, testImageName, testImageName, DEFAULTTAG, sf); err == nil {
+		t.Fatal("Expected an error pulling a tampered manifest")
+	}
+
+	// The images of v1 registries are not signed
+	if err := pullStore.verifyTrust(ioutil.Discard, testImageName, nil, sf); err != nil {
+		t.Fatalf("Expected unsigned images to be accepted with the warn policy, got %v", err)
+	}
+	trustStore.Policy = trust.PolicyRefuse
+	if err := pullStore.verifyTrust(ioutil.Discard, testImageName, nil, sf); err == nil {
+		t.Fatal("Expected unsigned images to be refused with the refuse policy")
+	}
+}
@@ -33,7 +33,16 @@ type Image struct {
 	Config          *runconfig.Config `json:"config,omitempty"`
 	Architecture    string            `json:"architecture,omitempty"`
 	OS              string            `json:"os,omitempty"`
-	Size            int64
+	Labels          map[string]string `json:"labels,omitempty"`
+	// LayerDigest is the tarsum of the uncompressed layer of the image
+	LayerDigest string `json:"layer_digest,omitempty"`
+	Size        int64
+
+	// ChainID addresses the layer of the image and the ones of its parents
+	// by their content, Layer is the id of the layer in the driver, which
+	// the images of the same chain share
+	ChainID string `json:"-"`
+	Layer   string `json:"-"`
 
 	graph Graph
 }
@@ -68,76 +77,72 @@ func LoadImage(root string) (*Image, error) {
 		img.Size = int64(size)
 	}
 
-	return img, nil
-}
-
-func StoreImage(img *Image, jsonData []byte, layerData archive.ArchiveReader, root, layer string) error {
-	// Store the layer
-	var (
-		size   int64
-		err    error
-		driver = img.graph.Driver()
-	)
-	if err := os.MkdirAll(layer, 0755); err != nil {
-		return err
+	// The images stored before the layers were addressed by their content
+	// have no chain id, and a layer of their own id in the driver
+	img.Layer = img.ID
+	if buf, err := ioutil.ReadFile(path.Join(root, "chainid")); err == nil {
+		img.ChainID = string(buf)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if buf, err := ioutil.ReadFile(path.Join(root, "layer")); err == nil {
+		img.Layer = string(buf)
+	} else if !os.IsNotExist(err) {
+		return nil, err
 	}
 
-	// If layerData is not nil, unpack it into the new layer
-	if layerData != nil {
-		if differ, ok := driver.(graphdriver.Differ); ok {
-			if err := differ.ApplyDiff(img.ID, layerData); err != nil {
-				return err
-			}
+	return img, nil
+}
 
-			if size, err = differ.DiffSize(img.ID); err != nil {
-				return err
-			}
-		} else {
-			start := time.Now().UTC()
-			log.Debugf("Start untar layer")
-			if err := archive.ApplyLayer(layer, layerData); err != nil {
-				return err
-			}
-			log.Debugf("Untar time: %vs", time.Now().UTC().Sub(start).Seconds())
-
-			if img.Parent == "" {
-				if size, err = utils.TreeSize(layer); err != nil {
-					return err
-				}
-			} else {
-				parent, err := driver.Get(img.Parent, "")
-				if err != nil {
-					return err
-				}
-				defer driver.Put(img.Parent)
-				changes, err := archive.ChangesDirs(layer, parent)
-				if err != nil {
-					return err
-				}
-				size = archive.ChangesSize(layer, changes)
-			}
+// StoreImage stores the config of img in root, along with the size and the
+// layer of img. jsonData, the raw config of the image when it was received,
+// is stored as it is apart from the digest of the layer.
+func StoreImage(img *Image, jsonData []byte, root string) error {
+	if img.Size >= 0 {
+		if err := img.SaveSize(root); err != nil {
+			return err
 		}
 	}
-
-	img.Size = size
-	if err := img.SaveSize(root); err != nil {
-		return err
-	}
-
-	// If raw json is provided, then use it
-	if jsonData != nil {
-		if err := ioutil.WriteFile(jsonPath(root), jsonData, 0600); err != nil {
+	if img.ChainID != "" {
+		if err := ioutil.WriteFile(path.Join(root, "chainid"), []byte(img.ChainID), 0600); err != nil {
 			return err
 		}
-	} else {
-		if jsonData, err = json.Marshal(img); err != nil {
+		if err := ioutil.WriteFile(path.Join(root, "layer"), []byte(img.Layer), 0600); err != nil {
 			return err
 		}
-		if err := ioutil.WriteFile(jsonPath(root), jsonData, 0600); err != nil {
+	}
+
+	var err error
+	// If raw json is provided, then use it
+	if jsonData != nil {
+		if jsonData, err = setLayerDigest(jsonData, img.LayerDigest); err != nil {
 			return err
 		}
+	} else if jsonData, err = json.Marshal(img); err != nil {
+		return err
 	}
-	return nil
+	return ioutil.WriteFile(jsonPath(root), jsonData, 0600)
+}
+
+// setLayerDigest sets the digest of the layer in the raw config jsonData,
+// keeping the fields unknown to Image
+func setLayerDigest(jsonData []byte, digest string) ([]byte, error) {
+	if digest == "" {
+		return jsonData, nil
+	}
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(jsonData, &config); err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(digest)
+	if err != nil {
+		return nil, err
+	}
+	if string(config["layer_digest"]) == string(value) {
+		return jsonData, nil
+	}
+	config["layer_digest"] = value
+	return json.Marshal(config)
 }
 
 func (img *Image) SetGraph(graph Graph) {
@@ -179,17 +184,17 @@ func (img *Image) TarLayer() (arch archive.Archive, err error) {
 	}
 	driver := img.graph.Driver()
 	if differ, ok := driver.(graphdriver.Differ); ok {
-		return differ.Diff(img.ID)
+		return differ.Diff(img.Layer)
 	}
 
-	imgFs, err := driver.Get(img.ID, "")
+	imgFs, err := driver.Get(img.Layer, "")
 	if err != nil {
 		return nil, err
 	}
 
 	defer func() {
 		if err != nil {
-			driver.Put(img.ID)
+			driver.Put(img.Layer)
 		}
 	}()
 
@@ -200,16 +205,20 @@ func (img *Image) TarLayer() (arch archive.Archive, err error) {
 		}
 		return utils.NewReadCloserWrapper(archive, func() error {
 			err := archive.Close()
-			driver.Put(img.ID)
+			driver.Put(img.Layer)
 			return err
 		}), nil
 	}
 
-	parentFs, err := driver.Get(img.Parent, "")
+	parent, err := img.GetParent()
+	if err != nil {
+		return nil, err
+	}
+	parentFs, err := driver.Get(parent.Layer, "")
 	if err != nil {
 		return nil, err
 	}
-	defer driver.Put(img.Parent)
+	defer driver.Put(parent.Layer)
 	changes, err := archive.ChangesDirs(imgFs, parentFs)
 	if err != nil {
 		return nil, err
@@ -220,7 +229,7 @@ func (img *Image) TarLayer() (arch archive.Archive, err error) {
 	}
 	return utils.NewReadCloserWrapper(archive, func() error {
 		err := archive.Close()
-		driver.Put(img.ID)
+		driver.Put(img.Layer)
 		return err
 	}), nil
 }
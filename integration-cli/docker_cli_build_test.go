@@ -705,6 +705,59 @@ func TestBuildEnv(t *testing.T) {
 	logDone("build - env")
 }
 
+func TestBuildLabel(t *testing.T) {
+	name := "testbuildlabel"
+	expected := `{"com.example.owner":"infra team","com.example.tier":"db"}`
+	defer deleteImages(name)
+	_, err := buildImage(name,
+		`FROM busybox
+		LABEL com.example.owner="infra team" com.example.tier=db`,
+		true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := inspectFieldJSON(name, "Config.Labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != expected {
+		t.Fatalf("Labels %s, expected %s", res, expected)
+	}
+
+	imagesCmd := exec.Command(dockerBinary, "images", "-q", "--no-trunc", "--filter", "label=com.example.tier=db")
+	out, _, err := runCommandWithOutput(imagesCmd)
+	errorOut(err, t, out)
+	id, err := getIDByName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != id {
+		t.Fatalf("Expected only %s to match the label filter, got %s", id, out)
+	}
+	logDone("build - label")
+}
+
+func TestBuildHealthcheck(t *testing.T) {
+	name := "testbuildhealthcheck"
+	expected := `{"Interval":5000000000,"Retries":2,"Test":["CMD-SHELL","cat /etc/hostname"],"Timeout":0}`
+	defer deleteImages(name)
+	_, err := buildImage(name,
+		`FROM busybox
+		HEALTHCHECK --interval=5s --retries=2 CMD cat /etc/hostname`,
+		true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := inspectFieldJSON(name, "Config.Healthcheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != expected {
+		t.Fatalf("Healthcheck %s, expected %s", res, expected)
+	}
+	logDone("build - healthcheck")
+}
+
 func TestBuildCmd(t *testing.T) {
 	name := "testbuildcmd"
 	expected := "[/bin/echo Hello World]"
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--name", "testing", "busybox", "sh", "-c", "echo test > /tmp/file && sleep 100")
+	out, _, err := runCommandWithOutput(runCmd)
+	errorOut(err, t, fmt.Sprintf("failed to start the container: %s, %v", out, err))
+
+	execCmd := exec.Command(dockerBinary, "exec", "testing", "cat", "/tmp/file")
+	out, _, err = runCommandWithOutput(execCmd)
+	errorOut(err, t, fmt.Sprintf("failed to exec in the container: %s, %v", out, err))
+
+	out = strings.Trim(out, "\r\n")
+
+	if expected := "test"; out != expected {
+		t.Errorf("container exec should've printed %q but printed %q", expected, out)
+	}
+
+	deleteAllContainers()
+
+	logDone("exec - basic test")
+}
+
+func TestExecInteractive(t *testing.T) {
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--name", "testing", "busybox", "sleep", "100")
+	out, _, err := runCommandWithOutput(runCmd)
+	errorOut(err, t, fmt.Sprintf("failed to start the container: %s, %v", out, err))
+
+	execCmd := exec.Command(dockerBinary, "exec", "-i", "testing", "cat")
+	execCmd.Stdin = strings.NewReader("hello from stdin\n")
+	out, _, err = runCommandWithOutput(execCmd)
+	errorOut(err, t, fmt.Sprintf("failed to exec in the container: %s, %v", out, err))
+
+	if expected := "hello from stdin"; strings.Trim(out, "\r\n") != expected {
+		t.Errorf("container exec should've echoed %q but printed %q", expected, out)
+	}
+
+	deleteAllContainers()
+
+	logDone("exec - interactive stdin")
+}
+
+func TestExecExitCode(t *testing.T) {
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--name", "testing", "busybox", "sleep", "100")
+	out, _, err := runCommandWithOutput(runCmd)
+	errorOut(err, t, fmt.Sprintf("failed to start the container: %s, %v", out, err))
+
+	execCmd := exec.Command(dockerBinary, "exec", "testing", "sh", "-c", "exit 23")
+	out, exitCode, err := runCommandWithOutput(execCmd)
+	if err == nil || exitCode != 23 {
+		t.Errorf("docker exec should've exited with the exit code 23 of the process, got %d: %s, %v", exitCode, out, err)
+	}
+
+	execCmd = exec.Command(dockerBinary, "exec", "testing", "true")
+	out, exitCode, err = runCommandWithOutput(execCmd)
+	if err != nil || exitCode != 0 {
+		t.Errorf("docker exec should've exited with 0, got %d: %s, %v", exitCode, out, err)
+	}
+
+	deleteAllContainers()
+
+	logDone("exec - exit code of the process")
+}
@@ -150,3 +150,96 @@ func TestInspectLinksStopped(t *testing.T) {
 
 	logDone("link - links in stopped container inspect")
 }
+
+func TestLinksResolvedByDns(t *testing.T) {
+	defer deleteAllContainers()
+	cmd(t, "run", "-d", "--name", "dnstarget", "busybox", "sleep", "100")
+	ip, err := inspectField("dnstarget", "NetworkSettings.IPAddress")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, _, _ := cmd(t, "run", "--rm", "busybox", "nslookup", "dnstarget")
+	if !strings.Contains(out, ip) {
+		t.Fatalf("Expected the name of the container to resolve to %s, got %s", ip, out)
+	}
+	out, _, _ = cmd(t, "run", "--rm", "--link", "dnstarget:alias", "busybox", "nslookup", "alias")
+	if !strings.Contains(out, ip) {
+		t.Fatalf("Expected the alias of the link to resolve to %s, got %s", ip, out)
+	}
+
+	runCmd := exec.Command(dockerBinary, "run", "--rm", "busybox", "nslookup", "nosuchcontainer")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("The name of a container which does not exist should not resolve: %s", out)
+	}
+
+	logDone("link - names and aliases resolved by the embedded dns server")
+}
+
+func TestDnsDoesNotDiscloseIsolatedNetworks(t *testing.T) {
+	cmd(t, "network", "create", "--subnet=10.67.0.0/24", "dnsisolated")
+	defer exec.Command(dockerBinary, "network", "rm", "dnsisolated").Run()
+	defer deleteAllContainers()
+
+	cmd(t, "run", "-d", "--name", "hidden", "--net=dnsisolated", "busybox", "sleep", "100")
+	cmd(t, "run", "-d", "--name", "visible", "busybox", "sleep", "100")
+	gateway, err := inspectField("visible", "NetworkSettings.Gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runCmd := exec.Command(dockerBinary, "run", "--rm", "busybox", "nslookup", "hidden")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil || strings.Contains(out, "10.67.0.") {
+		t.Fatalf("The name of a container on another network should not resolve: %s", out)
+	}
+
+	// the dns server only listens on the gateway of the default network
+	out, _, _ := cmd(t, "exec", "visible", "cat", "/etc/resolv.conf")
+	if !strings.Contains(out, gateway) {
+		t.Fatalf("Expected the containers of the default network to use the dns server, got %s", out)
+	}
+	out, _, _ = cmd(t, "exec", "hidden", "cat", "/etc/resolv.conf")
+	if strings.Contains(out, gateway) {
+		t.Fatalf("Expected the containers of a user-defined network to keep the nameservers of the daemon, got %s", out)
+	}
+
+	logDone("link - names of isolated networks not resolved by the embedded dns server")
+}
+
+func TestLinksUpdatedWhenChildRestarts(t *testing.T) {
+	defer deleteAllContainers()
+	cmd(t, "run", "-d", "--name", "child", "--expose", "80", "busybox", "sleep", "100")
+	cmd(t, "run", "-d", "--name", "parent", "--link", "child:alias", "busybox", "sleep", "100")
+	oldIp := findContainerIp(t, "child")
+
+	cmd(t, "stop", "-t", "0", "child")
+	// take the next address, so that the child gets another one
+	cmd(t, "run", "-d", "--name", "filler", "busybox", "sleep", "100")
+	cmd(t, "start", "child")
+	childIp := findContainerIp(t, "child")
+	if childIp == oldIp {
+		t.Fatalf("Expected the child to get a new address, got %s again", childIp)
+	}
+	parentIp := findContainerIp(t, "parent")
+
+	out, _, _ := cmd(t, "exec", "parent", "cat", "/etc/hosts")
+	if !strings.Contains(out, childIp+"\talias child") {
+		t.Fatalf("Expected the hosts file of the parent to list %s, got %s", childIp, out)
+	}
+	if strings.Contains(out, oldIp+"\t") {
+		t.Fatalf("Expected the previous address %s to be removed, got %s", oldIp, out)
+	}
+	cmd(t, "exec", "parent", "ping", "-c", "1", "-W", "1", "alias")
+
+	rule := func(childIp string) []string {
+		return []string{"FORWARD", "-i", "docker0", "-o", "docker0", "-p", "tcp", "-s", parentIp, "--dport", "80", "-d", childIp, "-j", "ACCEPT"}
+	}
+	if !iptables.Exists(rule(childIp)...) {
+		t.Fatal("Iptables rules not found for the new address")
+	}
+	if iptables.Exists(rule(oldIp)...) {
+		t.Fatal("Iptables rules of the previous address should be removed")
+	}
+
+	logDone("link - links follow a linked container restarting with a new address")
+}
@@ -213,3 +213,46 @@ func TestLogsTail(t *testing.T) {
 	deleteContainer(cleanedContainerID)
 	logDone("logs - logs tail")
 }
+
+func TestLogsRotatedJSONFile(t *testing.T) {
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--log-opt", "max-size=1k", "--log-opt", "max-file=2", "busybox", "sh", "-c", "for i in $(seq 1 200); do echo line$i; done")
+	out, _, _, err := runCommandWithStdoutStderr(runCmd)
+	errorOut(err, t, fmt.Sprintf("run failed with errors: %v", err))
+
+	cleanedContainerID := stripTrailingCharacters(out)
+	exec.Command(dockerBinary, "wait", cleanedContainerID).Run()
+
+	logsCmd := exec.Command(dockerBinary, "logs", cleanedContainerID)
+	out, _, _, err = runCommandWithStdoutStderr(logsCmd)
+	errorOut(err, t, fmt.Sprintf("failed to log container: %v %v", out, err))
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) >= 200 {
+		t.Fatalf("Expected the oldest lines to be rotated away, got %d lines", len(lines))
+	}
+	if lines[len(lines)-1] != "line200" {
+		t.Fatalf("Expected the last line to be line200, got %q", lines[len(lines)-1])
+	}
+
+	deleteContainer(cleanedContainerID)
+
+	logDone("logs - logs of a container with a rotated json-file")
+}
+
+func TestLogsNoneDriver(t *testing.T) {
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--log-driver=none", "busybox", "echo", "hello")
+	out, _, _, err := runCommandWithStdoutStderr(runCmd)
+	errorOut(err, t, fmt.Sprintf("run failed with errors: %v", err))
+
+	cleanedContainerID := stripTrailingCharacters(out)
+	exec.Command(dockerBinary, "wait", cleanedContainerID).Run()
+
+	logsCmd := exec.Command(dockerBinary, "logs", cleanedContainerID)
+	if out, _, _, err := runCommandWithStdoutStderr(logsCmd); err == nil {
+		t.Fatalf("Expected logs to fail with the none logging driver, got %q", out)
+	}
+
+	deleteContainer(cleanedContainerID)
+
+	logDone("logs - logs fail for a container with the none logging driver")
+}
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"net"
 	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/docker/docker/daemon"
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/iptables"
 )
 
 func TestNetworkNat(t *testing.T) {
@@ -68,3 +71,68 @@ func TestNetworkNat(t *testing.T) {
 
 	logDone("network - make sure nat works through the host")
 }
+
+func TestNetworkNatPortRange(t *testing.T) {
+	out, _, _ := cmd(t, "run", "-d", "--name", "ranged", "-p", "9000-9002:9000-9002", "busybox", "sleep", "10")
+	cleanedContainerID := stripTrailingCharacters(out)
+
+	inspectCmd := exec.Command(dockerBinary, "inspect", cleanedContainerID)
+	inspectOut, _, err := runCommandWithOutput(inspectCmd)
+	errorOut(err, t, fmt.Sprintf("out should've been a container id: %v %v", inspectOut, err))
+
+	containers := []*daemon.Container{}
+	if err := json.Unmarshal([]byte(inspectOut), &containers); err != nil {
+		t.Fatalf("Error inspecting the container: %s", err)
+	}
+	for _, port := range []string{"9000", "9001", "9002"} {
+		bindings := containers[0].NetworkSettings.Ports[nat.Port(port+"/tcp")]
+		if len(bindings) != 1 || bindings[0].HostPort != port {
+			t.Fatalf("Expected port %s to be published on the same host port, got %v", port, bindings)
+		}
+	}
+
+	// the whole range is forwarded by a single rule
+	rule := []string{"FORWARD", "!", "-i", "docker0", "-o", "docker0", "-p", "tcp", "-d", findContainerIp(t, "ranged"), "--dport", "9000:9002", "-j", "ACCEPT"}
+	if !iptables.Exists(rule...) {
+		t.Fatal("Iptables rule of the range not found")
+	}
+
+	cmd(t, "kill", cleanedContainerID)
+	if iptables.Exists(rule...) {
+		t.Fatal("Iptables rule of the range should be removed when the container stops")
+	}
+	deleteAllContainers()
+
+	logDone("network - publish a range of ports")
+}
+
+func TestNetworkNatPublishAllPortRange(t *testing.T) {
+	out, _, _ := cmd(t, "run", "-d", "--name", "ranged-all", "-P", "--expose", "9100-9102", "busybox", "sleep", "10")
+	cleanedContainerID := stripTrailingCharacters(out)
+	defer deleteAllContainers()
+
+	for _, port := range []string{"9100", "9101", "9102"} {
+		out, _, _ := cmd(t, "port", cleanedContainerID, port)
+		if _, hostPort, err := net.SplitHostPort(stripTrailingCharacters(out)); err != nil || hostPort != port {
+			t.Fatalf("Expected port %s to be published on the same host port, got %s", port, out)
+		}
+	}
+
+	// the whole range is forwarded by a single DNAT rule
+	ip := findContainerIp(t, "ranged-all")
+	rules, err := iptables.Raw("-t", "nat", "-S", "DOCKER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dnat []string
+	for _, rule := range strings.Split(string(rules), "\n") {
+		if strings.Contains(rule, "DNAT") && strings.Contains(rule, ip) {
+			dnat = append(dnat, rule)
+		}
+	}
+	if len(dnat) != 1 || !strings.Contains(dnat[0], "--dport 9100:9102") {
+		t.Fatalf("Expected a single DNAT rule for the range, got %v", dnat)
+	}
+
+	logDone("network - publish a range of exposed ports with a single rule")
+}
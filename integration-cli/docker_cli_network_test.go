@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/iptables"
+)
+
+func TestNetworkCreateAndRemove(t *testing.T) {
+	cmd(t, "network", "create", "--subnet=10.66.0.0/24", "testnet")
+
+	out, _, _ := cmd(t, "network", "ls")
+	if !strings.Contains(out, "testnet") || !strings.Contains(out, "10.66.0.0/24") {
+		t.Fatalf("The network should be listed with its subnet, got %s", out)
+	}
+
+	runCmd := exec.Command(dockerBinary, "network", "create", "testnet")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Creating a network with a name in use should have failed: %s", out)
+	}
+
+	cmd(t, "network", "rm", "testnet")
+
+	runCmd = exec.Command(dockerBinary, "network", "inspect", "testnet")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("The network should be removed: %s", out)
+	}
+
+	logDone("network - create and remove")
+}
+
+func TestNetworkRunOnUserDefinedNetwork(t *testing.T) {
+	cmd(t, "network", "create", "--subnet=10.66.0.0/24", "testnet")
+	defer exec.Command(dockerBinary, "network", "rm", "testnet").Run()
+
+	cmd(t, "run", "-d", "--name", "first", "--net=testnet", "busybox", "sleep", "100")
+	cmd(t, "run", "-d", "--name", "second", "--net=testnet", "busybox", "sleep", "100")
+	cmd(t, "run", "-d", "--name", "outsider", "busybox", "sleep", "100")
+
+	ip, err := inspectField("first", "NetworkSettings.IPAddress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ip, "10.66.0.") {
+		t.Fatalf("Expected an address in the subnet of the network, got %s", ip)
+	}
+
+	runCmd := exec.Command(dockerBinary, "exec", "second", "ping", "-c", "1", "-W", "1", ip)
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		t.Fatalf("Containers on the same network should reach each other: %s", out)
+	}
+
+	runCmd = exec.Command(dockerBinary, "exec", "outsider", "ping", "-c", "1", "-W", "1", ip)
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Containers on other networks should be isolated: %s", out)
+	}
+
+	runCmd = exec.Command(dockerBinary, "network", "rm", "testnet")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Removing a network with containers attached should have failed: %s", out)
+	}
+
+	deleteAllContainers()
+
+	logDone("network - run on a user-defined network")
+}
+
+func TestNetworkConnectDisconnect(t *testing.T) {
+	cmd(t, "network", "create", "--subnet=10.66.0.0/24", "testnet")
+	defer exec.Command(dockerBinary, "network", "rm", "testnet").Run()
+
+	cmd(t, "run", "-d", "--name", "connected", "busybox", "sleep", "100")
+	cmd(t, "network", "connect", "testnet", "connected")
+
+	ip, err := inspectField("connected", "NetworkSettings.Networks.testnet.IPAddress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ip, "10.66.0.") {
+		t.Fatalf("Expected an address in the subnet of the network, got %s", ip)
+	}
+	out, _, _ := cmd(t, "exec", "connected", "ip", "addr", "show", "eth1")
+	if !strings.Contains(out, ip) {
+		t.Fatalf("Expected the container to have an interface on the network, got %s", out)
+	}
+
+	// the container is connected again when it restarts
+	cmd(t, "restart", "connected")
+	out, _, _ = cmd(t, "exec", "connected", "ip", "addr", "show", "eth1")
+	if !strings.Contains(out, "10.66.0.") {
+		t.Fatalf("Expected the container to be connected again after a restart, got %s", out)
+	}
+
+	cmd(t, "network", "disconnect", "testnet", "connected")
+	runCmd := exec.Command(dockerBinary, "exec", "connected", "ip", "addr", "show", "eth1")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("The interface on the network should be removed: %s", out)
+	}
+
+	runCmd = exec.Command(dockerBinary, "network", "disconnect", "bridge", "connected")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Disconnecting from the network of the network mode should have failed: %s", out)
+	}
+
+	deleteAllContainers()
+
+	logDone("network - connect and disconnect")
+}
+
+func TestNetworkRunMacvlan(t *testing.T) {
+	// a dummy interface stands for the interface of the host on the LAN
+	for _, args := range [][]string{
+		{"link", "add", "dockermv0", "type", "dummy"},
+		{"addr", "add", "10.77.0.1/24", "dev", "dockermv0"},
+		{"link", "set", "dockermv0", "up"},
+	} {
+		if out, _, err := runCommandWithOutput(exec.Command("ip", args...)); err != nil {
+			t.Skipf("Unable to set up the parent interface: %s", out)
+		}
+	}
+	defer exec.Command("ip", "link", "del", "dockermv0").Run()
+
+	cmd(t, "run", "-d", "--name", "static", "--net=macvlan:dockermv0", "--ip=10.77.0.20", "busybox", "sleep", "100")
+	cmd(t, "run", "-d", "--name", "other", "--net=macvlan:dockermv0", "--ip=10.77.0.21", "busybox", "sleep", "100")
+
+	out, _, _ := cmd(t, "exec", "static", "ip", "addr", "show", "eth0")
+	if !strings.Contains(out, "10.77.0.20/24") {
+		t.Fatalf("Expected the requested address on the interface of the container, got %s", out)
+	}
+
+	runCmd := exec.Command(dockerBinary, "exec", "other", "ping", "-c", "1", "-W", "1", "10.77.0.20")
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		t.Fatalf("Containers on the same parent interface should reach each other: %s", out)
+	}
+
+	// the daemon of the tests has no --macvlan-range for the interface, the
+	// address has to be given
+	runCmd = exec.Command(dockerBinary, "run", "--net=macvlan:dockermv0", "busybox", "true")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Running in the macvlan mode without --ip should have failed: %s", out)
+	}
+
+	runCmd = exec.Command(dockerBinary, "run", "--net=macvlan:dockermv0", "-p", "80:80", "busybox", "true")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Publishing ports in the macvlan mode should have failed: %s", out)
+	}
+
+	deleteAllContainers()
+
+	logDone("network - run on the network of an interface of the host with macvlan")
+}
+
+func TestNetworkCreateOverlayWithoutClusterStore(t *testing.T) {
+	// the daemon of the tests runs without --cluster-store
+	runCmd := exec.Command(dockerBinary, "network", "create", "--driver=overlay", "testoverlay")
+	out, _, err := runCommandWithOutput(runCmd)
+	if err == nil {
+		exec.Command(dockerBinary, "network", "rm", "testoverlay").Run()
+		t.Fatalf("Creating an overlay network without a cluster store should have failed: %s", out)
+	}
+	if !strings.Contains(out, "cluster store") {
+		t.Fatalf("Expected an error about the cluster store, got %s", out)
+	}
+
+	runCmd = exec.Command(dockerBinary, "network", "create", "--driver=unknown", "testoverlay")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Creating a network with an unknown driver should have failed: %s", out)
+	}
+
+	logDone("network - create overlay network without a cluster store")
+}
+
+func TestNetworkRunWithPolicy(t *testing.T) {
+	out, _, _ := cmd(t, "run", "-d", "--name", "policed", "--allow-egress=10.0.0.0/8:5432", "busybox", "sleep", "10")
+	id := stripTrailingCharacters(out)
+	defer deleteAllContainers()
+
+	policy, err := inspectFieldJSON("policed", "HostConfig.NetworkPolicy.Egress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(policy, "10.0.0.0/8") || !strings.Contains(policy, "5432") {
+		t.Fatalf("The policy should be visible in inspect, got %s", policy)
+	}
+
+	chain := "DOCKER-POLICY-" + id[:12]
+	ip := findContainerIp(t, "policed")
+	jump := []string{"FORWARD", "-t", "mangle", "-s", ip, "-j", chain}
+	if !iptables.Exists(jump...) {
+		t.Fatal("The traffic of the container should go through the chain of its policy")
+	}
+	if !iptables.Exists("INPUT", "-t", "mangle", "-s", ip, "-j", chain) {
+		t.Fatal("The traffic of the container to the host should go through the chain of its policy")
+	}
+	gateway, err := inspectField("policed", "NetworkSettings.Gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !iptables.Exists(chain, "-t", "mangle", "-s", ip, "-d", gateway, "-p", "udp", "--dport", "53", "-j", "RETURN") {
+		t.Fatal("The dns queries of the container to the gateway should be allowed")
+	}
+
+	cmd(t, "kill", "policed")
+	if iptables.Exists(jump...) {
+		t.Fatal("The chain of the policy should be removed when the container stops")
+	}
+	if _, err := iptables.Raw("-t", "mangle", "-n", "-L", chain); err == nil {
+		t.Fatalf("The chain %s should be removed when the container stops", chain)
+	}
+
+	logDone("network - run with a network policy")
+}
+
+func TestNetworkPolicyIPv6(t *testing.T) {
+	out, _, _ := cmd(t, "run", "-d", "--name", "policed", "--allow-egress=10.0.0.0/8:5432", "busybox", "sleep", "10")
+	id := stripTrailingCharacters(out)
+	defer deleteAllContainers()
+
+	linkLocal, err := inspectField("policed", "NetworkSettings.LinkLocalIPv6Address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linkLocal == "" {
+		t.Skip("Test not running with a daemon started with --ipv6")
+	}
+	global, err := inspectField("policed", "NetworkSettings.GlobalIPv6Address")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain := "DOCKER-POLICY-" + id[:12]
+	addresses := []string{linkLocal}
+	if global != "" {
+		addresses = append(addresses, global)
+	}
+	for _, ip := range addresses {
+		if !iptables.Exists6("FORWARD", "-t", "mangle", "-s", ip, "-j", chain) {
+			t.Fatalf("The IPv6 traffic of the address %s should go through the chain of the policy", ip)
+		}
+		if !iptables.Exists6("INPUT", "-t", "mangle", "-s", ip, "-j", chain) {
+			t.Fatalf("The IPv6 traffic of the address %s to the host should go through the chain of the policy", ip)
+		}
+		// the IPv4 peers of the rule cannot be reached over IPv6
+		if !iptables.Exists6(chain, "-t", "mangle", "-s", ip, "-j", "DROP") {
+			t.Fatalf("The IPv6 traffic of the address %s should be dropped", ip)
+		}
+	}
+
+	cmd(t, "kill", "policed")
+	if _, err := iptables.Raw6("-t", "mangle", "-n", "-L", chain); err == nil {
+		t.Fatalf("The IPv6 chain %s should be removed when the container stops", chain)
+	}
+
+	logDone("network - network policy over IPv6")
+}
+
+func TestNetworkPolicyToTheHost(t *testing.T) {
+	l, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	accepted := make(chan struct{}, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+			accepted <- struct{}{}
+		}
+	}()
+	defer deleteAllContainers()
+
+	// the container reaches a service of the host through its gateway
+	connect := fmt.Sprintf("nc -w 2 $(ip route | awk '/default/ { print $3 }') %d </dev/null", l.Addr().(*net.TCPAddr).Port)
+	cmd(t, "run", "busybox", "sh", "-c", connect)
+	select {
+	case <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the container without policy to reach the host")
+	}
+
+	runCmd := exec.Command(dockerBinary, "run", "--allow-egress=10.0.0.0/8:5432", "busybox", "sh", "-c", connect)
+	runCommandWithOutput(runCmd)
+	select {
+	case <-accepted:
+		t.Fatal("The policy of the container should keep it from reaching the host")
+	case <-time.After(time.Second):
+	}
+
+	logDone("network - the network policy restricts the traffic to the host")
+}
@@ -183,6 +183,36 @@ func TestListContainers(t *testing.T) {
 	logDone("ps - test ps options")
 }
 
+func TestPsListContainersFilterLabel(t *testing.T) {
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--label", "owner=web", "busybox", "true")
+	out, _, err := runCommandWithOutput(runCmd)
+	errorOut(err, t, out)
+	webID := stripTrailingCharacters(out)
+
+	runCmd = exec.Command(dockerBinary, "run", "-d", "--label", "owner=infra", "busybox", "true")
+	out, _, err = runCommandWithOutput(runCmd)
+	errorOut(err, t, out)
+	infraID := stripTrailingCharacters(out)
+
+	runCmd = exec.Command(dockerBinary, "ps", "-a", "-q", "--no-trunc", "--filter", "label=owner=web")
+	out, _, err = runCommandWithOutput(runCmd)
+	errorOut(err, t, out)
+	if stripTrailingCharacters(out) != webID {
+		t.Fatalf("Expected only %s to match label=owner=web, got %s", webID, out)
+	}
+
+	runCmd = exec.Command(dockerBinary, "ps", "-a", "-q", "--no-trunc", "--filter", "label=owner")
+	out, _, err = runCommandWithOutput(runCmd)
+	errorOut(err, t, out)
+	if !strings.Contains(out, webID) || !strings.Contains(out, infraID) {
+		t.Fatalf("Expected both containers to match label=owner, got %s", out)
+	}
+
+	deleteAllContainers()
+
+	logDone("ps - filter containers by label")
+}
+
 func assertContainerList(out string, expected []string) bool {
 	lines := strings.Split(strings.Trim(out, "\n "), "\n")
 	if len(lines)-1 != len(expected) {
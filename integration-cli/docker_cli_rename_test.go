@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRenameRunningContainer(t *testing.T) {
+	runCmd := exec.Command(dockerBinary, "run", "--name", "first_name", "-d", "busybox", "sh", "-c", "sleep 100")
+	out, _, err := runCommandWithOutput(runCmd)
+	errorOut(err, t, out)
+	cleanedContainerID := stripTrailingCharacters(out)
+
+	runCmd = exec.Command(dockerBinary, "rename", "first_name", "new_name")
+	out, _, err = runCommandWithOutput(runCmd)
+	errorOut(err, t, out)
+
+	name, err := inspectField(cleanedContainerID, "Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "/new_name" {
+		t.Fatalf("Failed to rename container, got %s", name)
+	}
+
+	if _, err := inspectField("first_name", "Name"); err == nil {
+		t.Fatal("The old name of the container should not be in use anymore")
+	}
+
+	deleteAllContainers()
+
+	logDone("rename - running container")
+}
+
+func TestRenameConflictingName(t *testing.T) {
+	cmd(t, "run", "--name", "first_name", "-d", "busybox", "true")
+	cmd(t, "run", "--name", "second_name", "-d", "busybox", "true")
+
+	runCmd := exec.Command(dockerBinary, "rename", "first_name", "second_name")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Renaming to a name in use should have failed: %s", out)
+	}
+
+	name, err := inspectField("first_name", "Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "/first_name" {
+		t.Fatalf("The container should have kept its name, got %s", name)
+	}
+
+	deleteAllContainers()
+
+	logDone("rename - conflicting name")
+}
+
+func TestRenameLinkedContainer(t *testing.T) {
+	cmd(t, "run", "-d", "--name", "child", "busybox", "sleep", "100")
+	cmd(t, "run", "-d", "--name", "parent", "--link", "child:db", "busybox", "sleep", "100")
+
+	cmd(t, "rename", "child", "renamed_child")
+
+	links, err := inspectFieldJSON("parent", "HostConfig.Links")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links != `["/renamed_child:/parent/db"]` {
+		t.Fatalf("The link should follow the new name, got %s", links)
+	}
+
+	out, _, _ := cmd(t, "exec", "parent", "cat", "/etc/hosts")
+	if !strings.Contains(out, "db renamed_child") {
+		t.Fatalf("The hosts file of the parent should resolve the new name, got %s", out)
+	}
+
+	deleteAllContainers()
+
+	logDone("rename - linked container")
+}
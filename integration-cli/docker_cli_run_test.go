@@ -1013,6 +1013,46 @@ func TestRunWithCpuset(t *testing.T) {
 	logDone("run - cpuset 0")
 }
 
+func TestRunWithCpuQuotaAndBlkioWeight(t *testing.T) {
+	cmd := exec.Command(dockerBinary, "run", "--name", "limited", "--cpu-period", "50000", "--cpu-quota", "25000", "--blkio-weight", "300", "busybox", "true")
+	if out, _, err := runCommandWithOutput(cmd); err != nil {
+		t.Fatal(err, out)
+	}
+
+	for field, expected := range map[string]string{
+		"HostConfig.CpuPeriod":   "50000",
+		"HostConfig.CpuQuota":    "25000",
+		"HostConfig.BlkioWeight": "300",
+	} {
+		value, err := inspectField("limited", field)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Fatalf("Expected %s to be %s, got %s", field, expected, value)
+		}
+	}
+
+	deleteAllContainers()
+
+	logDone("run - cpu quota and blkio weight")
+}
+
+func TestRunWithInvalidBlkioWeight(t *testing.T) {
+	cmd := exec.Command(dockerBinary, "run", "--blkio-weight", "5", "busybox", "true")
+	out, _, err := runCommandWithOutput(cmd)
+	if err == nil {
+		t.Fatalf("Running with a blkio weight of 5 should have failed: %s", out)
+	}
+	if !strings.Contains(out, "Block I/O weight") {
+		t.Fatalf("Expected an error about the blkio weight, got %s", out)
+	}
+
+	deleteAllContainers()
+
+	logDone("run - invalid blkio weight")
+}
+
 func TestDeviceNumbers(t *testing.T) {
 	cmd := exec.Command(dockerBinary, "run", "busybox", "sh", "-c", "ls -l /dev/null")
 
@@ -1185,23 +1225,27 @@ func TestDockerRunWithVolumesIsRecursive(t *testing.T) {
 }
 
 func TestDnsDefaultOptions(t *testing.T) {
-	cmd := exec.Command(dockerBinary, "run", "busybox", "cat", "/etc/resolv.conf")
-
-	actual, _, err := runCommandWithOutput(cmd)
-	if err != nil {
-		t.Fatal(err, actual)
-	}
+	defer deleteAllContainers()
+	cmd(t, "run", "-d", "--name", "dnsdefault", "busybox", "sleep", "100")
+	actual, _, _ := cmd(t, "exec", "dnsdefault", "cat", "/etc/resolv.conf")
 
 	resolvConf, err := ioutil.ReadFile("/etc/resolv.conf")
 	if os.IsNotExist(err) {
 		t.Fatalf("/etc/resolv.conf does not exist")
 	}
-
-	if actual != string(resolvConf) {
-		t.Fatalf("expected resolv.conf is not the same of actual")
+	gateway, err := inspectField("dnsdefault", "NetworkSettings.Gateway")
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	deleteAllContainers()
+	// the embedded dns server listens on the gateway of the container
+	if nameservers := resolvconf.GetNameservers([]byte(actual)); len(nameservers) != 1 || nameservers[0] != gateway {
+		t.Fatalf("expected the nameserver %s, but says: %v", gateway, nameservers)
+	}
+	hostSearch := resolvconf.GetSearchDomains(resolvConf)
+	if actualSearch := resolvconf.GetSearchDomains([]byte(actual)); !reflect.DeepEqual(actualSearch, hostSearch) {
+		t.Fatalf("expected the search domains %v of the host, but says: %v", hostSearch, actualSearch)
+	}
 
 	logDone("run - dns default options")
 }
@@ -1240,7 +1284,6 @@ func TestDnsOptionsBasedOnHostResolvConf(t *testing.T) {
 		t.Fatalf("/etc/resolv.conf does not exist")
 	}
 
-	hostNamservers := resolvconf.GetNameservers(resolvConf)
 	hostSearch := resolvconf.GetSearchDomains(resolvConf)
 
 	cmd := exec.Command(dockerBinary, "run", "--dns=127.0.0.1", "busybox", "cat", "/etc/resolv.conf")
@@ -1264,21 +1307,27 @@ func TestDnsOptionsBasedOnHostResolvConf(t *testing.T) {
 		}
 	}
 
-	cmd = exec.Command(dockerBinary, "run", "--dns-search=mydomain", "busybox", "cat", "/etc/resolv.conf")
+	cmd = exec.Command(dockerBinary, "run", "-d", "--name", "dnssearch", "--dns-search=mydomain", "busybox", "sleep", "100")
 
 	out, _, err = runCommandWithOutput(cmd)
 	if err != nil {
 		t.Fatal(err, out)
 	}
+	gateway, err := inspectField("dnssearch", "NetworkSettings.Gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command(dockerBinary, "exec", "dnssearch", "cat", "/etc/resolv.conf")
 
-	actualNameservers := resolvconf.GetNameservers([]byte(out))
-	if len(actualNameservers) != len(hostNamservers) {
-		t.Fatalf("expected '%s' nameserver(s), but it has: '%s'", len(hostNamservers), len(actualNameservers))
+	out, _, err = runCommandWithOutput(cmd)
+	if err != nil {
+		t.Fatal(err, out)
 	}
-	for i := range actualNameservers {
-		if actualNameservers[i] != hostNamservers[i] {
-			t.Fatalf("expected '%s' nameserver, but says: '%s'", actualNameservers[i], hostNamservers[i])
-		}
+
+	// the queries go to the embedded dns server, which forwards them to the
+	// nameservers of the host
+	if actualNameservers := resolvconf.GetNameservers([]byte(out)); len(actualNameservers) != 1 || actualNameservers[0] != gateway {
+		t.Fatalf("expected the nameserver %s, but says: %v", gateway, actualNameservers)
 	}
 
 	if actualSearch = resolvconf.GetSearchDomains([]byte(out)); string(actualSearch[0]) != "mydomain" {
@@ -1630,3 +1679,83 @@ func TestWriteResolvFileAndNotCommit(t *testing.T) {
 
 	logDone("run - write to /etc/resolv.conf and not commited")
 }
+
+func TestRunWithIPAddress(t *testing.T) {
+	cmd(t, "network", "create", "--subnet=10.66.0.0/24", "testnet")
+	defer exec.Command(dockerBinary, "network", "rm", "testnet").Run()
+	defer deleteAllContainers()
+
+	cmd(t, "run", "-d", "--name", "fixed", "--net=testnet", "--ip=10.66.0.42", "busybox", "sleep", "100")
+	ip, err := inspectField("fixed", "NetworkSettings.IPAddress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip != "10.66.0.42" {
+		t.Fatalf("Expected the requested ip 10.66.0.42, got %s", ip)
+	}
+
+	runCmd := exec.Command(dockerBinary, "run", "--net=testnet", "--ip=10.66.0.42", "busybox", "true")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Requesting an ip in use should have failed: %s", out)
+	}
+	runCmd = exec.Command(dockerBinary, "run", "--net=testnet", "--ip=10.67.0.42", "busybox", "true")
+	if out, _, err := runCommandWithOutput(runCmd); err == nil {
+		t.Fatalf("Requesting an ip outside of the network should have failed: %s", out)
+	}
+
+	logDone("run - with a requested ip address")
+}
+
+func TestRunWithHealthcheck(t *testing.T) {
+	defer deleteAllContainers()
+
+	cmd(t, "run", "-d", "--name", "probed", "--health-cmd", "test -e /healthy", "--health-interval", "1s", "--health-retries", "1", "busybox", "sh", "-c", "touch /healthy && sleep 100")
+
+	waitHealth := func(expected string) {
+		var status string
+		for i := 0; i < 20; i++ {
+			var err error
+			if status, err = inspectField("probed", "State.Health.Status"); err != nil {
+				t.Fatal(err)
+			}
+			if status == expected {
+				return
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+		t.Fatalf("Expected the container to be %s, got %s", expected, status)
+	}
+
+	waitHealth("healthy")
+	out, _, err := dockerCmd(t, "ps")
+	if err != nil {
+		t.Fatal(err, out)
+	}
+	if !strings.Contains(out, "(healthy)") {
+		t.Fatalf("Expected ps to show the health status: %s", out)
+	}
+
+	cmd(t, "exec", "probed", "rm", "/healthy")
+	waitHealth("unhealthy")
+
+	eventsCmd := exec.Command(dockerBinary, "events", "--since=0", fmt.Sprintf("--until=%d", time.Now().Unix()+1))
+	out, _, err = runCommandWithOutput(eventsCmd)
+	if err != nil {
+		t.Fatal(err, out)
+	}
+	if !strings.Contains(out, "health_status: healthy") || !strings.Contains(out, "health_status: unhealthy") {
+		t.Fatalf("Expected the health status changes in the events: %s", out)
+	}
+
+	// The health status of a stopped container is cleared
+	cmd(t, "stop", "-t", "1", "probed")
+	health, err := inspectFieldJSON("probed", "State.Health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health != "null" {
+		t.Fatalf("Expected no health status once stopped, got %s", health)
+	}
+
+	logDone("run - with a healthcheck")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/stats"
+)
+
+func TestStatsAPI(t *testing.T) {
+	runCmd := exec.Command(dockerBinary, "run", "-d", "busybox", "top")
+	out, _, err := runCommandWithOutput(runCmd)
+	errorOut(err, t, fmt.Sprintf("failed to start the container: %s, %v", out, err))
+	id := strings.TrimSpace(out)
+	defer deleteAllContainers()
+
+	body, status, err := sockRequest("GET", "/containers/"+id+"/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if status != 200 {
+		t.Fatalf("Expected the status 200 streaming the stats, got %d", status)
+	}
+
+	var s stats.Stats
+	if err := json.NewDecoder(body).Decode(&s); err != nil {
+		t.Fatalf("failed to read a sample of the stats: %v", err)
+	}
+	if s.Read.IsZero() {
+		t.Fatal("the sample should have the time it was read at")
+	}
+	if s.CpuStats.SystemUsage == 0 || len(s.CpuStats.CpuUsage.PercpuUsage) == 0 {
+		t.Fatalf("the sample should have the CPU usage of the container and of the host, got %+v", s.CpuStats)
+	}
+	if s.MemoryStats.Usage == 0 || s.MemoryStats.Limit == 0 {
+		t.Fatalf("the sample should have the memory usage and limit of the container, got %+v", s.MemoryStats)
+	}
+
+	logDone("stats - read a sample through the API")
+}
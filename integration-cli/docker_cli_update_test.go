@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+)
+
+// readCgroupFile reads the file of the cgroup of the container id in the
+// hierarchy of subsystem, from the host: inside the container the cgroup
+// files show the limits of the root cgroup
+func readCgroupFile(t *testing.T, id, subsystem, file string) string {
+	for _, dir := range []string{
+		path.Join("/sys/fs/cgroup", subsystem, "docker", id),
+		path.Join("/sys/fs/cgroup", subsystem, "system.slice", "docker-"+id+".scope"),
+	} {
+		if content, err := ioutil.ReadFile(path.Join(dir, file)); err == nil {
+			return strings.TrimSpace(string(content))
+		}
+	}
+	t.Fatalf("Could not find the %s cgroup of the container %s", subsystem, id)
+	return ""
+}
+
+func TestUpdateRunningContainer(t *testing.T) {
+	cmd(t, "run", "-d", "--name", "parent", "-m", "300M", "busybox", "sleep", "100")
+
+	cmd(t, "update", "-m", "500M", "-c", "512", "parent")
+
+	memory, err := inspectField("parent", "Config.Memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if memory != "524288000" {
+		t.Fatalf("Expected the memory limit to be updated, got %s", memory)
+	}
+	shares, err := inspectField("parent", "Config.CpuShares")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shares != "512" {
+		t.Fatalf("Expected the cpu shares to be updated, got %s", shares)
+	}
+
+	// The new limits reach the cgroup of the running container
+	id, err := inspectField("parent", "Id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit := readCgroupFile(t, id, "memory", "memory.limit_in_bytes"); limit != "524288000" {
+		t.Fatalf("Expected the memory cgroup to be updated, got %s", limit)
+	}
+	if cgroupShares := readCgroupFile(t, id, "cpu", "cpu.shares"); cgroupShares != "512" {
+		t.Fatalf("Expected the cpu cgroup to be updated, got %s", cgroupShares)
+	}
+
+	deleteAllContainers()
+
+	logDone("update - running container")
+}
+
+func TestUpdateStoppedContainer(t *testing.T) {
+	cmd(t, "run", "--name", "parent", "-m", "300M", "busybox", "true")
+
+	cmd(t, "update", "-m", "500M", "parent")
+
+	cmd(t, "start", "parent")
+	memory, err := inspectField("parent", "Config.Memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if memory != "524288000" {
+		t.Fatalf("Expected the memory limit to persist, got %s", memory)
+	}
+
+	deleteAllContainers()
+
+	logDone("update - stopped container")
+}
+
+func TestUpdateInvalidMemory(t *testing.T) {
+	cmd(t, "run", "-d", "--name", "parent", "busybox", "sleep", "100")
+
+	runCmd := exec.Command(dockerBinary, "update", "-m", "1k", "parent")
+	out, _, err := runCommandWithOutput(runCmd)
+	if err == nil {
+		t.Fatalf("Updating to a memory limit below 512k should have failed: %s", out)
+	}
+	if !strings.Contains(out, "Minimum memory limit allowed is 512k") {
+		t.Fatalf("Expected an error about the minimum memory limit, got %s", out)
+	}
+
+	deleteAllContainers()
+
+	logDone("update - invalid memory")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestVolumeCreateInspectRm(t *testing.T) {
+	createCmd := exec.Command(dockerBinary, "volume", "create", "--name", "testvolume")
+	out, _, err := runCommandWithOutput(createCmd)
+	errorOut(err, t, fmt.Sprintf("failed to create volume: %v %v", out, err))
+	if strings.TrimSpace(out) != "testvolume" {
+		t.Fatalf("Expected the name of the volume, got %q", out)
+	}
+
+	inspectCmd := exec.Command(dockerBinary, "volume", "inspect", "testvolume")
+	out, _, err = runCommandWithOutput(inspectCmd)
+	errorOut(err, t, fmt.Sprintf("failed to inspect volume: %v %v", out, err))
+	if !strings.Contains(out, "\"Mountpoint\"") {
+		t.Fatalf("Expected the mountpoint of the volume, got %q", out)
+	}
+
+	lsCmd := exec.Command(dockerBinary, "volume", "ls")
+	out, _, err = runCommandWithOutput(lsCmd)
+	errorOut(err, t, fmt.Sprintf("failed to list volumes: %v %v", out, err))
+	if !strings.Contains(out, "testvolume") {
+		t.Fatalf("Expected testvolume to be listed, got %q", out)
+	}
+
+	rmCmd := exec.Command(dockerBinary, "volume", "rm", "testvolume")
+	out, _, err = runCommandWithOutput(rmCmd)
+	errorOut(err, t, fmt.Sprintf("failed to remove volume: %v %v", out, err))
+
+	inspectCmd = exec.Command(dockerBinary, "volume", "inspect", "testvolume")
+	if out, _, err = runCommandWithOutput(inspectCmd); err == nil {
+		t.Fatalf("Expected the volume to be removed, got %q", out)
+	}
+
+	logDone("volume - create, inspect and remove a named volume")
+}
+
+func TestVolumeNamedPersistsAcrossContainers(t *testing.T) {
+	defer deleteAllContainers()
+
+	runCmd := exec.Command(dockerBinary, "run", "-v", "testdata:/data", "busybox", "sh", "-c", "echo hello > /data/file")
+	out, _, err := runCommandWithOutput(runCmd)
+	errorOut(err, t, fmt.Sprintf("failed to run container: %v %v", out, err))
+
+	rmCmd := exec.Command(dockerBinary, "volume", "rm", "testdata")
+	if out, _, err = runCommandWithOutput(rmCmd); err == nil {
+		t.Fatalf("Expected the removal of a volume in use to fail, got %q", out)
+	}
+
+	runCmd = exec.Command(dockerBinary, "run", "-v", "testdata:/data", "busybox", "cat", "/data/file")
+	out, _, err = runCommandWithOutput(runCmd)
+	errorOut(err, t, fmt.Sprintf("failed to run container: %v %v", out, err))
+	if strings.TrimSpace(out) != "hello" {
+		t.Fatalf("Expected the content of the volume to persist, got %q", out)
+	}
+
+	deleteAllContainers()
+
+	rmCmd = exec.Command(dockerBinary, "volume", "rm", "testdata")
+	out, _, err = runCommandWithOutput(rmCmd)
+	errorOut(err, t, fmt.Sprintf("failed to remove volume: %v %v", out, err))
+
+	logDone("volume - named volumes persist across containers")
+}
+
+func TestVolumePrune(t *testing.T) {
+	defer deleteAllContainers()
+
+	runCmd := exec.Command(dockerBinary, "run", "-v", "/data", "busybox", "true")
+	out, _, err := runCommandWithOutput(runCmd)
+	errorOut(err, t, fmt.Sprintf("failed to run container: %v %v", out, err))
+
+	deleteAllContainers()
+
+	pruneCmd := exec.Command(dockerBinary, "volume", "prune")
+	out, _, err = runCommandWithOutput(pruneCmd)
+	errorOut(err, t, fmt.Sprintf("failed to prune volumes: %v %v", out, err))
+	if strings.TrimSpace(out) == "" {
+		t.Fatalf("Expected the orphaned volume to be pruned")
+	}
+
+	logDone("volume - prune removes orphaned volumes")
+}
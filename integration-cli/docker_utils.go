@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
 	"os"
 	"os/exec"
 	"path"
@@ -108,6 +111,46 @@ func dockerCmdInDir(t *testing.T, path string, args ...string) (string, int, err
 	return out, status, err
 }
 
+// sockRequest sends a request to the API of the daemon of DOCKER_HOST, or of
+// the default unix socket, and returns the body of the response, which
+// closes the connection once closed
+func sockRequest(method, endpoint string) (io.ReadCloser, int, error) {
+	proto, addr := "unix", "/var/run/docker.sock"
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		parts := strings.SplitN(host, "://", 2)
+		if len(parts) != 2 {
+			return nil, -1, fmt.Errorf("invalid DOCKER_HOST %q", host)
+		}
+		proto, addr = parts[0], parts[1]
+	}
+	conn, err := net.Dial(proto, addr)
+	if err != nil {
+		return nil, -1, err
+	}
+	client := httputil.NewClientConn(conn, nil)
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		client.Close()
+		return nil, -1, err
+	}
+	res, err := client.Do(req)
+	if err != nil && err != httputil.ErrPersistEOF {
+		client.Close()
+		return nil, -1, err
+	}
+	return &sockBody{res.Body, client}, res.StatusCode, nil
+}
+
+type sockBody struct {
+	io.ReadCloser
+	client *httputil.ClientConn
+}
+
+func (b *sockBody) Close() error {
+	b.ReadCloser.Close()
+	return b.client.Close()
+}
+
 func findContainerIp(t *testing.T, id string) string {
 	cmd := exec.Command(dockerBinary, "inspect", "--format='{{ .NetworkSettings.IPAddress }}'", id)
 	out, _, err := runCommandWithOutput(cmd)
@@ -657,6 +657,51 @@ func TestRestore(t *testing.T) {
 	container2.State.SetStopped(0)
 }
 
+// The containers restarted with the daemon get their previous address back,
+// even when the daemon stopped them cleanly
+func TestRestoreIPAddress(t *testing.T) {
+	eng := newTestEngine(t, true, "")
+	daemon1 := mkDaemonFromEngine(eng, t)
+	root := daemon1.Config().Root
+
+	config, _, _, err := runconfig.Parse([]string{"-i", unitTestImageID, "/bin/cat"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := createTestContainer(eng, config, t)
+	job := eng.Job("start", id)
+	if err := job.SetenvJson("RestartPolicy", runconfig.RestartPolicy{Name: "always"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := job.Run(); err != nil {
+		t.Fatal(err)
+	}
+	ip := daemon1.Get(id).NetworkSettings.IPAddress
+	if ip == "" {
+		t.Fatal("Expected the container to have an ip")
+	}
+
+	// A clean shutdown stops the containers and releases their network
+	eng.Shutdown()
+	if c := daemon1.Get(id); c.State.IsRunning() || c.NetworkSettings.IPAddress != "" {
+		t.Fatalf("Expected the container to be stopped without ip, got %s", c.NetworkSettings.IPAddress)
+	}
+
+	eng = newTestEngine(t, true, root)
+	daemon2 := mkDaemonFromEngine(eng, t)
+	defer nuke(daemon2)
+	container := daemon2.Get(id)
+	if container == nil {
+		t.Fatal("Unable to Get container")
+	}
+	if !container.State.IsRunning() {
+		t.Fatalf("Expected the container %s to be restarted with the daemon", id)
+	}
+	if container.NetworkSettings.IPAddress != ip {
+		t.Fatalf("Expected the container to get its ip %s back, got %s", ip, container.NetworkSettings.IPAddress)
+	}
+}
+
 func TestDefaultContainerName(t *testing.T) {
 	eng := NewTestEngine(t)
 	daemon := mkDaemonFromEngine(eng, t)
@@ -1,6 +1,7 @@
 package nat
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -187,3 +188,32 @@ func TestParsePortSpecs(t *testing.T) {
 		t.Fatal("Received no error while trying to parse a hostname instead of ip")
 	}
 }
+
+func TestParsePortSpecsWithRange(t *testing.T) {
+	portMap, bindingMap, err := ParsePortSpecs([]string{"8000-8002:9000-9002/udp", "127.0.0.1::7000-7001"})
+	if err != nil {
+		t.Fatalf("Error while processing ParsePortSpecs: %s", err.Error())
+	}
+	if len(portMap) != 5 {
+		t.Fatalf("Expected each port of the ranges to be exposed, got %v", portMap)
+	}
+
+	for i, port := range []Port{"9000/udp", "9001/udp", "9002/udp"} {
+		bindings := bindingMap[port]
+		if len(bindings) != 1 || bindings[0].HostPort != fmt.Sprint(8000+i) {
+			t.Fatalf("Expected %s to be bound to %d, got %v", port, 8000+i, bindings)
+		}
+	}
+	for _, port := range []Port{"7000/tcp", "7001/tcp"} {
+		bindings := bindingMap[port]
+		if len(bindings) != 1 || bindings[0].HostIp != "127.0.0.1" || bindings[0].HostPort != "" {
+			t.Fatalf("Expected %s to be bound to any port of 127.0.0.1, got %v", port, bindings)
+		}
+	}
+
+	for _, spec := range []string{"8000-8001:9000-9002", "8000:9000-9001", "9002-9000", "9000-"} {
+		if _, _, err := ParsePortSpecs([]string{spec}); err == nil {
+			t.Fatalf("Received no error while parsing the invalid range %s", spec)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package nat
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// BindingRange binds consecutive ports of a container to consecutive ports
+// of the host, published at once
+type BindingRange struct {
+	Proto    string
+	Port     int // first port of the container
+	Count    int
+	HostIp   string
+	HostPort int // first port of the host, 0 when any free range will do
+	// Index is the index of the binding in the bindings of each of the
+	// ports, which may be published several times
+	Index int
+}
+
+// Ports returns the ports of the container in the range
+func (r BindingRange) Ports() []Port {
+	ports := make([]Port, r.Count)
+	for i := range ports {
+		ports[i] = NewPort(r.Proto, strconv.Itoa(r.Port+i))
+	}
+	return ports
+}
+
+// GroupBindings groups the bindings of consecutive ports into ranges, the
+// host ports of which are either all to be allocated or consecutive too
+func GroupBindings(bindings PortMap) ([]BindingRange, error) {
+	var ranges []BindingRange
+	for port, portBindings := range bindings {
+		for i, b := range portBindings {
+			r := BindingRange{
+				Proto:  port.Proto(),
+				Count:  1,
+				HostIp: b.HostIp,
+				Index:  i,
+			}
+			containerPort, err := ParsePort(port.Port())
+			if err != nil {
+				return nil, fmt.Errorf("Invalid port: %s", port)
+			}
+			r.Port = containerPort
+			if b.HostPort != "" {
+				if r.HostPort, err = ParsePort(b.HostPort); err != nil {
+					return nil, fmt.Errorf("Invalid hostPort: %s", b.HostPort)
+				}
+			}
+			ranges = append(ranges, r)
+		}
+	}
+	sort.Sort(byBinding(ranges))
+
+	var grouped []BindingRange
+	for _, r := range ranges {
+		if n := len(grouped); n > 0 && grouped[n-1].extendedBy(r) {
+			grouped[n-1].Count++
+			continue
+		}
+		grouped = append(grouped, r)
+	}
+	return grouped, nil
+}
+
+// extendedBy tells whether the single port binding next continues r
+func (r BindingRange) extendedBy(next BindingRange) bool {
+	if r.Proto != next.Proto || r.HostIp != next.HostIp || r.Index != next.Index {
+		return false
+	}
+	if next.Port != r.Port+r.Count {
+		return false
+	}
+	if r.HostPort == 0 {
+		return next.HostPort == 0
+	}
+	return next.HostPort == r.HostPort+r.Count
+}
+
+type byBinding []BindingRange
+
+func (s byBinding) Len() int      { return len(s) }
+func (s byBinding) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byBinding) Less(i, j int) bool {
+	a, b := s[i], s[j]
+	if a.Proto != b.Proto {
+		return a.Proto < b.Proto
+	}
+	if a.HostIp != b.HostIp {
+		return a.HostIp < b.HostIp
+	}
+	if a.Index != b.Index {
+		return a.Index < b.Index
+	}
+	return a.Port < b.Port
+}
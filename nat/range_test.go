@@ -0,0 +1,37 @@
+package nat
+
+import (
+	"testing"
+)
+
+func TestGroupBindings(t *testing.T) {
+	_, bindings, err := ParsePortSpecs([]string{"8000-8002:9000-9002", "9003", "9004", "10.0.0.1::9005", "53:53/udp", "8080:80", "8081:80"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranges, err := GroupBindings(bindings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []BindingRange{
+		{Proto: "tcp", Port: 80, Count: 1, HostPort: 8080},
+		{Proto: "tcp", Port: 9000, Count: 3, HostPort: 8000},
+		{Proto: "tcp", Port: 9003, Count: 2},
+		{Proto: "tcp", Port: 80, Count: 1, HostPort: 8081, Index: 1},
+		{Proto: "tcp", Port: 9005, Count: 1, HostIp: "10.0.0.1"},
+		{Proto: "udp", Port: 53, Count: 1, HostPort: 53},
+	}
+	if len(ranges) != len(expected) {
+		t.Fatalf("Expected %d ranges, got %v", len(expected), ranges)
+	}
+	for i, r := range ranges {
+		if r != expected[i] {
+			t.Fatalf("Expected %+v, got %+v", expected[i], r)
+		}
+	}
+
+	if ports := ranges[2].Ports(); len(ports) != 2 || ports[0] != "9003/tcp" || ports[1] != "9004/tcp" {
+		t.Fatalf("Expected the ports of the range, got %v", ports)
+	}
+}
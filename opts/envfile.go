@@ -43,6 +43,34 @@ func ParseEnvFile(filename string) ([]string, error) {
 	return lines, nil
 }
 
+/*
+Read in a line delimited file with labels given as key=value, or key for an
+empty value
+*/
+func ParseLabelFile(filename string) (map[string]string, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	labels := map[string]string{}
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), whiteSpaces)
+		// line is not empty, and not starting with '#'
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := ValidateLabel(line); err != nil {
+			return nil, err
+		}
+		k, v := ParseLabel(line)
+		labels[k] = v
+	}
+	return labels, scanner.Err()
+}
+
 var whiteSpaces = " \t"
 
 type ErrBadEnvVariable struct {
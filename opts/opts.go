@@ -161,6 +161,24 @@ func ValidateEnv(val string) (string, error) {
 	return fmt.Sprintf("%s=%s", val, os.Getenv(val)), nil
 }
 
+// ValidateLabel checks that a label is given as key=value, or key for an
+// empty value
+func ValidateLabel(val string) (string, error) {
+	if k, _ := ParseLabel(val); k == "" {
+		return val, fmt.Errorf("bad format for label: %s", val)
+	}
+	return val, nil
+}
+
+// ParseLabel splits a label in its key and its value
+func ParseLabel(val string) (string, string) {
+	arr := strings.SplitN(val, "=", 2)
+	if len(arr) == 1 {
+		return arr[0], ""
+	}
+	return arr[0], arr[1]
+}
+
 func ValidateIPAddress(val string) (string, error) {
 	var ip = net.ParseIP(strings.TrimSpace(val))
 	if ip != nil {
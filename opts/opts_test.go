@@ -88,3 +88,19 @@ func TestValidateDnsSearch(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateLabel(t *testing.T) {
+	for _, label := range []string{"key=value", "key", "key=", "com.example.key=a=b"} {
+		if _, err := ValidateLabel(label); err != nil {
+			t.Fatalf("ValidateLabel(`%s`) should succeed: %s", label, err)
+		}
+	}
+	for _, label := range []string{"", "=value"} {
+		if _, err := ValidateLabel(label); err == nil {
+			t.Fatalf("ValidateLabel(`%s`) should have failed validation", label)
+		}
+	}
+	if k, v := ParseLabel("com.example.key=a=b"); k != "com.example.key" || v != "a=b" {
+		t.Fatalf("Expected com.example.key and a=b, got %s and %s", k, v)
+	}
+}
@@ -0,0 +1,128 @@
+package dnsserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+const (
+	headerLen = 12
+
+	typeA   = 1
+	typeANY = 255
+	classIN = 1
+
+	rcodeServFail = 2
+
+	flagResponse      = 0x8000
+	flagAuthoritative = 0x0400
+	flagRecursionWant = 0x0100
+	flagRecursionOK   = 0x0080
+)
+
+var (
+	ErrNotQuery   = errors.New("dns message is not a standard query")
+	ErrBadMessage = errors.New("malformed dns message")
+)
+
+// question is the single question of a standard query
+type question struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+	// end is the offset of the end of the question in the message
+	end int
+}
+
+// parseQuery returns the question of msg, which must be a standard query
+// with exactly one question
+func parseQuery(msg []byte) (*question, error) {
+	if len(msg) < headerLen {
+		return nil, ErrBadMessage
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&flagResponse != 0 || (flags>>11)&0xF != 0 {
+		return nil, ErrNotQuery
+	}
+	if binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return nil, ErrNotQuery
+	}
+
+	var (
+		labels []string
+		offset = headerLen
+	)
+	for {
+		if offset >= len(msg) {
+			return nil, ErrBadMessage
+		}
+		length := int(msg[offset])
+		offset++
+		if length == 0 {
+			break
+		}
+		// the names of the questions are never compressed
+		if length&0xC0 != 0 || offset+length > len(msg) {
+			return nil, ErrBadMessage
+		}
+		labels = append(labels, string(msg[offset:offset+length]))
+		offset += length
+	}
+	if offset+4 > len(msg) {
+		return nil, ErrBadMessage
+	}
+	return &question{
+		name:   strings.Join(labels, "."),
+		qtype:  binary.BigEndian.Uint16(msg[offset:]),
+		qclass: binary.BigEndian.Uint16(msg[offset+2:]),
+		end:    offset + 4,
+	}, nil
+}
+
+// response returns the header and question of query turned into a response
+// with rcode and no records
+func response(query []byte, q *question, rcode int) []byte {
+	resp := make([]byte, q.end, q.end+16)
+	copy(resp, query[:q.end])
+
+	flags := binary.BigEndian.Uint16(query[2:])
+	flags = flagResponse | flagAuthoritative | flags&flagRecursionWant | flagRecursionOK | uint16(rcode)
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	// only the question is kept
+	binary.BigEndian.PutUint16(resp[6:], 0)
+	binary.BigEndian.PutUint16(resp[8:], 0)
+	binary.BigEndian.PutUint16(resp[10:], 0)
+	return resp
+}
+
+// answer returns the response to query with an A record for each of the
+// IPv4 addresses of ips
+func answer(query []byte, q *question, ips []net.IP, ttl uint32) []byte {
+	resp := response(query, q, 0)
+	if q.qclass != classIN || (q.qtype != typeA && q.qtype != typeANY) {
+		// the name exists, but has no record of this type
+		return resp
+	}
+
+	var count uint16
+	for _, ip := range ips {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			continue
+		}
+		record := make([]byte, 16)
+		// pointer to the name of the question, right after the header
+		binary.BigEndian.PutUint16(record[0:], 0xC000|headerLen)
+		binary.BigEndian.PutUint16(record[2:], typeA)
+		binary.BigEndian.PutUint16(record[4:], classIN)
+		binary.BigEndian.PutUint32(record[6:], ttl)
+		binary.BigEndian.PutUint16(record[10:], net.IPv4len)
+		copy(record[12:], ip4)
+		resp = append(resp, record...)
+		count++
+	}
+	binary.BigEndian.PutUint16(resp[6:], count)
+	return resp
+}
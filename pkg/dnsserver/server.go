@@ -0,0 +1,217 @@
+// Package dnsserver implements a small DNS server answering the A queries
+// for the names it knows and forwarding the other queries to upstream
+// nameservers.
+package dnsserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+const (
+	// TTL of the answers, short as the addresses of the names change when
+	// their containers restart
+	answerTTL = 10
+
+	forwardTimeout = 2 * time.Second
+	tcpIdleTimeout = 10 * time.Second
+	udpBufSize     = 4096
+)
+
+var ErrNoForwarder = errors.New("no nameserver to forward the query to")
+
+// Lookup returns the addresses of name for the client sending the query.
+// It returns nil when name is not known, the query being forwarded then.
+type Lookup func(name string, client net.IP) []net.IP
+
+// Server answers the queries received on an address over UDP and TCP
+type Server struct {
+	udp        *net.UDPConn
+	tcp        *net.TCPListener
+	lookup     Lookup
+	forwarders func() []string
+}
+
+// NewServer listens on the port of ip, an available port being chosen when
+// port is 0. The queries for the names lookup does not know are forwarded to
+// the nameservers returned by forwarders, given as host or host:port.
+func NewServer(ip net.IP, port int, lookup Lookup, forwarders func() []string) (*Server, error) {
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: port})
+	if err != nil {
+		return nil, err
+	}
+	tcp, err := net.ListenTCP("tcp", &net.TCPAddr{IP: ip, Port: udp.LocalAddr().(*net.UDPAddr).Port})
+	if err != nil {
+		udp.Close()
+		return nil, err
+	}
+	return &Server{
+		udp:        udp,
+		tcp:        tcp,
+		lookup:     lookup,
+		forwarders: forwarders,
+	}, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() *net.UDPAddr {
+	return s.udp.LocalAddr().(*net.UDPAddr)
+}
+
+// Start serves the queries in the background until the server is closed
+func (s *Server) Start() {
+	go s.serveUDP()
+	go s.serveTCP()
+}
+
+func (s *Server) Close() error {
+	s.tcp.Close()
+	return s.udp.Close()
+}
+
+func (s *Server) serveUDP() {
+	for {
+		buf := make([]byte, udpBufSize)
+		read, client, err := s.udp.ReadFromUDP(buf)
+		if err != nil {
+			if !isClosedError(err) {
+				log.Errorf("Stopping the dns server: %s", err)
+			}
+			return
+		}
+		go func() {
+			if resp := s.handle(buf[:read], client.IP, "udp"); resp != nil {
+				if _, err := s.udp.WriteToUDP(resp, client); err != nil {
+					log.Debugf("Unable to answer %s: %s", client, err)
+				}
+			}
+		}()
+	}
+}
+
+func (s *Server) serveTCP() {
+	for {
+		conn, err := s.tcp.AcceptTCP()
+		if err != nil {
+			if !isClosedError(err) {
+				log.Errorf("Stopping the dns server: %s", err)
+			}
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn *net.TCPConn) {
+	defer conn.Close()
+	client := conn.RemoteAddr().(*net.TCPAddr).IP
+	for {
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			return
+		}
+		resp := s.handle(query, client, "tcp")
+		if resp == nil {
+			return
+		}
+		if err := writeTCPMessage(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// handle returns the response to query, nil when there is none to send
+func (s *Server) handle(query []byte, client net.IP, proto string) []byte {
+	q, err := parseQuery(query)
+	if err != nil && err != ErrNotQuery {
+		log.Debugf("Dropping the dns query of %s: %s", client, err)
+		return nil
+	}
+	if q != nil && q.name != "" {
+		if ips := s.lookup(q.name, client); ips != nil {
+			return answer(query, q, ips, answerTTL)
+		}
+	}
+
+	resp, err := s.forward(query, proto)
+	if err != nil {
+		log.Debugf("Unable to forward the dns query of %s: %s", client, err)
+		if q == nil {
+			return nil
+		}
+		return response(query, q, rcodeServFail)
+	}
+	return resp
+}
+
+// forward sends query to the forwarders in turn until one of them answers
+func (s *Server) forward(query []byte, proto string) ([]byte, error) {
+	err := ErrNoForwarder
+	for _, ns := range s.forwarders() {
+		if _, _, splitErr := net.SplitHostPort(ns); splitErr != nil {
+			ns = net.JoinHostPort(ns, "53")
+		}
+		var resp []byte
+		if resp, err = exchange(proto, ns, query); err == nil {
+			return resp, nil
+		}
+	}
+	return nil, err
+}
+
+func exchange(proto, addr string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(proto, addr, forwardTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(forwardTimeout))
+
+	if proto == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(conn)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, udpBufSize)
+	read, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// the messages sent over TCP are prefixed with their length
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+	_, err := w.Write(buf)
+	return err
+}
+
+func isClosedError(err error) bool {
+	return strings.HasSuffix(err.Error(), "use of closed network connection")
+}
@@ -0,0 +1,137 @@
+package dnsserver
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+func newQuery(id uint16, name string, qtype uint16) []byte {
+	msg := make([]byte, headerLen)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], flagRecursionWant)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	for _, label := range strings.Split(name, ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(msg[len(msg)-4:], qtype)
+	binary.BigEndian.PutUint16(msg[len(msg)-2:], classIN)
+	return msg
+}
+
+// answers returns the rcode and the addresses of the A records of resp
+func answers(t *testing.T, resp []byte) (int, []string) {
+	q, err := parseQuery(append([]byte{}, resp...))
+	if err != ErrNotQuery {
+		t.Fatalf("Expected a response, got %v %v", q, err)
+	}
+	rcode := int(binary.BigEndian.Uint16(resp[2:]) & 0xF)
+	count := int(binary.BigEndian.Uint16(resp[6:]))
+
+	// skip the header and the question
+	offset := headerLen
+	for resp[offset] != 0 {
+		offset += int(resp[offset]) + 1
+	}
+	offset += 5
+
+	var ips []string
+	for i := 0; i < count; i++ {
+		if binary.BigEndian.Uint16(resp[offset+2:]) != typeA {
+			t.Fatalf("Expected an A record, got %v", resp[offset:])
+		}
+		ips = append(ips, net.IP(resp[offset+12:offset+16]).String())
+		offset += 16
+	}
+	return rcode, ips
+}
+
+func lookupNames(names map[string]string) Lookup {
+	return func(name string, client net.IP) []net.IP {
+		if ip, exists := names[name]; exists {
+			return []net.IP{net.ParseIP(ip)}
+		}
+		return nil
+	}
+}
+
+func newTestServer(t *testing.T, names map[string]string, forwarders ...string) *Server {
+	s, err := NewServer(net.ParseIP("127.0.0.1"), 0, lookupNames(names), func() []string { return forwarders })
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	return s
+}
+
+func TestParseQuery(t *testing.T) {
+	q, err := parseQuery(newQuery(1, "web.example.com", typeA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.name != "web.example.com" || q.qtype != typeA || q.qclass != classIN {
+		t.Fatalf("Unexpected question %+v", q)
+	}
+
+	query := newQuery(1, "web", typeA)
+	if _, err := parseQuery(query[:len(query)-2]); err != ErrBadMessage {
+		t.Fatalf("Expected %s for a truncated query, got %v", ErrBadMessage, err)
+	}
+	binary.BigEndian.PutUint16(query[4:], 2)
+	if _, err := parseQuery(query); err != ErrNotQuery {
+		t.Fatalf("Expected %s for a query with 2 questions, got %v", ErrNotQuery, err)
+	}
+}
+
+func TestAnswerAndForward(t *testing.T) {
+	upstream := newTestServer(t, map[string]string{"example.com": "10.0.0.1"})
+	defer upstream.Close()
+	s := newTestServer(t, map[string]string{"web": "172.17.0.2"}, upstream.Addr().String())
+	defer s.Close()
+
+	for _, proto := range []string{"udp", "tcp"} {
+		resp, err := exchange(proto, s.Addr().String(), newQuery(42, "web", typeA))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id := binary.BigEndian.Uint16(resp); id != 42 {
+			t.Fatalf("Expected the id of the query, got %d", id)
+		}
+		if rcode, ips := answers(t, resp); rcode != 0 || len(ips) != 1 || ips[0] != "172.17.0.2" {
+			t.Fatalf("Expected the address of web over %s, got %d %v", proto, rcode, ips)
+		}
+
+		resp, err = exchange(proto, s.Addr().String(), newQuery(43, "example.com", typeA))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rcode, ips := answers(t, resp); rcode != 0 || len(ips) != 1 || ips[0] != "10.0.0.1" {
+			t.Fatalf("Expected the answer of the forwarder over %s, got %d %v", proto, rcode, ips)
+		}
+	}
+
+	// a known name has no AAAA record
+	resp, err := exchange("udp", s.Addr().String(), newQuery(44, "web", 28))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcode, ips := answers(t, resp); rcode != 0 || len(ips) != 0 {
+		t.Fatalf("Expected an empty answer, got %d %v", rcode, ips)
+	}
+}
+
+func TestServFailWithoutForwarder(t *testing.T) {
+	s := newTestServer(t, nil)
+	defer s.Close()
+
+	resp, err := exchange("udp", s.Addr().String(), newQuery(1, "unknown", typeA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcode, _ := answers(t, resp); rcode != rcodeServFail {
+		t.Fatalf("Expected a server failure, got %d", rcode)
+	}
+}
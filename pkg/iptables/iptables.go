@@ -26,27 +26,37 @@ var (
 type Chain struct {
 	Name   string
 	Bridge string
+	// HairpinMode sends the traffic of the bridge and of the loopback
+	// interface to the published ports through the chain, as no userland
+	// proxy forwards it
+	HairpinMode bool
 }
 
 func init() {
 	supportsXlock = exec.Command("iptables", "--wait", "-L", "-n").Run() == nil
 }
 
-func NewChain(name, bridge string) (*Chain, error) {
+func NewChain(name, bridge string, hairpinMode bool) (*Chain, error) {
 	if output, err := Raw("-t", "nat", "-N", name); err != nil {
 		return nil, err
 	} else if len(output) != 0 {
 		return nil, fmt.Errorf("Error creating new iptables chain: %s", output)
 	}
 	chain := &Chain{
-		Name:   name,
-		Bridge: bridge,
+		Name:        name,
+		Bridge:      bridge,
+		HairpinMode: hairpinMode,
 	}
 
 	if err := chain.Prerouting(Add, "-m", "addrtype", "--dst-type", "LOCAL"); err != nil {
 		return nil, fmt.Errorf("Failed to inject docker in PREROUTING chain: %s", err)
 	}
-	if err := chain.Output(Add, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", "127.0.0.0/8"); err != nil {
+	outputArgs := []string{"-m", "addrtype", "--dst-type", "LOCAL"}
+	if !hairpinMode {
+		// the userland proxy listens on the loopback addresses
+		outputArgs = append(outputArgs, "!", "--dst", "127.0.0.0/8")
+	}
+	if err := chain.Output(Add, outputArgs...); err != nil {
 		return nil, fmt.Errorf("Failed to inject docker in OUTPUT chain: %s", err)
 	}
 	return chain, nil
@@ -60,6 +70,14 @@ func RemoveExistingChain(name string) error {
 }
 
 func (c *Chain) Forward(action Action, ip net.IP, port int, proto, dest_addr string, dest_port int) error {
+	return c.ForwardRange(action, ip, port, proto, dest_addr, dest_port, 1)
+}
+
+// ForwardRange forwards count consecutive ports of ip starting at port to
+// the ports of dest_addr starting at dest_port. The traffic of the whole
+// range goes through a single rule when the ports are the same on both
+// sides, DNAT being unable to shift ports otherwise.
+func (c *Chain) ForwardRange(action Action, ip net.IP, port int, proto, dest_addr string, dest_port, count int) error {
 	daddr := ip.String()
 	if ip.IsUnspecified() {
 		// iptables interprets "0.0.0.0" as "0.0.0.0/32", whereas we
@@ -67,16 +85,38 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, dest_addr str
 		// value" by both iptables and ip6tables.
 		daddr = "0/0"
 	}
-	if output, err := Raw("-t", "nat", fmt.Sprint(action), c.Name,
-		"-p", proto,
-		"-d", daddr,
-		"--dport", strconv.Itoa(port),
-		"!", "-i", c.Bridge,
-		"-j", "DNAT",
-		"--to-destination", net.JoinHostPort(dest_addr, strconv.Itoa(dest_port))); err != nil {
-		return err
-	} else if len(output) != 0 {
-		return fmt.Errorf("Error iptables forward: %s", output)
+	dnat := func(dports, destination string) error {
+		args := []string{"-t", "nat", fmt.Sprint(action), c.Name,
+			"-p", proto,
+			"-d", daddr,
+			"--dport", dports,
+			"-j", "DNAT",
+			"--to-destination", destination}
+		if !c.HairpinMode {
+			// the containers reach the published ports through the proxy
+			args = append(args, "!", "-i", c.Bridge)
+		}
+		if output, err := Raw(args...); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return fmt.Errorf("Error iptables forward: %s", output)
+		}
+		return nil
+	}
+	if count > 1 && port == dest_port {
+		// the destination port is kept when none is given
+		if err := dnat(portRange(port, count), dest_addr); err != nil {
+			return err
+		}
+	} else {
+		// a range shifted to other ports needs one rule per port, the
+		// destination of DNAT being a single port or a range which is
+		// not mapped port to port
+		for i := 0; i < count; i++ {
+			if err := dnat(strconv.Itoa(port+i), net.JoinHostPort(dest_addr, strconv.Itoa(dest_port+i))); err != nil {
+				return err
+			}
+		}
 	}
 
 	fAction := action
@@ -88,16 +128,39 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, dest_addr str
 		"-o", c.Bridge,
 		"-p", proto,
 		"-d", dest_addr,
-		"--dport", strconv.Itoa(dest_port),
+		"--dport", portRange(dest_port, count),
 		"-j", "ACCEPT"); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return fmt.Errorf("Error iptables forward: %s", output)
 	}
 
+	if c.HairpinMode {
+		// a container reaching its own published port gets the answers
+		// through the bridge
+		if output, err := Raw("-t", "nat", fmt.Sprint(action), "POSTROUTING",
+			"-p", proto,
+			"-s", dest_addr,
+			"-d", dest_addr,
+			"--dport", portRange(dest_port, count),
+			"-j", "MASQUERADE"); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return fmt.Errorf("Error iptables forward: %s", output)
+		}
+	}
+
 	return nil
 }
 
+// portRange returns the iptables notation of count ports starting at port
+func portRange(port, count int) string {
+	if count > 1 {
+		return fmt.Sprintf("%d:%d", port, port+count-1)
+	}
+	return strconv.Itoa(port)
+}
+
 func (c *Chain) Prerouting(action Action, args ...string) error {
 	a := append(nat, fmt.Sprint(action), "PREROUTING")
 	if len(args) > 0 {
@@ -128,7 +191,7 @@ func (c *Chain) Remove() error {
 	// Ignore errors - This could mean the chains were never set up
 	c.Prerouting(Delete, "-m", "addrtype", "--dst-type", "LOCAL")
 	c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", "127.0.0.0/8")
-	c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL") // Created in versions <= 0.1.6
+	c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL") // Created in versions <= 0.1.6 and in hairpin mode
 
 	c.Prerouting(Delete)
 	c.Output(Delete)
@@ -147,8 +210,25 @@ func Exists(args ...string) bool {
 	return true
 }
 
+// Exists6 is Exists for the ip6tables rules
+func Exists6(args ...string) bool {
+	if _, err := Raw6(append([]string{"-C"}, args...)...); err != nil {
+		return false
+	}
+	return true
+}
+
 func Raw(args ...string) ([]byte, error) {
-	path, err := exec.LookPath("iptables")
+	return raw("iptables", args...)
+}
+
+// Raw6 calls ip6tables with args
+func Raw6(args ...string) ([]byte, error) {
+	return raw("ip6tables", args...)
+}
+
+func raw(command string, args ...string) ([]byte, error) {
+	path, err := exec.LookPath(command)
 	if err != nil {
 		return nil, ErrIptablesNotFound
 	}
@@ -163,7 +243,7 @@ func Raw(args ...string) ([]byte, error) {
 
 	output, err := exec.Command(path, args...).CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("iptables failed: iptables %v: %s (%s)", strings.Join(args, " "), output, err)
+		return nil, fmt.Errorf("%s failed: %s %v: %s (%s)", command, command, strings.Join(args, " "), output, err)
 	}
 
 	// ignore iptables' message about xtables lock
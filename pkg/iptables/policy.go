@@ -0,0 +1,192 @@
+package iptables
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/docker/docker/pkg/parsers"
+)
+
+// PolicyRule allows the traffic of a container with some peers
+type PolicyRule struct {
+	CIDR  string // addresses of the peers, any address when empty
+	Proto string // tcp or udp, any protocol when empty
+	Ports string // port or range first-last of the destination, any port when empty
+}
+
+// Validate returns an error when the rule cannot be translated to iptables:
+// the peers must be an IPv4 subnet, and the ports need a protocol
+func (r PolicyRule) Validate() error {
+	if r.CIDR != "" {
+		if ip, _, err := net.ParseCIDR(r.CIDR); err != nil || ip.To4() == nil {
+			return fmt.Errorf("Invalid policy rule, the peers %s must be an IPv4 subnet", r.CIDR)
+		}
+	}
+	if r.Proto != "" && r.Proto != "tcp" && r.Proto != "udp" {
+		return fmt.Errorf("Invalid policy rule, the protocol %s must be tcp or udp", r.Proto)
+	}
+	if r.Ports != "" {
+		if r.Proto == "" {
+			return fmt.Errorf("Invalid policy rule, the ports %s need a protocol", r.Ports)
+		}
+		if start, _, err := parsers.ParsePortRange(r.Ports); err != nil || start == 0 {
+			return fmt.Errorf("Invalid policy rule, invalid ports %s", r.Ports)
+		}
+	}
+	return nil
+}
+
+// Policy restricts the traffic forwarded to and from the addresses of a
+// container to the peers of its rules, the traffic in a direction without
+// rules being left alone. The rules are kept in a chain of the mangle table,
+// as the traffic goes through its FORWARD chain before the one of the filter
+// table, where the rules of the links and published ports accept it. The
+// traffic of the container to the host itself goes through the INPUT chain
+// instead, which jumps to the chain of the policy too. The IPv6 addresses of
+// the container are restricted with ip6tables, only the rules without peers
+// and the answers allowing their traffic.
+type Policy struct {
+	Name    string // name of the chain of the container
+	Gateway string // address of the gateway, always allowed to answer dns queries
+	Egress  []PolicyRule
+	Ingress []PolicyRule
+}
+
+var mangle = []string{"-t", "mangle"}
+
+// Enable restricts the traffic of the address ip of the container, creating
+// the chain of the policy on the first address of its family
+func (p *Policy) Enable(ip string) error {
+	raw := family(ip)
+	if _, err := raw("-t", "mangle", "-n", "-L", p.Name); err != nil {
+		if output, err := raw("-t", "mangle", "-N", p.Name); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return fmt.Errorf("Error creating the policy chain %s: %s", p.Name, output)
+		}
+	}
+	for _, rule := range p.Rules(ip) {
+		if err := p.raw(raw, append([]string{"-A", p.Name}, rule...)); err != nil {
+			p.Disable(ip)
+			return err
+		}
+	}
+	for _, jump := range p.jumps(ip) {
+		if err := p.raw(raw, append([]string{"-I"}, jump...)); err != nil {
+			p.Disable(ip)
+			return err
+		}
+	}
+	return nil
+}
+
+// Disable lifts the restrictions of the address ip of the container
+func (p *Policy) Disable(ip string) error {
+	raw := family(ip)
+	for _, jump := range p.jumps(ip) {
+		raw(append(append(mangle, "-D"), jump...)...)
+	}
+	for _, rule := range p.Rules(ip) {
+		raw(append(append(mangle, "-D", p.Name), rule...)...)
+	}
+	return nil
+}
+
+// Remove removes the chains of the policy, once all the addresses of the
+// container are disabled
+func (p *Policy) Remove() error {
+	for _, raw := range []func(...string) ([]byte, error){Raw, Raw6} {
+		if _, err := raw("-t", "mangle", "-n", "-L", p.Name); err != nil {
+			continue
+		}
+		raw("-t", "mangle", "-F", p.Name)
+		raw("-t", "mangle", "-X", p.Name)
+	}
+	return nil
+}
+
+// Rules returns the rules of the chain of the policy for the address ip:
+// the answers, the dns queries to the gateway and the traffic with the peers
+// of the rules return to the chain they come from, the rest is dropped. The
+// rules with peers or a gateway of another family than ip are left out.
+func (p *Policy) Rules(ip string) [][]string {
+	var (
+		rules [][]string
+		ipv6  = isIPv6(ip)
+	)
+	for _, direction := range []struct {
+		own, peer string
+		allowed   []PolicyRule
+	}{
+		{"-s", "-d", p.Egress},
+		{"-d", "-s", p.Ingress},
+	} {
+		if len(direction.allowed) == 0 {
+			continue
+		}
+		rules = append(rules, []string{direction.own, ip, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"})
+		if direction.own == "-s" && p.Gateway != "" && isIPv6(p.Gateway) == ipv6 {
+			for _, proto := range []string{"udp", "tcp"} {
+				rules = append(rules, []string{"-s", ip, "-d", p.Gateway, "-p", proto, "--dport", "53", "-j", "RETURN"})
+			}
+		}
+		for _, allowed := range direction.allowed {
+			if allowed.CIDR != "" && isIPv6(allowed.CIDR) != ipv6 {
+				continue
+			}
+			rule := []string{direction.own, ip}
+			if allowed.CIDR != "" {
+				rule = append(rule, direction.peer, allowed.CIDR)
+			}
+			if allowed.Proto != "" {
+				rule = append(rule, "-p", allowed.Proto)
+				if allowed.Ports != "" {
+					rule = append(rule, "--dport", strings.Replace(allowed.Ports, "-", ":", 1))
+				}
+			}
+			rules = append(rules, append(rule, "-j", "RETURN"))
+		}
+		rules = append(rules, []string{direction.own, ip, "-j", "DROP"})
+	}
+	return rules
+}
+
+// jumps returns the rules sending the traffic of the address ip to the
+// chain of the policy, each starting with the chain it goes from: FORWARD,
+// and INPUT for the traffic of the container to the host
+func (p *Policy) jumps(ip string) [][]string {
+	var jumps [][]string
+	if len(p.Egress) > 0 {
+		jumps = append(jumps,
+			[]string{"FORWARD", "-s", ip, "-j", p.Name},
+			[]string{"INPUT", "-s", ip, "-j", p.Name})
+	}
+	if len(p.Ingress) > 0 {
+		jumps = append(jumps, []string{"FORWARD", "-d", ip, "-j", p.Name})
+	}
+	return jumps
+}
+
+func (p *Policy) raw(raw func(...string) ([]byte, error), args []string) error {
+	if output, err := raw(append(mangle, args...)...); err != nil {
+		return err
+	} else if len(output) != 0 {
+		return fmt.Errorf("Error iptables policy %s: %s", p.Name, output)
+	}
+	return nil
+}
+
+// family returns the command managing the rules of the address ip: Raw6
+// for an IPv6 address, Raw otherwise
+func family(ip string) func(...string) ([]byte, error) {
+	if isIPv6(ip) {
+		return Raw6
+	}
+	return Raw
+}
+
+// isIPv6 returns whether the address or subnet addr is an IPv6 one
+func isIPv6(addr string) bool {
+	return strings.Contains(addr, ":")
+}
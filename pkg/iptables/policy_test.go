@@ -0,0 +1,117 @@
+package iptables
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPolicyRules(t *testing.T) {
+	p := &Policy{
+		Name:   "DOCKER-POLICY-test",
+		Egress: []PolicyRule{{CIDR: "10.0.0.0/8", Proto: "tcp", Ports: "5432-5433"}, {Proto: "udp"}},
+	}
+	expected := [][]string{
+		{"-s", "172.17.0.2", "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+		{"-s", "172.17.0.2", "-d", "10.0.0.0/8", "-p", "tcp", "--dport", "5432:5433", "-j", "RETURN"},
+		{"-s", "172.17.0.2", "-p", "udp", "-j", "RETURN"},
+		{"-s", "172.17.0.2", "-j", "DROP"},
+	}
+	if rules := p.Rules("172.17.0.2"); !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Expected the rules %v, got %v", expected, rules)
+	}
+	if jumps := p.jumps("172.17.0.2"); len(jumps) != 2 || jumps[0][0] != "FORWARD" || jumps[0][1] != "-s" {
+		t.Fatalf("Expected the egress traffic to jump to the policy from FORWARD and INPUT, got %v", jumps)
+	}
+
+	p = &Policy{Name: "DOCKER-POLICY-test", Ingress: []PolicyRule{{CIDR: "192.168.0.0/16"}}}
+	expected = [][]string{
+		{"-d", "172.17.0.2", "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+		{"-d", "172.17.0.2", "-s", "192.168.0.0/16", "-j", "RETURN"},
+		{"-d", "172.17.0.2", "-j", "DROP"},
+	}
+	if rules := p.Rules("172.17.0.2"); !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Expected the rules %v, got %v", expected, rules)
+	}
+	if jumps := p.jumps("172.17.0.2"); len(jumps) != 1 || jumps[0][0] != "FORWARD" {
+		t.Fatalf("Expected only the forwarded ingress traffic to jump to the policy, got %v", jumps)
+	}
+}
+
+func TestPolicyRulesToTheHost(t *testing.T) {
+	p := &Policy{
+		Name:    "DOCKER-POLICY-test",
+		Gateway: "172.17.42.1",
+		Egress:  []PolicyRule{{CIDR: "10.0.0.0/8", Proto: "tcp", Ports: "5432"}},
+	}
+	expected := [][]string{
+		{"-s", "172.17.0.2", "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+		{"-s", "172.17.0.2", "-d", "172.17.42.1", "-p", "udp", "--dport", "53", "-j", "RETURN"},
+		{"-s", "172.17.0.2", "-d", "172.17.42.1", "-p", "tcp", "--dport", "53", "-j", "RETURN"},
+		{"-s", "172.17.0.2", "-d", "10.0.0.0/8", "-p", "tcp", "--dport", "5432", "-j", "RETURN"},
+		{"-s", "172.17.0.2", "-j", "DROP"},
+	}
+	if rules := p.Rules("172.17.0.2"); !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Expected the rules %v, got %v", expected, rules)
+	}
+	input := []string{"INPUT", "-s", "172.17.0.2", "-j", "DOCKER-POLICY-test"}
+	for _, jump := range p.jumps("172.17.0.2") {
+		if reflect.DeepEqual(jump, input) {
+			return
+		}
+	}
+	t.Fatalf("Expected the traffic of the container to the host to jump to the policy, got %v", p.jumps("172.17.0.2"))
+}
+
+func TestPolicyRulesIPv6(t *testing.T) {
+	p := &Policy{
+		Name:    "DOCKER-POLICY-test",
+		Gateway: "172.17.42.1",
+		Egress:  []PolicyRule{{CIDR: "10.0.0.0/8", Proto: "tcp", Ports: "5432"}, {Proto: "udp", Ports: "123"}},
+		Ingress: []PolicyRule{{CIDR: "192.168.0.0/16"}},
+	}
+	expected := [][]string{
+		{"-s", "fe80::42:acff:fe11:2", "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+		{"-s", "fe80::42:acff:fe11:2", "-p", "udp", "--dport", "123", "-j", "RETURN"},
+		{"-s", "fe80::42:acff:fe11:2", "-j", "DROP"},
+		{"-d", "fe80::42:acff:fe11:2", "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+		{"-d", "fe80::42:acff:fe11:2", "-j", "DROP"},
+	}
+	if rules := p.Rules("fe80::42:acff:fe11:2"); !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Expected the IPv4 peers and gateway to be left out of the IPv6 rules %v, got %v", expected, rules)
+	}
+	if raw := family("fe80::42:acff:fe11:2"); reflect.ValueOf(raw).Pointer() != reflect.ValueOf(Raw6).Pointer() {
+		t.Fatal("Expected the rules of an IPv6 address to be managed by ip6tables")
+	}
+	if raw := family("172.17.0.2"); reflect.ValueOf(raw).Pointer() != reflect.ValueOf(Raw).Pointer() {
+		t.Fatal("Expected the rules of an IPv4 address to be managed by iptables")
+	}
+}
+
+func TestPolicyRuleValidate(t *testing.T) {
+	valid := []PolicyRule{
+		{CIDR: "10.0.0.0/8"},
+		{Proto: "udp"},
+		{CIDR: "10.0.0.0/8", Proto: "tcp", Ports: "5432"},
+		{Proto: "tcp", Ports: "8000-8080"},
+	}
+	for _, rule := range valid {
+		if err := rule.Validate(); err != nil {
+			t.Fatalf("Expected %+v to be valid, got %s", rule, err)
+		}
+	}
+
+	invalid := []PolicyRule{
+		{CIDR: "10.0.0.0/8", Ports: "5432"},
+		{Ports: "80"},
+		{CIDR: "10.0.0.1"},
+		{CIDR: "fd00::/64"},
+		{Proto: "icmp"},
+		{Proto: "tcp", Ports: "0"},
+		{Proto: "tcp", Ports: "80-70"},
+	}
+	for _, rule := range invalid {
+		if err := rule.Validate(); err == nil {
+			t.Fatalf("Expected %+v to be invalid", rule)
+		}
+	}
+}
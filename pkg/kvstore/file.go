@@ -0,0 +1,114 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// fileStore keeps the keys in a JSON file, which is locked while it is read
+// or written, so that the daemons sharing the file through the host or a
+// network filesystem see the changes of each other
+type fileStore struct {
+	path string
+}
+
+// NewFileStore returns the store kept in the file at path, which is created
+// on the first change
+func NewFileStore(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return &fileStore{path: path}, nil
+}
+
+// locked calls f with the values of the store while holding the lock of the
+// file, saving them afterwards when f says they changed
+func (s *fileStore) locked(f func(values map[string][]byte) (bool, error)) error {
+	lock, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	values := make(map[string][]byte)
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+	}
+
+	changed, err := f(values)
+	if err != nil || !changed {
+		return err
+	}
+	if data, err = json.Marshal(values); err != nil {
+		return err
+	}
+	// the file is replaced at once, for the readers not to see it half
+	// written
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *fileStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.locked(func(values map[string][]byte) (bool, error) {
+		v, exists := values[key]
+		if !exists {
+			return false, ErrKeyNotFound
+		}
+		value = v
+		return false, nil
+	})
+	return value, err
+}
+
+func (s *fileStore) Put(key string, value []byte) error {
+	return s.locked(func(values map[string][]byte) (bool, error) {
+		values[key] = value
+		return true, nil
+	})
+}
+
+func (s *fileStore) Create(key string, value []byte) error {
+	return s.locked(func(values map[string][]byte) (bool, error) {
+		if _, exists := values[key]; exists {
+			return false, ErrKeyExists
+		}
+		values[key] = value
+		return true, nil
+	})
+}
+
+func (s *fileStore) Delete(key string) error {
+	return s.locked(func(values map[string][]byte) (bool, error) {
+		if _, exists := values[key]; !exists {
+			return false, ErrKeyNotFound
+		}
+		delete(values, key)
+		return true, nil
+	})
+}
+
+func (s *fileStore) List(prefix string) (map[string][]byte, error) {
+	var res map[string][]byte
+	err := s.locked(func(values map[string][]byte) (bool, error) {
+		res = listPrefix(values, prefix)
+		return false, nil
+	})
+	return res, err
+}
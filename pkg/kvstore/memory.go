@@ -0,0 +1,89 @@
+package kvstore
+
+import (
+	"strings"
+	"sync"
+)
+
+// memoryStore keeps the keys in the memory of the process, for the daemons
+// running in a same process, as in tests
+type memoryStore struct {
+	sync.Mutex
+	values map[string][]byte
+}
+
+var (
+	memoryStoresLock sync.Mutex
+	memoryStores     = make(map[string]*memoryStore)
+)
+
+// NewMemoryStore returns the in-process store named addr, the stores created
+// with the same name being the same store
+func NewMemoryStore(addr string) (Store, error) {
+	memoryStoresLock.Lock()
+	defer memoryStoresLock.Unlock()
+	s, exists := memoryStores[addr]
+	if !exists {
+		s = &memoryStore{values: make(map[string][]byte)}
+		memoryStores[addr] = s
+	}
+	return s, nil
+}
+
+func (s *memoryStore) Get(key string) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	value, exists := s.values[key]
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+	return copyValue(value), nil
+}
+
+func (s *memoryStore) Put(key string, value []byte) error {
+	s.Lock()
+	s.values[key] = copyValue(value)
+	s.Unlock()
+	return nil
+}
+
+func (s *memoryStore) Create(key string, value []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.values[key]; exists {
+		return ErrKeyExists
+	}
+	s.values[key] = copyValue(value)
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.values[key]; !exists {
+		return ErrKeyNotFound
+	}
+	delete(s.values, key)
+	return nil
+}
+
+func (s *memoryStore) List(prefix string) (map[string][]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	return listPrefix(s.values, prefix), nil
+}
+
+// listPrefix returns a copy of the values of the keys starting with prefix
+func listPrefix(values map[string][]byte, prefix string) map[string][]byte {
+	res := make(map[string][]byte)
+	for key, value := range values {
+		if strings.HasPrefix(key, prefix) {
+			res[key] = copyValue(value)
+		}
+	}
+	return res
+}
+
+func copyValue(value []byte) []byte {
+	return append([]byte{}, value...)
+}
@@ -0,0 +1,70 @@
+// Package kvstore defines a small key-value store shared by the daemons of a
+// cluster, along with the backends implementing it. The backend of a store
+// is chosen by the scheme of its url, as in file:///var/lib/docker/cluster.json.
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrKeyNotFound         = errors.New("key not found in the store")
+	ErrKeyExists           = errors.New("key already exists in the store")
+	ErrBackendNotSupported = errors.New("backend not supported")
+)
+
+// Store is a key-value store, the keys being paths separated by slashes
+type Store interface {
+	// Get returns the value of key, or ErrKeyNotFound
+	Get(key string) ([]byte, error)
+	// Put sets the value of key, creating it when needed
+	Put(key string, value []byte) error
+	// Create sets the value of key unless it exists, in which case it fails
+	// with ErrKeyExists. The daemons sharing a store claim keys with it.
+	Create(key string, value []byte) error
+	// Delete removes key, or fails with ErrKeyNotFound
+	Delete(key string) error
+	// List returns the values of the keys starting with prefix, by key
+	List(prefix string) (map[string][]byte, error)
+}
+
+// Backend returns the store at addr, the part of the url of the store after
+// the scheme
+type Backend func(addr string) (Store, error)
+
+var (
+	backendsLock sync.Mutex
+	backends     = map[string]Backend{
+		"memory": NewMemoryStore,
+		"file":   NewFileStore,
+	}
+)
+
+// Register makes a backend available to New under scheme
+func Register(scheme string, backend Backend) error {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	if _, exists := backends[scheme]; exists {
+		return fmt.Errorf("A backend is already registered for %s", scheme)
+	}
+	backends[scheme] = backend
+	return nil
+}
+
+// New returns the store at url, in the form <scheme>://<address>
+func New(url string) (Store, error) {
+	parts := strings.SplitN(url, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid store url %s, expected <backend>://<address>", url)
+	}
+	backendsLock.Lock()
+	backend, exists := backends[parts[0]]
+	backendsLock.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("%s: %s", ErrBackendNotSupported, parts[0])
+	}
+	return backend(parts[1])
+}
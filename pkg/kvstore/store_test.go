@@ -0,0 +1,131 @@
+package kvstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func testStore(t *testing.T, s Store) {
+	if _, err := s.Get("networks/web"); err != ErrKeyNotFound {
+		t.Fatalf("Expected %s, got %v", ErrKeyNotFound, err)
+	}
+	if err := s.Put("networks/web", []byte("10.0.0.0/24")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := s.Get("networks/web"); err != nil || string(value) != "10.0.0.0/24" {
+		t.Fatalf("Expected the value put, got %q %v", value, err)
+	}
+
+	if err := s.Create("networks/web", []byte("10.1.0.0/24")); err != ErrKeyExists {
+		t.Fatalf("Expected %s, got %v", ErrKeyExists, err)
+	}
+	if err := s.Create("networks/db", []byte("10.2.0.0/24")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("peers/10.0.2.15", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := s.List("networks/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || string(values["networks/web"]) != "10.0.0.0/24" || string(values["networks/db"]) != "10.2.0.0/24" {
+		t.Fatalf("Expected the 2 networks, got %v", values)
+	}
+
+	if err := s.Delete("networks/web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("networks/web"); err != ErrKeyNotFound {
+		t.Fatalf("Expected %s, got %v", ErrKeyNotFound, err)
+	}
+	if values, err := s.List("networks/"); err != nil || len(values) != 1 {
+		t.Fatalf("Expected a single network left, got %v %v", values, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s, err := New("memory://TestMemoryStore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+
+	// the stores of a same name are shared
+	other, err := New("memory://TestMemoryStore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Get("networks/db"); err != nil {
+		t.Fatalf("Expected the key set through the other store, got %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kvstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cluster", "store.json")
+
+	s, err := New("file://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+
+	other, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Get("networks/db"); err != nil {
+		t.Fatalf("Expected the key set through the other store, got %v", err)
+	}
+
+	// a single one of concurrent creations of a key succeeds
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		created int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store, _ := NewFileStore(path)
+			if err := store.Create("vni/256", nil); err == nil {
+				lock.Lock()
+				created++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Fatalf("Expected the key to be created once, got %d", created)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("/var/lib/docker/cluster.json"); err == nil {
+		t.Fatal("Expected an error for an url without a scheme")
+	}
+	if _, err := New("zookeeper://127.0.0.1:2181"); err == nil {
+		t.Fatal("Expected an error for an unknown backend")
+	}
+
+	if err := Register("test", NewMemoryStore); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("test", NewMemoryStore); err == nil {
+		t.Fatal("Expected an error registering a backend twice")
+	}
+	if _, err := New("test://TestNew"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,140 @@
+package netdev
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/network"
+	"github.com/docker/libcontainer/utils"
+)
+
+// CreateVeth creates a veth pair with one end attached to bridge and the
+// other one moved in the network namespace bound on nsPath, configured after
+// config. It returns the name of the end left on the host.
+func CreateVeth(bridge, nsPath string, config *Config) (string, error) {
+	host, err := utils.GenerateRandomName("veth", 7)
+	if err != nil {
+		return "", err
+	}
+	child, err := utils.GenerateRandomName("veth", 7)
+	if err != nil {
+		return "", err
+	}
+	if err := network.CreateVethPair(host, child); err != nil {
+		return "", err
+	}
+	if err := setupVeth(host, child, bridge, nsPath, config); err != nil {
+		// removing one end of the pair removes the other one
+		netlink.NetworkLinkDel(host)
+		return "", err
+	}
+	return host, nil
+}
+
+func setupVeth(host, child, bridge, nsPath string, config *Config) error {
+	if config.Mtu != 0 {
+		if err := network.SetMtu(host, config.Mtu); err != nil {
+			return err
+		}
+	}
+	if err := network.SetInterfaceMaster(host, bridge); err != nil {
+		return err
+	}
+	if config.HairpinMode {
+		if err := SetHairpinMode(host, true); err != nil {
+			return err
+		}
+	}
+	if err := network.InterfaceUp(host); err != nil {
+		return err
+	}
+	return moveInterface(child, nsPath, config)
+}
+
+// SetHairpinMode lets the port name of a bridge send the frames back through
+// the port they were received on, for a container to reach itself through an
+// address of the host
+func SetHairpinMode(name string, enabled bool) error {
+	value := []byte("0")
+	if enabled {
+		value = []byte("1")
+	}
+	return ioutil.WriteFile(filepath.Join("/sys/class/net", name, "brport/hairpin_mode"), value, 0644)
+}
+
+// CreateMacvlan creates a macvlan interface on parent and moves it in the
+// network namespace bound on nsPath, configured after config
+func CreateMacvlan(parent, nsPath string, config *Config) error {
+	child, err := utils.GenerateRandomName("macvlan", 7)
+	if err != nil {
+		return err
+	}
+	if err := LinkAddMacvlan(parent, child); err != nil {
+		return err
+	}
+	if err := moveInterface(child, nsPath, config); err != nil {
+		// the interface is left to the namespace when it was moved
+		netlink.NetworkLinkDel(child)
+		return err
+	}
+	return nil
+}
+
+// moveInterface moves the interface child in the network namespace bound on
+// nsPath, where it is renamed, configured and brought up after config
+func moveInterface(child, nsPath string, config *Config) error {
+	ns, err := os.Open(nsPath)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+	if err := network.SetInterfaceInNamespaceFd(child, ns.Fd()); err != nil {
+		return err
+	}
+
+	return InNetns(nsPath, func() error {
+		if err := network.ChangeInterfaceName(child, config.Device); err != nil {
+			return err
+		}
+		if config.MacAddress != "" {
+			iface, err := net.InterfaceByName(config.Device)
+			if err != nil {
+				return err
+			}
+			if err := SetMacAddress(iface, config.MacAddress); err != nil {
+				return err
+			}
+		}
+		if config.Mtu != 0 {
+			if err := network.SetMtu(config.Device, config.Mtu); err != nil {
+				return err
+			}
+		}
+		if err := network.SetInterfaceIp(config.Device, config.Address); err != nil {
+			return err
+		}
+		if config.IPv6Address != "" {
+			if err := network.SetInterfaceIp(config.Device, config.IPv6Address); err != nil {
+				return err
+			}
+		}
+		if err := network.InterfaceUp(config.Device); err != nil {
+			return err
+		}
+		if config.Gateway != "" {
+			if err := network.SetDefaultGateway(config.Gateway, config.Device); err != nil {
+				return fmt.Errorf("Unable to set the gateway %s on %s: %s", config.Gateway, config.Device, err)
+			}
+		}
+		if config.IPv6Gateway != "" {
+			if err := network.SetDefaultGateway(config.IPv6Gateway, config.Device); err != nil {
+				return fmt.Errorf("Unable to set the gateway %s on %s: %s", config.IPv6Gateway, config.Device, err)
+			}
+		}
+		return nil
+	})
+}
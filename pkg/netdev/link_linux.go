@@ -0,0 +1,26 @@
+package netdev
+
+import (
+	"net"
+	"syscall"
+)
+
+// SetMacAddress sets the mac address of the interface. This is identical to
+// running: ip link set $name address $macaddr
+func SetMacAddress(iface *net.Interface, macaddr string) error {
+	hwaddr, err := net.ParseMAC(macaddr)
+	if err != nil {
+		return err
+	}
+
+	wb := newNetlinkRequest(syscall.RTM_SETLINK, syscall.NLM_F_ACK)
+	wb.addData(&ifInfomsg{syscall.IfInfomsg{
+		Family: syscall.AF_UNSPEC,
+		Type:   syscall.RTM_SETLINK,
+		Flags:  syscall.NLM_F_REQUEST,
+		Index:  int32(iface.Index),
+		Change: 0xFFFFFFFF,
+	}})
+	wb.addData(newRtAttr(syscall.IFLA_ADDRESS, []byte(hwaddr)))
+	return wb.execute()
+}
@@ -0,0 +1,37 @@
+package netdev
+
+import (
+	"net"
+	"syscall"
+)
+
+const (
+	iflaMacvlanMode = 1
+
+	// macvlanModeBridge lets the macvlan interfaces of a same parent reach
+	// each other directly
+	macvlanModeBridge = 4
+)
+
+// LinkAddMacvlan adds a macvlan interface on the parent interface, in bridge
+// mode. This is identical to running:
+// ip link add link $parent name $name type macvlan mode bridge
+func LinkAddMacvlan(parent, name string) error {
+	parentIface, err := net.InterfaceByName(parent)
+	if err != nil {
+		return err
+	}
+
+	wb := newNetlinkRequest(syscall.RTM_NEWLINK, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	wb.addData(&ifInfomsg{syscall.IfInfomsg{Family: syscall.AF_UNSPEC}})
+
+	linkInfo := newRtAttr(syscall.IFLA_LINKINFO, nil)
+	linkInfo.addChild(iflaInfoKind, []byte("macvlan"))
+	infoData := linkInfo.addChild(iflaInfoData, nil)
+	infoData.addChild(iflaMacvlanMode, uint32Attr(macvlanModeBridge))
+	wb.addData(linkInfo)
+
+	wb.addData(newRtAttr(syscall.IFLA_LINK, uint32Attr(uint32(parentIface.Index))))
+	wb.addData(newRtAttr(syscall.IFLA_IFNAME, zeroTerminated(name)))
+	return wb.execute()
+}
@@ -0,0 +1,23 @@
+// Package netdev creates and configures the network devices that the netlink
+// package of libcontainer does not handle, such as the vxlan interfaces of
+// the overlay networks and their forwarding entries, and the network
+// namespaces of the containers along with their interfaces.
+package netdev
+
+import (
+	"errors"
+)
+
+var ErrNotImplemented = errors.New("netdev: not implemented")
+
+// Config is the configuration of an interface moved in a network namespace
+type Config struct {
+	Device      string // name of the interface in the namespace
+	Address     string // ip of the interface, with the prefix length of its network
+	IPv6Address string // global ipv6 of the interface with its prefix length, none when empty
+	MacAddress  string
+	Mtu         int    // the default mtu is kept when 0
+	Gateway     string // default gateway, none when empty
+	IPv6Gateway string
+	HairpinMode bool // hairpin mode of the port of the bridge, for a veth pair
+}
@@ -0,0 +1,51 @@
+// +build !linux
+
+package netdev
+
+import (
+	"net"
+)
+
+func LinkAddVxlan(name string, vni uint32, local net.IP, port int) error {
+	return ErrNotImplemented
+}
+
+func FdbAdd(iface *net.Interface, mac net.HardwareAddr, dst net.IP) error {
+	return ErrNotImplemented
+}
+
+func FdbDel(iface *net.Interface, mac net.HardwareAddr, dst net.IP) error {
+	return ErrNotImplemented
+}
+
+func LinkAddMacvlan(parent, name string) error {
+	return ErrNotImplemented
+}
+
+func SetMacAddress(iface *net.Interface, macaddr string) error {
+	return ErrNotImplemented
+}
+
+func NewNetns(path string) error {
+	return ErrNotImplemented
+}
+
+func DeleteNetns(path string) error {
+	return ErrNotImplemented
+}
+
+func InNetns(path string, f func() error) error {
+	return ErrNotImplemented
+}
+
+func CreateVeth(bridge, nsPath string, config *Config) (string, error) {
+	return "", ErrNotImplemented
+}
+
+func SetHairpinMode(name string, enabled bool) error {
+	return ErrNotImplemented
+}
+
+func CreateMacvlan(parent, nsPath string, config *Config) error {
+	return ErrNotImplemented
+}
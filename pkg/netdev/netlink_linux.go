@@ -0,0 +1,194 @@
+package netdev
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	iflaInfoKind = 1
+	iflaInfoData = 2
+)
+
+var (
+	errShortResponse = errors.New("Got short response from netlink")
+
+	nextSeqNr uint32
+)
+
+func nativeEndian() binary.ByteOrder {
+	var x uint32 = 0x01020304
+	if *(*byte)(unsafe.Pointer(&x)) == 0x01 {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+func rtaAlignOf(attrlen int) int {
+	return (attrlen + syscall.RTA_ALIGNTO - 1) & ^(syscall.RTA_ALIGNTO - 1)
+}
+
+// wireFormat is a part of a netlink request
+type wireFormat interface {
+	toWireFormat() []byte
+}
+
+// ifInfomsg is the header of the requests on links
+type ifInfomsg struct {
+	syscall.IfInfomsg
+}
+
+func (msg *ifInfomsg) toWireFormat() []byte {
+	native := nativeEndian()
+
+	b := make([]byte, syscall.SizeofIfInfomsg)
+	b[0] = msg.Family
+	native.PutUint16(b[2:4], msg.Type)
+	native.PutUint32(b[4:8], uint32(msg.Index))
+	native.PutUint32(b[8:12], msg.Flags)
+	native.PutUint32(b[12:16], msg.Change)
+	return b
+}
+
+// rtAttr is an attribute of a request, holding either data or nested
+// attributes
+type rtAttr struct {
+	attrType int
+	data     []byte
+	children []*rtAttr
+}
+
+func newRtAttr(attrType int, data []byte) *rtAttr {
+	return &rtAttr{attrType: attrType, data: data}
+}
+
+// addChild nests a new attribute in a and returns it
+func (a *rtAttr) addChild(attrType int, data []byte) *rtAttr {
+	child := newRtAttr(attrType, data)
+	a.children = append(a.children, child)
+	return child
+}
+
+func (a *rtAttr) toWireFormat() []byte {
+	payload := append([]byte{}, a.data...)
+	for _, child := range a.children {
+		b := child.toWireFormat()
+		payload = append(payload, b...)
+	}
+	length := syscall.SizeofRtAttr + len(payload)
+	b := make([]byte, rtaAlignOf(length))
+	nativeEndian().PutUint16(b[0:2], uint16(length))
+	nativeEndian().PutUint16(b[2:4], uint16(a.attrType))
+	copy(b[syscall.SizeofRtAttr:], payload)
+	return b
+}
+
+type netlinkRequest struct {
+	syscall.NlMsghdr
+	data []wireFormat
+}
+
+func newNetlinkRequest(proto, flags int) *netlinkRequest {
+	return &netlinkRequest{
+		NlMsghdr: syscall.NlMsghdr{
+			Len:   uint32(syscall.NLMSG_HDRLEN),
+			Type:  uint16(proto),
+			Flags: syscall.NLM_F_REQUEST | uint16(flags),
+			Seq:   atomic.AddUint32(&nextSeqNr, 1),
+		},
+	}
+}
+
+func (r *netlinkRequest) addData(data wireFormat) {
+	r.data = append(r.data, data)
+}
+
+func (r *netlinkRequest) toWireFormat() []byte {
+	native := nativeEndian()
+
+	var payload []byte
+	for _, data := range r.data {
+		payload = append(payload, data.toWireFormat()...)
+	}
+	b := make([]byte, int(r.Len)+len(payload))
+	native.PutUint32(b[0:4], uint32(len(b)))
+	native.PutUint16(b[4:6], r.Type)
+	native.PutUint16(b[6:8], r.Flags)
+	native.PutUint32(b[8:12], r.Seq)
+	native.PutUint32(b[12:16], r.Pid)
+	copy(b[r.Len:], payload)
+	return b
+}
+
+// execute sends the request on a new route netlink socket and waits for the
+// kernel to acknowledge it
+func (r *netlinkRequest) execute() error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	lsa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
+	if err := syscall.Bind(fd, lsa); err != nil {
+		return err
+	}
+	if err := syscall.Sendto(fd, r.toWireFormat(), 0, lsa); err != nil {
+		return err
+	}
+
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		return err
+	}
+	local, ok := sa.(*syscall.SockaddrNetlink)
+	if !ok {
+		return fmt.Errorf("Wrong socket type %T", sa)
+	}
+
+	rb := make([]byte, syscall.Getpagesize())
+	for {
+		nr, _, err := syscall.Recvfrom(fd, rb, 0)
+		if err != nil {
+			return err
+		}
+		if nr < syscall.NLMSG_HDRLEN {
+			return errShortResponse
+		}
+		msgs, err := syscall.ParseNetlinkMessage(rb[:nr])
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != r.Seq {
+				return fmt.Errorf("Wrong Seq nr %d, expected %d", m.Header.Seq, r.Seq)
+			}
+			if m.Header.Pid != local.Pid {
+				return fmt.Errorf("Wrong pid %d, expected %d", m.Header.Pid, local.Pid)
+			}
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				if errno := int32(nativeEndian().Uint32(m.Data[0:4])); errno != 0 {
+					return syscall.Errno(-errno)
+				}
+				return nil
+			}
+		}
+	}
+}
+
+func zeroTerminated(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+func uint32Attr(v uint32) []byte {
+	b := make([]byte, 4)
+	nativeEndian().PutUint32(b, v)
+	return b
+}
@@ -0,0 +1,66 @@
+package netdev
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/docker/libcontainer/netlink"
+)
+
+func TestRtAttrWireFormat(t *testing.T) {
+	native := nativeEndian()
+
+	attr := newRtAttr(1, nil)
+	attr.addChild(2, []byte("abc"))
+	attr.addChild(3, []byte{1, 2, 3, 4})
+	b := attr.toWireFormat()
+
+	// 4 bytes of header, then the children aligned on 4 bytes
+	if len(b) != 4+8+8 {
+		t.Fatalf("Expected 20 bytes, got %d: %v", len(b), b)
+	}
+	if l := native.Uint16(b[0:2]); l != 20 {
+		t.Fatalf("Expected a length of 20, got %d", l)
+	}
+	if l := native.Uint16(b[4:6]); l != 7 {
+		t.Fatalf("Expected the unaligned length 7 for the first child, got %d", l)
+	}
+	if !bytes.Equal(b[8:12], []byte{'a', 'b', 'c', 0}) {
+		t.Fatalf("Expected the padded data of the first child, got %v", b[8:12])
+	}
+	if typ := native.Uint16(b[14:16]); typ != 3 {
+		t.Fatalf("Expected the type of the second child, got %d", typ)
+	}
+}
+
+func TestLinkAddVxlan(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("creating interfaces requires root")
+	}
+	if err := LinkAddVxlan("testvxlan0", 4242, net.ParseIP("127.0.0.1"), 4789); err != nil {
+		t.Skipf("vxlan interfaces are not supported: %s", err)
+	}
+	defer netlink.NetworkLinkDel("testvxlan0")
+
+	iface, err := net.InterfaceByName("testvxlan0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := net.HardwareAddr{0, 0, 0, 0, 0, 0}
+	if err := FdbAdd(iface, mac, net.ParseIP("10.1.2.3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := FdbAdd(iface, mac, net.ParseIP("10.1.2.4")); err != nil {
+		t.Fatalf("Expected several remote ends for the broadcasts: %s", err)
+	}
+	for _, dst := range []string{"10.1.2.3", "10.1.2.4"} {
+		if err := FdbDel(iface, mac, net.ParseIP(dst)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := FdbDel(iface, mac, net.ParseIP("10.1.2.4")); err == nil {
+		t.Fatal("Expected an error removing a missing entry")
+	}
+}
@@ -0,0 +1,94 @@
+package netdev
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+
+	"github.com/docker/libcontainer/system"
+)
+
+// NewNetns creates a network namespace bound on the file path, which keeps it
+// alive until DeleteNetns is called. A namespace left on path, by a daemon
+// that died for instance, is released first.
+func NewNetns(path string) error {
+	if err := DeleteNetns(path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	err = onThread(func() error {
+		return syscall.Unshare(syscall.CLONE_NEWNET)
+	}, func() error {
+		return syscall.Mount(threadNetns(), path, "bind", syscall.MS_BIND, "")
+	})
+	if err != nil {
+		DeleteNetns(path)
+		return err
+	}
+	return nil
+}
+
+// DeleteNetns releases the network namespace bound on path. The namespace is
+// destroyed along with its interfaces once no process is left in it.
+func DeleteNetns(path string) error {
+	if err := syscall.Unmount(path, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// InNetns runs f in the network namespace bound on path, which may also be
+// the namespace of a process such as /proc/<pid>/ns/net
+func InNetns(path string, f func() error) error {
+	target, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	return onThread(func() error {
+		return system.Setns(target.Fd(), syscall.CLONE_NEWNET)
+	}, f)
+}
+
+// onThread locks the calling goroutine to its thread, moves the thread to
+// another network namespace with enter and runs f there. The thread is moved
+// back to the namespace of the process afterwards. If it cannot be moved
+// back, it stays locked so that it is never given to another goroutine.
+func onThread(enter func() error, f func() error) (err error) {
+	runtime.LockOSThread()
+
+	origin, err := os.Open(threadNetns())
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer origin.Close()
+
+	if err := enter(); err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer func() {
+		if restoreErr := system.Setns(origin.Fd(), syscall.CLONE_NEWNET); restoreErr != nil {
+			err = fmt.Errorf("Unable to go back to the network namespace of the daemon: %s", restoreErr)
+			return
+		}
+		runtime.UnlockOSThread()
+	}()
+	return f()
+}
+
+// threadNetns is the network namespace of the calling thread
+func threadNetns() string {
+	return fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), syscall.Gettid())
+}
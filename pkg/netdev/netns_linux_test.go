@@ -0,0 +1,180 @@
+package netdev
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/libcontainer/netlink"
+)
+
+func TestNetnsVeth(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("creating network namespaces requires root")
+	}
+	root, err := ioutil.TempDir("", "docker-netns-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	path := filepath.Join(root, "netns")
+
+	if err := netlink.CreateBridge("testbr0", false); err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.DeleteBridge("testbr0")
+
+	if err := NewNetns(path); err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteNetns(path)
+
+	err = InNetns(path, func() error {
+		ifaces, err := net.Interfaces()
+		if err != nil {
+			return err
+		}
+		if len(ifaces) != 1 || ifaces[0].Name != "lo" {
+			t.Errorf("Expected only a loopback interface in the namespace, got %v", ifaces)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host, err := CreateVeth("testbr0", path, &Config{
+		Device:      "eth0",
+		Address:     "10.99.0.2/24",
+		IPv6Address: "fd00:99::2/64",
+		MacAddress:  "02:42:0a:63:00:02",
+		Mtu:         1400,
+		Gateway:     "10.99.0.1",
+		IPv6Gateway: "fd00:99::1",
+		HairpinMode: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostIface, err := net.InterfaceByName(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostIface.MTU != 1400 {
+		t.Fatalf("Expected the mtu 1400 on the host end, got %d", hostIface.MTU)
+	}
+	hairpin, err := ioutil.ReadFile(filepath.Join("/sys/class/net", host, "brport/hairpin_mode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(hairpin) != "1\n" {
+		t.Fatalf("Expected the port of the bridge in hairpin mode, got %q", hairpin)
+	}
+
+	err = InNetns(path, func() error {
+		iface, err := net.InterfaceByName("eth0")
+		if err != nil {
+			return err
+		}
+		if iface.HardwareAddr.String() != "02:42:0a:63:00:02" {
+			t.Errorf("Expected the requested mac, got %s", iface.HardwareAddr)
+		}
+		if iface.Flags&net.FlagUp == 0 {
+			t.Error("Expected the interface to be up")
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return err
+		}
+		found := map[string]bool{}
+		for _, addr := range addrs {
+			found[addr.String()] = true
+		}
+		if !found["10.99.0.2/24"] || !found["fd00:99::2/64"] {
+			t.Errorf("Expected the requested addresses, got %v", addrs)
+		}
+
+		routes, err := netlink.NetworkGetRoutes()
+		if err != nil {
+			return err
+		}
+		for _, r := range routes {
+			if r.Default {
+				return nil
+			}
+		}
+		t.Errorf("Expected a default route, got %v", routes)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the interfaces are destroyed with the namespace
+	if err := DeleteNetns(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected the namespace file to be removed, got %v", err)
+	}
+	for i := 0; ; i++ {
+		if _, err := net.InterfaceByName(host); err != nil {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("Expected %s to be removed with the namespace", host)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestNetnsMacvlan(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("creating network namespaces requires root")
+	}
+	root, err := ioutil.TempDir("", "docker-netns-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	path := filepath.Join(root, "netns")
+
+	// one end of a veth pair stands for the interface of the host
+	if err := netlink.NetworkCreateVethPair("testparent0", "testparent1"); err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.NetworkLinkDel("testparent0")
+
+	if err := NewNetns(path); err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteNetns(path)
+
+	if err := CreateMacvlan("testparent0", path, &Config{Device: "eth0", Address: "10.98.0.2/24"}); err != nil {
+		t.Fatal(err)
+	}
+	err = InNetns(path, func() error {
+		iface, err := net.InterfaceByName("eth0")
+		if err != nil {
+			return err
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return err
+		}
+		if len(addrs) == 0 || addrs[0].String() != "10.98.0.2/24" {
+			t.Errorf("Expected the requested address, got %v", addrs)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CreateMacvlan("testmissing0", path, &Config{Device: "eth1", Address: "10.98.0.3/24"}); err == nil {
+		t.Fatal("Expected an error for a missing parent")
+	}
+}
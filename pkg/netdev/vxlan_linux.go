@@ -0,0 +1,98 @@
+package netdev
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+)
+
+const (
+	iflaVxlanID       = 1
+	iflaVxlanLocal    = 4
+	iflaVxlanLearning = 7
+	iflaVxlanPort     = 15
+
+	ndaDst    = 1
+	ndaLladdr = 2
+	ntfSelf   = 0x02
+
+	nudPermanent = 0x80
+)
+
+// LinkAddVxlan adds a vxlan interface tunneling the frames of the network
+// vni over udp from the local address. This is identical to running:
+// ip link add $name type vxlan id $vni local $local dstport $port
+func LinkAddVxlan(name string, vni uint32, local net.IP, port int) error {
+	wb := newNetlinkRequest(syscall.RTM_NEWLINK, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	wb.addData(&ifInfomsg{syscall.IfInfomsg{Family: syscall.AF_UNSPEC}})
+
+	linkInfo := newRtAttr(syscall.IFLA_LINKINFO, nil)
+	linkInfo.addChild(iflaInfoKind, []byte("vxlan"))
+	infoData := linkInfo.addChild(iflaInfoData, nil)
+	infoData.addChild(iflaVxlanID, uint32Attr(vni))
+	if local4 := local.To4(); local4 != nil {
+		infoData.addChild(iflaVxlanLocal, []byte(local4))
+	}
+	infoData.addChild(iflaVxlanLearning, []byte{1})
+	// the port is in network byte order
+	dstPort := make([]byte, 2)
+	binary.BigEndian.PutUint16(dstPort, uint16(port))
+	infoData.addChild(iflaVxlanPort, dstPort)
+	wb.addData(linkInfo)
+
+	wb.addData(newRtAttr(syscall.IFLA_IFNAME, zeroTerminated(name)))
+	return wb.execute()
+}
+
+// ndmsg is the header of the requests on neighbours
+type ndmsg struct {
+	family uint8
+	index  int32
+	state  uint16
+	flags  uint8
+	ndType uint8
+}
+
+func (msg *ndmsg) toWireFormat() []byte {
+	native := nativeEndian()
+
+	b := make([]byte, 12)
+	b[0] = msg.family
+	native.PutUint32(b[4:8], uint32(msg.index))
+	native.PutUint16(b[8:10], msg.state)
+	b[10] = msg.flags
+	b[11] = msg.ndType
+	return b
+}
+
+// fdbAction adds or removes the forwarding entry sending the frames for mac
+// to the remote end dst of the tunnel iface
+func fdbAction(action, flags int, iface *net.Interface, mac net.HardwareAddr, dst net.IP) error {
+	wb := newNetlinkRequest(action, flags)
+	wb.addData(&ndmsg{
+		family: syscall.AF_BRIDGE,
+		index:  int32(iface.Index),
+		state:  nudPermanent,
+		flags:  ntfSelf,
+	})
+	wb.addData(newRtAttr(ndaLladdr, []byte(mac)))
+	if dst4 := dst.To4(); dst4 != nil {
+		wb.addData(newRtAttr(ndaDst, []byte(dst4)))
+	} else {
+		wb.addData(newRtAttr(ndaDst, []byte(dst.To16())))
+	}
+	return wb.execute()
+}
+
+// FdbAdd adds a forwarding entry to a vxlan interface, several remote ends
+// being allowed for the all-zeros mac, to which the broadcasts are sent. This
+// is identical to running: bridge fdb append $mac dev $iface dst $dst
+func FdbAdd(iface *net.Interface, mac net.HardwareAddr, dst net.IP) error {
+	return fdbAction(syscall.RTM_NEWNEIGH, syscall.NLM_F_CREATE|syscall.NLM_F_APPEND|syscall.NLM_F_ACK, iface, mac, dst)
+}
+
+// FdbDel removes a forwarding entry of a vxlan interface. This is identical
+// to running: bridge fdb del $mac dev $iface dst $dst
+func FdbDel(iface *net.Interface, mac net.HardwareAddr, dst net.IP) error {
+	return fdbAction(syscall.RTM_DELNEIGH, syscall.NLM_F_ACK, iface, mac, dst)
+}
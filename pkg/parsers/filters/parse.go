@@ -61,3 +61,21 @@ func FromParam(p string) (Args, error) {
 	}
 	return args, nil
 }
+
+// MatchKVList returns true if sources matches all the key=value, or key,
+// filters given for field. It also returns true when no filter is given for
+// field.
+func (filters Args) MatchKVList(field string, sources map[string]string) bool {
+	fieldValues := filters[field]
+	for _, value := range fieldValues {
+		kv := strings.SplitN(value, "=", 2)
+		sourceValue, exists := sources[kv[0]]
+		if !exists {
+			return false
+		}
+		if len(kv) == 2 && kv[1] != sourceValue {
+			return false
+		}
+	}
+	return true
+}
@@ -76,3 +76,30 @@ func TestEmpty(t *testing.T) {
 		t.Errorf("these should both be empty sets")
 	}
 }
+
+func TestMatchKVList(t *testing.T) {
+	sources := map[string]string{"owner": "infra", "tier": "db", "flag": ""}
+	matching := []Args{
+		{},
+		{"label": {"owner"}},
+		{"label": {"owner=infra"}},
+		{"label": {"owner=infra", "tier=db", "flag"}},
+		{"label": {"flag="}},
+		{"other": {"owner=web"}},
+	}
+	for _, a := range matching {
+		if !a.MatchKVList("label", sources) {
+			t.Errorf("expected %v to match %v", a, sources)
+		}
+	}
+	notMatching := []Args{
+		{"label": {"owner=web"}},
+		{"label": {"missing"}},
+		{"label": {"owner=infra", "tier=web"}},
+	}
+	for _, a := range notMatching {
+		if a.MatchKVList("label", sources) {
+			t.Errorf("expected %v not to match %v", a, sources)
+		}
+	}
+}
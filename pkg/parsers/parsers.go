@@ -66,10 +66,14 @@ func ParseHost(defaultHost string, defaultUnix, addr string) (string, error) {
 	return fmt.Sprintf("%s://%s:%d", proto, host, port), nil
 }
 
-// Get a repos name and returns the right reposName + tag
+// Get a repos name and returns the right reposName + tag or digest
 // The tag can be confusing because of a port in a repository name.
 //     Ex: localhost.localdomain:5000/samalba/hipache:latest
+//     Ex: localhost.localdomain:5000/samalba/hipache@sha256:...
 func ParseRepositoryTag(repos string) (string, string) {
+	if n := strings.Index(repos, "@"); n >= 0 {
+		return repos[:n], repos[n+1:]
+	}
 	n := strings.LastIndex(repos, ":")
 	if n < 0 {
 		return repos, ""
@@ -108,3 +112,29 @@ func ParseKeyValueOpt(opt string) (string, string, error) {
 	}
 	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
 }
+
+// ParsePortRange parses a port, like 80, or a range of ports, like
+// 8000-8080, and returns the first and the last ports
+func ParsePortRange(ports string) (uint64, uint64, error) {
+	if ports == "" {
+		return 0, 0, fmt.Errorf("Empty string specified for ports.")
+	}
+	if !strings.Contains(ports, "-") {
+		start, err := strconv.ParseUint(ports, 10, 16)
+		return start, start, err
+	}
+
+	parts := strings.SplitN(ports, "-", 2)
+	start, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("Invalid range specified for the ports: %s", ports)
+	}
+	return start, end, nil
+}
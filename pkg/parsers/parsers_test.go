@@ -60,6 +60,12 @@ func TestParseRepositoryTag(t *testing.T) {
 	if repo, tag := ParseRepositoryTag("url:5000/repo:tag"); repo != "url:5000/repo" || tag != "tag" {
 		t.Errorf("Expected repo: '%s' and tag: '%s', got '%s' and '%s'", "url:5000/repo", "tag", repo, tag)
 	}
+	if repo, digest := ParseRepositoryTag("root@sha256:abc"); repo != "root" || digest != "sha256:abc" {
+		t.Errorf("Expected repo: '%s' and digest: '%s', got '%s' and '%s'", "root", "sha256:abc", repo, digest)
+	}
+	if repo, digest := ParseRepositoryTag("url:5000/repo@sha256:abc"); repo != "url:5000/repo" || digest != "sha256:abc" {
+		t.Errorf("Expected repo: '%s' and digest: '%s', got '%s' and '%s'", "url:5000/repo", "sha256:abc", repo, digest)
+	}
 }
 
 func TestParsePortMapping(t *testing.T) {
@@ -81,3 +87,17 @@ func TestParsePortMapping(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestParsePortRange(t *testing.T) {
+	if start, end, err := ParsePortRange("8000-8080"); err != nil || start != 8000 || end != 8080 {
+		t.Fatalf("Expected 8000-8080, got %d-%d %v", start, end, err)
+	}
+	if start, end, err := ParsePortRange("80"); err != nil || start != 80 || end != 80 {
+		t.Fatalf("Expected a single port, got %d-%d %v", start, end, err)
+	}
+	for _, ports := range []string{"", "8080-8000", "80-", "-80", "80-70000", "http"} {
+		if _, _, err := ParsePortRange(ports); err == nil {
+			t.Fatalf("Expected an error for %q", ports)
+		}
+	}
+}
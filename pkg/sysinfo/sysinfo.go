@@ -12,6 +12,11 @@ import (
 type SysInfo struct {
 	MemoryLimit            bool
 	SwapLimit              bool
+	OomKillDisable         bool
+	CpuCfsPeriod           bool
+	CpuCfsQuota            bool
+	BlkioWeight            bool
+	BlkioThrottle          bool
 	IPv4ForwardingDisabled bool
 	AppArmor               bool
 }
@@ -35,6 +40,48 @@ func New(quiet bool) *SysInfo {
 		if !sysInfo.SwapLimit && !quiet {
 			log.Printf("WARNING: Your kernel does not support cgroup swap limit.")
 		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupMemoryMountpoint, "memory.oom_control"))
+		sysInfo.OomKillDisable = err == nil
+		if !sysInfo.OomKillDisable && !quiet {
+			log.Printf("WARNING: Your kernel does not support oom control.")
+		}
+	}
+
+	if cgroupCpuMountpoint, err := cgroups.FindCgroupMountpoint("cpu"); err != nil {
+		if !quiet {
+			log.Printf("WARNING: %s\n", err)
+		}
+	} else {
+		_, err := ioutil.ReadFile(path.Join(cgroupCpuMountpoint, "cpu.cfs_period_us"))
+		sysInfo.CpuCfsPeriod = err == nil
+		if !sysInfo.CpuCfsPeriod && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup cfs period.")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupCpuMountpoint, "cpu.cfs_quota_us"))
+		sysInfo.CpuCfsQuota = err == nil
+		if !sysInfo.CpuCfsQuota && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup cfs quotas.")
+		}
+	}
+
+	if cgroupBlkioMountpoint, err := cgroups.FindCgroupMountpoint("blkio"); err != nil {
+		if !quiet {
+			log.Printf("WARNING: %s\n", err)
+		}
+	} else {
+		_, err := ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.weight"))
+		sysInfo.BlkioWeight = err == nil
+		if !sysInfo.BlkioWeight && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup blkio weight.")
+		}
+
+		_, err = ioutil.ReadFile(path.Join(cgroupBlkioMountpoint, "blkio.throttle.read_bps_device"))
+		sysInfo.BlkioThrottle = err == nil
+		if !sysInfo.BlkioThrottle && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup blkio throttling.")
+		}
 	}
 
 	// Check if AppArmor seems to be enabled on this system.
@@ -0,0 +1,268 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"github.com/docker/docker/utils"
+)
+
+var (
+	testV2Server *httptest.Server
+	// Protects the content of the mock v2 registry
+	testV2Lock      sync.Mutex
+	testV2Manifests = make(map[string][]byte) // by "name:tag" and "name@digest"
+	testV2Blobs     = make(map[string][]byte) // by digest
+	testV2Uploads   = make(map[string]*bytes.Buffer)
+	testV2UploadID  int
+	// Number of the next chunks received only halfway
+	testV2FailedChunks int
+	// Number of the tokens given by the token server
+	testV2Tokens int
+)
+
+func init() {
+	r := mux.NewRouter()
+	r.HandleFunc("/v2/", handlerV2Ping).Methods("GET")
+	r.HandleFunc("/token", handlerV2Token).Methods("GET")
+	r.HandleFunc("/v2/{name:.+}/manifests/{reference}", handlerV2GetManifest).Methods("GET")
+	r.HandleFunc("/v2/{name:.+}/manifests/{reference}", handlerV2PutManifest).Methods("PUT")
+	r.HandleFunc("/v2/{name:.+}/tags/list", handlerV2GetTags).Methods("GET")
+	r.HandleFunc("/v2/{name:.+}/blobs/uploads/", handlerV2StartUpload).Methods("POST")
+	r.HandleFunc("/v2/{name:.+}/blobs/uploads/{uuid}", handlerV2Upload).Methods("GET", "PATCH", "PUT", "DELETE")
+	r.HandleFunc("/v2/{name:.+}/blobs/{digest}", handlerV2GetBlob).Methods("GET", "HEAD")
+	testV2Server = httptest.NewServer(handlerAccessLog(handlerV2Version(r)))
+}
+
+func handlerV2Version(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func v2APIError(w http.ResponseWriter, code string, message string, status int) {
+	writeResponse(w, map[string]interface{}{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	}, status)
+}
+
+// v2Authorized checks that the request has a token of the mock token server
+// allowing action on the repository name, and answers a challenge otherwise
+func v2Authorized(w http.ResponseWriter, r *http.Request, name, action string) bool {
+	// The tokens of the mock are their scope
+	scope := strings.SplitN(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ":", 3)
+	if len(scope) == 3 && scope[0] == "repository" && (name == "" || scope[1] == name) && strings.Contains(scope[2], action) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="mock-registry"`, r.Host))
+	v2APIError(w, "UNAUTHORIZED", "access to the requested resource is not authorized", 401)
+	return false
+}
+
+func handlerV2Ping(w http.ResponseWriter, r *http.Request) {
+	if !v2Authorized(w, r, "", "pull") {
+		return
+	}
+	writeResponse(w, map[string]string{}, 200)
+}
+
+func handlerV2Token(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if auth != "" && auth != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:password")) {
+		apiError(w, "Wrong login/password", 401)
+		return
+	}
+	if r.URL.Query().Get("service") != "mock-registry" {
+		apiError(w, "Unknown service", 400)
+		return
+	}
+	testV2Lock.Lock()
+	testV2Tokens++
+	testV2Lock.Unlock()
+	writeResponse(w, map[string]string{"token": r.URL.Query().Get("scope")}, 200)
+}
+
+func handlerV2GetManifest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !v2Authorized(w, r, vars["name"], "pull") {
+		return
+	}
+	key := vars["name"] + ":" + vars["reference"]
+	if utils.DigestReference(vars["reference"]) {
+		key = vars["name"] + "@" + vars["reference"]
+	}
+	testV2Lock.Lock()
+	manifest, exists := testV2Manifests[key]
+	testV2Lock.Unlock()
+	if !exists {
+		v2APIError(w, "MANIFEST_UNKNOWN", "manifest unknown", 404)
+		return
+	}
+	writeHeaders(w)
+	w.WriteHeader(200)
+	w.Write(manifest)
+}
+
+func handlerV2PutManifest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !v2Authorized(w, r, vars["name"], "push") {
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apiError(w, err.Error(), 500)
+		return
+	}
+	manifest := &ManifestData{}
+	if err := json.Unmarshal(body, manifest); err != nil {
+		v2APIError(w, "MANIFEST_INVALID", err.Error(), 400)
+		return
+	}
+	digest, _ := ManifestDigest(body)
+
+	testV2Lock.Lock()
+	defer testV2Lock.Unlock()
+	for _, layer := range manifest.FSLayers {
+		if _, exists := testV2Blobs[layer.BlobSum]; !exists {
+			v2APIError(w, "MANIFEST_BLOB_UNKNOWN", layer.BlobSum, 400)
+			return
+		}
+	}
+	testV2Manifests[vars["name"]+":"+vars["reference"]] = body
+	testV2Manifests[vars["name"]+"@"+digest] = body
+	w.Header().Set("Docker-Content-Digest", digest)
+	writeResponse(w, map[string]string{}, 201)
+}
+
+func handlerV2GetTags(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !v2Authorized(w, r, name, "pull") {
+		return
+	}
+	tagList := &TagList{Name: name}
+	testV2Lock.Lock()
+	for key := range testV2Manifests {
+		if strings.HasPrefix(key, name+":") {
+			tagList.Tags = append(tagList.Tags, strings.TrimPrefix(key, name+":"))
+		}
+	}
+	testV2Lock.Unlock()
+	if tagList.Tags == nil {
+		v2APIError(w, "NAME_UNKNOWN", "repository name not known to registry", 404)
+		return
+	}
+	writeResponse(w, tagList, 200)
+}
+
+func handlerV2GetBlob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !v2Authorized(w, r, vars["name"], "pull") {
+		return
+	}
+	testV2Lock.Lock()
+	blob, exists := testV2Blobs[vars["digest"]]
+	testV2Lock.Unlock()
+	if !exists {
+		v2APIError(w, "BLOB_UNKNOWN", "blob unknown to registry", 404)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+	w.Header().Set("Docker-Content-Digest", vars["digest"])
+	w.WriteHeader(200)
+	if r.Method == "GET" {
+		w.Write(blob)
+	}
+}
+
+// writeUploadHeaders sets the location and the range received of an upload
+func writeUploadHeaders(w http.ResponseWriter, name, uuid string, size int) {
+	// The location of the uploads is relative, as allowed
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", name, uuid))
+	w.Header().Set("Docker-Upload-UUID", uuid)
+	end := size - 1
+	if end < 0 {
+		end = 0
+	}
+	w.Header().Set("Range", fmt.Sprintf("0-%d", end))
+}
+
+func handlerV2StartUpload(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !v2Authorized(w, r, name, "push") {
+		return
+	}
+	testV2Lock.Lock()
+	testV2UploadID++
+	uuid := fmt.Sprintf("upload-%d", testV2UploadID)
+	testV2Uploads[uuid] = &bytes.Buffer{}
+	testV2Lock.Unlock()
+	writeUploadHeaders(w, name, uuid, 0)
+	w.WriteHeader(202)
+}
+
+func handlerV2Upload(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !v2Authorized(w, r, vars["name"], "push") {
+		return
+	}
+	testV2Lock.Lock()
+	defer testV2Lock.Unlock()
+	upload, exists := testV2Uploads[vars["uuid"]]
+	if !exists {
+		v2APIError(w, "BLOB_UPLOAD_UNKNOWN", "blob upload unknown to registry", 404)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apiError(w, err.Error(), 500)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeUploadHeaders(w, vars["name"], vars["uuid"], upload.Len())
+		w.WriteHeader(204)
+	case "PATCH":
+		if !strings.HasPrefix(r.Header.Get("Content-Range"), fmt.Sprintf("%d-", upload.Len())) {
+			writeUploadHeaders(w, vars["name"], vars["uuid"], upload.Len())
+			v2APIError(w, "BLOB_UPLOAD_INVALID", "invalid content range", 416)
+			return
+		}
+		if testV2FailedChunks > 0 {
+			testV2FailedChunks--
+			upload.Write(body[:len(body)/2])
+			v2APIError(w, "UNKNOWN", "connection lost", 500)
+			return
+		}
+		upload.Write(body)
+		writeUploadHeaders(w, vars["name"], vars["uuid"], upload.Len())
+		w.WriteHeader(202)
+	case "PUT":
+		upload.Write(body)
+		digest, _ := Digest(bytes.NewReader(upload.Bytes()))
+		if expected := r.URL.Query().Get("digest"); digest != expected {
+			v2APIError(w, "DIGEST_INVALID", fmt.Sprintf("expected %s, got %s", expected, digest), 400)
+			return
+		}
+		testV2Blobs[digest] = upload.Bytes()
+		delete(testV2Uploads, vars["uuid"])
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", vars["name"], digest))
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(201)
+	case "DELETE":
+		delete(testV2Uploads, vars["uuid"])
+		w.WriteHeader(204)
+	}
+}
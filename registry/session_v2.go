@@ -0,0 +1,664 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/trust"
+	"github.com/docker/docker/utils"
+)
+
+var (
+	// ErrManifestNotFound is returned when a v2 registry has no manifest for
+	// the tag or the digest of a repository, or no tag for the repository
+	ErrManifestNotFound = errors.New("Manifest not found")
+	errNoV2             = errors.New("The registry does not support the v2 protocol")
+)
+
+const (
+	// indexServerV2Address is the v2 endpoint of the official registry
+	indexServerV2Address = "https://registry-1.docker.io/v2/"
+	// blobChunkRetries is the number of times the upload of a chunk of a blob
+	// is resumed before giving up
+	blobChunkRetries = 3
+)
+
+// blobChunkSize is the size of the chunks of the blobs uploaded to a v2
+// registry
+var blobChunkSize = 5 * 1024 * 1024
+
+// A V2Session talks to a registry through the v2 protocol: the manifests of
+// the tags of a repository are addressed by tag or by digest, and the layers
+// of the images are blobs addressed by digest. There is no separate index,
+// the registry itself sends the authentication challenges.
+type V2Session struct {
+	authConfig *AuthConfig
+	reqFactory *utils.HTTPRequestFactory
+	endpoint   string
+	official   bool
+	jar        *cookiejar.Jar
+	timeout    TimeoutType
+
+	// The authentication challenges of the registry, by scheme
+	challenges map[string]map[string]string
+	// Protects the bearer tokens of the session
+	sync.Mutex
+	tokens map[string]string // bearer token of each scope
+}
+
+// NewV2Session returns a session with the registry of hostname, or an error if
+// the registry does not speak the v2 protocol.
+func NewV2Session(authConfig *AuthConfig, factory *utils.HTTPRequestFactory, hostname string, timeout bool) (r *V2Session, err error) {
+	r = &V2Session{
+		authConfig: authConfig,
+		reqFactory: factory,
+		official:   hostname == IndexServerAddress(),
+		tokens:     make(map[string]string),
+	}
+
+	if timeout {
+		r.timeout = ReceiveTimeout
+	}
+
+	r.jar, err = cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, endpoint := range v2Endpoints(hostname) {
+		if err = r.ping(endpoint); err == nil {
+			r.endpoint = endpoint
+			return r, nil
+		}
+		log.Debugf("Registry v2 endpoint %s is not usable: %s", endpoint, err)
+	}
+	return nil, err
+}
+
+// v2Endpoints returns the v2 endpoints to try for the registry of hostname,
+// https first
+func v2Endpoints(hostname string) []string {
+	if hostname == IndexServerAddress() {
+		return []string{indexServerV2Address}
+	}
+	if utils.IsURL(hostname) {
+		if u, err := url.Parse(hostname); err == nil {
+			return []string{u.Scheme + "://" + u.Host + "/v2/"}
+		}
+	}
+	return []string{"https://" + hostname + "/v2/", "http://" + hostname + "/v2/"}
+}
+
+// ping checks that the registry of endpoint speaks the v2 protocol, and reads
+// its authentication challenges
+func (r *V2Session) ping(endpoint string) error {
+	req, err := r.reqFactory.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	res, _, err := doRequest(req, r.jar, ConnectTimeout)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	supported := false
+	for _, version := range res.Header[http.CanonicalHeaderKey("Docker-Distribution-API-Version")] {
+		if version == "registry/2.0" {
+			supported = true
+		}
+	}
+	if !supported {
+		return errNoV2
+	}
+
+	switch res.StatusCode {
+	case 200:
+		return nil
+	case 401:
+		r.challenges = parseChallenges(res.Header)
+		if r.challenges["bearer"] == nil && r.challenges["basic"] == nil {
+			return fmt.Errorf("Unsupported authentication of the registry: %s", res.Header.Get("WWW-Authenticate"))
+		}
+		return nil
+	}
+	return utils.NewHTTPRequestError(fmt.Sprintf("Unexpected HTTP code %d while pinging %s", res.StatusCode, endpoint), res)
+}
+
+// parseChallenges returns the parameters of each challenge of the
+// WWW-Authenticate headers, by lowercased scheme
+func parseChallenges(header http.Header) map[string]map[string]string {
+	challenges := make(map[string]map[string]string)
+	for _, h := range header[http.CanonicalHeaderKey("WWW-Authenticate")] {
+		scheme, params := parseChallenge(h)
+		challenges[scheme] = params
+	}
+	return challenges
+}
+
+// parseChallenge parses a challenge such as
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`
+func parseChallenge(challenge string) (string, map[string]string) {
+	challenge = strings.TrimSpace(challenge)
+	scheme, rest := challenge, ""
+	if i := strings.Index(challenge, " "); i >= 0 {
+		scheme, rest = challenge[:i], challenge[i+1:]
+	}
+
+	params := make(map[string]string)
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		i := strings.Index(rest, "=")
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:i]))
+		rest = strings.TrimLeft(rest[i+1:], " ")
+
+		var value bytes.Buffer
+		if strings.HasPrefix(rest, `"`) {
+			j := 1
+			for ; j < len(rest) && rest[j] != '"'; j++ {
+				if rest[j] == '\\' && j+1 < len(rest) {
+					j++
+				}
+				value.WriteByte(rest[j])
+			}
+			if j < len(rest) {
+				j++
+			}
+			rest = rest[j:]
+		} else {
+			j := strings.Index(rest, ",")
+			if j < 0 {
+				j = len(rest)
+			}
+			value.WriteString(strings.TrimSpace(rest[:j]))
+			rest = rest[j:]
+		}
+		params[key] = value.String()
+	}
+	return strings.ToLower(scheme), params
+}
+
+func pullScope(name string) string {
+	return "repository:" + name + ":pull"
+}
+
+func pushScope(name string) string {
+	return "repository:" + name + ":pull,push"
+}
+
+// authorize sets the authorization of req for scope, as asked by the
+// challenges of the registry
+func (r *V2Session) authorize(req *http.Request, scope string) error {
+	if r.challenges["bearer"] != nil {
+		token, err := r.token(scope)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if r.challenges["basic"] != nil && r.authConfig.Username != "" {
+		req.SetBasicAuth(r.authConfig.Username, r.authConfig.Password)
+	}
+	return nil
+}
+
+// token returns the bearer token of scope, asking the token server of the
+// realm of the challenge for a new one the first time
+func (r *V2Session) token(scope string) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+	if token, exists := r.tokens[scope]; exists {
+		return token, nil
+	}
+
+	params := r.challenges["bearer"]
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("Invalid realm of the bearer challenge: %q", params["realm"])
+	}
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", scope)
+	if r.authConfig.Username != "" {
+		query.Set("account", r.authConfig.Username)
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := r.reqFactory.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if r.authConfig.Username != "" {
+		req.SetBasicAuth(r.authConfig.Username, r.authConfig.Password)
+	}
+	res, _, err := doRequest(req, r.jar, r.timeout)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 401 {
+		return "", errLoginRequired
+	} else if res.StatusCode != 200 {
+		return "", utils.NewHTTPRequestError(fmt.Sprintf("HTTP code %d while getting a token for %s", res.StatusCode, scope), res)
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+	token := tokenResponse.Token
+	if token == "" {
+		token = tokenResponse.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("No token for %s in the response of %s", scope, params["realm"])
+	}
+	r.tokens[scope] = token
+	return token, nil
+}
+
+// forgetToken drops the bearer token of scope refused by the registry
+func (r *V2Session) forgetToken(scope string) {
+	r.Lock()
+	delete(r.tokens, scope)
+	r.Unlock()
+}
+
+// do sends a request of body to the registry with the authorization of scope.
+// A bearer token refused by the registry, which may have expired, is renewed
+// once.
+func (r *V2Session) do(method, urlStr string, body []byte, header http.Header, scope string) (*http.Response, error) {
+	for renewed := false; ; renewed = true {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := r.reqFactory.NewRequest(method, urlStr, reader)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if err := r.authorize(req, scope); err != nil {
+			return nil, err
+		}
+		res, _, err := doRequest(req, r.jar, r.timeout)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == 401 && r.challenges["bearer"] != nil && !renewed {
+			res.Body.Close()
+			r.forgetToken(scope)
+			continue
+		}
+		return res, nil
+	}
+}
+
+// Repository returns the name of the repository name in the registry, where
+// the official repositories are in the "library" namespace
+func (r *V2Session) Repository(name string) string {
+	if r.official && !strings.Contains(name, "/") {
+		return "library/" + name
+	}
+	return name
+}
+
+func (r *V2Session) url(name string, parts ...string) string {
+	return r.endpoint + name + "/" + strings.Join(parts, "/")
+}
+
+// v2Error returns the error of the response res of the registry to action,
+// with the messages of the errors of its body
+func v2Error(res *http.Response, action string) error {
+	if res.StatusCode == 401 {
+		return errLoginRequired
+	}
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	msg := fmt.Sprintf("HTTP code %d while %s", res.StatusCode, action)
+	if data, err := ioutil.ReadAll(res.Body); err == nil && json.Unmarshal(data, &body) == nil {
+		for _, e := range body.Errors {
+			msg += fmt.Sprintf(": %s %s", e.Code, e.Message)
+		}
+	}
+	return utils.NewHTTPRequestError(msg, res)
+}
+
+// Digest returns the digest of the content of r, such as
+// "sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b"
+func Digest(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newDigestHash returns the hash of the algorithm of digest
+func newDigestHash(digest string) (hash.Hash, error) {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, fmt.Errorf("Unsupported digest %q", digest)
+	}
+	return sha256.New(), nil
+}
+
+// ManifestDigest returns the digest of manifest, which is the digest of its
+// payload: the digest of a signed manifest does not depend on its signatures.
+func ManifestDigest(manifest []byte) (string, error) {
+	payload, err := trust.Payload(manifest)
+	if err != nil {
+		return "", fmt.Errorf("Invalid manifest: %s", err)
+	}
+	return Digest(bytes.NewReader(payload))
+}
+
+// GetManifest returns the manifest of reference, a tag or a digest, in the
+// repository name, and its digest. The digest of a manifest asked by digest
+// is checked.
+func (r *V2Session) GetManifest(name, reference string) ([]byte, string, error) {
+	name = r.Repository(name)
+	res, err := r.do("GET", r.url(name, "manifests", reference), nil, nil, pullScope(name))
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		return nil, "", ErrManifestNotFound
+	} else if res.StatusCode != 200 {
+		return nil, "", v2Error(res, fmt.Sprintf("fetching the manifest %s of %s", reference, name))
+	}
+
+	manifest, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	digest, err := ManifestDigest(manifest)
+	if err != nil {
+		return nil, "", err
+	}
+	if utils.DigestReference(reference) && digest != reference {
+		return nil, "", fmt.Errorf("The manifest %s of %s has the digest %s", reference, name, digest)
+	}
+	return manifest, digest, nil
+}
+
+// PutManifest stores manifest as the manifest of reference in the repository
+// name, and returns its digest
+func (r *V2Session) PutManifest(name, reference string, manifest []byte) (string, error) {
+	name = r.Repository(name)
+	header := http.Header{"Content-Type": {"application/json"}}
+	res, err := r.do("PUT", r.url(name, "manifests", reference), manifest, header, pushScope(name))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 201 && res.StatusCode != 202 {
+		return "", v2Error(res, fmt.Sprintf("pushing the manifest %s of %s", reference, name))
+	}
+	if digest := res.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	return ManifestDigest(manifest)
+}
+
+// GetTags returns the tags of the repository name
+func (r *V2Session) GetTags(name string) ([]string, error) {
+	name = r.Repository(name)
+	res, err := r.do("GET", r.url(name, "tags", "list"), nil, nil, pullScope(name))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		return nil, ErrManifestNotFound
+	} else if res.StatusCode != 200 {
+		return nil, v2Error(res, fmt.Sprintf("fetching the tags of %s", name))
+	}
+	tagList := &TagList{}
+	if err := json.NewDecoder(res.Body).Decode(tagList); err != nil {
+		return nil, err
+	}
+	return tagList.Tags, nil
+}
+
+// HeadBlob returns true if the registry has the blob of digest in the
+// repository name
+func (r *V2Session) HeadBlob(name, digest string) (bool, error) {
+	name = r.Repository(name)
+	res, err := r.do("HEAD", r.url(name, "blobs", digest), nil, nil, pullScope(name))
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	}
+	return false, v2Error(res, fmt.Sprintf("checking the blob %s of %s", digest, name))
+}
+
+// GetBlob returns the blob of digest in the repository name, and its size. The
+// reader returns an error at the end of a blob of another digest.
+func (r *V2Session) GetBlob(name, digest string) (io.ReadCloser, int64, error) {
+	h, err := newDigestHash(digest)
+	if err != nil {
+		return nil, 0, err
+	}
+	name = r.Repository(name)
+	res, err := r.do("GET", r.url(name, "blobs", digest), nil, nil, pullScope(name))
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.StatusCode != 200 {
+		defer res.Body.Close()
+		if res.StatusCode == 404 {
+			return nil, 0, fmt.Errorf("Blob %s not found in %s", digest, name)
+		}
+		return nil, 0, v2Error(res, fmt.Sprintf("fetching the blob %s of %s", digest, name))
+	}
+	return &digestVerifier{ReadCloser: res.Body, hash: h, digest: digest}, res.ContentLength, nil
+}
+
+// digestVerifier checks at the end of a blob that it has the expected digest
+type digestVerifier struct {
+	io.ReadCloser
+	hash   hash.Hash
+	digest string
+}
+
+func (v *digestVerifier) Read(p []byte) (int, error) {
+	n, err := v.ReadCloser.Read(p)
+	v.hash.Write(p[:n])
+	if err == io.EOF {
+		algorithm := v.digest[:strings.Index(v.digest, ":")+1]
+		if digest := algorithm + hex.EncodeToString(v.hash.Sum(nil)); digest != v.digest {
+			return n, fmt.Errorf("The blob %s has the digest %s", v.digest, digest)
+		}
+	}
+	return n, err
+}
+
+// A BlobUpload is the upload of a blob to a repository, sent by chunks. An
+// interrupted upload is resumed from the offset received by the registry.
+type BlobUpload struct {
+	session  *V2Session
+	name     string
+	location string
+	Offset   int64
+}
+
+// StartBlobUpload starts the upload of a blob to the repository name
+func (r *V2Session) StartBlobUpload(name string) (*BlobUpload, error) {
+	name = r.Repository(name)
+	res, err := r.do("POST", r.url(name, "blobs", "uploads/"), []byte{}, nil, pushScope(name))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 202 {
+		return nil, v2Error(res, fmt.Sprintf("starting an upload to %s", name))
+	}
+	upload := &BlobUpload{session: r, name: name}
+	if err := upload.setLocation(res); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// setLocation moves the upload to the location of the response res
+func (u *BlobUpload) setLocation(res *http.Response) error {
+	location, err := res.Location()
+	if err != nil {
+		return fmt.Errorf("Invalid location of the upload to %s: %s", u.name, err)
+	}
+	u.location = location.String()
+	return nil
+}
+
+// WriteChunk sends chunk, the content of the blob at the offset of the upload
+func (u *BlobUpload) WriteChunk(chunk []byte) error {
+	header := http.Header{
+		"Content-Type":  {"application/octet-stream"},
+		"Content-Range": {fmt.Sprintf("%d-%d", u.Offset, u.Offset+int64(len(chunk))-1)},
+	}
+	res, err := u.session.do("PATCH", u.location, chunk, header, pushScope(u.name))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 202 {
+		return v2Error(res, fmt.Sprintf("uploading a chunk to %s", u.name))
+	}
+	u.Offset += int64(len(chunk))
+	return u.setLocation(res)
+}
+
+// Status sets the offset of the upload to the size received by the registry
+func (u *BlobUpload) Status() error {
+	res, err := u.session.do("GET", u.location, nil, nil, pushScope(u.name))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 204 {
+		return v2Error(res, fmt.Sprintf("getting the status of an upload to %s", u.name))
+	}
+	// The range of a blob is inclusive, "0-0" for an empty one
+	u.Offset = 0
+	if parts := strings.SplitN(res.Header.Get("Range"), "-", 2); len(parts) == 2 {
+		end, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid range of the upload to %s: %q", u.name, res.Header.Get("Range"))
+		}
+		if end > 0 {
+			u.Offset = end + 1
+		}
+	}
+	return u.setLocation(res)
+}
+
+// Commit completes the upload of the blob of digest, checked by the registry
+func (u *BlobUpload) Commit(digest string) error {
+	location, err := url.Parse(u.location)
+	if err != nil {
+		return err
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	res, err := u.session.do("PUT", location.String(), []byte{}, nil, pushScope(u.name))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 201 {
+		return v2Error(res, fmt.Sprintf("completing the upload of %s to %s", digest, u.name))
+	}
+	return nil
+}
+
+// Cancel drops the upload
+func (u *BlobUpload) Cancel() error {
+	res, err := u.session.do("DELETE", u.location, nil, nil, pushScope(u.name))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 204 && res.StatusCode != 404 {
+		return v2Error(res, fmt.Sprintf("canceling an upload to %s", u.name))
+	}
+	return nil
+}
+
+// PushBlob uploads blob, of digest, to the repository name by chunks. The
+// upload of a chunk is resumed from the offset received by the registry after
+// an error.
+func (r *V2Session) PushBlob(name, digest string, blob io.ReadSeeker) error {
+	upload, err := r.StartBlobUpload(name)
+	if err != nil {
+		return err
+	}
+	var (
+		chunk    = make([]byte, blobChunkSize)
+		failures int
+	)
+	for {
+		n, err := io.ReadFull(blob, chunk)
+		if n > 0 {
+			if werr := upload.WriteChunk(chunk[:n]); werr != nil {
+				if failures++; failures > blobChunkRetries {
+					upload.Cancel()
+					return werr
+				}
+				log.Debugf("Resuming the upload of %s to %s after: %s", digest, name, werr)
+				if serr := upload.Status(); serr != nil {
+					upload.Cancel()
+					return serr
+				}
+				if _, serr := blob.Seek(upload.Offset, 0); serr != nil {
+					upload.Cancel()
+					return serr
+				}
+				continue
+			}
+			failures = 0
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			upload.Cancel()
+			return err
+		}
+	}
+	return upload.Commit(digest)
+}
@@ -0,0 +1,253 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/trust"
+	"github.com/docker/docker/utils"
+)
+
+func spawnTestV2Session(t *testing.T) *V2Session {
+	r, err := NewV2Session(&AuthConfig{}, utils.NewHTTPRequestFactory(), testV2Server.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func testV2Manifest(t *testing.T, name, tag string, blobs ...string) []byte {
+	manifest := &ManifestData{SchemaVersion: 1, Name: name, Tag: tag}
+	for i, blob := range blobs {
+		manifest.FSLayers = append(manifest.FSLayers, &FSLayer{BlobSum: blob})
+		manifest.History = append(manifest.History, &ManifestHistory{V1Compatibility: `{"id":"` + strings.Repeat(string(rune('a'+i)), 64) + `"}`})
+	}
+	data, err := json.MarshalIndent(manifest, "", "   ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io", scope="repository:samalba/my-app:pull,push",error=invalid_token`)
+	assertEqual(t, scheme, "bearer", "Wrong scheme")
+	assertEqual(t, params["realm"], "https://auth.docker.io/token", "Wrong realm")
+	assertEqual(t, params["service"], "registry.docker.io", "Wrong service")
+	assertEqual(t, params["scope"], "repository:samalba/my-app:pull,push", "Wrong scope")
+	assertEqual(t, params["error"], "invalid_token", "Wrong unquoted parameter")
+
+	scheme, params = parseChallenge(`Basic realm="a \"quoted\" realm"`)
+	assertEqual(t, scheme, "basic", "Wrong scheme")
+	assertEqual(t, params["realm"], `a "quoted" realm`, "Wrong escaped realm")
+}
+
+func TestV2Ping(t *testing.T) {
+	r := spawnTestV2Session(t)
+	assertEqual(t, r.endpoint, testV2Server.URL+"/v2/", "Wrong v2 endpoint")
+	assertEqual(t, r.challenges["bearer"]["service"], "mock-registry", "Expected the challenge of the registry")
+
+	if _, err := NewV2Session(&AuthConfig{}, utils.NewHTTPRequestFactory(), testHttpServer.URL, true); err == nil {
+		t.Fatal("Expected an error for a v1 registry")
+	}
+}
+
+func TestV2Tokens(t *testing.T) {
+	r := spawnTestV2Session(t)
+	testV2Lock.Lock()
+	testV2Blobs["sha256:tokens"] = []byte{}
+	tokens := testV2Tokens
+	testV2Lock.Unlock()
+
+	for i := 0; i < 2; i++ {
+		if exists, err := r.HeadBlob(REPO, "sha256:tokens"); err != nil || !exists {
+			t.Fatalf("Expected the blob to exist, got %v, %v", exists, err)
+		}
+	}
+	testV2Lock.Lock()
+	assertEqual(t, testV2Tokens, tokens+1, "Expected the token of the scope to be reused")
+	testV2Lock.Unlock()
+
+	// An expired token is renewed
+	r.tokens[pullScope(REPO)] = "expired"
+	if _, err := r.HeadBlob(REPO, "sha256:tokens"); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, r.tokens[pullScope(REPO)], pullScope(REPO), "Expected the token to be renewed")
+
+	wrongLogin, err := NewV2Session(&AuthConfig{Username: "user", Password: "wrong"}, utils.NewHTTPRequestFactory(), testV2Server.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrongLogin.HeadBlob(REPO, "sha256:tokens"); err != errLoginRequired {
+		t.Fatalf("Expected a login error, got %v", err)
+	}
+	login, err := NewV2Session(&AuthConfig{Username: "user", Password: "password"}, utils.NewHTTPRequestFactory(), testV2Server.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := login.HeadBlob(REPO, "sha256:tokens"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestV2Manifest(t *testing.T) {
+	r := spawnTestV2Session(t)
+	blob := []byte("manifest layer")
+	blobDigest, err := Digest(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := testV2Manifest(t, REPO, "v2", blobDigest)
+	if _, err := r.PutManifest(REPO, "v2", manifest); err == nil {
+		t.Fatal("Expected an error for a manifest of unknown blobs")
+	}
+	if err := r.PushBlob(REPO, blobDigest, bytes.NewReader(blob)); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := r.PutManifest(REPO, "v2", manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byTag, tagDigest, err := r.GetManifest(REPO, "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(byTag), string(manifest), "Wrong manifest of the tag")
+	assertEqual(t, tagDigest, digest, "Wrong digest of the manifest of the tag")
+
+	byDigest, _, err := r.GetManifest(REPO, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(byDigest), string(manifest), "Wrong manifest of the digest")
+
+	// The signatures of a manifest do not change its digest
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := trust.Sign(manifest, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedDigest, err := r.PutManifest(REPO, "v2", signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, signedDigest, digest, "Wrong digest of the signed manifest")
+	if byDigest, _, err = r.GetManifest(REPO, digest); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(byDigest), string(signed), "Wrong signed manifest of the digest")
+
+	if _, _, err := r.GetManifest(REPO, "unknown"); err != ErrManifestNotFound {
+		t.Fatalf("Expected ErrManifestNotFound, got %v", err)
+	}
+
+	// A manifest not matching the digest it was asked by is refused
+	testV2Lock.Lock()
+	testV2Manifests[REPO+"@sha256:tampered"] = manifest
+	testV2Lock.Unlock()
+	if _, _, err := r.GetManifest(REPO, "sha256:tampered"); err == nil {
+		t.Fatal("Expected an error for a manifest of another digest")
+	}
+
+	tags, err := r.GetTags(REPO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, len(tags), 1, "Expected one tag")
+	assertEqual(t, tags[0], "v2", "Wrong tag")
+	if _, err := r.GetTags("foo42/unknown"); err != ErrManifestNotFound {
+		t.Fatalf("Expected ErrManifestNotFound, got %v", err)
+	}
+}
+
+func TestV2Blob(t *testing.T) {
+	r := spawnTestV2Session(t)
+	blob := []byte(strings.Repeat("blob content ", 100))
+	digest, err := Digest(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := r.HeadBlob(REPO, digest); err != nil || exists {
+		t.Fatalf("Expected the blob to be missing, got %v, %v", exists, err)
+	}
+	if err := r.PushBlob(REPO, "sha256:wrong", bytes.NewReader(blob)); err == nil {
+		t.Fatal("Expected an error for a blob of another digest")
+	}
+	if err := r.PushBlob(REPO, digest, bytes.NewReader(blob)); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := r.HeadBlob(REPO, digest); err != nil || !exists {
+		t.Fatalf("Expected the blob to exist, got %v, %v", exists, err)
+	}
+
+	layer, size, err := r.GetBlob(REPO, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer layer.Close()
+	assertEqual(t, size, int64(len(blob)), "Wrong size of the blob")
+	content, err := ioutil.ReadAll(layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(content), string(blob), "Wrong content of the blob")
+
+	// A blob not matching its digest fails at its end
+	testV2Lock.Lock()
+	testV2Blobs["sha256:tampered"] = blob
+	testV2Lock.Unlock()
+	tampered, _, err := r.GetBlob(REPO, "sha256:tampered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tampered.Close()
+	if _, err := ioutil.ReadAll(tampered); err == nil {
+		t.Fatal("Expected an error for a blob of another digest")
+	}
+}
+
+func TestV2BlobResume(t *testing.T) {
+	defer func(size int) { blobChunkSize = size }(blobChunkSize)
+	blobChunkSize = 1024
+
+	r := spawnTestV2Session(t)
+	blob := []byte(strings.Repeat("resumed blob ", 400))
+	digest, err := Digest(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testV2Lock.Lock()
+	testV2FailedChunks = 2
+	testV2Lock.Unlock()
+	if err := r.PushBlob(REPO, digest, bytes.NewReader(blob)); err != nil {
+		t.Fatal(err)
+	}
+	testV2Lock.Lock()
+	assertEqual(t, string(testV2Blobs[digest]), string(blob), "Wrong content of the resumed upload")
+	testV2Lock.Unlock()
+
+	// The upload gives up after too many failures of a chunk
+	testV2Lock.Lock()
+	testV2FailedChunks = blobChunkRetries + 1
+	testV2Lock.Unlock()
+	if err := r.PushBlob(REPO, digest, bytes.NewReader(blob)); err == nil {
+		t.Fatal("Expected an error after too many failures")
+	}
+	testV2Lock.Lock()
+	testV2FailedChunks = 0
+	testV2Lock.Unlock()
+}
@@ -31,3 +31,28 @@ type RegistryInfo struct {
 	Version    string `json:"version"`
 	Standalone bool   `json:"standalone"`
 }
+
+// ManifestData is the manifest of a tag of a repository in a v2 registry. The
+// layers and the history go from the top image of the tag to its base image:
+// the blob of the layer of each image, and its config in the v1 format.
+type ManifestData struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Name          string             `json:"name"`
+	Tag           string             `json:"tag"`
+	Architecture  string             `json:"architecture"`
+	FSLayers      []*FSLayer         `json:"fsLayers"`
+	History       []*ManifestHistory `json:"history"`
+}
+
+type FSLayer struct {
+	BlobSum string `json:"blobSum"`
+}
+
+type ManifestHistory struct {
+	V1Compatibility string `json:"v1Compatibility"`
+}
+
+type TagList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
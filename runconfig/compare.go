@@ -22,7 +22,8 @@ func Compare(a, b *Config) bool {
 		len(a.PortSpecs) != len(b.PortSpecs) ||
 		len(a.ExposedPorts) != len(b.ExposedPorts) ||
 		len(a.Entrypoint) != len(b.Entrypoint) ||
-		len(a.Volumes) != len(b.Volumes) {
+		len(a.Volumes) != len(b.Volumes) ||
+		len(a.Labels) != len(b.Labels) {
 		return false
 	}
 
@@ -56,5 +57,28 @@ func Compare(a, b *Config) bool {
 			return false
 		}
 	}
+	for key, value := range a.Labels {
+		if v, exists := b.Labels[key]; !exists || v != value {
+			return false
+		}
+	}
+	return compareHealthConfig(a.Healthcheck, b.Healthcheck)
+}
+
+func compareHealthConfig(a, b *HealthConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Interval != b.Interval ||
+		a.Timeout != b.Timeout ||
+		a.Retries != b.Retries ||
+		len(a.Test) != len(b.Test) {
+		return false
+	}
+	for i := 0; i < len(a.Test); i++ {
+		if a.Test[i] != b.Test[i] {
+			return false
+		}
+	}
 	return true
 }
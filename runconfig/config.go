@@ -1,6 +1,8 @@
 package runconfig
 
 import (
+	"time"
+
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/nat"
 )
@@ -32,6 +34,21 @@ type Config struct {
 	Entrypoint      []string
 	NetworkDisabled bool
 	OnBuild         []string
+	Labels          map[string]string // Arbitrary metadata, e.g. com.example.owner=team
+	Healthcheck     *HealthConfig     // Command probing the health of the container
+}
+
+// HealthConfig holds the configuration of the command probing the health of
+// a running container. The zero durations and retries mean the defaults of
+// the daemon are used.
+type HealthConfig struct {
+	// Test is either ["NONE"] to disable the healthcheck inherited from the
+	// image, ["CMD", args...] to exec args, or ["CMD-SHELL", command] to run
+	// command with /bin/sh -c
+	Test     []string
+	Interval time.Duration // Time to wait between two probes
+	Timeout  time.Duration // Time after which a probe is considered to have failed
+	Retries  int           // Consecutive failures needed to report the container as unhealthy
 }
 
 func ContainerConfigFromJob(job *engine.Job) *Config {
@@ -55,6 +72,8 @@ func ContainerConfigFromJob(job *engine.Job) *Config {
 	}
 	job.GetenvJson("ExposedPorts", &config.ExposedPorts)
 	job.GetenvJson("Volumes", &config.Volumes)
+	job.GetenvJson("Labels", &config.Labels)
+	job.GetenvJson("Healthcheck", &config.Healthcheck)
 	if PortSpecs := job.GetenvList("PortSpecs"); PortSpecs != nil {
 		config.PortSpecs = PortSpecs
 	}
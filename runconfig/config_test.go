@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/nat"
 )
@@ -262,3 +263,46 @@ func TestMerge(t *testing.T) {
 	}
 
 }
+
+func TestMergeLabels(t *testing.T) {
+	configImage := &Config{
+		Labels: map[string]string{"com.example.owner": "infra", "com.example.tier": "db"},
+	}
+	configUser := &Config{
+		Labels: map[string]string{"com.example.owner": "web"},
+	}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	if len(configUser.Labels) != 2 || configUser.Labels["com.example.owner"] != "web" || configUser.Labels["com.example.tier"] != "db" {
+		t.Fatalf("Expected the user labels to override the image ones, got %v", configUser.Labels)
+	}
+}
+
+func TestMergeHealthcheck(t *testing.T) {
+	configImage := &Config{
+		Healthcheck: &HealthConfig{
+			Test:     []string{"CMD-SHELL", "true"},
+			Interval: time.Minute,
+			Retries:  5,
+		},
+	}
+	configUser := &Config{
+		Healthcheck: &HealthConfig{Interval: time.Second},
+	}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	hc := configUser.Healthcheck
+	if len(hc.Test) != 2 || hc.Test[1] != "true" || hc.Interval != time.Second || hc.Retries != 5 {
+		t.Fatalf("Expected the user healthcheck options to override the image ones, got %v", hc)
+	}
+
+	configUser = &Config{}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	if configUser.Healthcheck != configImage.Healthcheck {
+		t.Fatalf("Expected the healthcheck of the image, got %v", configUser.Healthcheck)
+	}
+}
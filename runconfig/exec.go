@@ -0,0 +1,59 @@
+package runconfig
+
+import (
+	"github.com/docker/docker/engine"
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+// ExecConfig describes a process to be run inside an already running container
+type ExecConfig struct {
+	User         string
+	Tty          bool
+	AttachStdin  bool
+	AttachStdout bool
+	AttachStderr bool
+	Cmd          []string
+}
+
+func ExecConfigFromJob(job *engine.Job) *ExecConfig {
+	execConfig := &ExecConfig{
+		User:         job.Getenv("User"),
+		Tty:          job.GetenvBool("Tty"),
+		AttachStdin:  job.GetenvBool("AttachStdin"),
+		AttachStdout: job.GetenvBool("AttachStdout"),
+		AttachStderr: job.GetenvBool("AttachStderr"),
+	}
+	if Cmd := job.GetenvList("Cmd"); Cmd != nil {
+		execConfig.Cmd = Cmd
+	}
+	return execConfig
+}
+
+// ParseExec parses the arguments of the exec command and returns the
+// name of the target container along with the process configuration
+func ParseExec(cmd *flag.FlagSet, args []string) (string, *ExecConfig, error) {
+	var (
+		flStdin = cmd.Bool([]string{"i", "-interactive"}, false, "Keep STDIN open even if not attached")
+		flTty   = cmd.Bool([]string{"t", "-tty"}, false, "Allocate a pseudo-TTY")
+		flUser  = cmd.String([]string{"u", "-user"}, "", "Username or UID to run the command as")
+	)
+
+	if err := cmd.Parse(args); err != nil {
+		return "", nil, err
+	}
+	parsedArgs := cmd.Args()
+	if len(parsedArgs) < 2 {
+		return "", nil, ErrMissingExecArgs
+	}
+
+	execConfig := &ExecConfig{
+		User:         *flUser,
+		Tty:          *flTty,
+		AttachStdin:  *flStdin,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          parsedArgs[1:],
+	}
+
+	return parsedArgs[0], execConfig, nil
+}
@@ -0,0 +1,46 @@
+package runconfig
+
+import (
+	"io/ioutil"
+	"testing"
+
+	flag "github.com/docker/docker/pkg/mflag"
+)
+
+func parseExec(args []string) (string, *ExecConfig, error) {
+	cmd := flag.NewFlagSet("exec", flag.ContinueOnError)
+	cmd.SetOutput(ioutil.Discard)
+	cmd.Usage = nil
+	return ParseExec(cmd, args)
+}
+
+func TestParseExec(t *testing.T) {
+	name, config, err := parseExec([]string{"-i", "-t", "-u", "daemon", "container", "ls", "-la"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "container" {
+		t.Fatalf("Expected container name to be container, got %s", name)
+	}
+	if !config.AttachStdin || !config.Tty {
+		t.Fatalf("Expected stdin and tty to be set")
+	}
+	if !config.AttachStdout || !config.AttachStderr {
+		t.Fatalf("Expected stdout and stderr to be attached")
+	}
+	if config.User != "daemon" {
+		t.Fatalf("Expected user to be daemon, got %s", config.User)
+	}
+	if len(config.Cmd) != 2 || config.Cmd[0] != "ls" || config.Cmd[1] != "-la" {
+		t.Fatalf("Unexpected command %v", config.Cmd)
+	}
+}
+
+func TestParseExecMissingArgs(t *testing.T) {
+	if _, _, err := parseExec([]string{"container"}); err != ErrMissingExecArgs {
+		t.Fatalf("Expected ErrMissingExecArgs, got %v", err)
+	}
+	if _, _, err := parseExec([]string{}); err != ErrMissingExecArgs {
+		t.Fatalf("Expected ErrMissingExecArgs, got %v", err)
+	}
+}
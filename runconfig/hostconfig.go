@@ -5,6 +5,7 @@ import (
 
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/iptables"
 	"github.com/docker/docker/utils"
 )
 
@@ -19,17 +20,63 @@ func (n NetworkMode) IsContainer() bool {
 	return len(parts) > 1 && parts[0] == "container"
 }
 
+// IsMacvlan tells whether the container gets a macvlan interface on an
+// interface of the host, as with macvlan:<parent>
+func (n NetworkMode) IsMacvlan() bool {
+	parts := strings.SplitN(string(n), ":", 2)
+	return len(parts) > 1 && parts[0] == "macvlan"
+}
+
+// MacvlanParent returns the interface of the host the macvlan interface of
+// the container is created on, or an empty string for the other modes
+func (n NetworkMode) MacvlanParent() string {
+	if !n.IsMacvlan() {
+		return ""
+	}
+	return strings.SplitN(string(n), ":", 2)[1]
+}
+
+// IsUserDefined tells whether the mode is the name of a network created with
+// docker network create
+func (n NetworkMode) IsUserDefined() bool {
+	switch n {
+	case "", "bridge", "none", "host":
+		return false
+	}
+	return !n.IsContainer() && !n.IsMacvlan()
+}
+
 type DeviceMapping struct {
 	PathOnHost        string
 	PathInContainer   string
 	CgroupPermissions string
 }
 
+// ThrottleDevice is a limit on the rate of I/O of a block device of the host,
+// in bytes or operations per second
+type ThrottleDevice struct {
+	Path string
+	Rate uint64
+}
+
+// NetworkPolicy restricts the traffic of a container on its networks to the
+// peers of its rules, in each direction having rules
+type NetworkPolicy struct {
+	Egress  []iptables.PolicyRule // peers the container may reach
+	Ingress []iptables.PolicyRule // peers which may reach the container
+}
+
 type RestartPolicy struct {
 	Name              string
 	MaximumRetryCount int
 }
 
+// LogConfig selects the logging driver of a container along with its options
+type LogConfig struct {
+	Type   string
+	Config map[string]string
+}
+
 type HostConfig struct {
 	Binds           []string
 	ContainerIDFile string
@@ -43,9 +90,25 @@ type HostConfig struct {
 	VolumesFrom     []string
 	Devices         []DeviceMapping
 	NetworkMode     NetworkMode
+	IPAddress       string // IPv4 address requested for the container on the network of NetworkMode
+	NetworkPolicy   NetworkPolicy
 	CapAdd          []string
 	CapDrop         []string
 	RestartPolicy   RestartPolicy
+	LogConfig       LogConfig
+	VolumeDriver    string
+
+	MemoryReservation    int64 // Memory soft limit (in bytes)
+	OomKillDisable       bool  // Whether to disable the OOM killer for the container
+	CpuPeriod            int64 // CPU CFS period (in usecs)
+	CpuQuota             int64 // CPU CFS quota (in usecs) allowed in each period
+	BlkioWeight          int64 // Block I/O weight (relative weight), from 10 to 1000
+	BlkioDeviceReadBps   []ThrottleDevice
+	BlkioDeviceWriteBps  []ThrottleDevice
+	BlkioDeviceReadIOps  []ThrottleDevice
+	BlkioDeviceWriteIOps []ThrottleDevice
+
+	Networks []string // Networks connected with docker network connect, besides the one of NetworkMode
 }
 
 func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
@@ -54,12 +117,26 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 		Privileged:      job.GetenvBool("Privileged"),
 		PublishAllPorts: job.GetenvBool("PublishAllPorts"),
 		NetworkMode:     NetworkMode(job.Getenv("NetworkMode")),
+		IPAddress:       job.Getenv("IPAddress"),
+		VolumeDriver:    job.Getenv("VolumeDriver"),
+
+		MemoryReservation: job.GetenvInt64("MemoryReservation"),
+		OomKillDisable:    job.GetenvBool("OomKillDisable"),
+		CpuPeriod:         job.GetenvInt64("CpuPeriod"),
+		CpuQuota:          job.GetenvInt64("CpuQuota"),
+		BlkioWeight:       job.GetenvInt64("BlkioWeight"),
 	}
 
 	job.GetenvJson("LxcConf", &hostConfig.LxcConf)
 	job.GetenvJson("PortBindings", &hostConfig.PortBindings)
 	job.GetenvJson("Devices", &hostConfig.Devices)
 	job.GetenvJson("RestartPolicy", &hostConfig.RestartPolicy)
+	job.GetenvJson("LogConfig", &hostConfig.LogConfig)
+	job.GetenvJson("NetworkPolicy", &hostConfig.NetworkPolicy)
+	job.GetenvJson("BlkioDeviceReadBps", &hostConfig.BlkioDeviceReadBps)
+	job.GetenvJson("BlkioDeviceWriteBps", &hostConfig.BlkioDeviceWriteBps)
+	job.GetenvJson("BlkioDeviceReadIOps", &hostConfig.BlkioDeviceReadIOps)
+	job.GetenvJson("BlkioDeviceWriteIOps", &hostConfig.BlkioDeviceWriteIOps)
 	if Binds := job.GetenvList("Binds"); Binds != nil {
 		hostConfig.Binds = Binds
 	}
@@ -100,5 +100,32 @@ func Merge(userConf, imageConf *Config) error {
 			userConf.Volumes[k] = v
 		}
 	}
+	// the healthcheck settings given by the user override the ones of the image
+	if userConf.Healthcheck == nil {
+		userConf.Healthcheck = imageConf.Healthcheck
+	} else if imageConf.Healthcheck != nil {
+		if len(userConf.Healthcheck.Test) == 0 {
+			userConf.Healthcheck.Test = imageConf.Healthcheck.Test
+		}
+		if userConf.Healthcheck.Interval == 0 {
+			userConf.Healthcheck.Interval = imageConf.Healthcheck.Interval
+		}
+		if userConf.Healthcheck.Timeout == 0 {
+			userConf.Healthcheck.Timeout = imageConf.Healthcheck.Timeout
+		}
+		if userConf.Healthcheck.Retries == 0 {
+			userConf.Healthcheck.Retries = imageConf.Healthcheck.Retries
+		}
+	}
+	// the labels given by the user override the ones of the image
+	if len(userConf.Labels) == 0 {
+		userConf.Labels = imageConf.Labels
+	} else {
+		for k, v := range imageConf.Labels {
+			if _, exists := userConf.Labels[k]; !exists {
+				userConf.Labels[k] = v
+			}
+		}
+	}
 	return nil
 }
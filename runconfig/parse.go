@@ -3,12 +3,16 @@ package runconfig
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/nat"
 	"github.com/docker/docker/opts"
+	"github.com/docker/docker/pkg/iptables"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/sysinfo"
@@ -23,8 +27,15 @@ var (
 	ErrConflictNetworkHostname            = fmt.Errorf("Conflicting options: -h and the network mode (--net)")
 	ErrConflictHostNetworkAndLinks        = fmt.Errorf("Conflicting options: --net=host can't be used with links. This would result in undefined behavior.")
 	ErrConflictRestartPolicyAndAutoRemove = fmt.Errorf("Conflicting options: --restart and --rm")
+	ErrConflictNetworkModeAndIP           = fmt.Errorf("Conflicting options: --ip and the network mode (--net)")
+	ErrConflictMacvlanAndPublish          = fmt.Errorf("Conflicting options: -p, -P and --net=macvlan, the ports of the container are reachable on the network of its parent interface")
+	ErrConflictNetworkModeAndPolicy       = fmt.Errorf("Conflicting options: --allow-egress, --allow-ingress and the network mode (--net), the policies apply to the containers on a bridge")
+	ErrMissingExecArgs                    = fmt.Errorf("Both a container and a command are required")
 )
 
+// validNetworkName matches the names of the user-defined networks
+var validNetworkName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
 //FIXME Only used in tests
 func Parse(args []string, sysInfo *sysinfo.SysInfo) (*Config, *HostConfig, *flag.FlagSet, error) {
 	cmd := flag.NewFlagSet("run", flag.ContinueOnError)
@@ -54,8 +65,18 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flVolumesFrom = opts.NewListOpts(nil)
 		flLxcOpts     = opts.NewListOpts(nil)
 		flEnvFile     = opts.NewListOpts(nil)
+		flLabels      = opts.NewListOpts(opts.ValidateLabel)
+		flLabelFile   = opts.NewListOpts(nil)
 		flCapAdd      = opts.NewListOpts(nil)
 		flCapDrop     = opts.NewListOpts(nil)
+		flLogOpts     = opts.NewListOpts(nil)
+		flEgress      = opts.NewListOpts(nil)
+		flIngress     = opts.NewListOpts(nil)
+
+		flDeviceReadBps   = opts.NewListOpts(nil)
+		flDeviceWriteBps  = opts.NewListOpts(nil)
+		flDeviceReadIOps  = opts.NewListOpts(nil)
+		flDeviceWriteIOps = opts.NewListOpts(nil)
 
 		flAutoRemove      = cmd.Bool([]string{"#rm", "-rm"}, false, "Automatically remove the container when it exits (incompatible with -d)")
 		flDetach          = cmd.Bool([]string{"d", "-detach"}, false, "Detached mode: run container in the background and print new container ID")
@@ -72,19 +93,35 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flWorkingDir      = cmd.String([]string{"w", "-workdir"}, "", "Working directory inside the container")
 		flCpuShares       = cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
 		flCpuset          = cmd.String([]string{"-cpuset"}, "", "CPUs in which to allow execution (0-3, 0,1)")
-		flNetMode         = cmd.String([]string{"-net"}, "bridge", "Set the Network mode for the container\n'bridge': creates a new network stack for the container on the docker bridge\n'none': no networking for this container\n'container:<name|id>': reuses another container network stack\n'host': use the host network stack inside the container.  Note: the host mode gives the container full access to local system services such as D-bus and is therefore considered insecure.")
+		flNetMode         = cmd.String([]string{"-net"}, "bridge", "Set the Network mode for the container\n'bridge': creates a new network stack for the container on the docker bridge\n'none': no networking for this container\n'container:<name|id>': reuses another container network stack\n'host': use the host network stack inside the container.  Note: the host mode gives the container full access to local system services such as D-bus and is therefore considered insecure.\n'macvlan:<interface>': puts the container on the network of an interface of the host with a macvlan interface\n'<network-name>': connects the container to a network created with docker network create")
+		flIPAddress       = cmd.String([]string{"-ip"}, "", "Container IPv4 address on its network (e.g. 172.17.0.10)")
 		flRestartPolicy   = cmd.String([]string{"-restart"}, "", "Restart policy to apply when a container exits (no, on-failure, always)")
+		flLogDriver       = cmd.String([]string{"-log-driver"}, "", "Logging driver for the container (json-file, syslog, journald, none)")
+		flVolumeDriver    = cmd.String([]string{"-volume-driver"}, "", "Driver creating the volumes of the container (local or the name of a volume plugin)")
+		flHealthCmd       = cmd.String([]string{"-health-cmd"}, "", "Command run in the container to check its health, use 'none' to disable the healthcheck of the image")
+		flHealthInterval  = cmd.Duration([]string{"-health-interval"}, 0, "Time between two runs of the health check command (e.g. 30s)")
+		flHealthTimeout   = cmd.Duration([]string{"-health-timeout"}, 0, "Time after which a run of the health check command is considered to have failed (e.g. 30s)")
+		flHealthRetries   = cmd.Int([]string{"-health-retries"}, 0, "Consecutive failures needed to report the container as unhealthy")
+
+		flMemoryReservation = cmd.String([]string{"-memory-reservation"}, "", "Memory soft limit (format: <number><optional unit>, where unit = b, k, m or g)")
+		flOomKillDisable    = cmd.Bool([]string{"-oom-kill-disable"}, false, "Disable the OOM killer for the container")
+		flCpuPeriod         = cmd.Int64([]string{"-cpu-period"}, 0, "Length of a CPU CFS (Completely Fair Scheduler) period in microseconds")
+		flCpuQuota          = cmd.Int64([]string{"-cpu-quota"}, 0, "CPU time in microseconds the container can use in each CPU CFS (Completely Fair Scheduler) period")
+		flBlkioWeight       = cmd.Int64([]string{"-blkio-weight"}, 0, "Block I/O weight (relative weight), between 10 and 1000")
+
 		// For documentation purpose
 		_ = cmd.Bool([]string{"#sig-proxy", "-sig-proxy"}, true, "Proxy received signals to the process (even in non-TTY mode). SIGCHLD, SIGSTOP, and SIGKILL are not proxied.")
 		_ = cmd.String([]string{"#name", "-name"}, "", "Assign a name to the container")
 	)
 
 	cmd.Var(&flAttach, []string{"a", "-attach"}, "Attach to STDIN, STDOUT or STDERR.")
-	cmd.Var(&flVolumes, []string{"v", "-volume"}, "Bind mount a volume (e.g., from the host: -v /host:/container, from Docker: -v /container)")
+	cmd.Var(&flVolumes, []string{"v", "-volume"}, "Bind mount a volume (e.g., from the host: -v /host:/container, from Docker: -v /container, named volume: -v name:/container)")
 	cmd.Var(&flLinks, []string{"#link", "-link"}, "Add link to another container in the form of name:alias")
 	cmd.Var(&flDevices, []string{"-device"}, "Add a host device to the container (e.g. --device=/dev/sdc:/dev/xvdc)")
 	cmd.Var(&flEnv, []string{"e", "-env"}, "Set environment variables")
 	cmd.Var(&flEnvFile, []string{"-env-file"}, "Read in a line delimited file of environment variables")
+	cmd.Var(&flLabels, []string{"l", "-label"}, "Set metadata on the container (e.g., --label com.example.key=value)")
+	cmd.Var(&flLabelFile, []string{"-label-file"}, "Read in a line delimited file of labels")
 
 	cmd.Var(&flPublish, []string{"p", "-publish"}, fmt.Sprintf("Publish a container's port to the host\nformat: %s\n(use 'docker port' to see the actual mapping)", nat.PortSpecTemplateFormat))
 	cmd.Var(&flExpose, []string{"#expose", "-expose"}, "Expose a port from the container without publishing it to your host")
@@ -95,6 +132,13 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 
 	cmd.Var(&flCapAdd, []string{"-cap-add"}, "Add Linux capabilities")
 	cmd.Var(&flCapDrop, []string{"-cap-drop"}, "Drop Linux capabilities")
+	cmd.Var(&flLogOpts, []string{"-log-opt"}, "Log driver options (format: key=value)")
+	cmd.Var(&flEgress, []string{"-allow-egress"}, "Only let the container reach these peers (format: [cidr][:port[-port][/proto]], e.g. --allow-egress=10.0.0.0/8:5432)")
+	cmd.Var(&flIngress, []string{"-allow-ingress"}, "Only let these peers reach the container (format: [cidr][:port[-port][/proto]], e.g. --allow-ingress=192.168.0.0/16)")
+	cmd.Var(&flDeviceReadBps, []string{"-device-read-bps"}, "Limit the read rate of a device (e.g. --device-read-bps=/dev/sda:1mb)")
+	cmd.Var(&flDeviceWriteBps, []string{"-device-write-bps"}, "Limit the write rate of a device (e.g. --device-write-bps=/dev/sda:1mb)")
+	cmd.Var(&flDeviceReadIOps, []string{"-device-read-iops"}, "Limit the read rate of a device in operations per second (e.g. --device-read-iops=/dev/sda:1000)")
+	cmd.Var(&flDeviceWriteIOps, []string{"-device-write-iops"}, "Limit the write rate of a device in operations per second (e.g. --device-write-iops=/dev/sda:1000)")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil, nil, cmd, err
@@ -116,7 +160,7 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		return nil, nil, cmd, ErrConflictDetachAutoRemove
 	}
 
-	if *flNetMode != "bridge" && *flNetMode != "none" && *flHostname != "" {
+	if (*flNetMode == "host" || strings.HasPrefix(*flNetMode, "container:")) && *flHostname != "" {
 		return nil, nil, cmd, ErrConflictNetworkHostname
 	}
 
@@ -144,6 +188,32 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flMemory = parsedMemory
 	}
 
+	var flMemoryReservationBytes int64
+	if *flMemoryReservation != "" {
+		parsedMemory, err := units.RAMInBytes(*flMemoryReservation)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		flMemoryReservationBytes = parsedMemory
+	}
+
+	deviceReadBps, err := parseThrottleDevices(flDeviceReadBps.GetAll(), true)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+	deviceWriteBps, err := parseThrottleDevices(flDeviceWriteBps.GetAll(), true)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+	deviceReadIOps, err := parseThrottleDevices(flDeviceReadIOps.GetAll(), false)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+	deviceWriteIOps, err := parseThrottleDevices(flDeviceWriteIOps.GetAll(), false)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	var binds []string
 	// add any bind targets to the list of container volumes
 	for bind := range flVolumes.GetMap() {
@@ -201,9 +271,16 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		if strings.Contains(e, ":") {
 			return nil, nil, cmd, fmt.Errorf("Invalid port format for --expose: %s", e)
 		}
-		p := nat.NewPort(nat.SplitProtoPort(e))
-		if _, exists := ports[p]; !exists {
-			ports[p] = struct{}{}
+		proto, port := nat.SplitProtoPort(e)
+		start, end, err := parsers.ParsePortRange(port)
+		if err != nil {
+			return nil, nil, cmd, fmt.Errorf("Invalid range format for --expose: %s, error: %s", e, err)
+		}
+		for i := start; i <= end; i++ {
+			p := nat.NewPort(proto, strconv.FormatUint(i, 10))
+			if _, exists := ports[p]; !exists {
+				ports[p] = struct{}{}
+			}
 		}
 	}
 
@@ -229,16 +306,68 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 	// parse the '-e' and '--env' after, to allow override
 	envVariables = append(envVariables, flEnv.GetAll()...)
 
+	// collect the labels of the container, the ones given with '--label'
+	// override the ones read from files
+	labels := map[string]string{}
+	for _, lf := range flLabelFile.GetAll() {
+		parsedLabels, err := opts.ParseLabelFile(lf)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		for k, v := range parsedLabels {
+			labels[k] = v
+		}
+	}
+	for _, label := range flLabels.GetAll() {
+		k, v := opts.ParseLabel(label)
+		labels[k] = v
+	}
+
 	netMode, err := parseNetMode(*flNetMode)
 	if err != nil {
 		return nil, nil, cmd, fmt.Errorf("--net: invalid net mode: %v", err)
 	}
 
+	if *flIPAddress != "" {
+		if ip := net.ParseIP(*flIPAddress); ip == nil || ip.To4() == nil {
+			return nil, nil, cmd, fmt.Errorf("Invalid IPv4 address: %s", *flIPAddress)
+		}
+		if !*flNetwork || netMode.IsHost() || netMode.IsContainer() || netMode == "none" {
+			return nil, nil, cmd, ErrConflictNetworkModeAndIP
+		}
+	}
+
+	if netMode.IsMacvlan() && (len(portBindings) > 0 || *flPublishAll) {
+		return nil, nil, cmd, ErrConflictMacvlanAndPublish
+	}
+
+	egress, err := parsePolicyRules(flEgress.GetAll())
+	if err != nil {
+		return nil, nil, cmd, fmt.Errorf("--allow-egress: %s", err)
+	}
+	ingress, err := parsePolicyRules(flIngress.GetAll())
+	if err != nil {
+		return nil, nil, cmd, fmt.Errorf("--allow-ingress: %s", err)
+	}
+	if (len(egress) > 0 || len(ingress) > 0) && (!*flNetwork || netMode.IsHost() || netMode.IsContainer() || netMode.IsMacvlan() || netMode == "none") {
+		return nil, nil, cmd, ErrConflictNetworkModeAndPolicy
+	}
+
 	restartPolicy, err := parseRestartPolicy(*flRestartPolicy)
 	if err != nil {
 		return nil, nil, cmd, err
 	}
 
+	logConfig, err := parseLogConfig(*flLogDriver, flLogOpts)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
+	healthConfig, err := parseHealthConfig(*flHealthCmd, *flHealthInterval, *flHealthTimeout, *flHealthRetries)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	if *flAutoRemove && (restartPolicy.Name == "always" || restartPolicy.Name == "on-failure") {
 		return nil, nil, cmd, ErrConflictRestartPolicyAndAutoRemove
 	}
@@ -264,6 +393,8 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		Volumes:         flVolumes.GetMap(),
 		Entrypoint:      entrypoint,
 		WorkingDir:      *flWorkingDir,
+		Labels:          labels,
+		Healthcheck:     healthConfig,
 	}
 
 	hostConfig := &HostConfig{
@@ -278,10 +409,24 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		DnsSearch:       flDnsSearch.GetAll(),
 		VolumesFrom:     flVolumesFrom.GetAll(),
 		NetworkMode:     netMode,
+		IPAddress:       *flIPAddress,
+		NetworkPolicy:   NetworkPolicy{Egress: egress, Ingress: ingress},
 		Devices:         deviceMappings,
 		CapAdd:          flCapAdd.GetAll(),
 		CapDrop:         flCapDrop.GetAll(),
 		RestartPolicy:   restartPolicy,
+		LogConfig:       logConfig,
+		VolumeDriver:    *flVolumeDriver,
+
+		MemoryReservation:    flMemoryReservationBytes,
+		OomKillDisable:       *flOomKillDisable,
+		CpuPeriod:            *flCpuPeriod,
+		CpuQuota:             *flCpuQuota,
+		BlkioWeight:          *flBlkioWeight,
+		BlkioDeviceReadBps:   deviceReadBps,
+		BlkioDeviceWriteBps:  deviceWriteBps,
+		BlkioDeviceReadIOps:  deviceReadIOps,
+		BlkioDeviceWriteIOps: deviceWriteIOps,
 	}
 
 	if sysInfo != nil && flMemory > 0 && !sysInfo.SwapLimit {
@@ -367,6 +512,57 @@ func parseKeyValueOpts(opts opts.ListOpts) ([]utils.KeyValuePair, error) {
 	return out, nil
 }
 
+// parseLogConfig returns the logging configuration for the given driver
+// and list of key=value options
+func parseLogConfig(driver string, opts opts.ListOpts) (LogConfig, error) {
+	config := LogConfig{Type: driver}
+	if opts.Len() == 0 {
+		return config, nil
+	}
+	config.Config = make(map[string]string, opts.Len())
+	for _, o := range opts.GetAll() {
+		k, v, err := parsers.ParseKeyValueOpt(o)
+		if err != nil {
+			return config, err
+		}
+		config.Config[k] = v
+	}
+	return config, nil
+}
+
+// parseHealthConfig returns the healthcheck given on the command line, or nil
+// when no health flag was given so that the one of the image is used
+func parseHealthConfig(command string, interval, timeout time.Duration, retries int) (*HealthConfig, error) {
+	if command == "" && interval == 0 && timeout == 0 && retries == 0 {
+		return nil, nil
+	}
+	if interval < 0 {
+		return nil, fmt.Errorf("--health-interval cannot be negative")
+	}
+	if timeout < 0 {
+		return nil, fmt.Errorf("--health-timeout cannot be negative")
+	}
+	if retries < 0 {
+		return nil, fmt.Errorf("--health-retries cannot be negative")
+	}
+
+	config := &HealthConfig{
+		Interval: interval,
+		Timeout:  timeout,
+		Retries:  retries,
+	}
+	switch {
+	case strings.ToLower(command) == "none":
+		if interval != 0 || timeout != 0 || retries != 0 {
+			return nil, fmt.Errorf("Conflicting options: --health-cmd=none and the other health options")
+		}
+		config.Test = []string{"NONE"}
+	case command != "":
+		config.Test = []string{"CMD-SHELL", command}
+	}
+	return config, nil
+}
+
 func parseNetMode(netMode string) (NetworkMode, error) {
 	parts := strings.Split(netMode, ":")
 	switch mode := parts[0]; mode {
@@ -375,12 +571,99 @@ func parseNetMode(netMode string) (NetworkMode, error) {
 		if len(parts) < 2 || parts[1] == "" {
 			return "", fmt.Errorf("invalid container format container:<name|id>")
 		}
+	case "macvlan":
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("invalid macvlan format macvlan:<parent interface>")
+		}
 	default:
-		return "", fmt.Errorf("invalid --net: %s", netMode)
+		// the name of a user-defined network
+		if !validNetworkName.MatchString(netMode) {
+			return "", fmt.Errorf("invalid --net: %s", netMode)
+		}
 	}
 	return NetworkMode(netMode), nil
 }
 
+// parseThrottleDevices parses the limits of the I/O rate of block devices
+// given in the form <device-path>:<rate>, the rate being a size in bytes per
+// second with an optional unit when bps is true and a number of operations per
+// second otherwise
+func parseThrottleDevices(specs []string, bps bool) ([]ThrottleDevice, error) {
+	var devices []ThrottleDevice
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("Invalid device throttle %s, expected <device-path>:<rate>", spec)
+		}
+		path, value := spec[:i], spec[i+1:]
+		if !strings.HasPrefix(path, "/dev/") {
+			return nil, fmt.Errorf("Invalid device throttle %s, the device must be a path under /dev", spec)
+		}
+		var rate uint64
+		if bps {
+			size, err := units.RAMInBytes(value)
+			if err != nil || size < 0 {
+				return nil, fmt.Errorf("Invalid device throttle %s, the rate must be a size in bytes per second", spec)
+			}
+			rate = uint64(size)
+		} else {
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid device throttle %s, the rate must be a number of operations per second", spec)
+			}
+			rate = n
+		}
+		devices = append(devices, ThrottleDevice{Path: path, Rate: rate})
+	}
+	return devices, nil
+}
+
+// parsePolicyRules parses the rules of a network policy given in the form
+// [cidr][:port[-port][/proto]], the protocol being tcp by default
+func parsePolicyRules(specs []string) ([]iptables.PolicyRule, error) {
+	var rules []iptables.PolicyRule
+	for _, spec := range specs {
+		var rule iptables.PolicyRule
+		cidr, ports := spec, ""
+		if i := strings.Index(spec, ":"); i != -1 {
+			cidr, ports = spec[:i], spec[i+1:]
+			if ports == "" {
+				return nil, fmt.Errorf("Invalid rule %s, expected [cidr][:port[-port][/proto]]", spec)
+			}
+		}
+		if cidr != "" {
+			if !strings.Contains(cidr, "/") {
+				cidr += "/32"
+			}
+			ip, network, err := net.ParseCIDR(cidr)
+			if err != nil || ip.To4() == nil {
+				return nil, fmt.Errorf("Invalid rule %s, the peers must be an IPv4 address or subnet", spec)
+			}
+			rule.CIDR = network.String()
+		}
+		if ports != "" {
+			proto, port := nat.SplitProtoPort(ports)
+			if proto != "tcp" && proto != "udp" {
+				return nil, fmt.Errorf("Invalid rule %s, the protocol must be tcp or udp", spec)
+			}
+			start, end, err := parsers.ParsePortRange(port)
+			if err != nil || start == 0 {
+				return nil, fmt.Errorf("Invalid rule %s, invalid ports %s", spec, port)
+			}
+			rule.Proto = proto
+			rule.Ports = strconv.FormatUint(start, 10)
+			if end != start {
+				rule.Ports += "-" + strconv.FormatUint(end, 10)
+			}
+		}
+		if rule == (iptables.PolicyRule{}) {
+			return nil, fmt.Errorf("Invalid empty rule")
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
 func ParseDevice(device string) (DeviceMapping, error) {
 	src := ""
 	dst := ""
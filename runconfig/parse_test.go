@@ -1,8 +1,13 @@
 package runconfig
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/iptables"
 	"github.com/docker/docker/pkg/parsers"
 )
 
@@ -48,3 +53,264 @@ func TestNetHostname(t *testing.T) {
 		t.Fatalf("Expected error ErrConflictNetworkHostname, got: %s", err)
 	}
 }
+
+func TestParseUserDefinedNetwork(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"-h=name", "--net=my_net-1", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hostConfig.NetworkMode != "my_net-1" || !hostConfig.NetworkMode.IsUserDefined() {
+		t.Fatalf("Expected the user-defined network my_net-1, got %s", hostConfig.NetworkMode)
+	}
+
+	for _, mode := range []NetworkMode{"", "bridge", "none", "host", "container:other"} {
+		if mode.IsUserDefined() {
+			t.Fatalf("%q should not be a user-defined network", mode)
+		}
+	}
+
+	if _, _, _, err := Parse([]string{"--net=-invalid", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid network name")
+	}
+}
+
+func TestParseMacvlan(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--net=macvlan:eth1", "--ip=192.168.1.20", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	mode := hostConfig.NetworkMode
+	if !mode.IsMacvlan() || mode.IsUserDefined() || mode.MacvlanParent() != "eth1" {
+		t.Fatalf("Expected a macvlan interface on eth1, got %s", mode)
+	}
+	if parent := NetworkMode("bridge").MacvlanParent(); parent != "" {
+		t.Fatalf("Expected no parent interface for the bridge mode, got %s", parent)
+	}
+
+	for _, mode := range []string{"macvlan", "macvlan:", "macvlan:eth1:eth2"} {
+		if _, _, _, err := Parse([]string{"--net=" + mode, "img", "cmd"}, nil); err == nil {
+			t.Fatalf("Expected an error for the network mode %s", mode)
+		}
+	}
+	for _, publish := range []string{"-p=80:80", "-P"} {
+		if _, _, _, err := Parse([]string{"--net=macvlan:eth1", publish, "img", "cmd"}, nil); err != ErrConflictMacvlanAndPublish {
+			t.Fatalf("Expected %s for %s, got %v", ErrConflictMacvlanAndPublish, publish, err)
+		}
+	}
+}
+
+func TestParseIPAddress(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--ip=172.17.0.10", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hostConfig.IPAddress != "172.17.0.10" {
+		t.Fatalf("Expected the requested ip 172.17.0.10, got %s", hostConfig.IPAddress)
+	}
+
+	for _, ip := range []string{"172.17.0", "2001:db8::10"} {
+		if _, _, _, err := Parse([]string{"--ip=" + ip, "img", "cmd"}, nil); err == nil {
+			t.Fatalf("Expected an error for the invalid ip %s", ip)
+		}
+	}
+	for _, mode := range []string{"none", "host", "container:other"} {
+		if _, _, _, err := Parse([]string{"--ip=172.17.0.10", "--net=" + mode, "img", "cmd"}, nil); err != ErrConflictNetworkModeAndIP {
+			t.Fatalf("Expected %s for the network mode %s, got %v", ErrConflictNetworkModeAndIP, mode, err)
+		}
+	}
+}
+
+func TestParsePortRanges(t *testing.T) {
+	config, hostConfig, _, err := Parse([]string{"--expose=3000-3002/udp", "-p", "8000-8001:9000-9001", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, port := range []nat.Port{"3000/udp", "3001/udp", "3002/udp", "9000/tcp", "9001/tcp"} {
+		if _, exists := config.ExposedPorts[port]; !exists {
+			t.Fatalf("Expected %s to be exposed, got %v", port, config.ExposedPorts)
+		}
+	}
+	if bindings := hostConfig.PortBindings["9001/tcp"]; len(bindings) != 1 || bindings[0].HostPort != "8001" {
+		t.Fatalf("Expected 9001/tcp to be published on 8001, got %v", bindings)
+	}
+
+	if _, _, _, err := Parse([]string{"--expose=3002-3000", "img", "cmd"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid range of exposed ports")
+	}
+}
+
+func TestParseLogConfig(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--log-driver=json-file", "--log-opt", "max-size=10m", "--log-opt", "max-file=3", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hostConfig.LogConfig.Type != "json-file" {
+		t.Fatalf("Expected log driver json-file, got %s", hostConfig.LogConfig.Type)
+	}
+	if len(hostConfig.LogConfig.Config) != 2 || hostConfig.LogConfig.Config["max-size"] != "10m" || hostConfig.LogConfig.Config["max-file"] != "3" {
+		t.Fatalf("Unexpected log options: %v", hostConfig.LogConfig.Config)
+	}
+
+	if _, _, _, err := Parse([]string{"--log-opt", "max-size", "img", "cmd"}, nil); err == nil {
+		t.Fatalf("Expected an error for a log option without a value")
+	}
+}
+
+func TestParseVolumeDriver(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--volume-driver=flocker", "-v", "data:/data", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hostConfig.VolumeDriver != "flocker" {
+		t.Fatalf("Expected volume driver flocker, got %s", hostConfig.VolumeDriver)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	f, err := ioutil.TempFile("", "docker-labels-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("# owners\ncom.example.owner=infra\ncom.example.tier=db\n\ncom.example.flag\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	config, _, _, err := Parse([]string{"--label-file", f.Name(), "-l", "com.example.owner=web", "--label", "com.example.empty=", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := map[string]string{
+		"com.example.owner": "web",
+		"com.example.tier":  "db",
+		"com.example.flag":  "",
+		"com.example.empty": "",
+	}
+	if len(config.Labels) != len(expected) {
+		t.Fatalf("Expected labels %v, got %v", expected, config.Labels)
+	}
+	for k, v := range expected {
+		if value, exists := config.Labels[k]; !exists || value != v {
+			t.Fatalf("Expected labels %v, got %v", expected, config.Labels)
+		}
+	}
+
+	if _, _, _, err := Parse([]string{"--label", "=value", "img", "cmd"}, nil); err == nil {
+		t.Fatalf("Expected an error for a label without a key")
+	}
+}
+
+func TestParseHealthcheck(t *testing.T) {
+	config, _, _, err := Parse([]string{"img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if config.Healthcheck != nil {
+		t.Fatalf("Expected no healthcheck, got %v", config.Healthcheck)
+	}
+
+	config, _, _, err = Parse([]string{"--health-cmd", "curl -f http://localhost/", "--health-interval", "5s", "--health-timeout", "2s", "--health-retries", "4", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	hc := config.Healthcheck
+	if hc == nil || len(hc.Test) != 2 || hc.Test[0] != "CMD-SHELL" || hc.Test[1] != "curl -f http://localhost/" {
+		t.Fatalf("Unexpected healthcheck command: %v", hc)
+	}
+	if hc.Interval != 5*time.Second || hc.Timeout != 2*time.Second || hc.Retries != 4 {
+		t.Fatalf("Unexpected healthcheck options: %v", hc)
+	}
+
+	config, _, _, err = Parse([]string{"--health-cmd", "none", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hc := config.Healthcheck; hc == nil || len(hc.Test) != 1 || hc.Test[0] != "NONE" {
+		t.Fatalf("Expected the healthcheck to be disabled, got %v", hc)
+	}
+
+	for _, args := range [][]string{
+		{"--health-retries", "-1", "img", "cmd"},
+		{"--health-interval", "-1s", "img", "cmd"},
+		{"--health-cmd", "none", "--health-retries", "2", "img", "cmd"},
+	} {
+		if _, _, _, err := Parse(args, nil); err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--memory-reservation", "64m", "--oom-kill-disable", "--cpu-period", "50000", "--cpu-quota", "25000", "--blkio-weight", "300", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hostConfig.MemoryReservation != 67108864 || !hostConfig.OomKillDisable {
+		t.Fatalf("Unexpected memory options: %d %v", hostConfig.MemoryReservation, hostConfig.OomKillDisable)
+	}
+	if hostConfig.CpuPeriod != 50000 || hostConfig.CpuQuota != 25000 {
+		t.Fatalf("Unexpected cpu options: %d %d", hostConfig.CpuPeriod, hostConfig.CpuQuota)
+	}
+	if hostConfig.BlkioWeight != 300 {
+		t.Fatalf("Expected a blkio weight of 300, got %d", hostConfig.BlkioWeight)
+	}
+}
+
+func TestParseThrottleDevices(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--device-read-bps", "/dev/sda:1mb", "--device-write-iops", "/dev/sdb:100", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if d := hostConfig.BlkioDeviceReadBps; len(d) != 1 || d[0].Path != "/dev/sda" || d[0].Rate != 1048576 {
+		t.Fatalf("Unexpected read bps throttle: %v", d)
+	}
+	if d := hostConfig.BlkioDeviceWriteIOps; len(d) != 1 || d[0].Path != "/dev/sdb" || d[0].Rate != 100 {
+		t.Fatalf("Unexpected write iops throttle: %v", d)
+	}
+
+	for _, args := range [][]string{
+		{"--device-read-bps", "/dev/sda", "img", "cmd"},
+		{"--device-read-bps", "sda:1mb", "img", "cmd"},
+		{"--device-write-bps", "/dev/sda:fast", "img", "cmd"},
+		{"--device-read-iops", "/dev/sda:1k", "img", "cmd"},
+	} {
+		if _, _, _, err := Parse(args, nil); err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestParseNetworkPolicy(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--allow-egress", "10.0.0.0/8:5432", "--allow-egress", ":53/udp", "--allow-ingress", "192.168.1.10", "img", "cmd"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	egress := hostConfig.NetworkPolicy.Egress
+	if len(egress) != 2 {
+		t.Fatalf("Expected 2 egress rules, got %v", egress)
+	}
+	if egress[0] != (iptables.PolicyRule{CIDR: "10.0.0.0/8", Proto: "tcp", Ports: "5432"}) {
+		t.Fatalf("Unexpected egress rule: %v", egress[0])
+	}
+	if egress[1] != (iptables.PolicyRule{Proto: "udp", Ports: "53"}) {
+		t.Fatalf("Unexpected egress rule: %v", egress[1])
+	}
+	if ingress := hostConfig.NetworkPolicy.Ingress; len(ingress) != 1 || ingress[0] != (iptables.PolicyRule{CIDR: "192.168.1.10/32"}) {
+		t.Fatalf("Unexpected ingress rules: %v", ingress)
+	}
+
+	for _, args := range [][]string{
+		{"--allow-egress", "", "img", "cmd"},
+		{"--allow-egress", "10.0.0.0/8:", "img", "cmd"},
+		{"--allow-egress", "10.0.0.0/33", "img", "cmd"},
+		{"--allow-egress", "10.0.0.0/8:80/icmp", "img", "cmd"},
+		{"--allow-egress", "10.0.0.0/8:90-80", "img", "cmd"},
+		{"--allow-ingress", "::1", "img", "cmd"},
+		{"--allow-ingress", "10.0.0.1", "--net=host", "img", "cmd"},
+	} {
+		if _, _, _, err := Parse(args, nil); err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package trust
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// The signatures of a JSON document are JSON Web Signatures in the JSON
+// serialization, added to the document as its "signatures" key. The payload
+// they sign is the document without this key, which the protected header of
+// each signature describes: the length of the document before the key, and
+// the end of the document after it.
+//
+//    {
+//       "name": "foo",
+//       "signatures": [
+//          {
+//             "header": {"jwk": {...}, "alg": "ES256"},
+//             "signature": "...",
+//             "protected": "..."
+//          }
+//       ]
+//    }
+
+// ErrInvalidSignature is returned for a signature which does not match its
+// key or its payload
+var ErrInvalidSignature = errors.New("invalid signature")
+
+type jsHeader struct {
+	JWK       *jsonWebKey `json:"jwk"`
+	Algorithm string      `json:"alg"`
+}
+
+type jsSignature struct {
+	Header    jsHeader `json:"header"`
+	Signature string   `json:"signature"`
+	Protected string   `json:"protected"`
+}
+
+type protectedHeader struct {
+	FormatLength int    `json:"formatLength"`
+	FormatTail   string `json:"formatTail"`
+	Time         string `json:"time"`
+}
+
+type signedDocument struct {
+	Signatures []*jsSignature `json:"signatures"`
+}
+
+// Sign signs content, a JSON object, with key, and returns it with the
+// signature
+func Sign(content []byte, key *ecdsa.PrivateKey) ([]byte, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, fmt.Errorf("Only JSON objects can be signed: %s", err)
+	}
+	if _, exists := object["signatures"]; exists {
+		return nil, fmt.Errorf("The content is already signed")
+	}
+	if len(object) == 0 {
+		return nil, fmt.Errorf("Empty JSON objects can not be signed")
+	}
+
+	// The signatures follow the last key of the object
+	end := bytes.LastIndex(content, []byte("}"))
+	formatLength := len(bytes.TrimRight(content[:end], " \t\r\n"))
+	tail := content[formatLength:]
+
+	protected, err := json.Marshal(&protectedHeader{
+		FormatLength: formatLength,
+		FormatTail:   joseBase64Encode(tail),
+		Time:         time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+	signature := &jsSignature{
+		Header:    jsHeader{JWK: newJSONWebKey(&key.PublicKey), Algorithm: "ES256"},
+		Protected: joseBase64Encode(protected),
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, key, signingHash(signature.Protected, content))
+	if err != nil {
+		return nil, err
+	}
+	signature.Signature = joseBase64Encode(append(coordinate(r), coordinate(s)...))
+
+	signatures, err := json.MarshalIndent([]*jsSignature{signature}, "   ", "   ")
+	if err != nil {
+		return nil, err
+	}
+	signed := bytes.NewBuffer(nil)
+	signed.Write(content[:formatLength])
+	signed.WriteString(",\n   \"signatures\": ")
+	signed.Write(signatures)
+	signed.Write(tail)
+	return signed.Bytes(), nil
+}
+
+// signingHash returns the hash of the signing input of a signature of
+// protected header protected over payload
+func signingHash(protected string, payload []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(protected + "." + joseBase64Encode(payload)))
+	return h.Sum(nil)
+}
+
+// parse returns the signatures of signed, and the payload they sign. The
+// payload of a document without signature is the document.
+func parse(signed []byte) ([]*jsSignature, []byte, error) {
+	document := &signedDocument{}
+	if err := json.Unmarshal(signed, document); err != nil {
+		return nil, nil, err
+	}
+	if len(document.Signatures) == 0 {
+		return nil, signed, nil
+	}
+
+	var payload []byte
+	for _, signature := range document.Signatures {
+		data, err := joseBase64Decode(signature.Protected)
+		if err != nil {
+			return nil, nil, ErrInvalidSignature
+		}
+		protected := &protectedHeader{}
+		if err := json.Unmarshal(data, protected); err != nil {
+			return nil, nil, ErrInvalidSignature
+		}
+		tail, err := joseBase64Decode(protected.FormatTail)
+		if err != nil || protected.FormatLength < 0 || protected.FormatLength > len(signed) {
+			return nil, nil, ErrInvalidSignature
+		}
+		p := append(append([]byte{}, signed[:protected.FormatLength]...), tail...)
+		if payload != nil && !bytes.Equal(payload, p) {
+			return nil, nil, fmt.Errorf("The signatures sign different payloads")
+		}
+		payload = p
+	}
+	return document.Signatures, payload, nil
+}
+
+// Payload returns the content signed by the signatures of signed: the
+// document without its signatures
+func Payload(signed []byte) ([]byte, error) {
+	_, payload, err := parse(signed)
+	return payload, err
+}
+
+// Verify checks the signatures of signed, and returns the keys which signed
+// it, none for a document without signature. It returns ErrInvalidSignature
+// if a signature does not match its key or the payload.
+func Verify(signed []byte) ([]*ecdsa.PublicKey, error) {
+	signatures, payload, err := parse(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*ecdsa.PublicKey
+	for _, signature := range signatures {
+		if signature.Header.Algorithm != "ES256" {
+			return nil, fmt.Errorf("Unsupported signature algorithm %q", signature.Header.Algorithm)
+		}
+		if signature.Header.JWK == nil {
+			return nil, fmt.Errorf("The signature has no key")
+		}
+		pub, err := signature.Header.JWK.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		rs, err := joseBase64Decode(signature.Signature)
+		if err != nil || len(rs) != 64 {
+			return nil, ErrInvalidSignature
+		}
+		r, s := new(big.Int).SetBytes(rs[:32]), new(big.Int).SetBytes(rs[32:])
+		if !ecdsa.Verify(pub, signingHash(signature.Protected, payload), r, s) {
+			return nil, ErrInvalidSignature
+		}
+		keys = append(keys, pub)
+	}
+	return keys, nil
+}
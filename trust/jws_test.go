@@ -0,0 +1,126 @@
+package trust
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSignVerify(t *testing.T) {
+	key := generateKey(t)
+	content := []byte("{\n   \"name\": \"foo\",\n   \"tag\": \"latest\"\n}")
+	signed, err := Sign(content, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(signed, &document); err != nil {
+		t.Fatalf("The signed content is not valid JSON: %s\n%s", err, signed)
+	}
+	if document["name"] != "foo" || document["signatures"] == nil {
+		t.Fatalf("Wrong signed content %s", signed)
+	}
+
+	payload, err := Payload(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload, content) {
+		t.Fatalf("Expected the payload %s, got %s", content, payload)
+	}
+
+	keys, err := Verify(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || KeyID(keys[0]) != KeyID(&key.PublicKey) {
+		t.Fatalf("Expected the content to be signed by %s, got %v", KeyID(&key.PublicKey), keys)
+	}
+
+	tampered := bytes.Replace(signed, []byte(`"foo"`), []byte(`"bar"`), 1)
+	if _, err := Verify(tampered); err != ErrInvalidSignature {
+		t.Fatalf("Expected ErrInvalidSignature for tampered content, got %v", err)
+	}
+
+	if _, err := Sign(signed, key); err == nil {
+		t.Fatal("Expected an error signing signed content")
+	}
+	for _, invalid := range []string{`[]`, `{}`, `foo`} {
+		if _, err := Sign([]byte(invalid), key); err == nil {
+			t.Fatalf("Expected an error signing %s", invalid)
+		}
+	}
+}
+
+func TestVerifyUnsigned(t *testing.T) {
+	content := []byte(`{"name":"foo"}`)
+	keys, err := Verify(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("Expected no key for unsigned content, got %v", keys)
+	}
+	payload, err := Payload(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload, content) {
+		t.Fatalf("Expected the payload of unsigned content to be the content, got %s", payload)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	key := generateKey(t)
+	id := KeyID(&key.PublicKey)
+	if groups := strings.Split(id, ":"); len(groups) != 12 || len(groups[0]) != 4 {
+		t.Fatalf("Wrong key id %s", id)
+	}
+
+	data, err := MarshalPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := UnmarshalPrivateKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.D.Cmp(key.D) != 0 || KeyID(&parsed.PublicKey) != id {
+		t.Fatal("The private key changed through the JWK format")
+	}
+
+	if data, err = MarshalPublicKey(&key.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(`"d"`)) {
+		t.Fatalf("The public key has a private part: %s", data)
+	}
+	pub, err := UnmarshalPublicKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyID(pub) != id {
+		t.Fatalf("Expected the key %s, got %s", id, KeyID(pub))
+	}
+	if _, err := UnmarshalPrivateKey(data); err == nil {
+		t.Fatal("Expected an error parsing a public key as a private key")
+	}
+
+	invalid := bytes.Replace(data, []byte(`"kid": "`), []byte(`"kid": "X`), 1)
+	if _, err := UnmarshalPublicKey(invalid); err == nil {
+		t.Fatal("Expected an error for a key with a wrong key id")
+	}
+}
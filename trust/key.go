@@ -0,0 +1,157 @@
+package trust
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// A jsonWebKey is an ECDSA key on the P-256 curve in the JSON Web Key
+// format, with its private part D for the private keys
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	KeyID   string `json:"kid"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+	D       string `json:"d,omitempty"`
+}
+
+// KeyID returns the id of pub: the base32 of the 240 first bits of the
+// SHA-256 of its DER encoding, in groups of 4 characters separated by ':'.
+func KeyID(pub *ecdsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	b32 := base32.StdEncoding.EncodeToString(sum[:30])
+	var groups []string
+	for i := 0; i < len(b32); i += 4 {
+		groups = append(groups, b32[i:i+4])
+	}
+	return strings.Join(groups, ":")
+}
+
+func newJSONWebKey(pub *ecdsa.PublicKey) *jsonWebKey {
+	return &jsonWebKey{
+		KeyType: "EC",
+		Curve:   "P-256",
+		KeyID:   KeyID(pub),
+		X:       joseBase64Encode(coordinate(pub.X)),
+		Y:       joseBase64Encode(coordinate(pub.Y)),
+	}
+}
+
+// coordinate returns the 32 bytes of a coordinate, or of a private key, on the
+// P-256 curve
+func coordinate(n *big.Int) []byte {
+	b := n.Bytes()
+	return append(make([]byte, 32-len(b)), b...)
+}
+
+// publicKey returns the public key of k
+func (k *jsonWebKey) publicKey() (*ecdsa.PublicKey, error) {
+	if k.KeyType != "EC" || k.Curve != "P-256" {
+		return nil, fmt.Errorf("Unsupported key type %s %s, only EC P-256 keys are supported", k.KeyType, k.Curve)
+	}
+	x, err := joseBase64Decode(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid x coordinate of the key: %s", err)
+	}
+	y, err := joseBase64Decode(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid y coordinate of the key: %s", err)
+	}
+	pub := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("Invalid key: the point is not on the P-256 curve")
+	}
+	if k.KeyID != "" && k.KeyID != KeyID(pub) {
+		return nil, fmt.Errorf("Invalid key: the key id %s does not match the key %s", k.KeyID, KeyID(pub))
+	}
+	return pub, nil
+}
+
+// privateKey returns the private key of k
+func (k *jsonWebKey) privateKey() (*ecdsa.PrivateKey, error) {
+	pub, err := k.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	d, err := joseBase64Decode(k.D)
+	if err != nil || len(d) == 0 {
+		return nil, fmt.Errorf("Invalid private key %s", k.KeyID)
+	}
+	return &ecdsa.PrivateKey{PublicKey: *pub, D: new(big.Int).SetBytes(d)}, nil
+}
+
+// MarshalPrivateKey returns key in the JSON Web Key format
+func MarshalPrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	k := newJSONWebKey(&key.PublicKey)
+	k.D = joseBase64Encode(coordinate(key.D))
+	return json.MarshalIndent(k, "", "   ")
+}
+
+// MarshalPublicKey returns pub in the JSON Web Key format
+func MarshalPublicKey(pub *ecdsa.PublicKey) ([]byte, error) {
+	return json.MarshalIndent(newJSONWebKey(pub), "", "   ")
+}
+
+// UnmarshalPrivateKey parses a private key in the JSON Web Key format
+func UnmarshalPrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	k := &jsonWebKey{}
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	return k.privateKey()
+}
+
+// UnmarshalPublicKey parses a public key in the JSON Web Key format, or in
+// a PEM "PUBLIC KEY" block
+func UnmarshalPublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("Unsupported PEM block %s, expected a PUBLIC KEY", block.Type)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("Unsupported public key, only EC P-256 keys are supported")
+		}
+		return pub, nil
+	}
+	k := &jsonWebKey{}
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	return k.publicKey()
+}
+
+// joseBase64Encode returns the base64url encoding of b without padding
+func joseBase64Encode(b []byte) string {
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(b), "=")
+}
+
+// joseBase64Decode decodes the base64url encoding s, with or without padding
+func joseBase64Decode(s string) ([]byte, error) {
+	if n := len(s) % 4; n != 0 {
+		s += strings.Repeat("=", 4-n)
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
@@ -0,0 +1,163 @@
+package trust
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/pkg/log"
+)
+
+// The policies of a Store for the content without a signature by a trusted
+// key
+const (
+	// PolicyOff does not check the signatures
+	PolicyOff = "off"
+	// PolicyWarn accepts untrusted content with a warning
+	PolicyWarn = "warn"
+	// PolicyRefuse refuses untrusted content
+	PolicyRefuse = "refuse"
+)
+
+// An UntrustedError is returned for content which no trusted key signed,
+// with the ids of the keys which signed it
+type UntrustedError struct {
+	KeyIDs []string
+}
+
+func (e *UntrustedError) Error() string {
+	if len(e.KeyIDs) == 0 {
+		return "the content is not signed"
+	}
+	return fmt.Sprintf("the content is signed by untrusted keys %s", strings.Join(e.KeyIDs, ", "))
+}
+
+// A Store holds the key of the daemon, which signs the content it publishes,
+// and checks the signatures of the content it receives against the trusted
+// public keys. Its directory has the layout:
+//
+//	key.json          the private key of the daemon
+//	public-key.json   its public key, to be trusted by other daemons
+//	trusted-keys/     the trusted public keys, in JWK (.json) or PEM (.pem)
+//
+// The public key of the daemon is always trusted.
+type Store struct {
+	root   string
+	key    *ecdsa.PrivateKey
+	Policy string
+}
+
+// NewStore returns the Store of the directory root, generating the key of the
+// daemon the first time
+func NewStore(root, policy string) (*Store, error) {
+	switch policy {
+	case PolicyOff, PolicyWarn, PolicyRefuse:
+	default:
+		return nil, fmt.Errorf("Invalid trust policy %q, expected %s, %s or %s", policy, PolicyOff, PolicyWarn, PolicyRefuse)
+	}
+	if err := os.MkdirAll(path.Join(root, "trusted-keys"), 0700); err != nil {
+		return nil, err
+	}
+	store := &Store{root: root, Policy: policy}
+	if err := store.loadKey(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// loadKey loads the key of the daemon, or generates it if there is none, and
+// writes its public key
+func (s *Store) loadKey() error {
+	keyPath := path.Join(s.root, "key.json")
+	data, err := ioutil.ReadFile(keyPath)
+	if err == nil {
+		if s.key, err = UnmarshalPrivateKey(data); err != nil {
+			return fmt.Errorf("Invalid key %s: %s", keyPath, err)
+		}
+	} else if os.IsNotExist(err) {
+		log.Debugf("Generating the key of the daemon")
+		if s.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			return err
+		}
+		if data, err = MarshalPrivateKey(s.key); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(keyPath, data, 0600); err != nil {
+			return err
+		}
+	} else {
+		return err
+	}
+
+	data, err = MarshalPublicKey(&s.key.PublicKey)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(s.root, "public-key.json"), data, 0644)
+}
+
+// KeyID returns the id of the key of the daemon
+func (s *Store) KeyID() string {
+	return KeyID(&s.key.PublicKey)
+}
+
+// Sign signs content, a JSON object, with the key of the daemon
+func (s *Store) Sign(content []byte) ([]byte, error) {
+	return Sign(content, s.key)
+}
+
+// trustedKeys returns the ids of the trusted keys. The directory of the
+// trusted keys is read each time, so that the keys added to it are trusted
+// without restarting the daemon.
+func (s *Store) trustedKeys() (map[string]bool, error) {
+	trusted := map[string]bool{s.KeyID(): true}
+	files, err := ioutil.ReadDir(path.Join(s.root, "trusted-keys"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if ext := filepath.Ext(file.Name()); file.IsDir() || (ext != ".json" && ext != ".pem") {
+			continue
+		}
+		keyPath := path.Join(s.root, "trusted-keys", file.Name())
+		data, err := ioutil.ReadFile(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := UnmarshalPublicKey(data)
+		if err != nil {
+			log.Errorf("Ignoring the trusted key %s: %s", keyPath, err)
+			continue
+		}
+		trusted[KeyID(pub)] = true
+	}
+	return trusted, nil
+}
+
+// Verify checks the signatures of signed, and returns the id of a trusted key
+// which signed it. It returns an UntrustedError if no trusted key signed it.
+func (s *Store) Verify(signed []byte) (string, error) {
+	keys, err := Verify(signed)
+	if err != nil {
+		return "", err
+	}
+	trusted, err := s.trustedKeys()
+	if err != nil {
+		return "", err
+	}
+	untrusted := &UntrustedError{}
+	for _, pub := range keys {
+		id := KeyID(pub)
+		if trusted[id] {
+			return id, nil
+		}
+		untrusted.KeyIDs = append(untrusted.KeyIDs, id)
+	}
+	return "", untrusted
+}
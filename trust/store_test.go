@@ -0,0 +1,88 @@
+package trust
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-trust-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if _, err := NewStore(root, "unknown"); err == nil {
+		t.Fatal("Expected an error for an unknown policy")
+	}
+	store, err := NewStore(root, PolicyRefuse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key is generated once
+	reloaded, err := NewStore(root, PolicyRefuse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.KeyID() != store.KeyID() {
+		t.Fatalf("Expected the key %s to be reloaded, got %s", store.KeyID(), reloaded.KeyID())
+	}
+	publicKey, err := ioutil.ReadFile(path.Join(root, "public-key.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte(`{"name":"foo"}`)
+	signed, err := store.Sign(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := store.Verify(signed); err != nil || id != store.KeyID() {
+		t.Fatalf("Expected the content signed by the daemon to be trusted, got %q, %v", id, err)
+	}
+	if _, err := store.Verify(content); err == nil || len(err.(*UntrustedError).KeyIDs) != 0 {
+		t.Fatalf("Expected an UntrustedError for unsigned content, got %v", err)
+	}
+
+	other, err := ioutil.TempDir("", "docker-trust-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(other)
+	otherStore, err := NewStore(other, PolicyRefuse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := otherStore.Verify(signed); err == nil || err.(*UntrustedError).KeyIDs[0] != store.KeyID() {
+		t.Fatalf("Expected an UntrustedError for the key %s, got %v", store.KeyID(), err)
+	}
+
+	// The keys of the directory of the trusted keys are trusted
+	if err := ioutil.WriteFile(path.Join(other, "trusted-keys", "store.json"), publicKey, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := otherStore.Verify(signed); err != nil || id != store.KeyID() {
+		t.Fatalf("Expected the trusted key %s, got %q, %v", store.KeyID(), id, err)
+	}
+
+	// Keys are trusted in the PEM format too
+	key := generateKey(t)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	if err := ioutil.WriteFile(path.Join(other, "trusted-keys", "key.pem"), pemKey, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if signed, err = Sign(content, key); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := otherStore.Verify(signed); err != nil || id != KeyID(&key.PublicKey) {
+		t.Fatalf("Expected the trusted PEM key %s, got %q, %v", KeyID(&key.PublicKey), id, err)
+	}
+}
@@ -281,6 +281,21 @@ func ValidateID(id string) error {
 	return nil
 }
 
+// DigestReference returns true if ref is the digest of an image manifest,
+// such as "sha256:...", rather than a tag, which cannot contain a colon
+func DigestReference(ref string) bool {
+	return strings.Contains(ref, ":")
+}
+
+// ImageReference returns the reference to the image of ref, a tag or a
+// digest, in the repository repo: "repo:tag" or "repo@digest"
+func ImageReference(repo, ref string) string {
+	if DigestReference(ref) {
+		return repo + "@" + ref
+	}
+	return repo + ":" + ref
+}
+
 // Code c/c from io.Copy() modified to handle escape sequence
 func CopyEscapable(dst io.Writer, src io.ReadCloser) (written int64, err error) {
 	buf := make([]byte, 32*1024)
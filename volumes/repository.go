@@ -0,0 +1,359 @@
+package volumes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/daemon/volumedriver"
+	"github.com/docker/docker/pkg/log"
+	"github.com/docker/docker/utils"
+)
+
+var (
+	ErrVolumeInUse = errors.New("volume is in use")
+
+	validVolumeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+)
+
+// ValidateName returns an error if name cannot be used as a volume name
+func ValidateName(name string) error {
+	if !validVolumeName.MatchString(name) {
+		return fmt.Errorf("Invalid volume name %q: only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	return nil
+}
+
+// Repository keeps track of the volumes and of the volume drivers storing
+// them
+type Repository struct {
+	configPath  string
+	driversRoot string
+	drivers     map[string]volumedriver.Driver
+	volumes     map[string]*Volume // by id
+	names       map[string]*Volume
+	lock        sync.Mutex
+}
+
+// NewRepository returns a repository keeping the volumes configuration in
+// configPath. The built-in volume drivers store their data under driversRoot.
+func NewRepository(configPath, driversRoot string) (*Repository, error) {
+	abspath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abspath, 0700); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	repo := &Repository{
+		configPath:  abspath,
+		driversRoot: driversRoot,
+		drivers:     make(map[string]volumedriver.Driver),
+		volumes:     make(map[string]*Volume),
+		names:       make(map[string]*Volume),
+	}
+	return repo, repo.restore()
+}
+
+func (r *Repository) restore() error {
+	dir, err := ioutil.ReadDir(r.configPath)
+	if err != nil {
+		return err
+	}
+	for _, fi := range dir {
+		id := fi.Name()
+		// _tmp is a leftover of the graph previously used to store volumes
+		if !fi.IsDir() || id == "_tmp" {
+			continue
+		}
+		vol := r.newVolume(id, "", "")
+		if err := vol.FromDisk(); err != nil {
+			if !os.IsNotExist(err) {
+				log.Errorf("Error loading volume %s: %s", id, err)
+				continue
+			}
+			// volumes created before the repository existed have no
+			// configuration, keep them as anonymous volumes
+			vol.Created = fi.ModTime()
+			if err := vol.ToDisk(); err != nil {
+				log.Errorf("Error migrating volume %s: %s", id, err)
+				continue
+			}
+		}
+		// volumes created before drivers existed are local ones
+		if vol.Driver == "" {
+			vol.Driver = volumedriver.DefaultDriver
+		}
+		pth, err := r.getPath(vol)
+		if err != nil {
+			// keep the stored path if the plugin is not running yet, it
+			// is looked up again when the volume is mounted
+			if vol.Driver == volumedriver.DefaultDriver || vol.Path == "" {
+				log.Errorf("Error getting path of volume %s: %s", id, err)
+				continue
+			}
+			log.Errorf("Error getting path of volume %s from driver %s: %s", id, vol.Driver, err)
+		} else {
+			vol.Path = pth
+		}
+		r.add(vol)
+	}
+	return nil
+}
+
+func (r *Repository) newVolume(id, name, driver string) *Volume {
+	return &Volume{
+		ID:         id,
+		Name:       name,
+		Driver:     driver,
+		containers: make(map[string]struct{}),
+		configPath: filepath.Join(r.configPath, id),
+	}
+}
+
+// getDriver returns the driver named name, loading it on first use. It must
+// be called with r.lock held, or during restore.
+func (r *Repository) getDriver(name string) (volumedriver.Driver, error) {
+	if name == "" {
+		name = volumedriver.DefaultDriver
+	}
+	if driver, exists := r.drivers[name]; exists {
+		return driver, nil
+	}
+	driver, err := volumedriver.GetDriver(name, r.driversRoot)
+	if err != nil {
+		if err == volumedriver.ErrNotSupported {
+			return nil, fmt.Errorf("Bad parameter: volume driver %s not found", name)
+		}
+		return nil, err
+	}
+	r.drivers[name] = driver
+	return driver, nil
+}
+
+func (r *Repository) getPath(vol *Volume) (string, error) {
+	driver, err := r.getDriver(vol.Driver)
+	if err != nil {
+		return "", err
+	}
+	pth, err := driver.Path(vol.ID)
+	if err != nil {
+		return "", fmt.Errorf("Driver %s failed to get path of volume %s: %s", vol.Driver, vol.ID, err)
+	}
+	// containers reference volumes by their evaluated path, which only
+	// exists once plugin volumes are mounted
+	if evaluated, err := filepath.EvalSymlinks(pth); err == nil {
+		return evaluated, nil
+	}
+	return pth, nil
+}
+
+// add must be called with r.lock held, or during restore
+func (r *Repository) add(vol *Volume) {
+	r.volumes[vol.ID] = vol
+	if !vol.IsAnonymous() {
+		r.names[vol.Name] = vol
+	}
+}
+
+// Create creates a new volume with the given driver, or the default one if
+// driver is empty. An empty name creates an anonymous volume.
+func (r *Repository) Create(name, driver string) (*Volume, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.create(name, driver)
+}
+
+func (r *Repository) create(name, driverName string) (*Volume, error) {
+	if name != "" {
+		if err := ValidateName(name); err != nil {
+			return nil, err
+		}
+		if _, exists := r.names[name]; exists {
+			return nil, fmt.Errorf("Conflict, a volume named %s already exists", name)
+		}
+	}
+
+	driver, err := r.getDriver(driverName)
+	if err != nil {
+		return nil, err
+	}
+	vol := r.newVolume(utils.GenerateRandomID(), name, driver.Name())
+	vol.Created = time.Now().UTC()
+	if err := driver.Create(vol.ID); err != nil {
+		return nil, err
+	}
+	pth, err := r.getPath(vol)
+	if err != nil {
+		driver.Remove(vol.ID)
+		return nil, err
+	}
+	vol.Path = pth
+	if err := vol.ToDisk(); err != nil {
+		driver.Remove(vol.ID)
+		return nil, err
+	}
+	r.add(vol)
+	return vol, nil
+}
+
+// FindOrCreate returns the volume named name, creating it with driver if
+// it does not exist yet
+func (r *Repository) FindOrCreate(name, driver string) (*Volume, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if vol, exists := r.names[name]; exists {
+		return vol, nil
+	}
+	return r.create(name, driver)
+}
+
+// Mount asks the driver of the volume to make it available on the host.
+// Volumes are mounted once, whatever the number of containers using them.
+func (r *Repository) Mount(vol *Volume) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if vol.mounts > 0 {
+		vol.mounts++
+		return nil
+	}
+	driver, err := r.getDriver(vol.Driver)
+	if err != nil {
+		return err
+	}
+	mountpoint, err := driver.Mount(vol.ID)
+	if err != nil {
+		return fmt.Errorf("Driver %s failed to mount volume %s: %s", vol.Driver, vol.ID, err)
+	}
+	if evaluated, err := filepath.EvalSymlinks(mountpoint); err == nil {
+		mountpoint = evaluated
+	}
+	// containers are configured with the path of the volume before it is
+	// mounted
+	if mountpoint != vol.Path {
+		driver.Unmount(vol.ID)
+		return fmt.Errorf("Driver %s mounted volume %s on %s instead of %s", vol.Driver, vol.ID, mountpoint, vol.Path)
+	}
+	vol.mounts++
+	return nil
+}
+
+// Unmount releases a mount of the volume, and asks its driver to unmount
+// it once it is not mounted by any container anymore
+func (r *Repository) Unmount(vol *Volume) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if vol.mounts == 0 {
+		return nil
+	}
+	vol.mounts--
+	if vol.mounts > 0 {
+		return nil
+	}
+	driver, err := r.getDriver(vol.Driver)
+	if err != nil {
+		return err
+	}
+	return driver.Unmount(vol.ID)
+}
+
+// Get returns the volume with the given name or id, or nil
+func (r *Repository) Get(nameOrID string) *Volume {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.get(nameOrID)
+}
+
+func (r *Repository) get(nameOrID string) *Volume {
+	if vol, exists := r.names[nameOrID]; exists {
+		return vol
+	}
+	return r.volumes[nameOrID]
+}
+
+// GetByPath returns the volume stored at the given path on the host, or nil
+// if the path does not belong to a volume, e.g. for a bind mount
+func (r *Repository) GetByPath(pth string) *Volume {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, vol := range r.volumes {
+		if vol.Path == pth {
+			return vol
+		}
+	}
+	return nil
+}
+
+// List returns all the volumes, sorted by creation date
+func (r *Repository) List() []*Volume {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	vols := make([]*Volume, 0, len(r.volumes))
+	for _, vol := range r.volumes {
+		vols = append(vols, vol)
+	}
+	sort.Sort(byCreated(vols))
+	return vols
+}
+
+// Delete removes an unused volume along with its data
+func (r *Repository) Delete(nameOrID string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	vol := r.get(nameOrID)
+	if vol == nil {
+		return fmt.Errorf("No such volume: %s", nameOrID)
+	}
+	return r.delete(vol)
+}
+
+func (r *Repository) delete(vol *Volume) error {
+	if vol.IsUsed() {
+		return ErrVolumeInUse
+	}
+	driver, err := r.getDriver(vol.Driver)
+	if err != nil {
+		return err
+	}
+	if err := driver.Remove(vol.ID); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(vol.configPath); err != nil {
+		return err
+	}
+	delete(r.volumes, vol.ID)
+	if !vol.IsAnonymous() {
+		delete(r.names, vol.Name)
+	}
+	return nil
+}
+
+// Prune removes all the volumes not used by any container and returns them
+func (r *Repository) Prune() ([]*Volume, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var pruned []*Volume
+	for _, vol := range r.volumes {
+		if vol.IsUsed() {
+			continue
+		}
+		if err := r.delete(vol); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, vol)
+	}
+	sort.Sort(byCreated(pruned))
+	return pruned, nil
+}
+
+type byCreated []*Volume
+
+func (v byCreated) Len() int           { return len(v) }
+func (v byCreated) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+func (v byCreated) Less(i, j int) bool { return v[i].Created.Before(v[j].Created) }
@@ -0,0 +1,197 @@
+package volumes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/volumedriver"
+	_ "github.com/docker/docker/daemon/volumedriver/local"
+)
+
+func newTestRepository(t *testing.T) (*Repository, string) {
+	root, err := ioutil.TempDir("", "docker-volumes-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := newRepositoryIn(root)
+	if err != nil {
+		os.RemoveAll(root)
+		t.Fatal(err)
+	}
+	return repo, root
+}
+
+func newRepositoryIn(root string) (*Repository, error) {
+	return NewRepository(filepath.Join(root, "volumes"), root)
+}
+
+func TestCreateNamedVolume(t *testing.T) {
+	repo, root := newTestRepository(t)
+	defer os.RemoveAll(root)
+
+	vol, err := repo.Create("data", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, err := os.Stat(vol.Path); err != nil || !st.IsDir() {
+		t.Fatalf("Expected volume path %s to be a directory: %v", vol.Path, err)
+	}
+	if repo.Get("data") != vol || repo.Get(vol.ID) != vol {
+		t.Fatal("Expected to find the volume by name and id")
+	}
+	if repo.GetByPath(vol.Path) != vol {
+		t.Fatal("Expected to find the volume by path")
+	}
+	if _, err := repo.Create("data", ""); err == nil {
+		t.Fatal("Expected an error when creating a volume with a duplicate name")
+	}
+	found, err := repo.FindOrCreate("data", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != vol {
+		t.Fatal("Expected FindOrCreate to return the existing volume")
+	}
+}
+
+func TestCreateInvalidName(t *testing.T) {
+	repo, root := newTestRepository(t)
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{"a", "-data", "da/ta", "/data", "da:ta"} {
+		if _, err := repo.Create(name, ""); err == nil {
+			t.Fatalf("Expected an error for volume name %q", name)
+		}
+	}
+}
+
+func TestDeleteUsedVolume(t *testing.T) {
+	repo, root := newTestRepository(t)
+	defer os.RemoveAll(root)
+
+	vol, err := repo.Create("data", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vol.AddContainer("c1")
+	vol.AddContainer("c2")
+	if err := repo.Delete("data"); err != ErrVolumeInUse {
+		t.Fatalf("Expected ErrVolumeInUse, got %v", err)
+	}
+
+	vol.RemoveContainer("c1")
+	vol.RemoveContainer("c2")
+	if err := repo.Delete("data"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.Get("data") != nil {
+		t.Fatal("Expected the volume to be removed")
+	}
+	if _, err := os.Stat(vol.Path); !os.IsNotExist(err) {
+		t.Fatalf("Expected the volume data to be removed: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	repo, root := newTestRepository(t)
+	defer os.RemoveAll(root)
+
+	used, err := repo.Create("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	used.AddContainer("c1")
+	unused, err := repo.Create("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := repo.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || pruned[0] != unused {
+		t.Fatalf("Expected only the unused volume to be pruned, got %v", pruned)
+	}
+	if vols := repo.List(); len(vols) != 1 || vols[0] != used {
+		t.Fatalf("Expected the used volume to remain, got %v", vols)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	repo, root := newTestRepository(t)
+	defer os.RemoveAll(root)
+
+	vol, err := repo.Create("data", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a volume left by the graph previously used to store volumes
+	legacyID := "2f9f8a7e6d5c4b3a29180716f5e4d3c2b1a09f8e7d6c5b4a3928170f6e5d4c3b"
+	if err := os.MkdirAll(filepath.Join(root, "vfs", "dir", legacyID), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "volumes", legacyID), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "volumes", legacyID, "json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err = newRepositoryIn(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := repo.Get("data")
+	if restored == nil || restored.ID != vol.ID || restored.Path != vol.Path {
+		t.Fatalf("Expected volume %v to be restored, got %v", vol, restored)
+	}
+	legacy := repo.Get(legacyID)
+	if legacy == nil || !legacy.IsAnonymous() || legacy.Driver != volumedriver.DefaultDriver {
+		t.Fatalf("Expected the legacy volume to be restored as an anonymous volume, got %v", legacy)
+	}
+}
+
+func TestCreateUnknownDriver(t *testing.T) {
+	repo, root := newTestRepository(t)
+	defer os.RemoveAll(root)
+
+	if _, err := repo.Create("data", "unknown"); err == nil {
+		t.Fatal("Expected an error when creating a volume with an unknown driver")
+	}
+	if repo.Get("data") != nil {
+		t.Fatal("Expected no volume to be created")
+	}
+}
+
+func TestMountRefcount(t *testing.T) {
+	repo, root := newTestRepository(t)
+	defer os.RemoveAll(root)
+
+	vol, err := repo.Create("data", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vol.Driver != volumedriver.DefaultDriver {
+		t.Fatalf("Expected driver %s, got %s", volumedriver.DefaultDriver, vol.Driver)
+	}
+	for i := 0; i < 2; i++ {
+		if err := repo.Mount(vol); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vol.mounts != 2 {
+		t.Fatalf("Expected 2 mounts, got %d", vol.mounts)
+	}
+	for i := 0; i < 3; i++ {
+		if err := repo.Unmount(vol); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vol.mounts != 0 {
+		t.Fatalf("Expected no mount, got %d", vol.mounts)
+	}
+}
@@ -0,0 +1,92 @@
+package volumes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Volume is a directory managed by docker which can be mounted into
+// containers. Named volumes are created explicitly or on first use by
+// name, anonymous ones are created for the VOLUME instructions of images.
+type Volume struct {
+	ID      string
+	Name    string
+	Driver  string
+	Path    string
+	Created time.Time
+
+	// containers holds the ids of the containers referencing the volume.
+	// It is not persisted and rebuilt from the containers when the daemon
+	// starts.
+	containers map[string]struct{}
+	// mounts counts the containers which mounted the volume through its
+	// driver
+	mounts     int
+	configPath string
+	lock       sync.Mutex
+}
+
+// IsAnonymous returns true if the volume was not given a name
+func (v *Volume) IsAnonymous() bool {
+	return v.Name == ""
+}
+
+func (v *Volume) AddContainer(containerID string) {
+	v.lock.Lock()
+	v.containers[containerID] = struct{}{}
+	v.lock.Unlock()
+}
+
+func (v *Volume) RemoveContainer(containerID string) {
+	v.lock.Lock()
+	delete(v.containers, containerID)
+	v.lock.Unlock()
+}
+
+// Containers returns the sorted ids of the containers using the volume
+func (v *Volume) Containers() []string {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	containers := make([]string, 0, len(v.containers))
+	for id := range v.containers {
+		containers = append(containers, id)
+	}
+	sort.Strings(containers)
+	return containers
+}
+
+// IsUsed returns true if at least one container references the volume
+func (v *Volume) IsUsed() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return len(v.containers) > 0
+}
+
+func (v *Volume) ToDisk() error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(v.configPath, 0750); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(v.jsonPath(), data, 0600)
+}
+
+func (v *Volume) FromDisk() error {
+	data, err := ioutil.ReadFile(v.jsonPath())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (v *Volume) jsonPath() string {
+	return filepath.Join(v.configPath, "config.json")
+}